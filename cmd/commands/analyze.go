@@ -1,9 +1,15 @@
 package commands
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/shieldcli/shieldcli/pkg/config"
 	"github.com/shieldcli/shieldcli/pkg/gemini"
 	"github.com/shieldcli/shieldcli/pkg/logging"
 	"github.com/spf13/cobra"
@@ -13,7 +19,9 @@ import (
 var analyzeCmd = &cobra.Command{
 	Use:   "analyze",
 	Short: "Analyze payloads and logs using AI",
-	Long:  `Use Gemini AI to analyze suspicious payloads and logs`,
+	Long: `Use AI to analyze suspicious payloads and logs. The provider is Gemini by
+default; set gemini.provider to openai to use any OpenAI-compatible chat
+completions endpoint instead (gemini.base_url, gemini.model, gemini.api_key).`,
 }
 
 var analyzePayloadCmd = &cobra.Command{
@@ -33,47 +41,60 @@ var analyzeLogCmd = &cobra.Command{
 	},
 }
 
+var analyzeDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the verdicts of two models on the same payloads",
+	Long: `Analyze every payload in a file (one per line) with two models and report the
+payloads whose verdict changed or whose confidence moved by at least
+--confidence-delta, to validate a model or prompt change.
+
+Example:
+  shieldcli analyze diff --input payloads.txt --model-a gemini-2.5-flash --model-b gemini-2.5-pro`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return analyzeDiff()
+	},
+}
+
 var (
-	logFilePath string
+	logFilePath    string
+	maxChunkTokens int
+
+	diffInput           string
+	diffModelA          string
+	diffModelB          string
+	diffConfidenceDelta float64
 )
 
 func init() {
 	analyzeCmd.AddCommand(analyzePayloadCmd)
 	analyzeCmd.AddCommand(analyzeLogCmd)
+	analyzeCmd.AddCommand(analyzeDiffCmd)
 
 	analyzeLogCmd.Flags().StringVar(&logFilePath, "log-file", "", "Path to the WAF log file")
+	analyzeLogCmd.Flags().IntVar(&maxChunkTokens, "max-chunk-tokens", 0, "Approximate token budget per log chunk sent to the model (default gemini.max_chunk_tokens or 8000)")
 	analyzeLogCmd.MarkFlagRequired("log-file")
+
+	analyzeDiffCmd.Flags().StringVar(&diffInput, "input", "", "File of payloads, one per line")
+	analyzeDiffCmd.Flags().StringVar(&diffModelA, "model-a", "", "Baseline model")
+	analyzeDiffCmd.Flags().StringVar(&diffModelB, "model-b", "", "Candidate model")
+	analyzeDiffCmd.Flags().Float64Var(&diffConfidenceDelta, "confidence-delta", 0.2, "Report payloads whose confidence changed by at least this much")
+	analyzeDiffCmd.MarkFlagRequired("input")
+	analyzeDiffCmd.MarkFlagRequired("model-a")
+	analyzeDiffCmd.MarkFlagRequired("model-b")
 }
 
 func analyzePayload(payload string) error {
-	// Get Gemini API key
-	geminiKey := os.Getenv("GEMINI_API_KEY")
-	if geminiKey == "" {
-		geminiKey = viper.GetString("gemini.api_key")
-	}
-
-	if geminiKey == "" {
-		return fmt.Errorf("Gemini API key not found. Set GEMINI_API_KEY environment variable or configure it in shieldcli.yaml")
-	}
-
 	// Create logger
 	logger := logging.NewLogger("")
 	defer logger.Close()
 
-	// Create Gemini client
-	model := viper.GetString("gemini.model")
-	if model == "" {
-		model = "gemini-2.5-flash"
-	}
-
-	client, err := gemini.NewClient(geminiKey, model, logger)
+	client, err := newAnalyzer("", "", logger)
 	if err != nil {
-		logger.Error("Failed to create Gemini client: %v", err)
 		return err
 	}
 	defer client.Close()
 
-	logger.Info("Analyzing payload with Gemini AI...")
+	logger.Info("Analyzing payload with %s...", providerName())
 
 	// Analyze the payload
 	result, err := client.AnalyzePayload(payload)
@@ -82,6 +103,10 @@ func analyzePayload(payload string) error {
 		return err
 	}
 
+	if result.ParseError != nil {
+		logger.Warn("Model response was not valid JSON, results may be incomplete: %v", result.ParseError)
+	}
+
 	// Display results
 	fmt.Println("\n=== Payload Analysis Results ===")
 	fmt.Printf("Verdict: %s\n", result.Verdict)
@@ -101,45 +126,105 @@ func analyzePayload(payload string) error {
 	return nil
 }
 
-func analyzeLog() error {
-	// Get Gemini API key
-	geminiKey := os.Getenv("GEMINI_API_KEY")
-	if geminiKey == "" {
-		geminiKey = viper.GetString("gemini.api_key")
+// resolveGeminiKey resolves the API key from the flag value and the
+// api_key_file, api_key_env, GEMINI_API_KEY and api_key settings, in that
+// order. For the openai provider OPENAI_API_KEY replaces GEMINI_API_KEY.
+func resolveGeminiKey(flagValue string) (string, error) {
+	defaultEnv := config.DefaultGeminiKeyEnv
+	if usesOpenAI() {
+		defaultEnv = config.DefaultOpenAIKeyEnv
 	}
+	return config.ResolveGeminiKey(config.GeminiKeySources{
+		Flag:       flagValue,
+		KeyFile:    viper.GetString("gemini.api_key_file"),
+		KeyEnv:     viper.GetString("gemini.api_key_env"),
+		Literal:    viper.GetString("gemini.api_key"),
+		DefaultEnv: defaultEnv,
+	})
+}
 
-	if geminiKey == "" {
-		return fmt.Errorf("Gemini API key not found. Set GEMINI_API_KEY environment variable or configure it in shieldcli.yaml")
-	}
+// usesOpenAI reports whether gemini.provider selects an OpenAI-compatible
+// endpoint instead of Gemini
+func usesOpenAI() bool {
+	return strings.EqualFold(viper.GetString("gemini.provider"), gemini.ProviderOpenAI)
+}
 
-	// Create logger
-	logger := logging.NewLogger("")
-	defer logger.Close()
+// providerName names the configured AI provider in messages
+func providerName() string {
+	if usesOpenAI() {
+		return "the OpenAI-compatible endpoint"
+	}
+	return "Gemini AI"
+}
 
-	// Read log file
-	logData, err := os.ReadFile(logFilePath)
+// newAnalyzer creates the client of the configured gemini.provider, using
+// flagKey as the --gemini-key flag and model or, if empty, gemini.model. Gemini needs an API key; a self-hosted
+// OpenAI-compatible endpoint may not. Payload analyses are cached per
+// gemini.cache_size and gemini.cache_ttl_seconds.
+func newAnalyzer(flagKey, model string, logger *logging.Logger) (gemini.Analyzer, error) {
+	key, err := resolveGeminiKey(flagKey)
 	if err != nil {
-		logger.Error("Failed to read log file: %v", err)
-		return err
+		return nil, err
+	}
+
+	if key == "" && !usesOpenAI() {
+		return nil, fmt.Errorf("Gemini API key not found. Set GEMINI_API_KEY environment variable or configure it in shieldcli.yaml")
 	}
 
-	// Create Gemini client
-	model := viper.GetString("gemini.model")
 	if model == "" {
+		model = viper.GetString("gemini.model")
+	}
+	if model == "" && !usesOpenAI() {
 		model = "gemini-2.5-flash"
 	}
 
-	client, err := gemini.NewClient(geminiKey, model, logger)
+	client, err := gemini.NewAnalyzer(gemini.AnalyzerConfig{
+		Provider: viper.GetString("gemini.provider"),
+		APIKey:   key,
+		Model:    model,
+		BaseURL:  viper.GetString("gemini.base_url"),
+	}, logger)
+	if err != nil {
+		logger.Error("Failed to create AI client: %v", err)
+		return nil, err
+	}
+
+	// Cache payload analyses unless gemini.cache_size is set to 0
+	cacheSize := gemini.DefaultCacheSize
+	if viper.IsSet("gemini.cache_size") {
+		cacheSize = viper.GetInt("gemini.cache_size")
+	}
+	if cacheSize <= 0 {
+		return client, nil
+	}
+	cacheTTL := gemini.DefaultCacheTTL
+	if viper.IsSet("gemini.cache_ttl_seconds") {
+		cacheTTL = time.Duration(viper.GetInt("gemini.cache_ttl_seconds")) * time.Second
+	}
+	return gemini.NewCachedAnalyzer(client, model, cacheSize, cacheTTL), nil
+}
+
+func analyzeLog() error {
+	// Create logger
+	logger := logging.NewLogger("")
+	defer logger.Close()
+
+	if maxChunkTokens <= 0 {
+		maxChunkTokens = viper.GetInt("gemini.max_chunk_tokens")
+	}
+	if maxChunkTokens <= 0 {
+		maxChunkTokens = gemini.DefaultMaxChunkTokens
+	}
+
+	client, err := newAnalyzer("", "", logger)
 	if err != nil {
-		logger.Error("Failed to create Gemini client: %v", err)
 		return err
 	}
 	defer client.Close()
 
 	logger.Info("Summarizing attack trends...")
 
-	// Summarize attacks
-	summary, err := client.SummarizeAttacks(string(logData))
+	summary, err := summarizeLog(client, logger)
 	if err != nil {
 		logger.Error("Failed to summarize attacks: %v", err)
 		return err
@@ -151,3 +236,127 @@ func analyzeLog() error {
 
 	return nil
 }
+
+// summarizeLog summarizes the log file. Structured event logs are
+// aggregated into a brief of their salient figures first; other logs are
+// streamed to the model in chunks and the partial summaries combined.
+func summarizeLog(client gemini.Analyzer, logger *logging.Logger) (string, error) {
+	brief, err := gemini.BuildEventBrief(logFilePath)
+	if err == nil {
+		logger.Info("Structured event log: summarizing a %d-token brief of it", gemini.EstimateTokens(brief))
+		return client.SummarizeEventBrief(brief)
+	}
+	if !errors.Is(err, gemini.ErrNotEventLog) {
+		logger.Error("Failed to read log file: %v", err)
+		return "", err
+	}
+
+	// Open log file; it is streamed in chunks rather than read at once
+	logData, err := os.Open(logFilePath)
+	if err != nil {
+		logger.Error("Failed to read log file: %v", err)
+		return "", err
+	}
+	defer logData.Close()
+
+	chunker := gemini.NewLogChunker(logData, maxChunkTokens)
+	return client.SummarizeAttacksChunked(chunker, maxChunkTokens, func(chunk int) {
+		logger.Info("Summarized log chunk %d", chunk)
+	})
+}
+
+func analyzeDiff() error {
+	payloads, err := readPayloadLines(diffInput)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	logger := logging.NewLogger("")
+	defer logger.Close()
+
+	clientA, err := newAnalyzer("", diffModelA, logger)
+	if err != nil {
+		return err
+	}
+	defer clientA.Close()
+
+	clientB, err := newAnalyzer("", diffModelB, logger)
+	if err != nil {
+		return err
+	}
+	defer clientB.Close()
+
+	logger.Info("Analyzing %d payloads with %s and %s...", len(payloads), diffModelA, diffModelB)
+
+	diffs, err := gemini.DiffAnalyses(clientA, clientB, payloads, diffConfidenceDelta)
+	if err != nil {
+		logger.Error("Failed to compare models: %v", err)
+		return err
+	}
+
+	fmt.Println("\n=== Model Verdict Diff ===")
+	fmt.Printf("Model A: %s\n", diffModelA)
+	fmt.Printf("Model B: %s\n", diffModelB)
+	fmt.Printf("Disagreements: %d of %d payloads\n", len(diffs), len(payloads))
+
+	if len(diffs) == 0 {
+		fmt.Println("\n✓ Both models agree on every payload.")
+		return nil
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Payload\tVerdict A\tVerdict B\tConfidence A\tConfidence B\tChange")
+	fmt.Fprintln(w, "-------\t---------\t---------\t------------\t------------\t------")
+	for _, diff := range diffs {
+		change := "confidence"
+		if diff.VerdictChanged {
+			change = "verdict"
+		}
+		fmt.Fprintf(w, "%q\t%s\t%s\t%.2f\t%.2f\t%s\n",
+			truncatePayload(diff.Payload, 40),
+			diff.A.Verdict,
+			diff.B.Verdict,
+			diff.A.Confidence,
+			diff.B.Confidence,
+			change,
+		)
+	}
+	w.Flush()
+
+	return nil
+}
+
+// readPayloadLines reads the non-blank lines of a payload file
+func readPayloadLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open payload file: %w", err)
+	}
+	defer file.Close()
+
+	var payloads []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			payloads = append(payloads, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read payload file: %w", err)
+	}
+	if len(payloads) == 0 {
+		return nil, fmt.Errorf("no payloads in %s", path)
+	}
+	return payloads, nil
+}
+
+// truncatePayload shortens a payload for table output
+func truncatePayload(payload string, max int) string {
+	if len(payload) <= max {
+		return payload
+	}
+	return payload[:max] + "..."
+}
@@ -2,11 +2,14 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"text/tabwriter"
+	"time"
 
 	"github.com/shieldcli/shieldcli/pkg/anomaly"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var anomalyCmd = &cobra.Command{
@@ -18,6 +21,16 @@ var anomalyCmd = &cobra.Command{
 var anomalyReportCmd = &cobra.Command{
 	Use:   "report",
 	Short: "Generate an anomaly detection report",
+	Long: `Generate an anomaly detection report grouped by severity, of the recent
+anomalies in the anomaly state file 'run' saves (anomaly.state_file in the
+config, or --state-file): those still counted by the risk score.
+
+With --output, the anomaly list is also written to a file for pipelines, as
+JSON (an array of objects) or CSV (columns timestamp, type, severity, value,
+threshold, description).
+
+Example:
+  shieldcli anomaly report --output anomalies.csv --format csv`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return generateAnomalyReport()
 	},
@@ -26,22 +39,85 @@ var anomalyReportCmd = &cobra.Command{
 var anomalyStatsCmd = &cobra.Command{
 	Use:   "stats",
 	Short: "Display traffic statistics",
+	Long: `Display the traffic statistics in the anomaly state file 'run' saves
+(anomaly.state_file in the config, or --state-file).`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return displayAnomalyStats()
 	},
 }
 
+var anomalyPathsCmd = &cobra.Command{
+	Use:   "paths",
+	Short: "Display per-endpoint traffic baselines",
+	Long: `Display the payload size and entropy baseline 'run' learned for each
+endpoint, busiest first, from the anomaly state file. Paths are normalized:
+numeric, UUID and long hex segments show as ":id".
+
+Example:
+  shieldcli anomaly paths --state-file shieldcli.anomaly.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return displayPathProfiles()
+	},
+}
+
+var anomalyExportPrometheusCmd = &cobra.Command{
+	Use:   "export-prometheus",
+	Short: "Push anomaly counts and traffic statistics to a Prometheus Pushgateway",
+	Long: `Push anomaly counts (labelled by type and severity) and traffic statistics
+to a Prometheus Pushgateway, for batch or cron-driven anomaly analysis. The
+metrics come from the anomaly state file 'run' saves (anomaly.state_file in
+the config, or --state-file).
+
+Example:
+  shieldcli anomaly export-prometheus --gateway http://localhost:9091 --job shieldcli_anomaly`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exportAnomalyPrometheus()
+	},
+}
+
+var (
+	anomalyStateFile string
+
+	pushGatewayURL string
+	pushJobName    string
+
+	anomalyOutput string
+	anomalyFormat string
+)
+
 func init() {
 	anomalyCmd.AddCommand(anomalyReportCmd)
 	anomalyCmd.AddCommand(anomalyStatsCmd)
+	anomalyCmd.AddCommand(anomalyPathsCmd)
+	anomalyCmd.AddCommand(anomalyExportPrometheusCmd)
+
+	anomalyCmd.PersistentFlags().StringVar(&anomalyStateFile, "state-file", "", "Anomaly state file saved by 'run' (default: anomaly.state_file from the config)")
+
+	anomalyReportCmd.Flags().StringVarP(&anomalyOutput, "output", "o", "", "Also write the anomaly list to this file")
+	anomalyReportCmd.Flags().StringVar(&anomalyFormat, "format", "json", "Output file format: json, csv")
+
+	anomalyExportPrometheusCmd.Flags().StringVar(&pushGatewayURL, "gateway", "", "Pushgateway base URL (e.g. http://localhost:9091)")
+	anomalyExportPrometheusCmd.Flags().StringVar(&pushJobName, "job", "shieldcli_anomaly", "Job name to push metrics under")
+	anomalyExportPrometheusCmd.MarkFlagRequired("gateway")
 }
 
 func generateAnomalyReport() error {
-	// Create a detector with a 1-minute window
-	detector := anomaly.NewAnomalyDetector(60 * 60) // 1 hour window
+	detector, err := loadAnomalyDetector()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	// Drained anomalies were only logged; the risk history still has them
+	anomalies := detector.RecentAnomalies()
 
-	// Get all anomalies
-	anomalies := detector.GetAnomalies()
+	if anomalyOutput != "" {
+		if err := writeAnomalyFile(anomalies); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return err
+		}
+		fmt.Printf("✓ Wrote %d anomalies to %s (%s)\n", len(anomalies), anomalyOutput, anomalyFormat)
+	}
 
 	if len(anomalies) == 0 {
 		fmt.Println("No anomalies detected.")
@@ -80,8 +156,38 @@ func generateAnomalyReport() error {
 	return nil
 }
 
+// writeAnomalyFile writes anomalies to the --output file in the --format format
+func writeAnomalyFile(anomalies []anomaly.Anomaly) error {
+	var write func(io.Writer, []anomaly.Anomaly) error
+	switch anomalyFormat {
+	case "json":
+		write = anomaly.WriteAnomaliesJSON
+	case "csv":
+		write = anomaly.WriteAnomaliesCSV
+	default:
+		return fmt.Errorf("unsupported output format: %s (use json or csv)", anomalyFormat)
+	}
+
+	f, err := os.Create(anomalyOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	if err := write(f, anomalies); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
 func displayAnomalyStats() error {
-	detector := anomaly.NewAnomalyDetector(60 * 60)
+	detector, err := loadAnomalyDetector()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
 	stats := detector.GetStatistics()
 
 	fmt.Println("\n=== Traffic Statistics ===")
@@ -93,6 +199,80 @@ func displayAnomalyStats() error {
 	fmt.Printf("Large Payloads: %v\n", stats["large_payloads"])
 	fmt.Printf("Encoded Payloads: %v\n", stats["encoded_payloads"])
 	fmt.Printf("Total Anomalies: %v\n", stats["total_anomalies"])
+	fmt.Printf("Risk Score: %.2f\n", stats["risk_score"])
+
+	return nil
+}
+
+// loadAnomalyDetector returns a detector holding the state 'run' saved to
+// --state-file, or else to the config's anomaly.state_file, scoring risk as
+// the config says
+func loadAnomalyDetector() (*anomaly.AnomalyDetector, error) {
+	path := anomalyStateFile
+	if path == "" {
+		path = viper.GetString("anomaly.state_file")
+	}
+	if path == "" {
+		return nil, fmt.Errorf("no anomaly state file: set anomaly.state_file in the config or pass --state-file")
+	}
+	// LoadState treats a missing file as a fresh start, which here would
+	// only report nothing
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to read anomaly state file: %w", err)
+	}
+
+	detector := anomaly.NewAnomalyDetector(time.Hour)
+	err := detector.Configure(anomaly.DetectorConfig{
+		RiskWeights:  configRiskWeights(),
+		RiskHalfLife: time.Duration(viper.GetInt("anomaly.risk_half_life_seconds")) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := detector.LoadState(path); err != nil {
+		return nil, err
+	}
+	return detector, nil
+}
+
+func displayPathProfiles() error {
+	detector, err := loadAnomalyDetector()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	profiles := detector.GetPathProfiles()
+	if len(profiles) == 0 {
+		fmt.Println("No endpoint baselines recorded.")
+		return nil
+	}
+
+	fmt.Println("\n=== Endpoint Baselines ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Path\tRequests\tAvg Size\tSize StdDev\tAvg Entropy\tEntropy StdDev")
+	fmt.Fprintln(w, "----\t--------\t--------\t-----------\t-----------\t--------------")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\n",
+			p.Path, p.Requests, p.AveragePayloadSize, p.PayloadSizeStdDev, p.AverageEntropy, p.EntropyStdDev)
+	}
+	w.Flush()
+
+	return nil
+}
+
+func exportAnomalyPrometheus() error {
+	detector, err := loadAnomalyDetector()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	if err := detector.PushToGateway(pushGatewayURL, pushJobName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
 
+	fmt.Printf("Pushed anomaly metrics to %s (job %q)\n", pushGatewayURL, pushJobName)
 	return nil
 }
@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/anomaly"
+	"github.com/spf13/viper"
+)
+
+func TestLoadAnomalyDetector(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "anomaly.json")
+	saved := anomaly.NewAnomalyDetector(time.Minute)
+	saved.RecordPathRequest("req-1", "/users/42", "192.0.2.1", "Mozilla/5.0", 100, 3)
+	if err := saved.SaveState(statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		flag        string
+		configValue string
+		wantErr     bool
+	}{
+		{name: "state file flag", flag: statePath},
+		{name: "config state file", configValue: statePath},
+		{name: "flag wins over config", flag: statePath, configValue: filepath.Join(dir, "other.json")},
+		{name: "missing state file", flag: filepath.Join(dir, "missing.json"), wantErr: true},
+		{name: "no state file", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomalyStateFile = tt.flag
+			viper.Set("anomaly.state_file", tt.configValue)
+			t.Cleanup(func() {
+				anomalyStateFile = ""
+				viper.Set("anomaly.state_file", "")
+			})
+
+			detector, err := loadAnomalyDetector()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("loadAnomalyDetector succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadAnomalyDetector: %v", err)
+			}
+			profiles := detector.GetPathProfiles()
+			if len(profiles) != 1 || profiles[0].Path != "/users/:id" {
+				t.Errorf("path profiles %+v, want /users/:id", profiles)
+			}
+		})
+	}
+}
+
+func TestLoadAnomalyDetectorRiskWeights(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "anomaly.json")
+	saved := anomaly.NewAnomalyDetector(time.Minute)
+	saved.RecordRequest("req-1", "192.0.2.1", "sqlmap/1.7", 10, 1)
+	saved.DrainAnomalies()
+	if err := saved.SaveState(statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		weights map[string]interface{}
+		want    float64
+	}{
+		{name: "default weights", want: 1},
+		{name: "configured weight", weights: map[string]interface{}{"low": 4}, want: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomalyStateFile = statePath
+			viper.Set("anomaly.risk_weights", tt.weights)
+			t.Cleanup(func() {
+				anomalyStateFile = ""
+				viper.Set("anomaly.risk_weights", nil)
+			})
+
+			detector, err := loadAnomalyDetector()
+			if err != nil {
+				t.Fatalf("loadAnomalyDetector: %v", err)
+			}
+			if got := detector.RiskScore(); got < tt.want*0.99 || got > tt.want {
+				t.Errorf("risk score %.3f, want about %.0f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateAnomalyReportOutput(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "anomaly.json")
+	saved := anomaly.NewAnomalyDetector(time.Minute)
+	saved.RecordRequest("req-1", "192.0.2.1", "sqlmap/1.7", 10, 1)
+	// run drains anomalies as it logs them; the report must still have them
+	saved.DrainAnomalies()
+	if err := saved.SaveState(statePath); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{name: "json", format: "json", want: `"request_id": "req-1"`},
+		{name: "csv", format: "csv", want: "Suspicious user agent: sqlmap/1.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anomalyStateFile = statePath
+			anomalyOutput = filepath.Join(dir, "report."+tt.format)
+			anomalyFormat = tt.format
+			t.Cleanup(func() {
+				anomalyStateFile = ""
+				anomalyOutput = ""
+				anomalyFormat = "json"
+			})
+
+			if err := generateAnomalyReport(); err != nil {
+				t.Fatalf("generateAnomalyReport: %v", err)
+			}
+			data, err := os.ReadFile(anomalyOutput)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("report %q does not contain %q", data, tt.want)
+			}
+		})
+	}
+}
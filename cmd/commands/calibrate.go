@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/anomaly"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// printCalibrationReport prints the thresholds recommended by run
+// --learn-and-report along with the rules that matched the observed traffic,
+// which is assumed benign
+func printCalibrationReport(report anomaly.CalibrationReport, engine *waf.Engine) {
+	fmt.Println("\n=== Calibration Report ===")
+	fmt.Printf("Observed: %d requests over %s\n", report.Requests, report.Duration.Round(time.Second))
+	if report.Requests == 0 {
+		fmt.Println("No traffic observed; nothing to recommend.")
+		return
+	}
+	fmt.Printf("Unique IPs: %d\n", report.UniqueIPs)
+	fmt.Printf("Unique User-Agents: %d (most common: %q, %.1f%% of requests)\n",
+		report.UniqueUserAgents, report.TopUserAgent, float64(report.TopUserAgentHits)/float64(report.Requests)*100)
+
+	fmt.Printf("\nRecommended anomaly thresholds (observed maximum + %.0f%%):\n", report.Margin*100)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tP50\tP99\tMAX\tRECOMMENDED\tDEFAULT")
+	fmt.Fprintln(w, "------\t---\t---\t---\t-----------\t-------")
+	fmt.Fprintf(w, "Payload size (bytes)\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\n",
+		report.PayloadSize.P50, report.PayloadSize.P99, report.PayloadSize.Max,
+		report.RecommendedPayloadSize, float64(anomaly.DefaultPayloadSizeThreshold))
+	fmt.Fprintf(w, "Request rate (req/s)\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\n",
+		report.RequestRate.P50, report.RequestRate.P99, report.RequestRate.Max,
+		report.RecommendedRequestRate, anomaly.DefaultRequestRateThreshold)
+	fmt.Fprintf(w, "Payload entropy (bits/char)\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\n",
+		report.Entropy.P50, report.Entropy.P99, report.Entropy.Max,
+		report.RecommendedEntropy, anomaly.DefaultEntropyThreshold)
+	w.Flush()
+	fmt.Println("Apply them with anomaly.payload_size_threshold, anomaly.request_rate_threshold and anomaly.entropy_threshold.")
+
+	stats := engine.GetRuleStats()
+	if len(stats) == 0 {
+		fmt.Println("\n✓ No rule matched the observed traffic.")
+		return
+	}
+
+	names := make(map[int]string)
+	for _, rule := range engine.GetRules() {
+		names[rule.ID] = rule.Name
+	}
+	ids := make([]int, 0, len(stats))
+	for id := range stats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if stats[ids[i]] != stats[ids[j]] {
+			return stats[ids[i]] > stats[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	fmt.Println("\n⚠️  Rules that matched benign traffic (likely false positive sources):")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tMATCHES\tSHARE")
+	fmt.Fprintln(w, "--\t----\t-------\t-----")
+	for _, id := range ids {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.1f%%\n", id, names[id], stats[id], float64(stats[id])/float64(report.Requests)*100)
+	}
+	w.Flush()
+}
@@ -1,11 +1,18 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/gemini"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/proxy"
+	"github.com/shieldcli/shieldcli/pkg/waf"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var configCmd = &cobra.Command{
@@ -30,19 +37,49 @@ var configExportCmd = &cobra.Command{
 	},
 }
 
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the configuration file with secrets redacted",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configShow()
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Write a JSON Schema of the configuration file",
+	Long: `Write a JSON Schema describing shieldcli.yaml, for validation and
+autocompletion in editors. The schema is generated from the configuration
+structure and lists the valid values of rule fields.
+
+Example:
+  shieldcli config schema --out shieldcli.schema.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return configSchema()
+	},
+}
+
 var (
+	schemaOutput string
+
 	outputFile string
 	exportFormat string
+	revealSecrets bool
 )
 
 func init() {
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configSchemaCmd)
 
 	configInitCmd.Flags().StringVar(&outputFile, "output", "shieldcli.yaml", "Output file path")
 	configExportCmd.Flags().StringVar(&outputFile, "output", "", "Output file path")
-	configExportCmd.Flags().StringVar(&exportFormat, "format", "terraform", "Export format: terraform, dockerfile")
+	configExportCmd.Flags().StringVar(&exportFormat, "format", "terraform", "Export format: terraform, dockerfile, yaml")
+	configExportCmd.Flags().BoolVar(&revealSecrets, "reveal-secrets", false, "Include secrets such as the Gemini API key instead of masking them")
+	configShowCmd.Flags().BoolVar(&revealSecrets, "reveal-secrets", false, "Include secrets such as the Gemini API key instead of masking them")
 	configExportCmd.MarkFlagRequired("output")
+	configSchemaCmd.Flags().StringVar(&schemaOutput, "out", "shieldcli.schema.json", "Output file path")
 }
 
 func configInit() error {
@@ -74,6 +111,8 @@ func configInit() error {
 	cfg.Gemini.Model = "gemini-2.5-flash"
 	cfg.Gemini.Enabled = true
 	cfg.Gemini.AnalysisThreshold = 5
+	cfg.Gemini.CacheSize = gemini.DefaultCacheSize
+	cfg.Gemini.CacheTTLSeconds = int(gemini.DefaultCacheTTL / time.Second)
 
 	// Save configuration
 	if err := config.SaveConfigFile(outputFile, cfg); err != nil {
@@ -94,6 +133,10 @@ func configExport() error {
 		return err
 	}
 
+	if !revealSecrets {
+		cfgFile = cfgFile.Redacted()
+	}
+
 	var exportContent string
 
 	switch exportFormat {
@@ -101,6 +144,12 @@ func configExport() error {
 		exportContent = generateTerraformConfig(cfgFile)
 	case "dockerfile":
 		exportContent = generateDockerfileConfig(cfgFile)
+	case "yaml":
+		data, err := yaml.Marshal(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		exportContent = string(data)
 	default:
 		return fmt.Errorf("unsupported export format: %s", exportFormat)
 	}
@@ -115,6 +164,32 @@ func configExport() error {
 	return nil
 }
 
+func configShow() error {
+	path := cfgFile
+	if path == "" {
+		path = "shieldcli.yaml"
+	}
+
+	cfg, err := config.LoadConfigFile(path)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		return err
+	}
+
+	if !revealSecrets {
+		cfg = cfg.Redacted()
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	fmt.Printf("# %s\n", path)
+	fmt.Print(string(data))
+	return nil
+}
+
 func generateTerraformConfig(cfg *config.ConfigFile) string {
 	return fmt.Sprintf(`# ShieldCLI Terraform Configuration
 # This is an example Terraform configuration for deploying ShieldCLI
@@ -172,3 +247,25 @@ ENV LISTEN_PORT=%d
 ENTRYPOINT ["./shieldcli", "run", "--proxy-to", "$PROXY_TO", "--port", "$LISTEN_PORT"]
 `, cfg.Proxy.ListenPort, cfg.Proxy.TargetURL, cfg.Proxy.ListenPort)
 }
+
+func configSchema() error {
+	enums := waf.ConfigEnums()
+	enums["proxy.plaintext_policy"] = []string{proxy.PlaintextAllow, proxy.PlaintextRedirect, proxy.PlaintextBlock}
+	enums["waf.fail_mode"] = []string{proxy.FailOpen, proxy.FailClosed}
+	enums["logging.terminal_level"] = []string{"debug", "info", "warn", "error"}
+	enums["logging.file_format"] = []string{"json", "text"}
+	enums["logging.stdout_format"] = []string{logging.StdoutPretty, logging.StdoutJSON}
+
+	data, err := json.MarshalIndent(config.Schema(enums), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+
+	if err := os.WriteFile(schemaOutput, append(data, '\n'), 0644); err != nil {
+		fmt.Printf("Error writing schema file: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("Configuration schema written to: %s\n", schemaOutput)
+	return nil
+}
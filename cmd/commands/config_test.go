@@ -0,0 +1,237 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigSecretsRedacted(t *testing.T) {
+	const key = "AIzaSy-test-secret-key"
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeFile(t, filepath.Join(dir, "shieldcli.yaml"), "gemini:\n  api_key: "+key+"\n  model: gemini-2.5-flash\n")
+
+	showOutput := func() (string, error) {
+		return captureStdout(t, configShow), nil
+	}
+	exportOutput := func() (string, error) {
+		exportFormat, outputFile = "yaml", filepath.Join(dir, "export.yaml")
+		if err := configExport(); err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(outputFile)
+		return string(data), err
+	}
+	t.Cleanup(func() {
+		revealSecrets, exportFormat, outputFile = false, "terraform", ""
+	})
+
+	tests := []struct {
+		name   string
+		run    func() (string, error)
+		reveal bool
+	}{
+		{name: "show", run: showOutput},
+		{name: "show --reveal-secrets", run: showOutput, reveal: true},
+		{name: "export", run: exportOutput},
+		{name: "export --reveal-secrets", run: exportOutput, reveal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revealSecrets = tt.reveal
+			out, err := tt.run()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := strings.Contains(out, key); got != tt.reveal {
+				t.Errorf("key in output %v, want %v:\n%s", got, tt.reveal, out)
+			}
+			if !tt.reveal && !strings.Contains(out, config.RedactedValue) {
+				t.Errorf("output has no masked api_key:\n%s", out)
+			}
+			if !strings.Contains(out, "model: gemini-2.5-flash") {
+				t.Errorf("output lost the other settings:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestConfigSchema(t *testing.T) {
+	dir := t.TempDir()
+	schemaOutput = filepath.Join(dir, "shieldcli.schema.json")
+	t.Cleanup(func() { schemaOutput = "shieldcli.schema.json" })
+	captureStdout(t, configSchema)
+
+	data, err := os.ReadFile(schemaOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	example, err := os.ReadFile(filepath.Join("..", "..", "shieldcli.yaml.example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		config     string
+		wantErrors []string
+	}{
+		{name: "example config", config: string(example)},
+		{
+			name: "custom rule",
+			config: `custom_rules:
+  - id: 9100
+    name: Admin Probe
+    phase: request_uri
+    operator: regex
+    target: REQUEST_URI
+    pattern: ^/admin
+    action: block
+    severity: high
+    enabled: true
+    transformations: [url_decode, lowercase]
+`,
+		},
+		{
+			name:       "unknown key",
+			config:     "proxy:\n  tagret_url: http://localhost:3000\n",
+			wantErrors: []string{"proxy.tagret_url: unknown key"},
+		},
+		{
+			name:       "wrong type",
+			config:     "proxy:\n  listen_port: eighty\n",
+			wantErrors: []string{"proxy.listen_port: got string, want integer"},
+		},
+		{
+			name: "invalid rule values",
+			config: `custom_rules:
+  - name: Admin Probe
+    phase: request_uri
+    operator: like
+    action: block
+    transformations: [rot13]
+`,
+			wantErrors: []string{
+				"custom_rules[0]: missing required key id",
+				`custom_rules[0].operator: "like" not in enum`,
+				`custom_rules[0].transformations[0]: "rot13" not in enum`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc interface{}
+			if err := yaml.Unmarshal([]byte(tt.config), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			errs := validateSchema(schema, doc, "")
+			if len(tt.wantErrors) == 0 && len(errs) > 0 {
+				t.Errorf("valid config failed the schema: %q", errs)
+			}
+			for _, want := range tt.wantErrors {
+				found := false
+				for _, err := range errs {
+					found = found || strings.HasPrefix(err, want)
+				}
+				if !found {
+					t.Errorf("schema errors %q, want one starting with %q", errs, want)
+				}
+			}
+		})
+	}
+}
+
+// validateSchema checks value against the JSON Schema keywords config schema
+// emits and returns the violations, each prefixed with its path
+func validateSchema(schema map[string]interface{}, value interface{}, path string) []string {
+	var errs []string
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, v := range enum {
+			found = found || v == value
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %q not in enum", path, value))
+		}
+	}
+
+	want := schema["type"]
+	if types, ok := want.([]interface{}); ok {
+		if value == nil {
+			for _, t := range types {
+				if t == "null" {
+					return errs
+				}
+			}
+		}
+		want = types[0]
+	}
+
+	switch want {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: got %T, want object", path, value))
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for key, v := range obj {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			if prop, ok := properties[key].(map[string]interface{}); ok {
+				errs = append(errs, validateSchema(prop, v, keyPath)...)
+			} else if extra, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				errs = append(errs, validateSchema(extra, v, keyPath)...)
+			} else if schema["additionalProperties"] == false {
+				errs = append(errs, fmt.Sprintf("%s: unknown key", keyPath))
+			}
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, key := range required {
+			if _, ok := obj[key.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required key %s", path, key))
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return append(errs, fmt.Sprintf("%s: got %T, want array", path, value))
+		}
+		for i, item := range items {
+			errs = append(errs, validateSchema(schema["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string", "boolean", "integer", "number":
+		got := ""
+		switch value.(type) {
+		case string:
+			got = "string"
+		case bool:
+			got = "boolean"
+		case int, int64, uint64:
+			got = "integer"
+		case float64:
+			got = "number"
+		}
+		if got != want && !(want == "number" && got == "integer") {
+			errs = append(errs, fmt.Sprintf("%s: got %s, want %s", path, got, want))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Work with structured event logs",
+	Long:  `Utilities for the structured event files written with --structured-events`,
+}
+
+var logsMergeCmd = &cobra.Command{
+	Use:   "merge [files...]",
+	Short: "Merge event files sorted by timestamp, dropping duplicates",
+	Long: `Consolidate event files from rotated logs or several instances into one
+JSON lines file, sorted by timestamp and de-duplicated by event_id. Inputs may
+be JSON lines or JSON arrays, optionally gzip-compressed.
+
+Example:
+  shieldcli logs merge --out merged.jsonl waf.events.jsonl waf.events.1.jsonl.gz`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return logsMerge(args)
+	},
+}
+
+var logsExportFPCmd = &cobra.Command{
+	Use:   "export-fp",
+	Short: "Export blocked events labeled benign as false positives for review",
+	Long: `Select the blocked events an analyst labeled benign ("label": "benign") and
+write them as benign test-suite corpus entries, so they can be reviewed, turned
+into exclusions, and kept as regression cases for 'shieldcli waf test-suite'.
+With --suspected, unlabeled blocked events from the same rule on the same path
+are exported too.
+
+Example:
+  shieldcli logs export-fp --input waf.events.jsonl --out fp.jsonl --suspected`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return logsExportFP()
+	},
+}
+
+var (
+	mergeOut string
+
+	fpInput     string
+	fpOut       string
+	fpSuspected bool
+)
+
+func init() {
+	logsCmd.AddCommand(logsMergeCmd)
+	logsCmd.AddCommand(logsExportFPCmd)
+
+	logsMergeCmd.Flags().StringVar(&mergeOut, "out", "", "Merged output file (required)")
+	logsMergeCmd.MarkFlagRequired("out")
+
+	logsExportFPCmd.Flags().StringVar(&fpInput, "input", "", "Event file with labeled events (required)")
+	logsExportFPCmd.Flags().StringVar(&fpOut, "out", "", "Output corpus file (required)")
+	logsExportFPCmd.Flags().BoolVar(&fpSuspected, "suspected", false, "Also export unlabeled events blocked by the same rule on the same path")
+	logsExportFPCmd.MarkFlagRequired("input")
+	logsExportFPCmd.MarkFlagRequired("out")
+}
+
+func logsMerge(files []string) error {
+	out, err := os.Create(mergeOut)
+	if err != nil {
+		fmt.Printf("Error: failed to create output file: %v\n", err)
+		return err
+	}
+	defer out.Close()
+
+	stats, err := logging.MergeEventFiles(out, files)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("Merged %d files into %s\n", len(files), mergeOut)
+	fmt.Printf("Events read: %d\n", stats.Read)
+	fmt.Printf("Events written: %d\n", stats.Written)
+	fmt.Printf("Duplicates dropped: %d\n", stats.Duplicates)
+
+	return nil
+}
+
+func logsExportFP() error {
+	events, err := logging.ReadEvents(fpInput)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	selected := logging.SelectFalsePositives(events, fpSuspected)
+
+	out, err := os.Create(fpOut)
+	if err != nil {
+		fmt.Printf("Error: failed to create output file: %v\n", err)
+		return err
+	}
+	defer out.Close()
+
+	bw := bufio.NewWriter(out)
+	enc := json.NewEncoder(bw)
+	suspected := 0
+	for _, fp := range selected {
+		name := fmt.Sprintf("rule %d false positive (event %s)", fp.Event.RuleID, fp.Event.EventID)
+		if fp.Suspected {
+			name = "suspected " + name
+			suspected++
+		}
+		entry := waf.CorpusEntry{
+			Name:       name,
+			Label:      waf.LabelBenign,
+			Method:     fp.Event.Method,
+			URL:        fp.Event.URL,
+			RemoteAddr: fp.Event.SourceIP,
+		}
+		if err := enc.Encode(entry); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("Events read: %d\n", len(events))
+	fmt.Printf("False positives exported: %d (%d suspected)\n", len(selected), suspected)
+	fmt.Printf("Written to: %s\n", fpOut)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/waf"
+	"github.com/spf13/cobra"
+)
+
+var payloadCmd = &cobra.Command{
+	Use:   "payload",
+	Short: "Utilities for triaging attack payloads",
+	Long:  `Utilities for inspecting payloads found in WAF logs`,
+}
+
+var payloadDecodeCmd = &cobra.Command{
+	Use:   "decode",
+	Short: "Decode layered URL/base64/hex/HTML-entity encodings",
+	Long: `Iteratively decode a payload, printing each intermediate stage and the final
+decoded payload. With --layers auto the encoding of each layer is detected;
+otherwise give the layers to remove in order, e.g. --layers base64,url.
+
+Example:
+  shieldcli payload decode --input 'MSUyNyUyME9SJTIwJTI3MSUyNyUzRCUyNzE=' --layers auto`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return payloadDecode()
+	},
+}
+
+var (
+	decodeInput     string
+	decodeLayers    string
+	decodeMaxLayers int
+	decodeAnalyze   bool
+)
+
+func init() {
+	payloadCmd.AddCommand(payloadDecodeCmd)
+
+	payloadDecodeCmd.Flags().StringVar(&decodeInput, "input", "", "Encoded payload (required)")
+	payloadDecodeCmd.Flags().StringVar(&decodeLayers, "layers", "auto", "'auto' or a comma-separated list of encodings to remove (url, html, hex, base64)")
+	payloadDecodeCmd.Flags().IntVar(&decodeMaxLayers, "max-layers", waf.DefaultMaxDecodeLayers, "Maximum number of layers to decode in auto mode")
+	payloadDecodeCmd.Flags().BoolVar(&decodeAnalyze, "analyze", false, "Analyze the decoded payload with Gemini AI")
+	payloadDecodeCmd.MarkFlagRequired("input")
+}
+
+func payloadDecode() error {
+	var stages []waf.DecodeStage
+	if strings.EqualFold(strings.TrimSpace(decodeLayers), "auto") {
+		stages = waf.DecodeLayers(decodeInput, decodeMaxLayers)
+	} else {
+		var err error
+		stages, err = waf.DecodeSequence(decodeInput, strings.Split(decodeLayers, ","))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return err
+		}
+	}
+
+	fmt.Println("=== Payload Decode ===")
+	fmt.Printf("Input: %s\n", decodeInput)
+
+	final := decodeInput
+	for i, stage := range stages {
+		fmt.Printf("\nLayer %d (%s):\n%s\n", i+1, stage.Encoding, stage.Output)
+		final = stage.Output
+	}
+
+	if len(stages) == 0 {
+		fmt.Println("\nNo encoding detected.")
+	} else if len(stages) == decodeMaxLayers && strings.EqualFold(strings.TrimSpace(decodeLayers), "auto") {
+		fmt.Printf("\nStopped after the maximum of %d layers.\n", decodeMaxLayers)
+	}
+
+	fmt.Printf("\nDecoded payload: %s\n", final)
+
+	if decodeAnalyze {
+		fmt.Println()
+		return analyzePayload(final)
+	}
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"os"
 	"text/tabwriter"
 
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
 	"github.com/shieldcli/shieldcli/pkg/replay"
+	"github.com/shieldcli/shieldcli/pkg/waf"
 	"github.com/spf13/cobra"
 )
 
@@ -39,22 +42,46 @@ var replayExportCmd = &cobra.Command{
 	},
 }
 
+var replayCompareWAFCmd = &cobra.Command{
+	Use:   "compare-waf",
+	Short: "A/B test two rule configurations against recorded traffic",
+	Long: `Run every recorded request through two WAF engines built from two configuration
+files and report the requests whose decision changed. This runs offline and
+does not contact a target server.
+
+Example:
+  shieldcli replay compare-waf --records traffic.json --config-a a.yaml --config-b b.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return compareWAF()
+	},
+}
+
 var (
 	recordFile string
 	targetURL  string
 	exportFile string
+	configA    string
+	configB    string
+	workers    int
 )
 
 func init() {
 	replayCmd.AddCommand(replayRecordCmd)
 	replayCmd.AddCommand(replayPlayCmd)
 	replayCmd.AddCommand(replayExportCmd)
+	replayCmd.AddCommand(replayCompareWAFCmd)
 
 	replayRecordCmd.Flags().StringVar(&recordFile, "output", "traffic.json", "Output file for recorded traffic")
 	replayPlayCmd.Flags().StringVar(&recordFile, "input", "traffic.json", "Input file with recorded traffic")
 	replayPlayCmd.Flags().StringVar(&targetURL, "target", "http://localhost:3000", "Target URL for replay")
+	replayPlayCmd.Flags().IntVar(&workers, "workers", 1, "Number of requests replayed concurrently")
 	replayExportCmd.Flags().StringVar(&recordFile, "input", "traffic.json", "Input file with recorded traffic")
 	replayExportCmd.Flags().StringVar(&exportFile, "output", "traffic.csv", "Output CSV file")
+	replayCompareWAFCmd.Flags().StringVar(&recordFile, "records", "traffic.json", "Input file with recorded traffic")
+	replayCompareWAFCmd.Flags().StringVar(&configA, "config-a", "", "Baseline configuration file (required)")
+	replayCompareWAFCmd.Flags().StringVar(&configB, "config-b", "", "Candidate configuration file (required)")
+	replayCompareWAFCmd.MarkFlagRequired("config-a")
+	replayCompareWAFCmd.MarkFlagRequired("config-b")
 }
 
 func recordTraffic() error {
@@ -83,7 +110,7 @@ func playTraffic() error {
 	fmt.Printf("Replaying traffic against: %s\n", targetURL)
 
 	// Replay all requests
-	if err := replayer.ReplayAll(); err != nil {
+	if err := replayer.ReplayAllConcurrent(workers); err != nil {
 		fmt.Printf("Error during replay: %v\n", err)
 		return err
 	}
@@ -144,3 +171,90 @@ func exportTraffic() error {
 
 	return nil
 }
+
+func compareWAF() error {
+	// Load recorded traffic
+	recorder := replay.NewRecorder(recordFile, 10000)
+	if err := recorder.LoadFromFile(); err != nil {
+		fmt.Printf("Error loading traffic file: %v\n", err)
+		return err
+	}
+
+	engineA, err := engineFromConfigFile(configA)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+	engineB, err := engineFromConfigFile(configB)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	comparison, err := replay.CompareWAF(recorder.GetRecords(), engineA, engineB)
+	if err != nil {
+		fmt.Printf("Error during comparison: %v\n", err)
+		return err
+	}
+
+	divergent := comparison.Divergent()
+
+	fmt.Println("\n=== WAF Comparison ===")
+	fmt.Printf("Config A: %s\n", configA)
+	fmt.Printf("Config B: %s\n", configB)
+	fmt.Printf("Total Requests: %d\n", len(comparison.Results))
+	fmt.Printf("Changed Decisions: %d\n", len(divergent))
+	fmt.Printf("Newly Blocked: %d\n", comparison.NewlyBlocked)
+	fmt.Printf("Newly Allowed: %d\n", comparison.NewlyAllowed)
+
+	if len(divergent) == 0 {
+		fmt.Println("\nNo divergent requests.")
+		return nil
+	}
+
+	fmt.Println("\n=== Divergent Requests ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Method\tURL\tDecision A\tDecision B\tReason")
+	fmt.Fprintln(w, "------\t---\t----------\t----------\t------")
+
+	for _, result := range divergent {
+		reason := result.ReasonB
+		if reason == "" {
+			reason = result.ReasonA
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			result.Request.Method,
+			result.Request.URL,
+			result.DecisionA,
+			result.DecisionB,
+			reason,
+		)
+	}
+	w.Flush()
+
+	return nil
+}
+
+// engineFromConfigFile builds a standalone WAF engine from a configuration file
+func engineFromConfigFile(path string) (*waf.Engine, error) {
+	cfgFile, err := config.LoadConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	cfg := &config.Config{
+		IPAllowlist: cfgFile.WAF.IPAllowlist,
+		IPBlocklist: cfgFile.WAF.IPBlocklist,
+
+		SeverityBoosts: cfgFile.WAF.SeverityBoosts,
+	}
+	engine, err := waf.NewEngine(cfg, &logging.Logger{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WAF engine: %w", err)
+	}
+	if err := engine.LoadConfigFile(cfgFile); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return engine, nil
+}
@@ -3,13 +3,18 @@ package commands
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile string
+	cfgFile      string
+	strictConfig bool
 )
 
 var rootCmd = &cobra.Command{
@@ -35,6 +40,7 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./shieldcli.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&strictConfig, "strict-config", false, "Fail on unknown keys in the config file instead of warning")
 
 	// Add subcommands
 	rootCmd.AddCommand(runCmd)
@@ -43,6 +49,9 @@ func init() {
 	rootCmd.AddCommand(analyzeCmd)
 	rootCmd.AddCommand(anomalyCmd)
 	rootCmd.AddCommand(replayCmd)
+	rootCmd.AddCommand(wafCmd)
+	rootCmd.AddCommand(payloadCmd)
+	rootCmd.AddCommand(logsCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -62,5 +71,23 @@ func initConfig() {
 	// If a config file is found, read it but don't fail if it's not found
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+		checkConfigKeys(viper.ConfigFileUsed())
+	}
+}
+
+// checkConfigKeys reports unknown keys in a YAML config file, which are
+// usually typos. With --strict-config they abort the command.
+func checkConfigKeys(path string) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".yaml" && ext != ".yml" {
+		return
+	}
+
+	_, warnings, err := config.LoadConfigFileChecked(path, strictConfig)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config %s\n", warning)
 	}
 }
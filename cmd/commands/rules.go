@@ -2,13 +2,19 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/shieldcli/shieldcli/pkg/config"
 	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/replay"
 	"github.com/shieldcli/shieldcli/pkg/waf"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 var rulesCmd = &cobra.Command{
@@ -33,6 +39,111 @@ var rulesListCmd = &cobra.Command{
 	},
 }
 
+var rulesBenchmarkPatternCmd = &cobra.Command{
+	Use:   "benchmark-pattern",
+	Short: "Benchmark a regex pattern against worst-case inputs",
+	Long: `Compile a regex pattern and time it against generated worst-case inputs
+before adding it as a rule. Warns when matching is slow or the pattern is structurally expensive.
+
+Example:
+  shieldcli rules benchmark-pattern --pattern '(a|b|c)+d' --input-size 1000000`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rulesBenchmarkPattern()
+	},
+}
+
+var rulesSimulatePatternCmd = &cobra.Command{
+	Use:   "simulate-pattern",
+	Short: "Show which recorded requests a candidate pattern would match",
+	Long: `Run a candidate regex pattern against a rule target of recorded traffic and
+print every matching request with the matched snippet. No configuration is changed.
+
+Example:
+  shieldcli rules simulate-pattern --pattern '(?i)union\s+select' --target ARGS --records traffic.json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rulesSimulatePattern()
+	},
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Check whether a rule matches a sample input",
+	Long: `Build a rule from an operator and pattern and run it against a sample input,
+printing whether it matched and, for regex rules, the matched text. The input is
+read from --input, --input-file, or stdin when neither is given.
+
+Example:
+  shieldcli rules test --operator regex --pattern '(?i)union\s+select' --input "1 UNION SELECT password"
+  cat payload.txt | shieldcli rules test --operator sqli`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rulesTest()
+	},
+}
+
+var rulesDebugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Show how every rule of a phase evaluates against an input",
+	Long: `Evaluate each rule of a phase against the input and print, per rule, the
+operator, whether it matched and, for regex rules, the matched text. Disabled
+rules are evaluated too. The input stands for whatever part of the request each
+rule targets. The built-in rules and the custom_rules of the config file are
+used. Where 'rules test' checks one ad-hoc rule, this shows why a configured
+rule does or does not fire.
+
+Example:
+  shieldcli rules debug --data "id=1' OR '1'='1" --phase request_body`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rulesDebug()
+	},
+}
+
+var rulesDedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find rules that duplicate another rule",
+	Long: `Report rules whose phase, operator, target, transformations and pattern match
+those of a rule with a lower ID, comparing regex patterns after normalization.
+The built-in rules and the custom_rules of the config file are checked; with
+--apply the duplicate custom rules are removed and the config file is written
+back. Comments and formatting of the file are not preserved.
+
+Example:
+  shieldcli rules dedupe --config shieldcli.yaml
+  shieldcli rules dedupe --config shieldcli.yaml --apply`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rulesDedupe()
+	},
+}
+
+var rulesExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the active ruleset as custom_rules YAML",
+	Long: `Write every active rule, built-in and from the config file's custom_rules,
+to a YAML file in the custom_rules shape, including each rule's enabled state.
+The file can be loaded back with 'rules import' or pasted into a config file.
+
+Example:
+  shieldcli rules export --output rules.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rulesExport()
+	},
+}
+
+var rulesImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import rules from a custom_rules YAML file into the config file",
+	Long: `Validate and compile every rule of a custom_rules YAML file, such as one
+written by 'rules export', and merge them into the custom_rules of the config
+file. A rule with the ID of an existing rule, built-in or custom, replaces it;
+other rules are added. Nothing is written unless every rule is valid. Comments
+and formatting of the config file are not preserved.
+
+Example:
+  shieldcli rules import --input rules.yaml --config shieldcli.yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rulesImport()
+	},
+}
+
 var (
 	ruleID          int
 	ruleName        string
@@ -43,52 +154,114 @@ var (
 	ruleTarget      string
 	ruleAction      string
 	ruleSeverity    string
+	ruleTransforms  []string
+
+	benchPattern     string
+	benchInputSize   int
+	benchMaxDuration time.Duration
+
+	simPattern string
+	simTarget  string
+	simRecords string
+
+	testOperator   string
+	testPattern    string
+	testInput      string
+	testInputFile  string
+	testTransforms []string
+
+	showHits    bool
+	hitsFile    string
+	coldAfter   time.Duration
+	coldMaxHits int64
+
+	dedupeApply bool
+
+	debugData  string
+	debugPhase string
+
+	rulesExportOutput string
+	rulesImportInput  string
 )
 
 func init() {
 	rulesCmd.AddCommand(rulesAddCmd)
 	rulesCmd.AddCommand(rulesListCmd)
+	rulesCmd.AddCommand(rulesBenchmarkPatternCmd)
+	rulesCmd.AddCommand(rulesSimulatePatternCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+	rulesCmd.AddCommand(rulesDedupeCmd)
+	rulesCmd.AddCommand(rulesDebugCmd)
+	rulesCmd.AddCommand(rulesExportCmd)
+	rulesCmd.AddCommand(rulesImportCmd)
 
 	rulesAddCmd.Flags().IntVar(&ruleID, "id", 0, "Rule ID")
 	rulesAddCmd.Flags().StringVar(&ruleName, "name", "", "Rule name")
 	rulesAddCmd.Flags().StringVar(&ruleDescription, "description", "", "Rule description")
 	rulesAddCmd.Flags().StringVar(&rulePhase, "phase", "request_body", "Rule phase (request_headers, request_uri, request_body)")
-	rulesAddCmd.Flags().StringVar(&ruleOperator, "operator", "contains", "Rule operator (contains, regex, startswith, endswith, equals, sqli, xss)")
+	rulesAddCmd.Flags().StringVar(&ruleOperator, "operator", "contains", "Rule operator (contains, regex, startswith, endswith, equals, notcontains, notregex, high_entropy, sqli, xss)")
 	rulesAddCmd.Flags().StringVar(&rulePattern, "pattern", "", "Rule pattern")
-	rulesAddCmd.Flags().StringVar(&ruleTarget, "target", "REQUEST_BODY", "Rule target (REQUEST_URI, REQUEST_HEADERS, REQUEST_BODY, ARGS)")
+	rulesAddCmd.Flags().StringVar(&ruleTarget, "target", "REQUEST_BODY", "Rule target (REQUEST_URI, REQUEST_HEADERS, REQUEST_HEADERS:<name>, REQUEST_BODY, ARGS, QUERY_STRING, RESPONSE_HEADERS, RESPONSE_HEADERS:<name>, RESPONSE_BODY)")
 	rulesAddCmd.Flags().StringVar(&ruleAction, "action", "block", "Rule action (block, log, pass)")
 	rulesAddCmd.Flags().StringVar(&ruleSeverity, "severity", "medium", "Rule severity (low, medium, high, critical)")
+	rulesAddCmd.Flags().StringSliceVar(&ruleTransforms, "transformations", nil, "Transformations applied in order before matching (url_decode, base64_decode, hex_decode, html_decode, lowercase, compress_whitespace)")
+
+	rulesListCmd.Flags().BoolVar(&showHits, "show-hits", false, "Show persisted match counts and flag cold rules")
+	rulesListCmd.Flags().StringVar(&hitsFile, "hits-file", "", "Rule hits state file (default: waf.hits_file from config)")
+	rulesListCmd.Flags().DurationVar(&coldAfter, "cold-after", 30*24*time.Hour, "Flag rules with no matches for this long as cold")
+	rulesListCmd.Flags().Int64Var(&coldMaxHits, "cold-max-hits", 3, "Rules with at most this many total matches can be cold")
 
 	rulesAddCmd.MarkFlagRequired("id")
 	rulesAddCmd.MarkFlagRequired("name")
 	rulesAddCmd.MarkFlagRequired("pattern")
+
+	rulesBenchmarkPatternCmd.Flags().StringVar(&benchPattern, "pattern", "", "Regex pattern to benchmark")
+	rulesBenchmarkPatternCmd.Flags().IntVar(&benchInputSize, "input-size", 1000000, "Size in bytes of each generated input")
+	rulesBenchmarkPatternCmd.Flags().DurationVar(&benchMaxDuration, "max-duration", 50*time.Millisecond, "Warn when a single match takes longer than this")
+	rulesBenchmarkPatternCmd.MarkFlagRequired("pattern")
+
+	rulesSimulatePatternCmd.Flags().StringVar(&simPattern, "pattern", "", "Regex pattern to simulate")
+	rulesSimulatePatternCmd.Flags().StringVar(&simTarget, "target", "REQUEST_URI", "Rule target to match (REQUEST_URI, REQUEST_HEADERS, REQUEST_HEADERS:<name>, REQUEST_BODY, ARGS, QUERY_STRING)")
+	rulesSimulatePatternCmd.Flags().StringVar(&simRecords, "records", "traffic.json", "Recorded traffic file")
+	rulesSimulatePatternCmd.MarkFlagRequired("pattern")
+
+	rulesTestCmd.Flags().StringVar(&testOperator, "operator", "contains", "Rule operator (contains, regex, startswith, endswith, equals, notcontains, notregex, high_entropy, sqli, xss)")
+	rulesTestCmd.Flags().StringVar(&testPattern, "pattern", "", "Rule pattern")
+	rulesTestCmd.Flags().StringVar(&testInput, "input", "", "Sample input to match")
+	rulesTestCmd.Flags().StringVar(&testInputFile, "input-file", "", "File to read the sample input from")
+	rulesDebugCmd.Flags().StringVar(&debugData, "data", "", "Input to evaluate the rules against")
+	rulesDebugCmd.Flags().StringVar(&debugPhase, "phase", "request_body", "Phase whose rules are evaluated (request_headers, request_uri, request_body, response_headers, response_body)")
+	rulesDebugCmd.MarkFlagRequired("data")
+	rulesExportCmd.Flags().StringVarP(&rulesExportOutput, "output", "o", "", "Output YAML file")
+	rulesExportCmd.MarkFlagRequired("output")
+	rulesImportCmd.Flags().StringVarP(&rulesImportInput, "input", "i", "", "YAML file with custom_rules to import")
+	rulesImportCmd.MarkFlagRequired("input")
+
+	rulesDedupeCmd.Flags().BoolVar(&dedupeApply, "apply", false, "Remove the duplicate custom rules and write the config file back")
+
+	rulesTestCmd.Flags().StringSliceVar(&testTransforms, "transformations", nil, "Transformations applied in order before matching (url_decode, base64_decode, hex_decode, html_decode, lowercase, compress_whitespace)")
 }
 
 func rulesAdd() error {
+	rule, err := buildRuleFromFlags()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
 	fmt.Println("Rule Management - Add Rule")
 	fmt.Println("===========================")
-	fmt.Printf("Rule ID: %d\n", ruleID)
-	fmt.Printf("Name: %s\n", ruleName)
-	fmt.Printf("Description: %s\n", ruleDescription)
-	fmt.Printf("Phase: %s\n", rulePhase)
-	fmt.Printf("Operator: %s\n", ruleOperator)
-	fmt.Printf("Pattern: %s\n", rulePattern)
-	fmt.Printf("Target: %s\n", ruleTarget)
-	fmt.Printf("Action: %s\n", ruleAction)
-	fmt.Printf("Severity: %s\n", ruleSeverity)
-
-	// Create rule object
-	rule := &waf.Rule{
-		ID:          ruleID,
-		Name:        ruleName,
-		Description: ruleDescription,
-		Phase:       waf.RulePhase(rulePhase),
-		Operator:    waf.RuleOperator(ruleOperator),
-		Pattern:     rulePattern,
-		Target:      ruleTarget,
-		Action:      waf.RuleAction(ruleAction),
-		Severity:    ruleSeverity,
-		Enabled:     true,
+	fmt.Printf("Rule ID: %d\n", rule.ID)
+	fmt.Printf("Name: %s\n", rule.Name)
+	fmt.Printf("Description: %s\n", rule.Description)
+	fmt.Printf("Phase: %s\n", rule.Phase)
+	fmt.Printf("Operator: %s\n", rule.Operator)
+	fmt.Printf("Pattern: %s\n", rule.Pattern)
+	fmt.Printf("Target: %s\n", rule.Target)
+	fmt.Printf("Action: %s\n", rule.Action)
+	fmt.Printf("Severity: %s\n", rule.Severity)
+	if len(rule.Transformations) > 0 {
+		fmt.Printf("Transformations: %s\n", strings.Join(rule.Transformations, ", "))
 	}
 
 	// Compile the rule
@@ -103,7 +276,55 @@ func rulesAdd() error {
 	return nil
 }
 
+// buildRuleFromFlags validates and normalizes the rules add flags into a rule
+func buildRuleFromFlags() (*waf.Rule, error) {
+	phase, err := waf.ParsePhase(rulePhase)
+	if err != nil {
+		return nil, err
+	}
+	operator, err := waf.ParseOperator(ruleOperator)
+	if err != nil {
+		return nil, err
+	}
+	target, err := waf.ParseTarget(ruleTarget)
+	if err != nil {
+		return nil, err
+	}
+	action, err := waf.ParseAction(ruleAction)
+	if err != nil {
+		return nil, err
+	}
+	severity, err := waf.ParseSeverity(ruleSeverity)
+	if err != nil {
+		return nil, err
+	}
+
+	return &waf.Rule{
+		ID:          ruleID,
+		Name:        ruleName,
+		Description: ruleDescription,
+		Phase:       phase,
+		Operator:    operator,
+		Pattern:     rulePattern,
+		Target:      target,
+		Action:      action,
+		Severity:    severity,
+		Enabled:     true,
+
+		Transformations: ruleTransforms,
+	}, nil
+}
+
 func rulesList() error {
+	if showHits {
+		// Custom rules have hits too, so load them with the built-in ones
+		engine, err := newConfiguredEngine()
+		if err != nil {
+			return err
+		}
+		return rulesListHits(engine)
+	}
+
 	// Create a temporary WAF engine to get default rules
 	logger := &logging.Logger{}
 	cfg := &config.Config{}
@@ -141,4 +362,435 @@ func rulesList() error {
 	return nil
 }
 
+// rulesListHits lists the rules with their persisted match counts
+func rulesListHits(engine *waf.Engine) error {
+	path := hitsFile
+	if path == "" {
+		path = viper.GetString("waf.hits_file")
+	}
+	if path == "" {
+		err := fmt.Errorf("no hits file: set --hits-file or waf.hits_file")
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+	if err := engine.LoadRuleHits(path); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	hits, since := engine.RuleHits()
+	cold := make(map[int]bool)
+	for _, id := range engine.ColdRules(coldMaxHits, coldAfter) {
+		cold[id] = true
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tACTION\tHITS\tLAST MATCHED\tCOLD")
+	fmt.Fprintln(w, "--\t----\t------\t----\t------------\t----")
+
+	for _, rule := range engine.GetRules() {
+		h := hits[rule.ID]
+		lastMatched := "never"
+		if !h.LastMatched.IsZero() {
+			lastMatched = h.LastMatched.Format("2006-01-02 15:04:05")
+		}
+		coldMark := ""
+		if cold[rule.ID] {
+			coldMark = "cold"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n", rule.ID, rule.Name, rule.Action, h.Count, lastMatched, coldMark)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\nCounting since: %s\n", since.Format("2006-01-02 15:04:05"))
+	if len(cold) > 0 {
+		fmt.Printf("Cold rules (<= %d hits, none in %v): %d\n", coldMaxHits, coldAfter, len(cold))
+	}
+	return nil
+}
+
+func rulesBenchmarkPattern() error {
+	if benchInputSize <= 0 {
+		return fmt.Errorf("--input-size must be positive")
+	}
+
+	result, err := waf.BenchmarkPattern(benchPattern, benchInputSize, benchMaxDuration)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	fmt.Println("\n=== Pattern Benchmark ===")
+	fmt.Printf("Pattern: %s\n", result.Pattern)
+	fmt.Printf("Program Size: %d instructions\n", result.ProgramSize)
+	fmt.Printf("Alternation Branches: %d\n", result.Alternations)
+	fmt.Printf("Repetition Depth: %d\n\n", result.RepeatDepth)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INPUT\tSIZE\tPER MATCH\tMATCHED")
+	fmt.Fprintln(w, "-----\t----\t---------\t-------")
+	for _, input := range result.Inputs {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\n", input.Name, input.Size, input.Duration, input.Matched)
+	}
+	w.Flush()
+
+	if len(result.Warnings) == 0 {
+		fmt.Printf("\n✓ Pattern looks safe (slowest: %v per match)\n", result.Slowest().Duration)
+		return nil
+	}
+
+	fmt.Println("\n⚠️  Warnings:")
+	for _, warning := range result.Warnings {
+		fmt.Printf("  - %s\n", warning)
+	}
+	return nil
+}
+
+func rulesSimulatePattern() error {
+	recorder := replay.NewRecorder(simRecords, 10000)
+	if err := recorder.LoadFromFile(); err != nil {
+		fmt.Printf("Error loading traffic file: %v\n", err)
+		return err
+	}
+	records := recorder.GetRecords()
+
+	results, err := replay.SimulatePattern(records, simPattern, simTarget)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	fmt.Println("\n=== Pattern Simulation ===")
+	fmt.Printf("Pattern: %s\n", simPattern)
+	fmt.Printf("Target: %s\n", simTarget)
+	fmt.Printf("Matched Requests: %d of %d\n", len(results), len(records))
+
+	if len(results) == 0 {
+		fmt.Println("\nNo recorded requests match.")
+		return nil
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tMethod\tURL\tLocation\tSnippet")
+	fmt.Fprintln(w, "--\t------\t---\t--------\t-------")
+	for _, result := range results {
+		for _, match := range result.Matches {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%q\n",
+				result.Request.ID,
+				result.Request.Method,
+				result.Request.URL,
+				match.Location,
+				match.Snippet,
+			)
+		}
+	}
+	w.Flush()
+
+	return nil
+}
+
+func rulesTest() error {
+	operator, err := waf.ParseOperator(testOperator)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	rule := &waf.Rule{
+		Name:     "test",
+		Operator: operator,
+		Pattern:  testPattern,
+		Enabled:  true,
+
+		Transformations: testTransforms,
+	}
+	if err := rule.Compile(); err != nil {
+		fmt.Printf("Error: invalid rule: %v\n", err)
+		return err
+	}
+
+	input, err := readTestInput()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	if !rule.Match(input) {
+		fmt.Println("✗ No match")
+		return nil
+	}
+
+	fmt.Println("✓ Match")
+	if text, ok := rule.FindMatch(input); ok {
+		fmt.Printf("Matched: %q\n", text)
+	}
+	return nil
+}
+
+// readTestInput returns the rules test input from --input, --input-file or stdin
+func readTestInput() (string, error) {
+	switch {
+	case testInput != "" && testInputFile != "":
+		return "", fmt.Errorf("use either --input or --input-file, not both")
+	case testInput != "":
+		return testInput, nil
+	case testInputFile != "":
+		data, err := os.ReadFile(testInputFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read input file: %w", err)
+		}
+		return string(data), nil
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input from stdin: %w", err)
+	}
+	return string(data), nil
+}
+
+func rulesDebug() error {
+	phase, err := waf.ParsePhase(debugPhase)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	engine, err := newConfiguredEngine()
+	if err != nil {
+		return err
+	}
+
+	results := engine.MatchAll(debugData, phase)
+	if len(results) == 0 {
+		fmt.Printf("No rules in phase %s.\n", phase)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tOPERATOR\tTARGET\tTRANSFORMATIONS\tSTATUS\tRESULT\tMATCHED TEXT")
+	fmt.Fprintln(w, "--\t----\t--------\t------\t---------------\t------\t------\t------------")
+
+	matched := 0
+	for _, result := range results {
+		status := "enabled"
+		if !result.Enabled {
+			status = "disabled"
+		}
+		outcome := "✗ no match"
+		if result.Matched {
+			outcome = "✓ match"
+			matched++
+		}
+		transformations := strings.Join(result.Transformations, ",")
+		if transformations == "" {
+			transformations = "-"
+		}
+		matchedText := ""
+		if result.MatchedText != "" {
+			matchedText = fmt.Sprintf("%q", result.MatchedText)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.RuleID, result.RuleName, result.Operator, result.Target, transformations, status, outcome, matchedText)
+	}
+
+	w.Flush()
+
+	fmt.Printf("\n%d of %d rules matched\n", matched, len(results))
+	return nil
+}
+
+func rulesDedupe() error {
+	path := cfgFile
+	if path == "" {
+		path = "shieldcli.yaml"
+	}
+
+	cfg, err := config.LoadConfigFile(path)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		return err
+	}
+
+	engine, err := waf.NewEngine(&config.Config{}, &logging.Logger{})
+	if err != nil {
+		fmt.Printf("Error: Failed to create WAF engine: %v\n", err)
+		return err
+	}
+
+	rules := engine.GetRules()
+	custom := make(map[int]bool)
+	for _, cr := range cfg.CustomRules {
+		rule, err := waf.RuleFromConfig(cr)
+		if err != nil {
+			fmt.Printf("⚠️  Skipping invalid custom rule: %v\n", err)
+			continue
+		}
+		rules = append(rules, rule)
+		custom[rule.ID] = true
+	}
+
+	duplicates := waf.FindDuplicateRules(rules)
+	if len(duplicates) == 0 {
+		fmt.Println("✓ No duplicate rules found.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tDUPLICATE OF\tOPERATOR\tTARGET\tPATTERN")
+	fmt.Fprintln(w, "--\t----\t------------\t--------\t------\t-------")
+	for _, d := range duplicates {
+		fmt.Fprintf(w, "%d\t%s\t%d (%s)\t%s\t%s\t%s\n",
+			d.Rule.ID, d.Rule.Name, d.DuplicateOf.ID, d.DuplicateOf.Name, d.Rule.Operator, d.Rule.Target, d.Rule.Pattern)
+	}
+	w.Flush()
+
+	fmt.Printf("\nTotal: %d duplicate rules\n", len(duplicates))
 
+	if !dedupeApply {
+		fmt.Println("Run with --apply to remove the duplicate custom rules.")
+		return nil
+	}
+
+	remove := make(map[int]bool)
+	for _, d := range duplicates {
+		if !custom[d.Rule.ID] {
+			fmt.Printf("⚠️  Rule %d is built in and cannot be removed\n", d.Rule.ID)
+			continue
+		}
+		remove[d.Rule.ID] = true
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+
+	kept := cfg.CustomRules[:0]
+	for _, cr := range cfg.CustomRules {
+		if !remove[cr.ID] {
+			kept = append(kept, cr)
+		}
+	}
+	cfg.CustomRules = kept
+
+	if err := config.SaveConfigFile(path, cfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✓ Removed %d duplicate custom rules from %s\n", len(remove), path)
+	return nil
+}
+
+// newConfiguredEngine creates an engine with the built-in rules and the rule
+// settings of the config file in use, if any
+func newConfiguredEngine() (*waf.Engine, error) {
+	engine, err := waf.NewEngine(&config.Config{}, &logging.Logger{})
+	if err != nil {
+		fmt.Printf("Error: Failed to create WAF engine: %v\n", err)
+		return nil, err
+	}
+
+	if path := viper.ConfigFileUsed(); path != "" {
+		cf, err := config.LoadConfigFile(path)
+		if err != nil {
+			fmt.Printf("Error loading configuration: %v\n", err)
+			return nil, err
+		}
+		if err := engine.LoadConfigFile(cf); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return nil, err
+		}
+	}
+	return engine, nil
+}
+
+// ruleSetFile is the file written by rules export: a config file holding
+// only custom_rules
+type ruleSetFile struct {
+	CustomRules []config.CustomRule `yaml:"custom_rules"`
+}
+
+func rulesExport() error {
+	engine, err := newConfiguredEngine()
+	if err != nil {
+		return err
+	}
+
+	rules := engine.ExportRules()
+	data, err := yaml.Marshal(ruleSetFile{CustomRules: rules})
+	if err != nil {
+		fmt.Printf("Error: failed to marshal rules: %v\n", err)
+		return err
+	}
+	if err := os.WriteFile(rulesExportOutput, data, 0644); err != nil {
+		fmt.Printf("Error writing export file: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✓ Exported %d rules to %s\n", len(rules), rulesExportOutput)
+	return nil
+}
+
+func rulesImport() error {
+	input, err := config.LoadConfigFile(rulesImportInput)
+	if err != nil {
+		fmt.Printf("Error loading rules: %v\n", err)
+		return err
+	}
+	if len(input.CustomRules) == 0 {
+		err := fmt.Errorf("no custom_rules in %s", rulesImportInput)
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	path := cfgFile
+	if path == "" {
+		path = "shieldcli.yaml"
+	}
+	cfg, err := config.LoadConfigFile(path)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		return err
+	}
+
+	// Load the rules into the configured ruleset first, so nothing is
+	// written unless every rule compiles
+	engine, err := waf.NewEngine(&config.Config{}, &logging.Logger{})
+	if err != nil {
+		fmt.Printf("Error: Failed to create WAF engine: %v\n", err)
+		return err
+	}
+	if err := engine.LoadConfigFile(cfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+	added, replaced, err := engine.ImportRules(input.CustomRules)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	index := make(map[int]int, len(cfg.CustomRules))
+	for i, cr := range cfg.CustomRules {
+		index[cr.ID] = i
+	}
+	for _, cr := range input.CustomRules {
+		if i, ok := index[cr.ID]; ok {
+			cfg.CustomRules[i] = cr
+			continue
+		}
+		index[cr.ID] = len(cfg.CustomRules)
+		cfg.CustomRules = append(cfg.CustomRules, cr)
+	}
+
+	if err := config.SaveConfigFile(path, cfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("✓ Imported %d rules into %s: %d new, %d replacing existing rules\n",
+		len(input.CustomRules), path, added, replaced)
+	return nil
+}
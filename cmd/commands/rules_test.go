@@ -0,0 +1,294 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+	"github.com/spf13/viper"
+)
+
+func TestRulesListHits(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "shieldcli.yaml")
+	writeFile(t, configPath, `custom_rules:
+  - id: 9100
+    name: Probe
+    phase: request_uri
+    pattern: probe
+    operator: contains
+    target: REQUEST_URI
+    action: log
+    severity: high
+    enabled: true
+`)
+	hitsPath := filepath.Join(dir, "hits.json")
+	writeFile(t, hitsPath, `{"since": "`+time.Now().Add(-time.Hour).Format(time.RFC3339)+`", "rules": {"1001": {"count": 2}, "9100": {"count": 7}}}`)
+
+	viper.SetConfigFile(configPath)
+	showHits, hitsFile = true, hitsPath
+	t.Cleanup(func() {
+		viper.SetConfigFile("")
+		showHits, hitsFile = false, ""
+	})
+
+	out := captureStdout(t, rulesList)
+
+	tests := []struct {
+		name string
+		want string // pattern of the rule's row
+	}{
+		{name: "built-in rule", want: `(?m)^1001\s+SQL Injection - Common Patterns\s+block\s+2\s`},
+		{name: "custom rule", want: `(?m)^9100\s+Probe\s+log\s+7\s`},
+		{name: "rule without hits", want: `(?m)^1003\s+Path Traversal\s+block\s+0\s`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !regexp.MustCompile(tt.want).MatchString(out) {
+				t.Errorf("output does not match %q:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+// captureStdout returns what run prints to stdout, failing the test if it
+// returns an error
+func captureStdout(t *testing.T, run func() error) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	runErr := run()
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runErr != nil {
+		t.Fatalf("%v\n%s", runErr, out)
+	}
+	return string(out)
+}
+
+// writeFile writes data to path, failing the test on error
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildRuleFromFlags(t *testing.T) {
+	type flags struct{ phase, operator, target, action, severity string }
+	valid := flags{"request_uri", "contains", "REQUEST_URI", "block", "high"}
+
+	tests := []struct {
+		name       string
+		modify     func(f *flags)
+		wantTarget string
+		wantErr    string
+	}{
+		{name: "valid", modify: func(f *flags) {}, wantTarget: "REQUEST_URI"},
+		{
+			name: "normalized",
+			modify: func(f *flags) {
+				*f = flags{" Request_URI ", "CONTAINS", "request_headers:X-Api-Key", "Block", "HIGH"}
+			},
+			wantTarget: "REQUEST_HEADERS:X-Api-Key",
+		},
+		{name: "invalid phase", modify: func(f *flags) { f.phase = "request_url" }, wantErr: `invalid phase "request_url" (valid: request_headers, request_uri`},
+		{name: "invalid operator", modify: func(f *flags) { f.operator = "containss" }, wantErr: `invalid operator "containss" (valid: contains, regex`},
+		{name: "invalid target", modify: func(f *flags) { f.target = "COOKIES" }, wantErr: `invalid target "COOKIES" (valid: REQUEST_URI`},
+		{name: "header target without a name", modify: func(f *flags) { f.target = "REQUEST_HEADERS:" }, wantErr: `invalid target "REQUEST_HEADERS:"`},
+		{name: "invalid action", modify: func(f *flags) { f.action = "deny" }, wantErr: `invalid action "deny" (valid: block, log, pass)`},
+		{name: "invalid severity", modify: func(f *flags) { f.severity = "urgent" }, wantErr: `invalid severity "urgent" (valid: low, medium, high, critical)`},
+	}
+
+	t.Cleanup(func() {
+		rulePhase, ruleOperator, ruleTarget, ruleAction, ruleSeverity = "", "", "", "", ""
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := valid
+			tt.modify(&f)
+			rulePhase, ruleOperator, ruleTarget, ruleAction, ruleSeverity = f.phase, f.operator, f.target, f.action, f.severity
+
+			rule, err := buildRuleFromFlags()
+			if tt.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one starting with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildRuleFromFlags: %v", err)
+			}
+			if rule.Phase != waf.PhaseRequestURI || rule.Operator != waf.OpContains || rule.Action != waf.ActionBlock || rule.Severity != "high" {
+				t.Errorf("rule %+v not normalized", rule)
+			}
+			if rule.Target != tt.wantTarget {
+				t.Errorf("target %q, want %q", rule.Target, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestRulesTest(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "payload.txt")
+	writeFile(t, inputFile, "id=1 UNION SELECT password FROM users")
+
+	tests := []struct {
+		name       string
+		operator   string
+		pattern    string
+		input      string
+		inputFile  string
+		transforms []string
+		want       string
+		wantErr    string
+	}{
+		{name: "regex match", operator: "regex", pattern: `(?i)union\s+select`, input: "q=1 union  select 2", want: "✓ Match\nMatched: \"union  select\"\n"},
+		{name: "no match", operator: "regex", pattern: `(?i)union\s+select`, input: "q=shoes", want: "✗ No match\n"},
+		{name: "input file", operator: "contains", pattern: "UNION", inputFile: inputFile, want: "✓ Match\n"},
+		{name: "after transformations", operator: "regex", pattern: `<script>`, input: "%3CSCRIPT%3E", transforms: []string{"url_decode", "lowercase"}, want: "✓ Match\nMatched: \"<script>\"\n"},
+		{name: "invalid regex", operator: "regex", pattern: "(unclosed", input: "x", wantErr: "error parsing regexp"},
+		{name: "both inputs", operator: "contains", pattern: "x", input: "x", inputFile: inputFile, wantErr: "use either --input or --input-file"},
+	}
+
+	t.Cleanup(func() {
+		testOperator, testPattern, testInput, testInputFile, testTransforms = "contains", "", "", "", nil
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testOperator, testPattern, testInput, testInputFile, testTransforms = tt.operator, tt.pattern, tt.input, tt.inputFile, tt.transforms
+
+			if tt.wantErr != "" {
+				if err := rulesTest(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if out := captureStdout(t, rulesTest); out != tt.want {
+				t.Errorf("output %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesDedupe(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "shieldcli.yaml")
+	rule := func(id int, pattern string) string {
+		return fmt.Sprintf(`  - id: %d
+    name: Rule %d
+    phase: request_uri
+    operator: contains
+    target: REQUEST_URI
+    pattern: %s
+    action: block
+    enabled: true
+`, id, id, pattern)
+	}
+	writeFile(t, configPath, "custom_rules:\n"+rule(9101, "/admin")+rule(9100, "/admin")+rule(9102, "/login"))
+
+	oldCfgFile := cfgFile
+	cfgFile = configPath
+	t.Cleanup(func() { cfgFile, dedupeApply = oldCfgFile, false })
+
+	out := captureStdout(t, rulesDedupe)
+	if !regexp.MustCompile(`(?m)^9101 +Rule 9101 +9100 \(Rule 9100\)`).MatchString(out) {
+		t.Errorf("output does not report 9101 as a duplicate of 9100:\n%s", out)
+	}
+	if !strings.Contains(out, "Total: 1 duplicate rules") {
+		t.Errorf("output does not report one duplicate:\n%s", out)
+	}
+
+	dedupeApply = true
+	captureStdout(t, rulesDedupe)
+	cf, err := config.LoadConfigFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, cr := range cf.CustomRules {
+		ids = append(ids, cr.ID)
+	}
+	if !reflect.DeepEqual(ids, []int{9100, 9102}) {
+		t.Errorf("custom rules %v after --apply, want [9100 9102]", ids)
+	}
+}
+
+func TestRulesExportImport(t *testing.T) {
+	dir := t.TempDir()
+	sourceConfig := filepath.Join(dir, "source.yaml")
+	writeFile(t, sourceConfig, `waf:
+  enabled_rules: [1001, 1002, 1003, 1004, 1006]
+custom_rules:
+  - id: 9100
+    name: Encoded Probe
+    phase: request_uri
+    operator: regex
+    pattern: (?i)<script
+    target: REQUEST_URI
+    transformations: [url_decode]
+    action: log
+    severity: low
+    enabled: true
+`)
+	targetConfig := filepath.Join(dir, "target.yaml")
+	writeFile(t, targetConfig, "proxy:\n  listen_port: 8080\n")
+
+	oldCfgFile := cfgFile
+	t.Cleanup(func() {
+		viper.SetConfigFile("")
+		cfgFile, rulesExportOutput, rulesImportInput = oldCfgFile, "", ""
+	})
+
+	exported := filepath.Join(dir, "rules.yaml")
+	viper.SetConfigFile(sourceConfig)
+	rulesExportOutput = exported
+	captureStdout(t, rulesExport)
+
+	cfgFile, rulesImportInput = targetConfig, exported
+	captureStdout(t, rulesImport)
+
+	reexported := filepath.Join(dir, "rules-again.yaml")
+	viper.SetConfigFile(targetConfig)
+	rulesExportOutput = reexported
+	captureStdout(t, rulesExport)
+
+	want, err := config.LoadConfigFile(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := config.LoadConfigFile(reexported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.CustomRules) != 7 {
+		t.Fatalf("exported %d rules, want the 6 built-in rules and 9100", len(want.CustomRules))
+	}
+	if !reflect.DeepEqual(got.CustomRules, want.CustomRules) {
+		t.Errorf("re-exported rules differ from the imported ones:\ngot  %+v\nwant %+v", got.CustomRules, want.CustomRules)
+	}
+
+	for _, cr := range got.CustomRules {
+		if cr.ID == 1005 && cr.Enabled {
+			t.Error("rule 1005 enabled after the round trip, want it disabled as in the source")
+		}
+	}
+}
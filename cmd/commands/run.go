@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/shieldcli/shieldcli/pkg/config"
 	"github.com/shieldcli/shieldcli/pkg/logging"
@@ -18,8 +23,27 @@ var (
 	port       int
 	dryRun     bool
 	interactive bool
+	explain    bool
+	controlFile string
 	geminiKey  string
 	logFile    string
+	configDir  string
+
+	maxRequests        int
+	maxConcurrent      int
+	summaryFile        string
+	summaryHalfLife    int
+	learnAndReport     time.Duration
+	learnMargin        float64
+	exitNonzeroOnBlock bool
+	structuredEvents   bool
+	stdoutFormat       string
+	tlsCertFile        string
+	tlsKeyFile         string
+	blockPageFile      string
+	adminAddr          string
+	snapshotInterval   time.Duration
+	snapshotFile       string
 )
 
 var runCmd = &cobra.Command{
@@ -39,8 +63,26 @@ func init() {
 	runCmd.Flags().IntVar(&port, "port", 8080, "Local port to listen on")
 	runCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Enable dry-run mode (log but don't block)")
 	runCmd.Flags().BoolVar(&interactive, "interactive", false, "Enable interactive mode (approve/deny requests)")
+	runCmd.Flags().BoolVar(&explain, "explain", false, "Print a per-request trace of evaluated rules and the decision to stderr")
+	runCmd.Flags().StringVar(&controlFile, "control-file", "", "File of 'enable|disable <rule-id>' lines applied on SIGUSR1")
 	runCmd.Flags().StringVar(&geminiKey, "gemini-key", "", "Google Gemini API key (or set GEMINI_API_KEY env var)")
 	runCmd.Flags().StringVar(&logFile, "log-file", "", "Path to export WAF logs")
+	runCmd.Flags().IntVar(&maxRequests, "max-requests", 0, "Stop after handling this many requests (0 = no limit)")
+	runCmd.Flags().IntVar(&maxConcurrent, "max-concurrent-requests", 0, "Reject requests with 503 while this many are in flight (0 = no limit)")
+	runCmd.Flags().StringVar(&summaryFile, "summary-file", "", "Write a JSON summary of total/blocked/by-rule counts here on shutdown")
+	runCmd.Flags().IntVar(&summaryHalfLife, "summary-half-life", 0, "Also report a block rate weighting requests by recency with this half-life in seconds (0 = off)")
+	runCmd.Flags().DurationVar(&learnAndReport, "learn-and-report", 0, "Observe traffic without blocking for this long, then print recommended anomaly thresholds and the rules that matched")
+	runCmd.Flags().Float64Var(&learnMargin, "learn-margin", 0.25, "Headroom added to observed maxima by --learn-and-report (0.25 = 25%)")
+	runCmd.Flags().BoolVar(&exitNonzeroOnBlock, "exit-nonzero-on-block", false, "Exit with a non-zero status if any request was blocked")
+	runCmd.Flags().BoolVar(&structuredEvents, "structured-events", false, "Write one JSON event per request next to the --log-file (<name>.events.jsonl)")
+	runCmd.Flags().StringVar(&stdoutFormat, "stdout-format", "", "Stdout format: 'pretty' (default) colored lines, or 'json' objects (log lines and request events) for container log collectors")
+	runCmd.Flags().StringVar(&tlsCertFile, "tls-cert", "", "PEM certificate file; serve HTTPS instead of HTTP (requires --tls-key)")
+	runCmd.Flags().StringVar(&tlsKeyFile, "tls-key", "", "PEM private key file for --tls-cert")
+	runCmd.Flags().StringVar(&blockPageFile, "block-page-file", "", "Block page template served to blocked requests, reloaded on SIGHUP (overrides waf.block_body_file)")
+	runCmd.Flags().StringVar(&configDir, "config-dir", "", "Directory of *.yaml config fragments merged in lexical order")
+	runCmd.Flags().StringVar(&adminAddr, "admin-addr", "", "Serve the admin API (POST /stats/reset, POST /rules/{id}/disable|enable) on this address, e.g. 127.0.0.1:9090 (also proxy.admin_addr)")
+	runCmd.Flags().DurationVar(&snapshotInterval, "snapshot-interval", 0, "Append a snapshot of the anomaly statistics to --snapshot-file and reset them this often (0 = off)")
+	runCmd.Flags().StringVar(&snapshotFile, "snapshot-file", "", "JSON lines file for --snapshot-interval (default ./shieldcli.snapshots.jsonl)")
 
 	// Mark required flags
 	runCmd.MarkFlagRequired("proxy-to")
@@ -53,8 +95,32 @@ func runWAF() error {
 		Port:        port,
 		DryRun:      dryRun,
 		Interactive: interactive,
+		Explain:     explain,
+		ControlFile: controlFile,
 		GeminiKey:   geminiKey,
 		LogFile:     logFile,
+		MaxRequests: maxRequests,
+		Calibrate:   learnAndReport > 0,
+
+		MaxConcurrentRequests: maxConcurrent,
+		SummaryHalfLife:       summaryHalfLife,
+		StdoutFormat:          stdoutFormat,
+		TLSCertFile:           tlsCertFile,
+		TLSKeyFile:            tlsKeyFile,
+		AdminAddr:             adminAddr,
+
+		AnomalySnapshotFile:     snapshotFile,
+		AnomalySnapshotInterval: int(snapshotInterval / time.Second),
+	}
+
+	// Merge config fragments on top of any --config file
+	if configDir != "" {
+		files, err := mergeConfigDir()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Using config directory: %s (%d files)\n", configDir, len(files))
 	}
 
 	// Override with viper config if available
@@ -64,29 +130,242 @@ func runWAF() error {
 	if viper.IsSet("proxy.listen_port") {
 		cfg.Port = viper.GetInt("proxy.listen_port")
 	}
+	if viper.IsSet("proxy.tls_cert_file") && tlsCertFile == "" {
+		cfg.TLSCertFile = viper.GetString("proxy.tls_cert_file")
+	}
+	if viper.IsSet("proxy.tls_key_file") && tlsKeyFile == "" {
+		cfg.TLSKeyFile = viper.GetString("proxy.tls_key_file")
+	}
+	if viper.IsSet("proxy.timeout") {
+		cfg.Timeout = viper.GetInt("proxy.timeout")
+	}
+	if viper.IsSet("proxy.read_header_timeout") {
+		cfg.ReadHeaderTimeout = viper.GetInt("proxy.read_header_timeout")
+	}
+	if viper.IsSet("proxy.read_timeout") {
+		cfg.ReadTimeout = viper.GetInt("proxy.read_timeout")
+	}
+	if viper.IsSet("proxy.write_timeout") {
+		cfg.WriteTimeout = viper.GetInt("proxy.write_timeout")
+	}
+	if viper.IsSet("proxy.idle_timeout") {
+		cfg.IdleTimeout = viper.GetInt("proxy.idle_timeout")
+	}
+	if viper.IsSet("proxy.shutdown_timeout") {
+		cfg.ShutdownTimeout = viper.GetInt("proxy.shutdown_timeout")
+	}
+	if viper.IsSet("proxy.max_concurrent_requests") && maxConcurrent == 0 {
+		cfg.MaxConcurrentRequests = viper.GetInt("proxy.max_concurrent_requests")
+	}
+	if viper.IsSet("proxy.rate_limit_requests_per_sec") {
+		cfg.RateLimitPerSecond = viper.GetFloat64("proxy.rate_limit_requests_per_sec")
+	}
+	if viper.IsSet("proxy.rate_limit_burst") {
+		cfg.RateLimitBurst = viper.GetInt("proxy.rate_limit_burst")
+	}
+	if viper.IsSet("proxy.allowed_hosts") {
+		cfg.AllowedHosts = viper.GetStringSlice("proxy.allowed_hosts")
+	}
+	if viper.IsSet("proxy.host_reject_status") {
+		cfg.HostRejectStatus = viper.GetInt("proxy.host_reject_status")
+	}
+	if viper.IsSet("proxy.plaintext_policy") {
+		cfg.PlaintextPolicy = viper.GetString("proxy.plaintext_policy")
+	}
+	if viper.IsSet("proxy.strip_headers") {
+		cfg.StripHeaders = viper.GetStringSlice("proxy.strip_headers")
+	}
+	if viper.IsSet("proxy.trusted_proxies") {
+		cfg.TrustedProxies = viper.GetStringSlice("proxy.trusted_proxies")
+	}
+	if viper.IsSet("proxy.upstream_ca_file") {
+		cfg.UpstreamCAFile = viper.GetString("proxy.upstream_ca_file")
+	}
+	if viper.IsSet("proxy.upstream_insecure_skip_verify") {
+		cfg.UpstreamInsecureSkipVerify = viper.GetBool("proxy.upstream_insecure_skip_verify")
+	}
+	if viper.IsSet("proxy.forwarded_header") {
+		cfg.ForwardedHeader = viper.GetBool("proxy.forwarded_header")
+	}
+	if viper.IsSet("proxy.conn_flood_max_conns") {
+		cfg.ConnFloodMaxConns = viper.GetInt("proxy.conn_flood_max_conns")
+	}
+	if viper.IsSet("proxy.conn_flood_window_seconds") {
+		cfg.ConnFloodWindow = viper.GetInt("proxy.conn_flood_window_seconds")
+	}
+	if viper.IsSet("proxy.conn_flood_min_requests_per_conn") {
+		cfg.ConnFloodMinRequestsPerConn = viper.GetFloat64("proxy.conn_flood_min_requests_per_conn")
+	}
+	if viper.IsSet("proxy.slow_client_min_bytes_per_sec") {
+		cfg.SlowClientMinRate = viper.GetFloat64("proxy.slow_client_min_bytes_per_sec")
+	}
+	if viper.IsSet("proxy.slow_client_grace_seconds") {
+		cfg.SlowClientGrace = viper.GetInt("proxy.slow_client_grace_seconds")
+	}
+	if viper.IsSet("proxy.admin_addr") && adminAddr == "" {
+		cfg.AdminAddr = viper.GetString("proxy.admin_addr")
+	}
 	if viper.IsSet("waf.default_action") {
 		cfg.WAFAction = viper.GetString("waf.default_action")
 	}
+	applyBlockResponseConfig(cfg)
+	if viper.IsSet("waf.control_file") && cfg.ControlFile == "" {
+		cfg.ControlFile = viper.GetString("waf.control_file")
+	}
+	if viper.IsSet("waf.scoring_mode") {
+		cfg.ScoringMode = viper.GetBool("waf.scoring_mode")
+	}
+	if viper.IsSet("waf.anomaly_threshold") {
+		cfg.AnomalyThreshold = viper.GetInt("waf.anomaly_threshold")
+	}
+	if viper.IsSet("waf.parallel_evaluation") {
+		cfg.ParallelEvaluation = viper.GetBool("waf.parallel_evaluation")
+	}
+	if viper.IsSet("waf.parallel_workers") {
+		cfg.ParallelWorkers = viper.GetInt("waf.parallel_workers")
+	}
+	if viper.IsSet("waf.body_inspection_window_bytes") {
+		cfg.BodyInspectionWindow = viper.GetInt("waf.body_inspection_window_bytes")
+	}
+	if viper.IsSet("waf.hits_file") {
+		cfg.HitsFile = viper.GetString("waf.hits_file")
+	}
+	if viper.IsSet("anomaly.state_file") {
+		cfg.AnomalyStateFile = viper.GetString("anomaly.state_file")
+	}
+	if viper.IsSet("anomaly.adaptive") {
+		cfg.AnomalyAdaptive = viper.GetBool("anomaly.adaptive")
+	}
+	if viper.IsSet("anomaly.z_threshold") {
+		cfg.AnomalyZThreshold = viper.GetFloat64("anomaly.z_threshold")
+	}
+	if viper.IsSet("anomaly.snapshot_file") && snapshotFile == "" {
+		cfg.AnomalySnapshotFile = viper.GetString("anomaly.snapshot_file")
+	}
+	if viper.IsSet("anomaly.snapshot_interval_seconds") && snapshotInterval == 0 {
+		cfg.AnomalySnapshotInterval = viper.GetInt("anomaly.snapshot_interval_seconds")
+	}
+	if cfg.AnomalySnapshotInterval > 0 && cfg.AnomalySnapshotFile == "" {
+		cfg.AnomalySnapshotFile = "./shieldcli.snapshots.jsonl"
+	}
+	if viper.IsSet("anomaly.header_fingerprint") {
+		cfg.AnomalyHeaderFingerprint = viper.GetBool("anomaly.header_fingerprint")
+	}
+	if viper.IsSet("anomaly.request_rate_threshold") {
+		cfg.AnomalyRequestRateThreshold = viper.GetFloat64("anomaly.request_rate_threshold")
+	}
+	if viper.IsSet("anomaly.payload_size_threshold") {
+		cfg.AnomalyPayloadSizeThreshold = viper.GetFloat64("anomaly.payload_size_threshold")
+	}
+	if viper.IsSet("anomaly.entropy_threshold") {
+		cfg.AnomalyEntropyThreshold = viper.GetFloat64("anomaly.entropy_threshold")
+	}
+	if viper.IsSet("anomaly.suspicious_user_agents") {
+		// An empty list is kept as such, flagging no User-Agent
+		cfg.AnomalySuspiciousUserAgents = append([]string{}, viper.GetStringSlice("anomaly.suspicious_user_agents")...)
+	}
+	cfg.AnomalyRiskWeights = configRiskWeights()
+	if viper.IsSet("anomaly.risk_half_life_seconds") {
+		cfg.AnomalyRiskHalfLife = viper.GetInt("anomaly.risk_half_life_seconds")
+	}
+	if viper.IsSet("gemini.escalate") {
+		cfg.GeminiEscalate = viper.GetBool("gemini.escalate")
+	}
+	if viper.IsSet("gemini.analysis_threshold") {
+		cfg.GeminiAnalysisThreshold = viper.GetInt("gemini.analysis_threshold")
+	}
+	if viper.IsSet("gemini.block_confidence") {
+		cfg.GeminiBlockConfidence = viper.GetFloat64("gemini.block_confidence")
+	}
+	if viper.IsSet("gemini.block_duration_seconds") {
+		cfg.GeminiBlockDuration = viper.GetInt("gemini.block_duration_seconds")
+	}
+	if viper.IsSet("custom_rules") {
+		if err := viper.UnmarshalKey("custom_rules", &cfg.CustomRules); err != nil {
+			fmt.Printf("Error: invalid custom_rules: %v\n", err)
+			return err
+		}
+	}
+	if viper.IsSet("waf.severity_boosts") {
+		if err := viper.UnmarshalKey("waf.severity_boosts", &cfg.SeverityBoosts); err != nil {
+			fmt.Printf("Error: invalid waf.severity_boosts: %v\n", err)
+			return err
+		}
+	}
+	if viper.IsSet("waf.ip_allowlist") {
+		cfg.IPAllowlist = viper.GetStringSlice("waf.ip_allowlist")
+	}
+	if viper.IsSet("waf.ip_blocklist") {
+		cfg.IPBlocklist = viper.GetStringSlice("waf.ip_blocklist")
+	}
+	if viper.IsSet("waf.fail_mode") {
+		cfg.FailMode = viper.GetString("waf.fail_mode")
+	}
+	if viper.IsSet("waf.overload_max_latency_ms") {
+		cfg.OverloadMaxLatency = viper.GetInt("waf.overload_max_latency_ms")
+	}
+	if viper.IsSet("waf.overload_max_in_flight") {
+		cfg.OverloadMaxInFlight = viper.GetInt("waf.overload_max_in_flight")
+	}
 	if viper.IsSet("logging.file_path") {
 		cfg.LogFile = viper.GetString("logging.file_path")
 	}
-	if viper.IsSet("gemini.api_key") {
-		cfg.GeminiKey = viper.GetString("gemini.api_key")
+	if structuredEvents || viper.GetBool("logging.structured_events") {
+		if cfg.LogFile == "" {
+			err := fmt.Errorf("structured events need a log file (--log-file or logging.file_path)")
+			fmt.Printf("Error: %v\n", err)
+			return err
+		}
+		cfg.EventsFile = eventsFilePath(cfg.LogFile)
+		cfg.EventsMaxFileBytes = viper.GetInt64("logging.events_max_file_bytes")
+		cfg.EventsMaxBackups = viper.GetInt("logging.events_max_backups")
+	}
+	if viper.IsSet("logging.stdout_format") && stdoutFormat == "" {
+		cfg.StdoutFormat = viper.GetString("logging.stdout_format")
+	}
+	key, err := resolveGeminiKey(cfg.GeminiKey)
+	if err != nil {
+		return err
+	}
+	cfg.GeminiKey = key
+
+	// Calibration only observes
+	if cfg.Calibrate {
+		cfg.DryRun = true
+		cfg.Interactive = false
 	}
 
 	// Initialize logger
 	logger := logging.NewLogger(cfg.LogFile)
 	defer logger.Close()
+	if err := logger.SetStdoutFormat(cfg.StdoutFormat); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
 
 	logger.Info("ShieldCLI starting...")
+	if cfg.EventsFile != "" {
+		logger.Info("Structured events: %s", cfg.EventsFile)
+	}
 	logger.Info("Target: %s", cfg.ProxyTo)
 	logger.Info("Listen: 0.0.0.0:%d", cfg.Port)
-	if cfg.DryRun {
+	if cfg.TLSCertFile != "" {
+		logger.Info("TLS: %s", cfg.TLSCertFile)
+	}
+	if cfg.UpstreamInsecureSkipVerify {
+		logger.Warn("Upstream TLS certificates are NOT verified (upstream_insecure_skip_verify)")
+	}
+	if cfg.Calibrate {
+		logger.Info("Learning from traffic for %s without blocking, then reporting", learnAndReport)
+	} else if cfg.DryRun {
 		logger.Warn("Running in DRY-RUN mode (no blocking)")
 	}
 	if cfg.Interactive {
 		logger.Info("Running in INTERACTIVE mode")
 	}
+	if cfg.Explain {
+		logger.Info("Decision explanations enabled (written to stderr)")
+	}
 
 	// Create and start proxy
 	p, err := proxy.NewProxy(cfg, logger)
@@ -95,25 +374,226 @@ func runWAF() error {
 		return err
 	}
 
+	if cfg.GeminiEscalate {
+		analyzer, err := newAnalyzer(cfg.GeminiKey, "", logger)
+		if err != nil {
+			logger.Warn("AI escalation of suspicious requests disabled: %v", err)
+		} else {
+			defer analyzer.Close()
+			p.EnableEscalation(analyzer, cfg.GeminiAnalysisThreshold, cfg.GeminiBlockConfidence,
+				time.Duration(cfg.GeminiBlockDuration)*time.Second)
+			logger.Info("Escalating suspicious requests to %s", providerName())
+		}
+	}
+
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
 		sig := <-sigChan
-		logger.Info("Received signal: %v", sig)
-		p.Stop()
+		logger.Info("Received signal: %v, finishing in-flight requests (up to %s)", sig, p.ShutdownTimeout())
+
+		// A second signal closes the remaining connections right away
+		ctx, cancel := context.WithTimeout(context.Background(), p.ShutdownTimeout())
+		defer cancel()
+		go func() {
+			select {
+			case <-sigChan:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		p.Stop(ctx)
 	}()
 
-	// Start proxy
-	fmt.Printf("ShieldCLI is running on 0.0.0.0:%d\n", cfg.Port)
-	fmt.Printf("Forwarding to: %s\n", cfg.ProxyTo)
-	fmt.Println("Press Ctrl+C to stop")
+	watchReloadSignal(func() error {
+		if err := rereadConfig(); err != nil {
+			return err
+		}
+		blockCfg := *cfg
+		applyBlockResponseConfig(&blockCfg)
+		return p.ReloadBlockResponse(&blockCfg)
+	}, logger)
+
+	if cfg.ControlFile != "" {
+		watchControlSignal(cfg.ControlFile, p.Engine(), logger)
+		logger.Info("Rule control file: %s (send SIGUSR1 to apply)", cfg.ControlFile)
+	}
+
+	if cfg.HitsFile != "" {
+		if err := p.Engine().LoadRuleHits(cfg.HitsFile); err != nil {
+			logger.Warn("Failed to load rule hits, counting from zero: %v", err)
+		}
+		stopHits := make(chan struct{})
+		hitsDone := make(chan struct{})
+		go func() {
+			p.Engine().PersistRuleHits(cfg.HitsFile, time.Minute, stopHits)
+			close(hitsDone)
+		}()
+		defer func() {
+			close(stopHits)
+			<-hitsDone
+		}()
+		logger.Info("Persisting rule hit counts to %s", cfg.HitsFile)
+	}
+
+	if cfg.AnomalyStateFile != "" {
+		if err := p.AnomalyDetector().LoadState(cfg.AnomalyStateFile); err != nil {
+			logger.Warn("Failed to load anomaly state, learning from scratch: %v", err)
+		}
+		stopState := make(chan struct{})
+		stateDone := make(chan struct{})
+		go func() {
+			p.PersistAnomalyState(cfg.AnomalyStateFile, time.Minute, stopState)
+			close(stateDone)
+		}()
+		defer func() {
+			close(stopState)
+			<-stateDone
+		}()
+		logger.Info("Persisting anomaly detector state to %s", cfg.AnomalyStateFile)
+	}
+
+	if cfg.AnomalySnapshotInterval > 0 {
+		interval := time.Duration(cfg.AnomalySnapshotInterval) * time.Second
+		stopSnapshots := make(chan struct{})
+		if err := p.AnomalyDetector().WriteSnapshots(cfg.AnomalySnapshotFile, interval, stopSnapshots); err != nil {
+			logger.Error("%v", err)
+			return err
+		}
+		defer close(stopSnapshots)
+		logger.Info("Writing anomaly statistics snapshots to %s every %s", cfg.AnomalySnapshotFile, interval)
+	}
+
+	if cfg.AdminAddr != "" {
+		logger.Info("Admin API: http://%s (POST /stats/reset)", cfg.AdminAddr)
+	}
+
+	// Start proxy; JSON stdout must stay one object per line
+	if cfg.StdoutFormat != logging.StdoutJSON {
+		fmt.Printf("ShieldCLI is running on 0.0.0.0:%d\n", cfg.Port)
+		fmt.Printf("Forwarding to: %s\n", cfg.ProxyTo)
+		fmt.Println("Press Ctrl+C to stop")
+	}
+
+	defer p.Close()
+
+	if cfg.Calibrate {
+		timer := time.AfterFunc(learnAndReport, func() {
+			logger.Info("Learning period over, shutting down")
+			ctx, cancel := context.WithTimeout(context.Background(), p.ShutdownTimeout())
+			defer cancel()
+			p.Stop(ctx)
+		})
+		defer timer.Stop()
+	}
 
 	if err := p.Start(); err != nil {
 		logger.Error("Proxy error: %v", err)
 		return err
 	}
 
+	summary := p.Summary()
+	logger.Info("Session summary: %d requests, %d blocked (%.1f%%)", summary.Total, summary.Blocked, summary.BlockRate*100)
+	if summary.DecayedBlockRate != nil {
+		logger.Info("Recency-weighted block rate: %.1f%% (half-life %ds)", *summary.DecayedBlockRate*100, cfg.SummaryHalfLife)
+	}
+	stats := p.Engine().GetRuleStats()
+	ids := make([]int, 0, len(stats))
+	for id := range stats {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		logger.Info("Rule %d matched %d times", id, stats[id])
+	}
+	if cfg.Calibrate {
+		printCalibrationReport(p.Calibrator().Report(learnMargin), p.Engine())
+	}
+	if summaryFile != "" {
+		if err := proxy.WriteSummary(summaryFile, summary); err != nil {
+			logger.Error("%v", err)
+			return err
+		}
+	}
+	if exitNonzeroOnBlock && summary.Blocked > 0 {
+		return fmt.Errorf("%d of %d requests were blocked", summary.Blocked, summary.Total)
+	}
+
+	return nil
+}
+
+// configRiskWeights returns the risk score weights set in the config's
+// anomaly.risk_weights, or nil if none are
+func configRiskWeights() map[string]float64 {
+	var weights map[string]float64
+	for _, severity := range []string{"low", "medium", "high", "critical"} {
+		if key := "anomaly.risk_weights." + severity; viper.IsSet(key) {
+			if weights == nil {
+				weights = make(map[string]float64)
+			}
+			weights[severity] = viper.GetFloat64(key)
+		}
+	}
+	return weights
+}
+
+// eventsFilePath returns the structured event file written next to a log
+// file, e.g. ./waf.log -> ./waf.events.jsonl
+func eventsFilePath(logFile string) string {
+	return strings.TrimSuffix(logFile, filepath.Ext(logFile)) + ".events.jsonl"
+}
+
+// applyBlockResponseConfig sets the block response settings of cfg from the
+// config file and --block-page-file
+func applyBlockResponseConfig(cfg *config.Config) {
+	if viper.IsSet("waf.block_status_code") {
+		cfg.BlockStatusCode = viper.GetInt("waf.block_status_code")
+	}
+	if viper.IsSet("waf.block_body") {
+		cfg.BlockBody = viper.GetString("waf.block_body")
+	}
+	if viper.IsSet("waf.block_body_file") {
+		cfg.BlockBodyFile = viper.GetString("waf.block_body_file")
+	}
+	if viper.IsSet("waf.block_content_type") {
+		cfg.BlockContentType = viper.GetString("waf.block_content_type")
+	}
+	if blockPageFile != "" {
+		cfg.BlockBodyFile = blockPageFile
+	}
+}
+
+// rereadConfig reads the config file and --config-dir fragments again, for
+// settings that are reloaded on SIGHUP
+func rereadConfig() error {
+	if viper.ConfigFileUsed() != "" {
+		if err := viper.ReadInConfig(); err != nil {
+			return fmt.Errorf("failed to read config file: %w", err)
+		}
+	}
+	if configDir != "" {
+		if _, err := mergeConfigDir(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// mergeConfigDir merges the --config-dir fragments on top of the config file
+// in use, printing a warning for each unknown key in them, and returns the
+// fragments that were read
+func mergeConfigDir() ([]string, error) {
+	merged, files, warnings, err := config.MergeConfigDir(viper.ConfigFileUsed(), configDir, strictConfig)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config %s\n", warning)
+	}
+	if err := viper.MergeConfigMap(merged); err != nil {
+		return nil, fmt.Errorf("failed to apply config directory: %w", err)
+	}
+	return files, nil
+}
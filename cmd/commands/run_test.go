@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/proxy"
+)
+
+// freePort returns a local TCP port that was free a moment ago
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestRunSummaryAndExitCode(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	t.Setenv("GEMINI_API_KEY", "")
+	proxyTo, port = upstream.URL, freePort(t)
+	maxRequests, exitNonzeroOnBlock = 2, true
+	summaryFile = filepath.Join(t.TempDir(), "summary.json")
+	t.Cleanup(func() {
+		proxyTo, port = "", 8080
+		maxRequests, exitNonzeroOnBlock, summaryFile = 0, false, ""
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- runWAF() }()
+
+	// The session ends on its own after one clean and one malicious request.
+	// Without keep-alives the client leaves no spare connection that would
+	// hold up the graceful shutdown.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	base := fmt.Sprintf("http://127.0.0.1:%d", port)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := client.Get(base + "/")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("proxy did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	resp, err := client.Post(base+"/login", "application/x-www-form-urlencoded", strings.NewReader("user=admin' OR 1=1--"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("malicious request got %d, want 403", resp.StatusCode)
+	}
+
+	select {
+	case err := <-done:
+		if err == nil || err.Error() != "1 of 2 requests were blocked" {
+			t.Errorf("runWAF = %v, want the blocked tally as an error for a non-zero exit", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not stop after --max-requests")
+	}
+
+	data, err := os.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary proxy.Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("summary is not JSON: %v\n%s", err, data)
+	}
+	if summary.Total != 2 || summary.Blocked != 1 || summary.BlockRate != 0.5 {
+		t.Errorf("summary %+v, want 1 of 2 blocked", summary)
+	}
+	if len(summary.ByRule) != 1 || summary.ByRule["Rule 1001: SQL Injection - Common Patterns"] != 1 {
+		t.Errorf("by_rule %v, want one SQL Injection block", summary.ByRule)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !windows
+
+package commands
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// watchControlSignal applies the rule control file every time the process
+// receives SIGUSR1, so individual rules can be toggled without a restart.
+func watchControlSignal(path string, engine *waf.Engine, logger *logging.Logger) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+
+	go func() {
+		for range usr1 {
+			logger.Info("Received SIGUSR1, applying rule control file %s", path)
+			if err := engine.ApplyControlFile(path); err != nil {
+				logger.Error("Failed to apply rule control file: %v", err)
+			}
+		}
+	}()
+}
+
+// watchReloadSignal calls reload every time the process receives SIGHUP, so
+// settings such as the block page can change without a restart
+func watchReloadSignal(reload func() error, logger *logging.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			logger.Info("Received SIGHUP, reloading the block response")
+			if err := reload(); err != nil {
+				logger.Error("Failed to reload, keeping the current block response: %v", err)
+			}
+		}
+	}()
+}
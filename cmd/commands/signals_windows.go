@@ -0,0 +1,16 @@
+//go:build windows
+
+package commands
+
+import (
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// watchControlSignal is a no-op on Windows, which has no SIGUSR1
+func watchControlSignal(path string, engine *waf.Engine, logger *logging.Logger) {
+	logger.Warn("Rule control file %s is ignored: SIGUSR1 is not supported on Windows", path)
+}
+
+// watchReloadSignal is a no-op on Windows, which has no SIGHUP
+func watchReloadSignal(reload func() error, logger *logging.Logger) {}
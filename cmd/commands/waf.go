@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+	"github.com/spf13/cobra"
+)
+
+var wafCmd = &cobra.Command{
+	Use:   "waf",
+	Short: "Test the WAF engine offline",
+	Long:  `Run the WAF engine against request corpora without starting the proxy`,
+}
+
+var wafTestSuiteCmd = &cobra.Command{
+	Use:   "test-suite",
+	Short: "Run a labeled request corpus through the engine and report pass/fail",
+	Long: `Run each labeled request in a JSONL corpus through the WAF engine and report
+a confusion matrix along with every misclassification: attacks that passed and
+benign requests that were blocked. Exits non-zero when accuracy or recall falls
+below the given thresholds, so it can be used as a CI check.
+
+Each corpus line is a JSON object:
+  {"label": "malicious", "method": "GET", "url": "/?id=1' OR '1'='1", "headers": {"User-Agent": "curl"}}
+
+Rules come from the --config file when set, otherwise the built-in rules are used.
+
+Example:
+  shieldcli waf test-suite --corpus corpus.jsonl --min-recall 0.95`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return wafTestSuite()
+	},
+}
+
+var (
+	corpusFile  string
+	minAccuracy float64
+	minRecall   float64
+)
+
+func init() {
+	wafCmd.AddCommand(wafTestSuiteCmd)
+
+	wafTestSuiteCmd.Flags().StringVar(&corpusFile, "corpus", "", "JSONL file of labeled requests (required)")
+	wafTestSuiteCmd.Flags().Float64Var(&minAccuracy, "min-accuracy", 0, "Fail when accuracy is below this fraction (0-1)")
+	wafTestSuiteCmd.Flags().Float64Var(&minRecall, "min-recall", 0, "Fail when recall on malicious requests is below this fraction (0-1)")
+	wafTestSuiteCmd.MarkFlagRequired("corpus")
+}
+
+func wafTestSuite() error {
+	entries, err := waf.LoadCorpus(corpusFile)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	var engine *waf.Engine
+	if cfgFile != "" {
+		engine, err = engineFromConfigFile(cfgFile)
+	} else {
+		engine, err = waf.NewEngine(&config.Config{}, &logging.Logger{})
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	result, err := engine.RunTestSuite(entries)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return err
+	}
+
+	fmt.Println("\n=== WAF Test Suite ===")
+	fmt.Printf("Corpus: %s (%d requests)\n", corpusFile, result.Total())
+
+	fmt.Println("\n=== Confusion Matrix ===")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tBlocked\tAllowed")
+	fmt.Fprintf(w, "Malicious\t%d\t%d\n", result.TruePositives, result.FalseNegatives)
+	fmt.Fprintf(w, "Benign\t%d\t%d\n", result.FalsePositives, result.TrueNegatives)
+	w.Flush()
+
+	fmt.Printf("\nAccuracy: %.2f%%\n", result.Accuracy()*100)
+	fmt.Printf("Recall: %.2f%%\n", result.Recall()*100)
+	fmt.Printf("Precision: %.2f%%\n", result.Precision()*100)
+
+	if len(result.MissedAttacks)+len(result.BlockedBenign) > 0 {
+		fmt.Println("\n=== Misclassifications ===")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Line\tLabel\tOutcome\tMethod\tURL\tDetail")
+		fmt.Fprintln(w, "----\t-----\t-------\t------\t---\t------")
+		for _, miss := range result.MissedAttacks {
+			fmt.Fprintf(w, "%d\t%s\tallowed\t%s\t%s\t%s\n", miss.Line, miss.Entry.Label, miss.Entry.Method, miss.Entry.URL, miss.Entry.Name)
+		}
+		for _, miss := range result.BlockedBenign {
+			fmt.Fprintf(w, "%d\t%s\tblocked\t%s\t%s\t%s\n", miss.Line, miss.Entry.Label, miss.Entry.Method, miss.Entry.URL, miss.Reason)
+		}
+		w.Flush()
+	}
+
+	if result.Accuracy() < minAccuracy {
+		err := fmt.Errorf("accuracy %.2f%% is below the minimum %.2f%%", result.Accuracy()*100, minAccuracy*100)
+		fmt.Printf("\nError: %v\n", err)
+		return err
+	}
+	if result.Recall() < minRecall {
+		err := fmt.Errorf("recall %.2f%% is below the minimum %.2f%%", result.Recall()*100, minRecall*100)
+		fmt.Printf("\nError: %v\n", err)
+		return err
+	}
+
+	fmt.Println("\nPASS")
+	return nil
+}
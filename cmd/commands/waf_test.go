@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWAFTestSuiteThresholds(t *testing.T) {
+	// One blocked attack, one missed attack and two allowed benign requests
+	corpus := filepath.Join(t.TempDir(), "corpus.jsonl")
+	writeFile(t, corpus, `{"label": "malicious", "method": "POST", "url": "/login", "body": "user=admin' OR 1=1--"}
+{"label": "malicious", "url": "/users?filter=*)(uid=*))(|(uid=*"}
+{"label": "benign", "url": "/"}
+{"label": "benign", "url": "/about"}
+`)
+	t.Cleanup(func() { corpusFile, minAccuracy, minRecall = "", 0, 0 })
+
+	tests := []struct {
+		name        string
+		minAccuracy float64
+		minRecall   float64
+		wantErr     string
+	}{
+		{name: "no thresholds"},
+		{name: "thresholds met", minAccuracy: 0.75, minRecall: 0.5},
+		{name: "accuracy too low", minAccuracy: 0.8, wantErr: "accuracy 75.00% is below the minimum 80.00%"},
+		{name: "recall too low", minRecall: 0.9, wantErr: "recall 50.00% is below the minimum 90.00%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corpusFile, minAccuracy, minRecall = corpus, tt.minAccuracy, tt.minRecall
+
+			if tt.wantErr != "" {
+				if err := wafTestSuite(); err == nil || err.Error() != tt.wantErr {
+					t.Errorf("error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			out := captureStdout(t, wafTestSuite)
+			for _, want := range []string{"Malicious  1        1", "Benign     0        2", "Accuracy: 75.00%", "PASS"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("output misses %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
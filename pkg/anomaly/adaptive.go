@@ -0,0 +1,87 @@
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// SetAdaptiveThresholds switches payload size and entropy detection from the
+// fixed thresholds to the traffic's own distribution: once the time window
+// holds more than minSamples requests, a payload is anomalous when its size
+// or entropy is more than zThreshold standard deviations above the window's
+// mean. Until then the fixed thresholds apply. A zThreshold of 0 turns
+// adaptive mode off; a minSamples below 2 defaults to
+// DefaultAdaptiveMinSamples.
+func (ad *AnomalyDetector) SetAdaptiveThresholds(zThreshold float64, minSamples int) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	if minSamples < 2 {
+		minSamples = DefaultAdaptiveMinSamples
+	}
+	ad.adaptiveZThreshold = zThreshold
+	ad.adaptiveMinSamples = minSamples
+}
+
+// detectDeviations flags a payload whose size or entropy lies too far above
+// the window's mean. The request being judged was already recorded as the
+// last sample and is left out of its own baseline. Callers must hold ad.mu.
+func (ad *AnomalyDetector) detectDeviations(payloadSize int64, entropy float64) {
+	sizes := ad.requestStats.PayloadSizes
+	n := len(sizes) - 1
+	mean, stddev := meanStdDev(n, func(i int) float64 { return float64(sizes[i]) })
+	if z, ok := ad.zScore(float64(payloadSize), mean, stddev); ok {
+		ad.anomalies = append(ad.anomalies, Anomaly{
+			Timestamp: time.Now(),
+			Type:      "payload_size",
+			Severity:  "medium",
+			Value:     float64(payloadSize),
+			Threshold: mean + ad.adaptiveZThreshold*stddev,
+			Description: fmt.Sprintf("Unusually large payload: %d bytes, %s standard deviations above the mean of %.0f",
+				payloadSize, formatZ(z), mean),
+		})
+		ad.payloadStats.LargePayloads++
+	}
+
+	entropies := ad.payloadStats.EntropyValues
+	mean, stddev = meanStdDev(n, func(i int) float64 { return entropies[i] })
+	if z, ok := ad.zScore(entropy, mean, stddev); ok {
+		ad.anomalies = append(ad.anomalies, Anomaly{
+			Timestamp: time.Now(),
+			Type:      "entropy",
+			Severity:  "medium",
+			Value:     entropy,
+			Threshold: mean + ad.adaptiveZThreshold*stddev,
+			Description: fmt.Sprintf("High entropy payload detected: %.2f, %s standard deviations above the mean of %.2f",
+				entropy, formatZ(z), mean),
+		})
+		ad.payloadStats.EncodedPayloads++
+	}
+}
+
+// zScore returns how many standard deviations value lies above mean and
+// whether that exceeds the adaptive threshold. Values at or below the mean
+// are never anomalous: a smaller or plainer payload than usual is not
+// suspicious. Callers must hold ad.mu.
+func (ad *AnomalyDetector) zScore(value, mean, stddev float64) (float64, bool) {
+	deviation := value - mean
+	if deviation <= 0 {
+		return 0, false
+	}
+
+	// A baseline with no variance flags any increase
+	z := math.Inf(1)
+	if stddev > 0 {
+		z = deviation / stddev
+	}
+	return z, z > ad.adaptiveZThreshold
+}
+
+// formatZ renders a z-score for a description
+func formatZ(z float64) string {
+	if math.IsInf(z, 1) {
+		return "infinitely many"
+	}
+	return fmt.Sprintf("%.1f", z)
+}
@@ -0,0 +1,57 @@
+package anomaly
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveThresholds(t *testing.T) {
+	tests := []struct {
+		name        string
+		payloadSize int64
+		entropy     float64
+		wantType    string
+	}{
+		{name: "size outlier", payloadSize: 5000, entropy: 3, wantType: "payload_size"},
+		{name: "entropy outlier", payloadSize: 1000, entropy: 4.2, wantType: "entropy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := NewAnomalyDetector(time.Minute)
+			ad.SetAdaptiveThresholds(DefaultAdaptiveZThreshold, 30)
+
+			// A tight cluster around 1000 bytes and 3 bits/char
+			for i := 0; i < 40; i++ {
+				ad.RecordRequest(fmt.Sprintf("req-%d", i), "192.0.2.1", "Mozilla/5.0", int64(990+i%5*5), 2.95+float64(i%3)*0.05)
+			}
+			if anomalies := ad.GetAnomalies(); len(anomalies) > 0 {
+				t.Fatalf("cluster raised %+v, want no anomalies", anomalies)
+			}
+
+			ad.RecordRequest("req-outlier", "192.0.2.1", "Mozilla/5.0", tt.payloadSize, tt.entropy)
+			ad.RecordRequest("req-after", "192.0.2.1", "Mozilla/5.0", 1000, 3)
+
+			anomalies := ad.GetAnomalies()
+			if len(anomalies) != 1 || anomalies[0].Type != tt.wantType || anomalies[0].RequestID != "req-outlier" {
+				t.Errorf("anomalies %+v, want one %s anomaly for req-outlier", anomalies, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestAdaptiveThresholdsNeedSamples(t *testing.T) {
+	ad := NewAnomalyDetector(time.Minute)
+	ad.SetAdaptiveThresholds(DefaultAdaptiveZThreshold, 30)
+
+	// Too few samples for a baseline: the fixed 10 MiB threshold applies
+	for i := 0; i < 10; i++ {
+		ad.RecordRequest(fmt.Sprintf("req-%d", i), "192.0.2.1", "Mozilla/5.0", 1000, 3)
+	}
+	ad.RecordRequest("req-outlier", "192.0.2.1", "Mozilla/5.0", 5000, 3)
+
+	if anomalies := ad.GetAnomalies(); len(anomalies) > 0 {
+		t.Errorf("anomalies %+v before the minimum samples, want none", anomalies)
+	}
+}
@@ -0,0 +1,129 @@
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Calibrator observes traffic assumed to be benign, such as a dry run against
+// normal users, and recommends global thresholds that this traffic would not
+// have crossed
+type Calibrator struct {
+	mu           sync.Mutex
+	start        time.Time
+	payloadSizes []float64
+	entropies    []float64
+	perSecond    map[int64]float64 // requests per Unix second
+	userAgents   map[string]int64
+	ips          map[string]int64
+}
+
+// Distribution summarizes observed values
+type Distribution struct {
+	P50 float64
+	P99 float64
+	Max float64
+}
+
+// CalibrationReport is the outcome of a calibration run. Each recommended
+// threshold is the observed maximum raised by the margin, so none of the
+// observed requests would have been flagged.
+type CalibrationReport struct {
+	Duration time.Duration
+	Requests int64
+
+	UniqueIPs        int
+	UniqueUserAgents int
+	TopUserAgent     string
+	TopUserAgentHits int64
+
+	PayloadSize Distribution // bytes
+	RequestRate Distribution // requests per second, over seconds with traffic
+	Entropy     Distribution // bits per character
+
+	Margin                 float64 // fraction added to the observed maxima
+	RecommendedPayloadSize float64
+	RecommendedRequestRate float64
+	RecommendedEntropy     float64
+}
+
+// NewCalibrator creates a calibrator observing from now
+func NewCalibrator() *Calibrator {
+	return &Calibrator{
+		start:      time.Now(),
+		perSecond:  make(map[int64]float64),
+		userAgents: make(map[string]int64),
+		ips:        make(map[string]int64),
+	}
+}
+
+// Record observes a request
+func (c *Calibrator) Record(ip string, userAgent string, payloadSize int64, entropy float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.payloadSizes = append(c.payloadSizes, float64(payloadSize))
+	c.entropies = append(c.entropies, entropy)
+	c.perSecond[time.Now().Unix()]++
+	c.userAgents[userAgent]++
+	c.ips[ip]++
+}
+
+// Report summarizes the traffic observed so far and recommends thresholds
+// margin above its maxima, e.g. 0.25 for 25% headroom
+func (c *Calibrator) Report(margin float64) CalibrationReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rates := make([]float64, 0, len(c.perSecond))
+	for _, count := range c.perSecond {
+		rates = append(rates, count)
+	}
+
+	report := CalibrationReport{
+		Duration:         time.Since(c.start),
+		Requests:         int64(len(c.payloadSizes)),
+		UniqueIPs:        len(c.ips),
+		UniqueUserAgents: len(c.userAgents),
+		PayloadSize:      distribution(c.payloadSizes),
+		RequestRate:      distribution(rates),
+		Entropy:          distribution(c.entropies),
+		Margin:           margin,
+	}
+	for userAgent, hits := range c.userAgents {
+		if hits > report.TopUserAgentHits || (hits == report.TopUserAgentHits && userAgent < report.TopUserAgent) {
+			report.TopUserAgent, report.TopUserAgentHits = userAgent, hits
+		}
+	}
+
+	report.RecommendedPayloadSize = math.Ceil(report.PayloadSize.Max * (1 + margin))
+	report.RecommendedRequestRate = math.Ceil(report.RequestRate.Max * (1 + margin))
+	report.RecommendedEntropy = math.Ceil(report.Entropy.Max*(1+margin)*100) / 100
+	return report
+}
+
+// distribution computes the nearest-rank percentiles of values
+func distribution(values []float64) Distribution {
+	if len(values) == 0 {
+		return Distribution{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return Distribution{
+		P50: percentile(sorted, 0.50),
+		P99: percentile(sorted, 0.99),
+		Max: sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
@@ -0,0 +1,59 @@
+package anomaly
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCalibratorReport(t *testing.T) {
+	const margin = 0.25
+	c := NewCalibrator()
+
+	// Benign traffic: 100 requests from 10 IPs, mostly one browser, with
+	// payloads of 100 to 1090 bytes and entropy of 3.0 to 3.99 bits/char
+	for i := 0; i < 100; i++ {
+		userAgent := "Mozilla/5.0"
+		if i%10 == 0 {
+			userAgent = "curl/8.5.0"
+		}
+		c.Record(fmt.Sprintf("192.0.2.%d", i%10), userAgent, int64(100+i*10), 3+float64(i)/100)
+	}
+
+	report := c.Report(margin)
+
+	if report.Requests != 100 || report.UniqueIPs != 10 || report.UniqueUserAgents != 2 {
+		t.Errorf("report counts %d requests, %d IPs, %d user agents, want 100, 10, 2",
+			report.Requests, report.UniqueIPs, report.UniqueUserAgents)
+	}
+	if report.TopUserAgent != "Mozilla/5.0" || report.TopUserAgentHits != 90 {
+		t.Errorf("top user agent %q with %d hits, want Mozilla/5.0 with 90", report.TopUserAgent, report.TopUserAgentHits)
+	}
+	if got, want := report.PayloadSize, (Distribution{P50: 590, P99: 1080, Max: 1090}); got != want {
+		t.Errorf("payload size distribution %+v, want %+v", got, want)
+	}
+	if report.RequestRate.Max < 50 {
+		t.Errorf("peak request rate %v, want the 100 requests in at most two seconds", report.RequestRate.Max)
+	}
+
+	recommended := []struct {
+		name        string
+		max         float64
+		recommended float64
+	}{
+		{"payload size", report.PayloadSize.Max, report.RecommendedPayloadSize},
+		{"request rate", report.RequestRate.Max, report.RecommendedRequestRate},
+		{"entropy", report.Entropy.Max, report.RecommendedEntropy},
+	}
+	for _, r := range recommended {
+		if r.recommended < r.max*(1+margin) {
+			t.Errorf("recommended %s %v, want at least the observed maximum %v plus %v%%", r.name, r.recommended, r.max, margin*100)
+		}
+	}
+}
+
+func TestCalibratorReportNoTraffic(t *testing.T) {
+	report := NewCalibrator().Report(0.25)
+	if report.Requests != 0 || report.RecommendedPayloadSize != 0 || report.TopUserAgent != "" {
+		t.Errorf("report of no traffic %+v, want it empty", report)
+	}
+}
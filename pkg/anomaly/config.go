@@ -0,0 +1,116 @@
+package anomaly
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultSuspiciousUserAgents are the User-Agent patterns of scanners,
+// attack tools and scripted clients flagged by default
+var DefaultSuspiciousUserAgents = []string{
+	"BadBot", "SQLMap", "Nikto", "Nmap", "Masscan", "Nessus",
+	"OpenVAS", "Metasploit", "Burp", "Zaproxy", "curl", "wget",
+}
+
+// defaultUserAgentPattern is DefaultSuspiciousUserAgents compiled
+var defaultUserAgentPattern = mustCompileUserAgents(DefaultSuspiciousUserAgents)
+
+// DetectorConfig holds the settings of a detector. Zero thresholds keep the
+// defaults.
+type DetectorConfig struct {
+	RequestRateThreshold float64 // requests per second
+	PayloadSizeThreshold float64 // bytes
+	EntropyThreshold     float64 // bits per character
+
+	// SuspiciousUserAgents are regular expressions matched case-insensitively
+	// anywhere in the User-Agent, so "curl" flags "curl/7.88.1". nil keeps
+	// DefaultSuspiciousUserAgents; an empty, non-nil list flags none.
+	SuspiciousUserAgents []string
+
+	// RiskWeights replace the risk score weights of the severities they
+	// name; the others keep theirs. A zero weight leaves a severity out of
+	// the score.
+	RiskWeights  map[string]float64
+	RiskHalfLife time.Duration // replaces the risk score's decay half-life when positive
+}
+
+// Configure applies the thresholds, User-Agent patterns and risk scoring of
+// cfg. An invalid pattern, or a risk weight for an unknown severity or below
+// zero, is an error and leaves the detector unchanged.
+func (ad *AnomalyDetector) Configure(cfg DetectorConfig) error {
+	for severity, weight := range cfg.RiskWeights {
+		if _, ok := DefaultRiskScoring.SeverityWeights[severity]; !ok {
+			return fmt.Errorf("invalid risk weight severity %q (expected low, medium, high or critical)", severity)
+		}
+		if weight < 0 {
+			return fmt.Errorf("invalid %s risk weight %v: must not be negative", severity, weight)
+		}
+	}
+
+	pattern := defaultUserAgentPattern
+	if cfg.SuspiciousUserAgents != nil {
+		var err error
+		if pattern, err = compileUserAgents(cfg.SuspiciousUserAgents); err != nil {
+			return err
+		}
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	if cfg.RequestRateThreshold > 0 {
+		ad.requestRateThreshold = cfg.RequestRateThreshold
+	}
+	if cfg.PayloadSizeThreshold > 0 {
+		ad.payloadSizeThreshold = cfg.PayloadSizeThreshold
+	}
+	if cfg.EntropyThreshold > 0 {
+		ad.entropyThreshold = cfg.EntropyThreshold
+	}
+	ad.userAgentPattern = pattern
+
+	if len(cfg.RiskWeights) > 0 {
+		// The weights map may be shared with DefaultRiskScoring
+		weights := make(map[string]float64, len(ad.riskScoring.SeverityWeights))
+		for severity, weight := range ad.riskScoring.SeverityWeights {
+			weights[severity] = weight
+		}
+		for severity, weight := range cfg.RiskWeights {
+			weights[severity] = weight
+		}
+		ad.riskScoring.SeverityWeights = weights
+	}
+	if cfg.RiskHalfLife > 0 {
+		ad.riskScoring.HalfLife = cfg.RiskHalfLife
+		ad.pruneRiskHistory(time.Now())
+	}
+	return nil
+}
+
+// compileUserAgents combines User-Agent patterns into one case-insensitive
+// regular expression, or nil for no patterns
+func compileUserAgents(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	alternatives := make([]string, len(patterns))
+	for i, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid suspicious User-Agent pattern %q: %w", p, err)
+		}
+		alternatives[i] = "(?:" + p + ")"
+	}
+	return regexp.Compile("(?i)" + strings.Join(alternatives, "|"))
+}
+
+// mustCompileUserAgents is compileUserAgents for known-good patterns
+func mustCompileUserAgents(patterns []string) *regexp.Regexp {
+	re, err := compileUserAgents(patterns)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
@@ -0,0 +1,74 @@
+package anomaly
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSuspiciousUserAgents(t *testing.T) {
+	tests := []struct {
+		name      string
+		patterns  []string
+		userAgent string
+		want      bool
+	}{
+		{name: "default curl with version", userAgent: "curl/7.88.1", want: true},
+		{name: "default, any case", userAgent: "sqlmap/1.7.2#stable", want: true},
+		{name: "default browser", userAgent: "Mozilla/5.0 (X11; Linux x86_64)", want: false},
+		{name: "custom regex", patterns: []string{`^python-requests/\d`}, userAgent: "Python-Requests/2.31.0", want: true},
+		{name: "custom list replaces the defaults", patterns: []string{`^python-requests/\d`}, userAgent: "curl/7.88.1", want: false},
+		{name: "empty list flags none", patterns: []string{}, userAgent: "curl/7.88.1", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := NewAnomalyDetector(time.Minute)
+			if err := ad.Configure(DetectorConfig{SuspiciousUserAgents: tt.patterns}); err != nil {
+				t.Fatal(err)
+			}
+			if got := ad.isAnomalousUserAgent(tt.userAgent); got != tt.want {
+				t.Errorf("isAnomalousUserAgent(%q) = %v, want %v", tt.userAgent, got, tt.want)
+			}
+		})
+	}
+
+	ad := NewAnomalyDetector(time.Minute)
+	if err := ad.Configure(DetectorConfig{SuspiciousUserAgents: []string{"(unclosed"}}); err == nil {
+		t.Error("Configure accepted an invalid User-Agent pattern")
+	}
+	if !ad.isAnomalousUserAgent("curl/7.88.1") {
+		t.Error("failed Configure changed the User-Agent patterns")
+	}
+}
+
+func TestConfigureThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       DetectorConfig
+		wantTypes []string
+	}{
+		{name: "defaults"},
+		{name: "lower payload size threshold", cfg: DetectorConfig{PayloadSizeThreshold: 1024}, wantTypes: []string{"payload_size"}},
+		{name: "lower entropy threshold", cfg: DetectorConfig{EntropyThreshold: 3.5}, wantTypes: []string{"entropy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := NewAnomalyDetector(time.Minute)
+			if err := ad.Configure(tt.cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			ad.RecordRequest("req-1", "192.0.2.1", "Mozilla/5.0", 2048, 4)
+
+			var types []string
+			for _, a := range ad.GetAnomalies() {
+				types = append(types, a.Type)
+			}
+			if !reflect.DeepEqual(types, tt.wantTypes) {
+				t.Errorf("anomaly types %v, want %v", types, tt.wantTypes)
+			}
+		})
+	}
+}
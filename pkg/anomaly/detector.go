@@ -1,22 +1,54 @@
 package anomaly
 
 import (
+	"container/list"
 	"fmt"
 	"math"
+	"regexp"
+	"sort"
 	"sync"
 	"time"
 )
 
+// Default global thresholds
+const (
+	DefaultRequestRateThreshold = 1000.0           // requests per second
+	DefaultPayloadSizeThreshold = 10 * 1024 * 1024 // bytes
+	DefaultEntropyThreshold     = 4.5              // bits per character
+)
+
+// Defaults for adaptive thresholds
+const (
+	DefaultAdaptiveZThreshold = 3.0 // standard deviations above the mean that count as anomalous
+	DefaultAdaptiveMinSamples = 30  // samples in the window before the fixed thresholds give way
+)
+
 // AnomalyDetector performs statistical anomaly detection on HTTP traffic
 type AnomalyDetector struct {
 	mu                    sync.RWMutex
 	requestStats          *RequestStatistics
 	payloadStats          *PayloadStatistics
 	timeWindowSize        time.Duration
+	ipLastSeen            map[string]time.Time // last request per IP, to decay idle entries
+	userAgentLastSeen     map[string]time.Time // last request per User-Agent
+	lastIdleSweep         time.Time
 	requestRateThreshold  float64
 	payloadSizeThreshold  float64
 	entropyThreshold      float64
-	anomalies             []Anomaly
+	userAgentPattern      *regexp.Regexp // suspicious User-Agents; nil flags none
+	adaptiveZThreshold    float64 // 0 keeps the fixed payload size and entropy thresholds
+	adaptiveMinSamples    int
+	headerProfiles        []HeaderProfile
+	riskScoring           RiskScoring
+	pathProfiles          map[string]*list.Element // normalized path -> *pathProfile
+	pathOrder             *list.List               // most recently seen path first
+	globalProfile         pathProfile
+	maxPaths              int
+	pathMinSamples        int
+	pathZThreshold        float64
+	anomalies             []Anomaly // detected and not yet drained
+	riskHistory           []Anomaly // recent anomalies for the risk score, oldest first
+	statsSince            time.Time
 }
 
 // RequestStatistics tracks request-level metrics
@@ -43,46 +75,120 @@ type PayloadStatistics struct {
 
 // Anomaly represents a detected anomaly
 type Anomaly struct {
-	Timestamp   time.Time
-	Type        string // "request_rate", "payload_size", "entropy", "user_agent", "ip_address"
-	Severity    string // "low", "medium", "high", "critical"
-	Value       float64
-	Threshold   float64
-	Description string
-	RequestID   string
+	Timestamp   time.Time `json:"timestamp"`
+	Type        string    `json:"type"`     // "request_rate", "payload_size", "entropy", "user_agent", "ip_address", "header_fingerprint", "path_baseline"
+	Severity    string    `json:"severity"` // "low", "medium", "high", "critical"
+	Value       float64   `json:"value"`
+	Threshold   float64   `json:"threshold"`
+	Description string    `json:"description"`
+	RequestID   string    `json:"request_id,omitempty"`
+	IP          string    `json:"ip,omitempty"` // client IP of the request that raised it
 }
 
 // NewAnomalyDetector creates a new anomaly detector
 func NewAnomalyDetector(timeWindowSize time.Duration) *AnomalyDetector {
-	return &AnomalyDetector{
+	ad := &AnomalyDetector{
 		requestStats:         &RequestStatistics{
 			UniqueUserAgents: make(map[string]int64),
 			UniqueIPs:        make(map[string]int64),
 		},
 		payloadStats:         &PayloadStatistics{},
 		timeWindowSize:       timeWindowSize,
-		requestRateThreshold: 1000.0, // requests per second
-		payloadSizeThreshold: 10 * 1024 * 1024, // 10MB
-		entropyThreshold:     4.5,
+		ipLastSeen:           make(map[string]time.Time),
+		userAgentLastSeen:    make(map[string]time.Time),
+		lastIdleSweep:        time.Now(),
+		requestRateThreshold: DefaultRequestRateThreshold,
+		payloadSizeThreshold: DefaultPayloadSizeThreshold,
+		entropyThreshold:     DefaultEntropyThreshold,
+		userAgentPattern:     defaultUserAgentPattern,
+		headerProfiles:       DefaultBrowserProfiles,
+		riskScoring:          DefaultRiskScoring,
+		maxPaths:             DefaultMaxPaths,
+		pathMinSamples:       DefaultPathMinSamples,
+		pathZThreshold:       DefaultPathZThreshold,
 		anomalies:            make([]Anomaly, 0),
+		statsSince:           time.Now(),
 	}
+	ad.resetPaths()
+	return ad
 }
 
-// RecordRequest records a new request for analysis
-func (ad *AnomalyDetector) RecordRequest(ip string, userAgent string, payloadSize int64, entropy float64) {
+// RecordRequest records a new request for analysis. Anomalies it raises carry
+// requestID, so they can be traced back to the request's log lines.
+func (ad *AnomalyDetector) RecordRequest(requestID string, ip string, userAgent string, payloadSize int64, entropy float64) {
 	ad.mu.Lock()
 	defer ad.mu.Unlock()
 
+	ad.recordRequest(requestID, ip, userAgent, payloadSize, entropy)
+}
+
+// recordRequest updates the global statistics; callers must hold ad.mu
+func (ad *AnomalyDetector) recordRequest(requestID string, ip string, userAgent string, payloadSize int64, entropy float64) {
+	now := time.Now()
+	ad.evictExpired(now)
+
 	ad.requestStats.TotalRequests++
-	ad.requestStats.RequestTimestamps = append(ad.requestStats.RequestTimestamps, time.Now())
+	ad.requestStats.RequestTimestamps = append(ad.requestStats.RequestTimestamps, now)
 	ad.requestStats.PayloadSizes = append(ad.requestStats.PayloadSizes, payloadSize)
 	ad.requestStats.UniqueIPs[ip]++
 	ad.requestStats.UniqueUserAgents[userAgent]++
+	ad.ipLastSeen[ip] = now
+	ad.userAgentLastSeen[userAgent] = now
 
 	ad.payloadStats.EntropyValues = append(ad.payloadStats.EntropyValues, entropy)
 
 	// Detect anomalies
+	detected := len(ad.anomalies)
 	ad.detectAnomalies(ip, userAgent, payloadSize, entropy)
+	ad.recordAnomalies(detected, requestID, ip)
+}
+
+// recordAnomalies sets the request ID and client IP of the anomalies from
+// index from on and adds them to the risk history; callers must hold ad.mu
+func (ad *AnomalyDetector) recordAnomalies(from int, requestID string, ip string) {
+	for i := from; i < len(ad.anomalies); i++ {
+		ad.anomalies[i].RequestID = requestID
+		ad.anomalies[i].IP = ip
+	}
+	ad.addRiskHistory(ad.anomalies[from:], time.Now())
+}
+
+// evictExpired drops request samples older than the time window, keeping the
+// timestamp, payload size and entropy slices paired, and forgets IPs and user
+// agents idle for longer than the window. Idle entries are swept at most once
+// per window since that scans the whole map. Callers must hold ad.mu.
+func (ad *AnomalyDetector) evictExpired(now time.Time) {
+	if ad.timeWindowSize <= 0 {
+		return
+	}
+	cutoff := now.Add(-ad.timeWindowSize)
+
+	timestamps := ad.requestStats.RequestTimestamps
+	expired := sort.Search(len(timestamps), func(i int) bool {
+		return timestamps[i].After(cutoff)
+	})
+	if expired > 0 {
+		ad.requestStats.RequestTimestamps = timestamps[expired:]
+		ad.requestStats.PayloadSizes = ad.requestStats.PayloadSizes[expired:]
+		ad.payloadStats.EntropyValues = ad.payloadStats.EntropyValues[expired:]
+	}
+
+	if now.Sub(ad.lastIdleSweep) < ad.timeWindowSize {
+		return
+	}
+	ad.lastIdleSweep = now
+	for ip, seen := range ad.ipLastSeen {
+		if seen.Before(cutoff) {
+			delete(ad.ipLastSeen, ip)
+			delete(ad.requestStats.UniqueIPs, ip)
+		}
+	}
+	for userAgent, seen := range ad.userAgentLastSeen {
+		if seen.Before(cutoff) {
+			delete(ad.userAgentLastSeen, userAgent)
+			delete(ad.requestStats.UniqueUserAgents, userAgent)
+		}
+	}
 }
 
 // detectAnomalies checks for statistical anomalies
@@ -102,6 +208,14 @@ func (ad *AnomalyDetector) detectAnomalies(ip string, userAgent string, payloadS
 		}
 	}
 
+	// Adaptive mode judges payloads against the window's own distribution
+	// once it has enough samples
+	if ad.adaptiveZThreshold > 0 && len(ad.requestStats.PayloadSizes) > ad.adaptiveMinSamples {
+		ad.detectDeviations(payloadSize, entropy)
+		ad.detectNonPayloadAnomalies(ip, userAgent)
+		return
+	}
+
 	// Payload size anomaly
 	if payloadSize > int64(ad.payloadSizeThreshold) {
 		ad.anomalies = append(ad.anomalies, Anomaly{
@@ -128,6 +242,12 @@ func (ad *AnomalyDetector) detectAnomalies(ip string, userAgent string, payloadS
 		ad.payloadStats.EncodedPayloads++
 	}
 
+	ad.detectNonPayloadAnomalies(ip, userAgent)
+}
+
+// detectNonPayloadAnomalies checks the user agent and request volume of a
+// request; callers must hold ad.mu
+func (ad *AnomalyDetector) detectNonPayloadAnomalies(ip string, userAgent string) {
 	// User-Agent anomaly (if it's a bot or unusual)
 	if ad.isAnomalousUserAgent(userAgent) {
 		ad.anomalies = append(ad.anomalies, Anomaly{
@@ -156,34 +276,19 @@ func (ad *AnomalyDetector) calculateRequestsPerSecond() float64 {
 		return 0
 	}
 
-	// Get timestamps from the last second
-	now := time.Now()
-	oneSecondAgo := now.Add(-time.Second)
-
-	count := 0
-	for _, ts := range ad.requestStats.RequestTimestamps {
-		if ts.After(oneSecondAgo) {
-			count++
-		}
-	}
+	// Timestamps are in order, so those of the last second are a suffix
+	oneSecondAgo := time.Now().Add(-time.Second)
+	timestamps := ad.requestStats.RequestTimestamps
+	first := sort.Search(len(timestamps), func(i int) bool {
+		return timestamps[i].After(oneSecondAgo)
+	})
 
-	return float64(count)
+	return float64(len(timestamps) - first)
 }
 
 // isAnomalousUserAgent checks if a user agent is suspicious
 func (ad *AnomalyDetector) isAnomalousUserAgent(userAgent string) bool {
-	suspiciousAgents := []string{
-		"BadBot", "SQLMap", "Nikto", "Nmap", "Masscan", "Nessus",
-		"OpenVAS", "Metasploit", "Burp", "Zaproxy", "curl", "wget",
-	}
-
-	for _, agent := range suspiciousAgents {
-		if agent == userAgent {
-			return true
-		}
-	}
-
-	return false
+	return userAgent != "" && ad.userAgentPattern != nil && ad.userAgentPattern.MatchString(userAgent)
 }
 
 // GetStatistics returns current statistics
@@ -191,6 +296,11 @@ func (ad *AnomalyDetector) GetStatistics() map[string]interface{} {
 	ad.mu.RLock()
 	defer ad.mu.RUnlock()
 
+	return ad.statistics()
+}
+
+// statistics computes current statistics; callers must hold ad.mu
+func (ad *AnomalyDetector) statistics() map[string]interface{} {
 	avgPayloadSize := 0.0
 	if len(ad.requestStats.PayloadSizes) > 0 {
 		sum := int64(0)
@@ -217,7 +327,8 @@ func (ad *AnomalyDetector) GetStatistics() map[string]interface{} {
 		"avg_entropy":          avgEntropy,
 		"large_payloads":       ad.payloadStats.LargePayloads,
 		"encoded_payloads":     ad.payloadStats.EncodedPayloads,
-		"total_anomalies":      len(ad.anomalies),
+		"total_anomalies":      len(ad.riskHistory),
+		"risk_score":           ad.riskScoreAt(time.Now()),
 	}
 }
 
@@ -246,12 +357,26 @@ func (ad *AnomalyDetector) GetAnomaliesBySeverity(severity string) []Anomaly {
 	return filtered
 }
 
-// ClearAnomalies clears all recorded anomalies
+// ClearAnomalies clears all recorded anomalies, including those the risk
+// score counts
 func (ad *AnomalyDetector) ClearAnomalies() {
 	ad.mu.Lock()
 	defer ad.mu.Unlock()
 
 	ad.anomalies = make([]Anomaly, 0)
+	ad.riskHistory = nil
+}
+
+// DrainAnomalies returns the recorded anomalies and clears them, for callers
+// that report anomalies as they come in. The risk score and RecentAnomalies
+// are unaffected: they keep their own history.
+func (ad *AnomalyDetector) DrainAnomalies() []Anomaly {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	anomalies := ad.anomalies
+	ad.anomalies = make([]Anomaly, 0)
+	return anomalies
 }
 
 // CalculateStandardDeviation calculates the standard deviation of payload sizes
@@ -259,25 +384,47 @@ func (ad *AnomalyDetector) CalculateStandardDeviation() float64 {
 	ad.mu.RLock()
 	defer ad.mu.RUnlock()
 
-	if len(ad.requestStats.PayloadSizes) < 2 {
-		return 0
+	sizes := ad.requestStats.PayloadSizes
+	_, stddev := meanStdDev(len(sizes), func(i int) float64 { return float64(sizes[i]) })
+	return stddev
+}
+
+// CalculateEntropyStandardDeviation calculates the standard deviation of
+// payload entropy
+func (ad *AnomalyDetector) CalculateEntropyStandardDeviation() float64 {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	entropies := ad.payloadStats.EntropyValues
+	_, stddev := meanStdDev(len(entropies), func(i int) float64 { return entropies[i] })
+	return stddev
+}
+
+// meanStdDev calculates the mean and population standard deviation of the
+// first n values; the deviation is 0 for fewer than 2 values
+func meanStdDev(n int, value func(i int) float64) (float64, float64) {
+	if n == 0 {
+		return 0, 0
 	}
 
 	// Calculate mean
-	sum := int64(0)
-	for _, size := range ad.requestStats.PayloadSizes {
-		sum += size
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		sum += value(i)
+	}
+	mean := sum / float64(n)
+	if n < 2 {
+		return mean, 0
 	}
-	mean := float64(sum) / float64(len(ad.requestStats.PayloadSizes))
 
 	// Calculate variance
 	variance := 0.0
-	for _, size := range ad.requestStats.PayloadSizes {
-		diff := float64(size) - mean
+	for i := 0; i < n; i++ {
+		diff := value(i) - mean
 		variance += diff * diff
 	}
-	variance /= float64(len(ad.requestStats.PayloadSizes))
+	variance /= float64(n)
 
 	// Return standard deviation
-	return math.Sqrt(variance)
+	return mean, math.Sqrt(variance)
 }
@@ -0,0 +1,104 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordRequestSetsRequestID(t *testing.T) {
+	tests := []struct {
+		name        string
+		userAgent   string
+		payloadSize int64
+		entropy     float64
+		wantTypes   []string
+	}{
+		{name: "no anomaly", userAgent: "Mozilla/5.0", payloadSize: 10, entropy: 1},
+		{name: "payload size", userAgent: "Mozilla/5.0", payloadSize: 2048, entropy: 1, wantTypes: []string{"payload_size"}},
+		{name: "entropy and user agent", userAgent: "sqlmap/1.7", payloadSize: 10, entropy: 6, wantTypes: []string{"entropy", "user_agent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := NewAnomalyDetector(time.Minute)
+			if err := ad.Configure(DetectorConfig{PayloadSizeThreshold: 1024}); err != nil {
+				t.Fatal(err)
+			}
+
+			ad.RecordRequest("req-1", "192.0.2.1", tt.userAgent, tt.payloadSize, tt.entropy)
+
+			anomalies := ad.GetAnomalies()
+			if len(anomalies) != len(tt.wantTypes) {
+				t.Fatalf("got %d anomalies %v, want types %v", len(anomalies), anomalies, tt.wantTypes)
+			}
+			for i, a := range anomalies {
+				if a.Type != tt.wantTypes[i] {
+					t.Errorf("anomaly %d type %q, want %q", i, a.Type, tt.wantTypes[i])
+				}
+				if a.RequestID != "req-1" {
+					t.Errorf("anomaly %d request ID %q, want %q", i, a.RequestID, "req-1")
+				}
+			}
+		})
+	}
+}
+
+func TestRecordPathRequestSetsRequestID(t *testing.T) {
+	ad := NewAnomalyDetector(time.Minute)
+	for i := 0; i < DefaultPathMinSamples; i++ {
+		ad.RecordPathRequest("warmup", "/users/42", "192.0.2.1", "Mozilla/5.0", 100, 3)
+	}
+	ad.ClearAnomalies()
+
+	ad.RecordPathRequest("req-2", "/users/43", "192.0.2.1", "Mozilla/5.0", 5000, 3)
+
+	anomalies := ad.GetAnomalies()
+	if len(anomalies) != 1 || anomalies[0].Type != "path_baseline" {
+		t.Fatalf("got anomalies %v, want one path_baseline", anomalies)
+	}
+	if anomalies[0].RequestID != "req-2" {
+		t.Errorf("request ID %q, want %q", anomalies[0].RequestID, "req-2")
+	}
+}
+
+func TestRequestSamplesStayBounded(t *testing.T) {
+	const (
+		window  = time.Minute
+		events  = 10000
+		perTick = 1000 // events per window
+	)
+	ad := NewAnomalyDetector(window)
+
+	for i := 0; i < events; i++ {
+		ad.RecordRequest("", "192.0.2.1", "Mozilla/5.0", 10, 1)
+
+		// Simulate the time between events by ageing what was recorded
+		for j := range ad.requestStats.RequestTimestamps {
+			ad.requestStats.RequestTimestamps[j] = ad.requestStats.RequestTimestamps[j].Add(-window / perTick)
+		}
+	}
+
+	stats := ad.requestStats
+	if n := len(stats.RequestTimestamps); n > perTick+1 {
+		t.Errorf("%d timestamps retained after %d events, want at most %d", n, events, perTick+1)
+	}
+	if len(stats.PayloadSizes) != len(stats.RequestTimestamps) || len(ad.payloadStats.EntropyValues) != len(stats.RequestTimestamps) {
+		t.Errorf("samples unpaired: %d timestamps, %d payload sizes, %d entropy values",
+			len(stats.RequestTimestamps), len(stats.PayloadSizes), len(ad.payloadStats.EntropyValues))
+	}
+	if stats.TotalRequests != events {
+		t.Errorf("total requests %d, want %d", stats.TotalRequests, events)
+	}
+}
+
+func TestCalculateRequestsPerSecond(t *testing.T) {
+	ad := NewAnomalyDetector(time.Minute)
+	now := time.Now()
+	for _, age := range []time.Duration{30 * time.Second, 5 * time.Second, 2 * time.Second, 500 * time.Millisecond, 100 * time.Millisecond, 0} {
+		ad.requestStats.RequestTimestamps = append(ad.requestStats.RequestTimestamps, now.Add(-age))
+	}
+
+	if rps := ad.calculateRequestsPerSecond(); rps != 3 {
+		t.Errorf("requests per second %v, want 3", rps)
+	}
+}
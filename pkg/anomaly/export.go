@@ -0,0 +1,109 @@
+package anomaly
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// csvHeader is the header row of an anomaly CSV export
+var csvHeader = []string{"timestamp", "type", "severity", "value", "threshold", "description"}
+
+// WriteAnomaliesJSON writes anomalies, such as those of GetAnomalies, as an
+// indented JSON array. An empty list is written as [].
+func WriteAnomaliesJSON(w io.Writer, anomalies []Anomaly) error {
+	if anomalies == nil {
+		anomalies = []Anomaly{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(anomalies); err != nil {
+		return fmt.Errorf("failed to write anomalies as JSON: %w", err)
+	}
+	return nil
+}
+
+// ReadAnomaliesJSON reads anomalies written by WriteAnomaliesJSON
+func ReadAnomaliesJSON(r io.Reader) ([]Anomaly, error) {
+	var anomalies []Anomaly
+	if err := json.NewDecoder(r).Decode(&anomalies); err != nil {
+		return nil, fmt.Errorf("failed to parse anomalies JSON: %w", err)
+	}
+	return anomalies, nil
+}
+
+// WriteAnomaliesCSV writes anomalies as CSV with a header row and the columns
+// timestamp (RFC 3339), type, severity, value, threshold and description.
+// Request IDs are not exported.
+func WriteAnomaliesCSV(w io.Writer, anomalies []Anomaly) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write anomalies as CSV: %w", err)
+	}
+	for _, a := range anomalies {
+		record := []string{
+			a.Timestamp.Format(time.RFC3339Nano),
+			a.Type,
+			a.Severity,
+			strconv.FormatFloat(a.Value, 'g', -1, 64),
+			strconv.FormatFloat(a.Threshold, 'g', -1, 64),
+			a.Description,
+		}
+		if err := cw.Write(record); err != nil {
+			return fmt.Errorf("failed to write anomalies as CSV: %w", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write anomalies as CSV: %w", err)
+	}
+	return nil
+}
+
+// ReadAnomaliesCSV reads anomalies written by WriteAnomaliesCSV
+func ReadAnomaliesCSV(r io.Reader) ([]Anomaly, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse anomalies CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("failed to parse anomalies CSV: missing header row")
+	}
+	for i, column := range csvHeader {
+		if records[0][i] != column {
+			return nil, fmt.Errorf("failed to parse anomalies CSV: expected column %q, got %q", column, records[0][i])
+		}
+	}
+
+	anomalies := make([]Anomaly, 0, len(records)-1)
+	for i, record := range records[1:] {
+		line := i + 2
+		timestamp, err := time.Parse(time.RFC3339Nano, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse anomalies CSV: line %d: invalid timestamp: %w", line, err)
+		}
+		value, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse anomalies CSV: line %d: invalid value: %w", line, err)
+		}
+		threshold, err := strconv.ParseFloat(record[4], 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse anomalies CSV: line %d: invalid threshold: %w", line, err)
+		}
+		anomalies = append(anomalies, Anomaly{
+			Timestamp:   timestamp,
+			Type:        record[1],
+			Severity:    record[2],
+			Value:       value,
+			Threshold:   threshold,
+			Description: record[5],
+		})
+	}
+	return anomalies, nil
+}
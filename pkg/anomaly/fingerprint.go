@@ -0,0 +1,108 @@
+package anomaly
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HeaderProfile describes the headers a real client of some kind is expected to send
+type HeaderProfile struct {
+	Name     string
+	Expected []string // canonical header names normally present
+}
+
+// DefaultBrowserProfiles are header sets typically sent by mainstream browsers
+var DefaultBrowserProfiles = []HeaderProfile{
+	{
+		Name:     "browser",
+		Expected: []string{"User-Agent", "Accept", "Accept-Language", "Accept-Encoding", "Connection"},
+	},
+	{
+		Name:     "browser-fetch",
+		Expected: []string{"User-Agent", "Accept", "Accept-Language", "Accept-Encoding", "Sec-Fetch-Mode", "Sec-Fetch-Site"},
+	},
+}
+
+// headerFingerprintThreshold is the score at or above which a request is flagged
+const headerFingerprintThreshold = 0.5
+
+// HeaderFingerprintScore scores how far a request's header set deviates from
+// the closest expected profile, from 0 (matches a profile) to 1 (nothing in
+// common). Only which headers are present counts: net/http canonicalizes
+// header names as it parses them, so their capitalization on the wire is
+// not available.
+func HeaderFingerprintScore(headers http.Header, profiles []HeaderProfile) float64 {
+	_, score := closestProfile(headers, profiles)
+	return score
+}
+
+// closestProfile returns the profile with the fewest missing headers and the
+// share of its headers that are missing. An empty profiles list uses
+// DefaultBrowserProfiles.
+func closestProfile(headers http.Header, profiles []HeaderProfile) (HeaderProfile, float64) {
+	if len(profiles) == 0 {
+		profiles = DefaultBrowserProfiles
+	}
+
+	var closest HeaderProfile
+	best := 1.0
+	for _, profile := range profiles {
+		if len(profile.Expected) == 0 {
+			continue
+		}
+		score := float64(len(missingHeaders(headers, profile))) / float64(len(profile.Expected))
+		if closest.Expected == nil || score < best {
+			closest, best = profile, score
+		}
+	}
+	return closest, best
+}
+
+// missingHeaders returns the expected headers of profile the request lacks
+func missingHeaders(headers http.Header, profile HeaderProfile) []string {
+	var missing []string
+	for _, name := range profile.Expected {
+		if headers.Get(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// RecordHeaderFingerprint scores a request's headers against the browser
+// profiles and records a low-severity "header_fingerprint" anomaly when the
+// score suggests an automated client, listing the headers missing from the
+// closest profile. It is observe-only and returns the score.
+func (ad *AnomalyDetector) RecordHeaderFingerprint(requestID string, ip string, headers http.Header) float64 {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	profile, score := closestProfile(headers, ad.headerProfiles)
+	if score < headerFingerprintThreshold {
+		return score
+	}
+
+	detected := len(ad.anomalies)
+	ad.anomalies = append(ad.anomalies, Anomaly{
+		Timestamp: time.Now(),
+		Type:      "header_fingerprint",
+		Severity:  "low",
+		Value:     score,
+		Threshold: headerFingerprintThreshold,
+		Description: fmt.Sprintf("Atypical header set from %s (likely automated client), missing %s headers: %s",
+			ip, profile.Name, strings.Join(missingHeaders(headers, profile), ", ")),
+	})
+	ad.recordAnomalies(detected, requestID, ip)
+
+	return score
+}
+
+// SetHeaderProfiles replaces the expected header profiles used for fingerprinting
+func (ad *AnomalyDetector) SetHeaderProfiles(profiles []HeaderProfile) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.headerProfiles = profiles
+}
@@ -0,0 +1,81 @@
+package anomaly
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordHeaderFingerprint(t *testing.T) {
+	profiles := []HeaderProfile{
+		{Name: "browser", Expected: []string{"User-Agent", "Accept", "Accept-Language", "Cookie"}},
+		{Name: "api", Expected: []string{"User-Agent", "Authorization"}},
+	}
+
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantScore   float64
+		wantAnomaly bool
+		wantMissing string
+	}{
+		{
+			name:      "matches a profile",
+			headers:   map[string]string{"User-Agent": "Mozilla/5.0", "Accept": "*/*", "Accept-Language": "en", "Cookie": "a=b"},
+			wantScore: 0,
+		},
+		{
+			name:      "closest profile is not the first",
+			headers:   map[string]string{"User-Agent": "client/1.0", "Authorization": "Bearer x"},
+			wantScore: 0,
+		},
+		{
+			name:        "missing headers listed from the closest profile",
+			headers:     map[string]string{"Authorization": "Bearer x"},
+			wantScore:   0.5,
+			wantAnomaly: true,
+			wantMissing: "missing api headers: User-Agent",
+		},
+		{
+			name:        "no headers",
+			headers:     map[string]string{},
+			wantScore:   1,
+			wantAnomaly: true,
+			wantMissing: "missing browser headers: User-Agent, Accept, Accept-Language, Cookie",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := NewAnomalyDetector(time.Minute)
+			ad.SetHeaderProfiles(profiles)
+
+			headers := make(http.Header)
+			for name, value := range tt.headers {
+				headers.Set(name, value)
+			}
+			if score := ad.RecordHeaderFingerprint("req-1", "192.0.2.1", headers); score != tt.wantScore {
+				t.Errorf("score %.2f, want %.2f", score, tt.wantScore)
+			}
+
+			anomalies := ad.GetAnomalies()
+			if !tt.wantAnomaly {
+				if len(anomalies) != 0 {
+					t.Errorf("got anomalies %v, want none", anomalies)
+				}
+				return
+			}
+			if len(anomalies) != 1 {
+				t.Fatalf("got anomalies %v, want one", anomalies)
+			}
+			a := anomalies[0]
+			if a.Type != "header_fingerprint" || a.RequestID != "req-1" {
+				t.Errorf("got %s anomaly for request %q, want header_fingerprint for req-1", a.Type, a.RequestID)
+			}
+			if !strings.HasSuffix(a.Description, tt.wantMissing) {
+				t.Errorf("description %q, want suffix %q", a.Description, tt.wantMissing)
+			}
+		})
+	}
+}
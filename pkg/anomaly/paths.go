@@ -0,0 +1,206 @@
+package anomaly
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Defaults for per-path profiles
+const (
+	DefaultMaxPaths       = 1000 // tracked paths before the least recently seen is evicted
+	DefaultPathMinSamples = 30   // samples before a baseline is trusted
+	DefaultPathZThreshold = 3.0  // standard deviations from the mean that count as anomalous
+)
+
+// idSegmentPattern matches path segments that are identifiers rather than
+// routes: numbers, UUIDs and long hex strings
+var idSegmentPattern = regexp.MustCompile(`^([0-9]+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// runningStats tracks the mean and variance of a series without storing it
+type runningStats struct {
+	n    int64
+	mean float64
+	m2   float64
+}
+
+// add adds a sample
+func (s *runningStats) add(x float64) {
+	s.n++
+	delta := x - s.mean
+	s.mean += delta / float64(s.n)
+	s.m2 += delta * (x - s.mean)
+}
+
+// stddev returns the population standard deviation
+func (s *runningStats) stddev() float64 {
+	if s.n < 2 {
+		return 0
+	}
+	return math.Sqrt(s.m2 / float64(s.n))
+}
+
+// pathProfile is the traffic baseline of one endpoint
+type pathProfile struct {
+	path        string
+	payloadSize runningStats
+	entropy     runningStats
+}
+
+// PathProfileSummary is the exported view of an endpoint's baseline
+type PathProfileSummary struct {
+	Path               string
+	Requests           int64
+	AveragePayloadSize float64
+	PayloadSizeStdDev  float64
+	AverageEntropy     float64
+	EntropyStdDev      float64
+}
+
+// NormalizePath reduces a request path to a path pattern so that e.g.
+// /users/42 and /users/43 share a profile. The query string is dropped and
+// numeric, UUID and long hex segments become ":id".
+func NormalizePath(path string) string {
+	if i := strings.IndexAny(path, "?#"); i >= 0 {
+		path = path[:i]
+	}
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if idSegmentPattern.MatchString(segment) {
+			segments[i] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// SetMaxPaths sets how many path profiles are kept before the least recently
+// seen one is evicted
+func (ad *AnomalyDetector) SetMaxPaths(maxPaths int) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.maxPaths = maxPaths
+	ad.evictPaths()
+}
+
+// RecordPathRequest records a request like RecordRequest and also checks its
+// payload against the baseline of its own endpoint. Endpoints without enough
+// history yet are checked against the global baseline instead, so a rarely
+// hit endpoint such as /admin is not judged by the traffic of a busy one.
+func (ad *AnomalyDetector) RecordPathRequest(requestID string, path string, ip string, userAgent string, payloadSize int64, entropy float64) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.recordRequest(requestID, ip, userAgent, payloadSize, entropy)
+
+	path = NormalizePath(path)
+	profile := ad.pathProfile(path)
+
+	baseline, scope := profile, path
+	if baseline.payloadSize.n < int64(ad.pathMinSamples) {
+		baseline, scope = &ad.globalProfile, "global"
+	}
+	if baseline.payloadSize.n >= int64(ad.pathMinSamples) {
+		detected := len(ad.anomalies)
+		ad.checkDeviation(path, scope, "payload size", float64(payloadSize), &baseline.payloadSize)
+		ad.checkDeviation(path, scope, "entropy", entropy, &baseline.entropy)
+		ad.recordAnomalies(detected, requestID, ip)
+	}
+
+	profile.payloadSize.add(float64(payloadSize))
+	profile.entropy.add(entropy)
+	ad.globalProfile.payloadSize.add(float64(payloadSize))
+	ad.globalProfile.entropy.add(entropy)
+}
+
+// checkDeviation records a path_baseline anomaly when value is more than the
+// z-score threshold above the baseline mean. Only unusually large payloads and
+// high entropy are flagged; smaller or plainer than usual is not suspicious.
+// Callers must hold ad.mu.
+func (ad *AnomalyDetector) checkDeviation(path, scope, metric string, value float64, baseline *runningStats) {
+	stddev := baseline.stddev()
+	deviation := value - baseline.mean
+	if deviation <= 0 {
+		return
+	}
+
+	// A baseline with no variance flags any change
+	z := math.Inf(1)
+	if stddev > 0 {
+		z = deviation / stddev
+	}
+	if z <= ad.pathZThreshold {
+		return
+	}
+
+	ad.anomalies = append(ad.anomalies, Anomaly{
+		Timestamp: time.Now(),
+		Type:      "path_baseline",
+		Severity:  "medium",
+		Value:     value,
+		Threshold: baseline.mean,
+		Description: fmt.Sprintf("%s %.2f on %s deviates from the %s baseline (mean %.2f, stddev %.2f)",
+			metric, value, path, scope, baseline.mean, stddev),
+	})
+}
+
+// pathProfile returns the profile for a normalized path, creating it and
+// evicting the least recently seen profile if needed; callers must hold ad.mu
+func (ad *AnomalyDetector) pathProfile(path string) *pathProfile {
+	if elem, ok := ad.pathProfiles[path]; ok {
+		ad.pathOrder.MoveToFront(elem)
+		return elem.Value.(*pathProfile)
+	}
+
+	profile := &pathProfile{path: path}
+	ad.pathProfiles[path] = ad.pathOrder.PushFront(profile)
+	ad.evictPaths()
+	return profile
+}
+
+// evictPaths drops the least recently seen profiles over the cap; callers must hold ad.mu
+func (ad *AnomalyDetector) evictPaths() {
+	for ad.maxPaths > 0 && ad.pathOrder.Len() > ad.maxPaths {
+		oldest := ad.pathOrder.Back()
+		ad.pathOrder.Remove(oldest)
+		delete(ad.pathProfiles, oldest.Value.(*pathProfile).path)
+	}
+}
+
+// resetPaths clears all path profiles; callers must hold ad.mu
+func (ad *AnomalyDetector) resetPaths() {
+	ad.pathProfiles = make(map[string]*list.Element)
+	ad.pathOrder = list.New()
+	ad.globalProfile = pathProfile{path: "global"}
+}
+
+// GetPathProfiles returns the baseline of every tracked endpoint, busiest first
+func (ad *AnomalyDetector) GetPathProfiles() []PathProfileSummary {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	summaries := make([]PathProfileSummary, 0, len(ad.pathProfiles))
+	for elem := ad.pathOrder.Front(); elem != nil; elem = elem.Next() {
+		p := elem.Value.(*pathProfile)
+		summaries = append(summaries, PathProfileSummary{
+			Path:               p.path,
+			Requests:           p.payloadSize.n,
+			AveragePayloadSize: p.payloadSize.mean,
+			PayloadSizeStdDev:  p.payloadSize.stddev(),
+			AverageEntropy:     p.entropy.mean,
+			EntropyStdDev:      p.entropy.stddev(),
+		})
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Requests > summaries[j].Requests
+	})
+	return summaries
+}
@@ -0,0 +1,88 @@
+package anomaly
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatPrometheus renders the detector's statistics and anomaly counts in
+// the Prometheus text exposition format. The recent anomalies the risk score
+// counts are counted per type and severity.
+func (ad *AnomalyDetector) FormatPrometheus() string {
+	stats := ad.GetStatistics()
+	anomalies := ad.RecentAnomalies()
+
+	var buf bytes.Buffer
+
+	writeMetric := func(name, help, metricType string, value interface{}) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, metricType)
+		fmt.Fprintf(&buf, "%s %v\n", name, value)
+	}
+
+	writeMetric("shieldcli_requests_total", "Total requests analyzed.", "gauge", stats["total_requests"])
+	writeMetric("shieldcli_unique_ips", "Distinct client IPs seen.", "gauge", stats["unique_ips"])
+	writeMetric("shieldcli_unique_user_agents", "Distinct user agents seen.", "gauge", stats["unique_user_agents"])
+	writeMetric("shieldcli_avg_payload_size_bytes", "Average request payload size in bytes.", "gauge", stats["avg_payload_size"])
+	writeMetric("shieldcli_avg_payload_entropy", "Average request payload Shannon entropy.", "gauge", stats["avg_entropy"])
+	writeMetric("shieldcli_large_payloads", "Payloads over the size threshold.", "gauge", stats["large_payloads"])
+	writeMetric("shieldcli_encoded_payloads", "Payloads over the entropy threshold.", "gauge", stats["encoded_payloads"])
+	writeMetric("shieldcli_risk_score", "Severity-weighted, recency-decayed anomaly score.", "gauge", stats["risk_score"])
+
+	counts := make(map[string]int)
+	for _, a := range anomalies {
+		counts[fmt.Sprintf(`type="%s",severity="%s"`, escapeLabel(a.Type), escapeLabel(a.Severity))]++
+	}
+	labels := make([]string, 0, len(counts))
+	for label := range counts {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	fmt.Fprintln(&buf, "# HELP shieldcli_anomalies Detected anomalies by type and severity.")
+	fmt.Fprintln(&buf, "# TYPE shieldcli_anomalies gauge")
+	for _, label := range labels {
+		fmt.Fprintf(&buf, "shieldcli_anomalies{%s} %d\n", label, counts[label])
+	}
+
+	return buf.String()
+}
+
+// PushToGateway pushes the detector's metrics to a Prometheus Pushgateway
+// under the given job name, replacing any metrics previously pushed for it.
+func (ad *AnomalyDetector) PushToGateway(gatewayURL, job string) error {
+	if job == "" {
+		return fmt.Errorf("job name is required")
+	}
+
+	endpoint := strings.TrimRight(gatewayURL, "/") + "/metrics/job/" + url.PathEscape(job)
+	req, err := http.NewRequest(http.MethodPut, endpoint, strings.NewReader(ad.FormatPrometheus()))
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway returned %s", resp.Status)
+	}
+	return nil
+}
+
+// escapeLabel escapes a Prometheus label value
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return strings.ReplaceAll(value, "\n", `\n`)
+}
@@ -0,0 +1,76 @@
+package anomaly
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushToGateway(t *testing.T) {
+	var method, path, contentType, body string
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, path, contentType, body = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), string(data)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer gateway.Close()
+
+	ad := NewAnomalyDetector(time.Minute)
+	if err := ad.Configure(DetectorConfig{PayloadSizeThreshold: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	ad.RecordRequest("req-1", "192.0.2.1", "sqlmap/1.7", 4096, 3)
+	ad.RecordRequest("req-2", "192.0.2.2", "Mozilla/5.0", 4096, 3)
+
+	if err := ad.PushToGateway(gateway.URL+"/", "shieldcli anomaly"); err != nil {
+		t.Fatalf("PushToGateway: %v", err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/shieldcli%20anomaly" {
+		t.Errorf("pushed with %s %s, want PUT /metrics/job/shieldcli%%20anomaly", method, path)
+	}
+	if !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("content type %q, want text/plain", contentType)
+	}
+
+	tests := []string{
+		"# TYPE shieldcli_requests_total gauge\nshieldcli_requests_total 2\n",
+		"# TYPE shieldcli_unique_ips gauge\nshieldcli_unique_ips 2\n",
+		"# TYPE shieldcli_large_payloads gauge\nshieldcli_large_payloads 2\n",
+		"# TYPE shieldcli_risk_score gauge\n",
+		"# TYPE shieldcli_anomalies gauge\n",
+		`shieldcli_anomalies{type="payload_size",severity="medium"} 2` + "\n",
+		`shieldcli_anomalies{type="user_agent",severity="low"} 1` + "\n",
+	}
+	for _, want := range tests {
+		if !strings.Contains(body, want) {
+			t.Errorf("pushed metrics lack %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestPushToGatewayErrors(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	tests := []struct {
+		name    string
+		gateway string
+		job     string
+	}{
+		{name: "no job", gateway: failing.URL},
+		{name: "gateway error status", gateway: failing.URL, job: "shieldcli"},
+		{name: "unreachable gateway", gateway: "http://127.0.0.1:1", job: "shieldcli"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := NewAnomalyDetector(time.Minute).PushToGateway(tt.gateway, tt.job); err == nil {
+				t.Error("PushToGateway succeeded, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package anomaly
+
+import (
+	"math"
+	"time"
+)
+
+// RiskScoring configures the composite risk score. Each anomaly contributes
+// its severity weight, halved for every HalfLife that has passed since it was
+// detected, so clustered recent high-severity anomalies dominate the score.
+type RiskScoring struct {
+	SeverityWeights map[string]float64
+	HalfLife        time.Duration
+}
+
+// Bounds of the risk history. An anomaly leaves it after
+// riskHistoryHalfLives half-lives, when it weighs under 0.1% of its
+// severity; under a flood the oldest leave early once the history holds
+// maxRiskHistory anomalies.
+const (
+	riskHistoryHalfLives = 10
+	maxRiskHistory       = 10000
+)
+
+// DefaultRiskScoring is the risk scoring used by new detectors
+var DefaultRiskScoring = RiskScoring{
+	SeverityWeights: map[string]float64{
+		"low":      1,
+		"medium":   3,
+		"high":     7,
+		"critical": 10,
+	},
+	HalfLife: 5 * time.Minute,
+}
+
+// SetRiskScoring replaces the severity weights and decay used for the risk score
+func (ad *AnomalyDetector) SetRiskScoring(scoring RiskScoring) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.riskScoring = scoring
+	ad.pruneRiskHistory(time.Now())
+}
+
+// RiskScore returns the current composite risk score: the severity-weighted,
+// recency-decayed sum of recent anomalies, whether or not they were drained. It can be used as a gate, for
+// example to tighten WAF actions while the score is above a threshold.
+func (ad *AnomalyDetector) RiskScore() float64 {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	return ad.riskScoreAt(time.Now())
+}
+
+// RecentAnomalies returns the anomalies the risk score still counts, oldest
+// first. Unlike GetAnomalies, they are kept when anomalies are drained for
+// reporting.
+func (ad *AnomalyDetector) RecentAnomalies() []Anomaly {
+	ad.mu.RLock()
+	defer ad.mu.RUnlock()
+
+	anomalies := make([]Anomaly, len(ad.riskHistory))
+	copy(anomalies, ad.riskHistory)
+	return anomalies
+}
+
+// addRiskHistory adds new anomalies to the risk history; callers must hold ad.mu
+func (ad *AnomalyDetector) addRiskHistory(anomalies []Anomaly, now time.Time) {
+	ad.riskHistory = append(ad.riskHistory, anomalies...)
+	ad.pruneRiskHistory(now)
+}
+
+// pruneRiskHistory drops anomalies that no longer weigh in on the risk
+// score, and the oldest over maxRiskHistory; callers must hold ad.mu
+func (ad *AnomalyDetector) pruneRiskHistory(now time.Time) {
+	expired := 0
+	if ad.riskScoring.HalfLife > 0 {
+		cutoff := now.Add(-riskHistoryHalfLives * ad.riskScoring.HalfLife)
+		for expired < len(ad.riskHistory) && ad.riskHistory[expired].Timestamp.Before(cutoff) {
+			expired++
+		}
+	}
+	if over := len(ad.riskHistory) - expired - maxRiskHistory; over > 0 {
+		expired += over
+	}
+	if expired > 0 {
+		ad.riskHistory = ad.riskHistory[expired:]
+	}
+}
+
+// riskScoreAt computes the risk score as of now; callers must hold ad.mu
+func (ad *AnomalyDetector) riskScoreAt(now time.Time) float64 {
+	score := 0.0
+	for _, a := range ad.riskHistory {
+		weight := ad.riskScoring.SeverityWeights[a.Severity]
+		if weight == 0 {
+			continue
+		}
+
+		age := now.Sub(a.Timestamp)
+		if ad.riskScoring.HalfLife > 0 && age > 0 {
+			weight *= math.Pow(0.5, float64(age)/float64(ad.riskScoring.HalfLife))
+		}
+		score += weight
+	}
+	return score
+}
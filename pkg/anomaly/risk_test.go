@@ -0,0 +1,125 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRiskScore(t *testing.T) {
+	now := time.Now()
+	at := func(ago time.Duration, severity string) Anomaly {
+		return Anomaly{Timestamp: now.Add(-ago), Severity: severity}
+	}
+
+	tests := []struct {
+		name      string
+		anomalies []Anomaly
+		want      float64
+	}{
+		{name: "none", want: 0},
+		{name: "fresh high", anomalies: []Anomaly{at(0, "high")}, want: 7},
+		{name: "clustered", anomalies: []Anomaly{at(0, "high"), at(0, "critical"), at(0, "low")}, want: 18},
+		{name: "one half-life old", anomalies: []Anomaly{at(5*time.Minute, "critical")}, want: 5},
+		{name: "two half-lives old", anomalies: []Anomaly{at(10*time.Minute, "high")}, want: 1.75},
+		{name: "unknown severity", anomalies: []Anomaly{at(0, "weird")}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := NewAnomalyDetector(time.Minute)
+			ad.addRiskHistory(tt.anomalies, now)
+			if got := ad.riskScoreAt(now); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("risk score %.4f, want %.4f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRiskScoreSurvivesDrain(t *testing.T) {
+	ad := NewAnomalyDetector(time.Minute)
+	ad.RecordRequest("req-1", "192.0.2.1", "sqlmap/1.7", 10, 6)
+
+	before := ad.RiskScore()
+	if before < 3 {
+		t.Fatalf("risk score %.2f before drain, want at least the entropy anomaly's 3", before)
+	}
+	if drained := ad.DrainAnomalies(); len(drained) != 2 {
+		t.Fatalf("drained %v, want entropy and user_agent anomalies", drained)
+	}
+	if after := ad.RiskScore(); math.Abs(after-before) > 0.01 {
+		t.Errorf("risk score %.2f after drain, want %.2f", after, before)
+	}
+	if recent := ad.RecentAnomalies(); len(recent) != 2 || recent[0].RequestID != "req-1" {
+		t.Errorf("recent anomalies %v, want the 2 of req-1", recent)
+	}
+}
+
+func TestRiskHistoryPruning(t *testing.T) {
+	now := time.Now()
+	ad := NewAnomalyDetector(time.Minute)
+	ad.addRiskHistory([]Anomaly{
+		{Timestamp: now.Add(-riskHistoryHalfLives*DefaultRiskScoring.HalfLife - time.Second), Severity: "critical"},
+		{Timestamp: now.Add(-time.Minute), Severity: "low"},
+	}, now)
+	if recent := ad.RecentAnomalies(); len(recent) != 1 || recent[0].Severity != "low" {
+		t.Errorf("recent anomalies %v, want only the low one", recent)
+	}
+
+	flood := make([]Anomaly, maxRiskHistory+5)
+	for i := range flood {
+		flood[i] = Anomaly{Timestamp: now, Severity: "medium"}
+	}
+	ad.addRiskHistory(flood, now)
+	if recent := ad.RecentAnomalies(); len(recent) != maxRiskHistory || recent[0].Severity != "medium" {
+		t.Errorf("history holds %d anomalies, oldest %q; want %d, oldest dropped first", len(recent), recent[0].Severity, maxRiskHistory)
+	}
+}
+
+func TestConfigureRiskScoring(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      DetectorConfig
+		wantErr  bool
+		severity string
+		want     float64
+	}{
+		{name: "defaults", severity: "high", want: 7},
+		{name: "override one weight", cfg: DetectorConfig{RiskWeights: map[string]float64{"high": 12}}, severity: "high", want: 12},
+		{name: "others keep default", cfg: DetectorConfig{RiskWeights: map[string]float64{"high": 12}}, severity: "critical", want: 10},
+		{name: "zero leaves a severity out", cfg: DetectorConfig{RiskWeights: map[string]float64{"low": 0}}, severity: "low", want: 0},
+		{name: "half-life", cfg: DetectorConfig{RiskHalfLife: time.Minute}, severity: "medium", want: 1.5},
+		{name: "unknown severity", cfg: DetectorConfig{RiskWeights: map[string]float64{"severe": 1}}, wantErr: true},
+		{name: "negative weight", cfg: DetectorConfig{RiskWeights: map[string]float64{"low": -1}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ad := NewAnomalyDetector(time.Minute)
+			err := ad.Configure(tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Configure succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Configure: %v", err)
+			}
+
+			now := time.Now()
+			ad.addRiskHistory([]Anomaly{{Timestamp: now.Add(-time.Minute), Severity: tt.severity}}, now)
+			want := tt.want
+			if tt.cfg.RiskHalfLife == 0 {
+				// One minute of the default five-minute half-life
+				want *= math.Pow(0.5, 0.2)
+			}
+			if got := ad.riskScoreAt(now); math.Abs(got-want) > 1e-9 {
+				t.Errorf("risk score %.4f, want %.4f", got, want)
+			}
+			if DefaultRiskScoring.SeverityWeights["high"] != 7 {
+				t.Error("Configure changed DefaultRiskScoring")
+			}
+		})
+	}
+}
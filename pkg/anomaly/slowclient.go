@@ -0,0 +1,25 @@
+package anomaly
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecordSlowClient records a slowloris anomaly for a connection dropped for
+// delivering a request, or reading a response, slower than minRate bytes per
+// second. what describes the transfer, e.g. "sent request headers".
+func (ad *AnomalyDetector) RecordSlowClient(ip, what string, rate, minRate float64) {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	detected := len(ad.anomalies)
+	ad.anomalies = append(ad.anomalies, Anomaly{
+		Timestamp:   time.Now(),
+		Type:        "slowloris",
+		Severity:    "high",
+		Value:       rate,
+		Threshold:   minRate,
+		Description: fmt.Sprintf("Slow client %s %s at %.1f bytes/s (minimum %.0f), connection dropped", ip, what, rate, minRate),
+	})
+	ad.recordAnomalies(detected, "", ip)
+}
@@ -0,0 +1,86 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Snapshot is the traffic summary for one interval
+type Snapshot struct {
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Statistics map[string]interface{} `json:"statistics"`
+}
+
+// Reset clears the accumulated traffic statistics while keeping the
+// detector's thresholds, configuration and per-path baselines, which take
+// far longer to learn than a snapshot interval. Anomalies not yet drained
+// are kept too, so resetting does not lose them before they are reported.
+func (ad *AnomalyDetector) Reset() {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.reset()
+}
+
+// reset clears statistics; callers must hold ad.mu
+func (ad *AnomalyDetector) reset() {
+	ad.requestStats = &RequestStatistics{
+		UniqueUserAgents: make(map[string]int64),
+		UniqueIPs:        make(map[string]int64),
+	}
+	ad.payloadStats = &PayloadStatistics{}
+	ad.ipLastSeen = make(map[string]time.Time)
+	ad.userAgentLastSeen = make(map[string]time.Time)
+	ad.statsSince = time.Now()
+}
+
+// SnapshotAndReset returns the statistics accumulated since the last reset
+// and then resets them, as a single atomic step.
+func (ad *AnomalyDetector) SnapshotAndReset() Snapshot {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	snapshot := Snapshot{
+		Start:      ad.statsSince,
+		End:        time.Now(),
+		Statistics: ad.statistics(),
+	}
+	ad.reset()
+	return snapshot
+}
+
+// WriteSnapshots appends an interval snapshot to the file at path every
+// interval, resetting the statistics each time, until stop is closed. Each
+// snapshot is written as one JSON object per line.
+func (ad *AnomalyDetector) WriteSnapshots(path string, interval time.Duration, stop <-chan struct{}) error {
+	if interval <= 0 {
+		return fmt.Errorf("snapshot interval must be positive")
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot file: %w", err)
+	}
+
+	go func() {
+		defer file.Close()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		encoder := json.NewEncoder(file)
+		for {
+			select {
+			case <-ticker.C:
+				encoder.Encode(ad.SnapshotAndReset())
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
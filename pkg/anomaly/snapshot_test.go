@@ -0,0 +1,55 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotAndReset(t *testing.T) {
+	ad := NewAnomalyDetector(time.Minute)
+	if err := ad.Configure(DetectorConfig{PayloadSizeThreshold: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	ad.RecordPathRequest("req-1", "/upload", "192.0.2.1", "Mozilla/5.0", 4096, 3)
+	ad.RecordPathRequest("req-2", "/upload", "192.0.2.2", "Mozilla/5.0", 100, 3)
+
+	snapshot := ad.SnapshotAndReset()
+	after := ad.GetStatistics()
+
+	tests := []struct {
+		key          string
+		wantSnapshot interface{}
+		wantAfter    interface{}
+	}{
+		{key: "total_requests", wantSnapshot: int64(2), wantAfter: int64(0)},
+		{key: "unique_ips", wantSnapshot: 2, wantAfter: 0},
+		{key: "large_payloads", wantSnapshot: int64(1), wantAfter: int64(0)},
+		{key: "avg_payload_size", wantSnapshot: 2098.0, wantAfter: 0.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := snapshot.Statistics[tt.key]; got != tt.wantSnapshot {
+				t.Errorf("snapshot %s = %v, want %v", tt.key, got, tt.wantSnapshot)
+			}
+			if got := after[tt.key]; got != tt.wantAfter {
+				t.Errorf("after reset %s = %v, want %v", tt.key, got, tt.wantAfter)
+			}
+		})
+	}
+
+	if snapshot.End.Before(snapshot.Start) {
+		t.Errorf("snapshot ends at %v, before its start %v", snapshot.End, snapshot.Start)
+	}
+	if profiles := ad.GetPathProfiles(); len(profiles) != 1 || profiles[0].Requests != 2 {
+		t.Errorf("path profiles %+v, want /upload kept with 2 requests", profiles)
+	}
+	if anomalies := ad.DrainAnomalies(); len(anomalies) != 1 || anomalies[0].RequestID != "req-1" {
+		t.Errorf("pending anomalies %v, want the payload_size anomaly of req-1 kept", anomalies)
+	}
+
+	// Thresholds survive the reset
+	ad.RecordRequest("req-3", "192.0.2.1", "Mozilla/5.0", 2048, 3)
+	if anomalies := ad.GetAnomalies(); len(anomalies) != 1 || anomalies[0].Type != "payload_size" {
+		t.Errorf("anomalies %v, want payload_size from the configured threshold", anomalies)
+	}
+}
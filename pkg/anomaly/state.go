@@ -0,0 +1,179 @@
+package anomaly
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// detectorState is the learned state of a detector as saved by SaveState
+type detectorState struct {
+	Since             time.Time            `json:"since"`
+	RequestStats      *RequestStatistics   `json:"request_stats"`
+	PayloadStats      *PayloadStatistics   `json:"payload_stats"`
+	IPLastSeen        map[string]time.Time `json:"ip_last_seen"`
+	UserAgentLastSeen map[string]time.Time `json:"user_agent_last_seen"`
+	Paths             []pathState          `json:"paths"` // most recently seen first
+	Global            pathState            `json:"global"`
+	Anomalies         []Anomaly            `json:"anomalies"`
+	RiskHistory       []Anomaly            `json:"risk_history,omitempty"`
+}
+
+// pathState is a saved path profile
+type pathState struct {
+	Path        string     `json:"path"`
+	PayloadSize statsState `json:"payload_size"`
+	Entropy     statsState `json:"entropy"`
+}
+
+// statsState is a saved runningStats
+type statsState struct {
+	N    int64   `json:"n"`
+	Mean float64 `json:"mean"`
+	M2   float64 `json:"m2"`
+}
+
+// SaveState writes the detector's statistics, path baselines and recorded
+// anomalies to a JSON state file, so a restarted detector can continue from
+// them with LoadState. The file is replaced atomically so a crash mid-write
+// does not lose the previous state.
+func (ad *AnomalyDetector) SaveState(path string) error {
+	ad.mu.RLock()
+	state := detectorState{
+		Since:             ad.statsSince,
+		RequestStats:      ad.requestStats,
+		PayloadStats:      ad.payloadStats,
+		IPLastSeen:        ad.ipLastSeen,
+		UserAgentLastSeen: ad.userAgentLastSeen,
+		Paths:             make([]pathState, 0, ad.pathOrder.Len()),
+		Global:            savePath(&ad.globalProfile),
+		Anomalies:         ad.anomalies,
+		RiskHistory:       ad.riskHistory,
+	}
+	for elem := ad.pathOrder.Front(); elem != nil; elem = elem.Next() {
+		state.Paths = append(state.Paths, savePath(elem.Value.(*pathProfile)))
+	}
+	// Marshal before unlocking, since the state shares the detector's maps
+	data, err := json.MarshalIndent(state, "", "  ")
+	ad.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal anomaly state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write anomaly state file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write anomaly state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write anomaly state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write anomaly state file: %w", err)
+	}
+	return nil
+}
+
+// LoadState replaces the detector's statistics, path baselines and anomalies
+// with those of a state file written by SaveState. Thresholds and other
+// settings are kept. A missing file is not an error; the detector then
+// starts from scratch.
+func (ad *AnomalyDetector) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read anomaly state file: %w", err)
+	}
+
+	var state detectorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse anomaly state file: %w", err)
+	}
+
+	requestStats := state.RequestStats
+	if requestStats == nil {
+		requestStats = &RequestStatistics{}
+	}
+	if requestStats.UniqueIPs == nil {
+		requestStats.UniqueIPs = make(map[string]int64)
+	}
+	if requestStats.UniqueUserAgents == nil {
+		requestStats.UniqueUserAgents = make(map[string]int64)
+	}
+	payloadStats := state.PayloadStats
+	if payloadStats == nil {
+		payloadStats = &PayloadStatistics{}
+	}
+	// Samples are evicted by index, so the series must stay paired
+	if len(requestStats.PayloadSizes) != len(requestStats.RequestTimestamps) ||
+		len(payloadStats.EntropyValues) != len(requestStats.RequestTimestamps) {
+		return fmt.Errorf("invalid anomaly state file: request samples are not paired")
+	}
+	if state.IPLastSeen == nil {
+		state.IPLastSeen = make(map[string]time.Time)
+	}
+	if state.UserAgentLastSeen == nil {
+		state.UserAgentLastSeen = make(map[string]time.Time)
+	}
+	if state.Anomalies == nil {
+		state.Anomalies = make([]Anomaly, 0)
+	}
+	if state.Since.IsZero() {
+		state.Since = time.Now()
+	}
+
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	ad.requestStats = requestStats
+	ad.payloadStats = payloadStats
+	ad.ipLastSeen = state.IPLastSeen
+	ad.userAgentLastSeen = state.UserAgentLastSeen
+	ad.anomalies = state.Anomalies
+	ad.riskHistory = state.RiskHistory
+	ad.pruneRiskHistory(time.Now())
+	ad.statsSince = state.Since
+
+	ad.pathProfiles = make(map[string]*list.Element, len(state.Paths))
+	ad.pathOrder = list.New()
+	for _, p := range state.Paths {
+		if _, ok := ad.pathProfiles[p.Path]; ok {
+			continue
+		}
+		ad.pathProfiles[p.Path] = ad.pathOrder.PushBack(loadPath(p))
+	}
+	ad.evictPaths()
+	ad.globalProfile = *loadPath(state.Global)
+	ad.globalProfile.path = "global"
+
+	return nil
+}
+
+// savePath converts a path profile to its saved form
+func savePath(p *pathProfile) pathState {
+	return pathState{
+		Path:        p.path,
+		PayloadSize: statsState{N: p.payloadSize.n, Mean: p.payloadSize.mean, M2: p.payloadSize.m2},
+		Entropy:     statsState{N: p.entropy.n, Mean: p.entropy.mean, M2: p.entropy.m2},
+	}
+}
+
+// loadPath converts a saved path profile back
+func loadPath(p pathState) *pathProfile {
+	return &pathProfile{
+		path:        p.Path,
+		payloadSize: runningStats{n: p.PayloadSize.N, mean: p.PayloadSize.Mean, m2: p.PayloadSize.M2},
+		entropy:     runningStats{n: p.Entropy.N, mean: p.Entropy.Mean, m2: p.Entropy.M2},
+	}
+}
@@ -0,0 +1,88 @@
+package anomaly
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveLoadState(t *testing.T) {
+	newDetector := func() *AnomalyDetector {
+		ad := NewAnomalyDetector(time.Minute)
+		if err := ad.Configure(DetectorConfig{PayloadSizeThreshold: 1024}); err != nil {
+			t.Fatal(err)
+		}
+		return ad
+	}
+
+	ad := newDetector()
+	ad.RecordPathRequest("req-1", "/upload", "192.0.2.1", "Mozilla/5.0", 4096, 3)
+	ad.RecordPathRequest("req-2", "/upload", "192.0.2.2", "Mozilla/5.0", 100, 3.5)
+	ad.RecordPathRequest("req-3", "/search", "192.0.2.1", "curl/8.5.0", 20, 2)
+
+	path := filepath.Join(t.TempDir(), "anomaly.json")
+	if err := ad.SaveState(path); err != nil {
+		t.Fatalf("SaveState: %v", err)
+	}
+	restored := newDetector()
+	if err := restored.LoadState(path); err != nil {
+		t.Fatalf("LoadState: %v", err)
+	}
+
+	gotStats, wantStats := restored.GetStatistics(), ad.GetStatistics()
+	// The risk score decays with time, so it moved on since the save
+	delete(gotStats, "risk_score")
+	delete(wantStats, "risk_score")
+	if !reflect.DeepEqual(gotStats, wantStats) {
+		t.Errorf("restored statistics %v, want %v", gotStats, wantStats)
+	}
+	if got, want := restored.GetPathProfiles(), ad.GetPathProfiles(); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored path profiles %+v, want %+v", got, want)
+	}
+	if got, want := restored.CalculateStandardDeviation(), ad.CalculateStandardDeviation(); got != want {
+		t.Errorf("restored payload size deviation %v, want %v", got, want)
+	}
+	if got, want := restored.CalculateEntropyStandardDeviation(), ad.CalculateEntropyStandardDeviation(); got != want {
+		t.Errorf("restored entropy deviation %v, want %v", got, want)
+	}
+
+	got, want := restored.GetAnomalies(), ad.GetAnomalies()
+	if len(want) == 0 || len(got) != len(want) {
+		t.Fatalf("restored %d anomalies, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].RequestID != want[i].RequestID || !got[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("restored anomaly %d %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// The restored detector keeps learning from where it left off
+	restored.RecordPathRequest("req-4", "/search", "192.0.2.3", "Mozilla/5.0", 30, 2)
+	if total := restored.GetStatistics()["total_requests"]; total != int64(4) {
+		t.Errorf("total requests %v after one more request, want 4", total)
+	}
+}
+
+func TestLoadStateErrors(t *testing.T) {
+	dir := t.TempDir()
+	ad := NewAnomalyDetector(time.Minute)
+
+	if err := ad.LoadState(filepath.Join(dir, "missing.json")); err != nil {
+		t.Errorf("LoadState of a missing file: %v", err)
+	}
+
+	for name, data := range map[string]string{
+		"malformed.json": "{",
+		"unpaired.json":  `{"request_stats": {"PayloadSizes": [10, 20], "RequestTimestamps": ["2026-01-02T15:04:05Z"]}}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ad.LoadState(path); err == nil {
+			t.Errorf("LoadState accepted %s", name)
+		}
+	}
+}
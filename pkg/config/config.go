@@ -5,25 +5,125 @@ type Config struct {
 	// Proxy settings
 	ProxyTo     string
 	Port        int
-	Timeout     int // in seconds
+	Timeout     int // in seconds; fallback for ReadTimeout and WriteTimeout
+
+	// Server timeouts in seconds; 0 uses the default
+	ReadHeaderTimeout int // time to receive request headers
+	ReadTimeout       int // time to receive the whole request, body included
+	WriteTimeout      int // time from the end of the request headers to the end of the response
+	IdleTimeout       int // time a keep-alive connection may wait for its next request
+	ShutdownTimeout   int // time in-flight requests may take to finish on shutdown
+
+	// TLS termination; plain HTTP when unset
+	TLSCertFile string // PEM certificate (chain) served to clients
+	TLSKeyFile  string // PEM private key of the certificate
+
+	// Verification of https:// upstreams; system roots when unset
+	UpstreamCAFile             string // PEM CA bundle trusted for the upstream certificate
+	UpstreamInsecureSkipVerify bool   // accept any upstream certificate; unsafe outside testing
+
+	// Host header validation
+	AllowedHosts     []string // allowed Host values, wildcards supported; empty disables the check
+	HostRejectStatus int      // status code returned for rejected Host headers
+
+	// Client-controlled header stripping
+	StripHeaders   []string // headers removed from requests not sent by a trusted proxy
+	TrustedProxies []string // IPs or CIDRs whose StripHeaders and X-Forwarded-For are trusted
+
+	ForwardedHeader bool // also append an RFC 7239 Forwarded element for each request
+
+	MaxConcurrentRequests int // requests handled at once before new ones get 503; 0 means no limit
+
+	// Per-client-IP token bucket; requests over the limit get 429
+	RateLimitPerSecond float64 // sustained requests per second per IP; 0 disables rate limiting
+	RateLimitBurst     int     // requests an idle IP may send at once; 0 uses the rate rounded up
+
+	// HTTPS-only policy for plaintext requests: 'allow', 'redirect' or 'block'; empty disables it
+	PlaintextPolicy string
+
+	// Connection-flood protection
+	ConnFloodMaxConns           int     // new connections per IP per window before it is judged; 0 disables
+	ConnFloodWindow             int     // counting window in seconds
+	ConnFloodMinRequestsPerConn float64 // IPs sending fewer requests per connection are refused
+
+	// Slow-client (Slowloris / slow POST) protection
+	SlowClientMinRate float64 // minimum bytes/sec for request headers, bodies and responses; 0 disables
+	SlowClientGrace   int     // seconds a request may deliver before its rate is judged
+
+	AdminAddr string // listen address of the admin API, e.g. 127.0.0.1:9090; empty disables it
 
 	// WAF settings
 	CRSPath       string
 	WAFAction     string // 'block', 'log', 'dry-run'
-	AnomalyThreshold int
+
+	// Response sent for blocked requests; the body is a text/template
+	BlockStatusCode  int    // 0 means 403
+	BlockBody        string // inline body template; "Forbidden" when empty
+	BlockBodyFile    string // file holding the body template; overrides BlockBody
+	BlockContentType string // by BlockBodyFile extension, else "text/plain; charset=utf-8", when empty
+
+	AnomalyThreshold int  // score at which scoring mode blocks
+	ScoringMode      bool // block on accumulated rule severity instead of the first blocking rule
+	ControlFile   string // rule enable/disable commands applied on SIGUSR1
+	HitsFile      string // per-rule match counts persisted across restarts
+	CustomRules   []CustomRule // custom_rules entries loaded into the engine at startup
+	SeverityBoosts []SeverityBoost // contexts that raise the severity of rule matches
+	IPAllowlist   []string // IPs or CIDRs allowed before any rule is evaluated
+	IPBlocklist   []string // IPs or CIDRs blocked before any rule is evaluated
+	ParallelEvaluation bool // evaluate a request's rules concurrently
+	ParallelWorkers    int  // goroutines per request in parallel evaluation; 0 uses the CPU count
+	BodyInspectionWindow int // bytes of a request body inspected, the rest is streamed; 0 buffers whole bodies
+
+	// Overload protection
+	FailMode            string // 'open' or 'closed'; empty disables the overload watchdog
+	OverloadMaxLatency  int    // average WAF evaluation latency in milliseconds
+	OverloadMaxInFlight int    // in-flight requests
 
 	// Logging settings
 	LogFile    string
+	EventsFile string // JSON lines file of one structured event per request; empty disables events
+	EventsMaxFileBytes int64 // rotate the events file past this size; 0 disables rotation
+	EventsMaxBackups   int   // rotated events files kept; 0 keeps all
+	StdoutFormat string // 'pretty' or 'json'; json prints log lines and events as JSON objects
 	LogFormat  string // 'json' or 'text'
 	LogLevel   string // 'info', 'warn', 'error', 'debug'
 
+	// Anomaly detector settings
+	AnomalyStateFile  string  // detector statistics and baselines persisted across restarts
+	AnomalyAdaptive   bool    // judge payload size and entropy by z-score instead of fixed thresholds
+	AnomalyZThreshold float64 // standard deviations above the mean for adaptive mode; 0 means 3
+	AnomalyHeaderFingerprint bool // flag header sets unlike any browser's as likely automated clients
+
+	// Periodic statistics snapshots; each one resets the statistics
+	AnomalySnapshotFile     string // JSON lines file the snapshots are appended to
+	AnomalySnapshotInterval int    // seconds between snapshots; 0 disables them
+
+	// Fixed anomaly thresholds; 0 keeps the detector's default
+	AnomalyRequestRateThreshold float64  // requests per second
+	AnomalyPayloadSizeThreshold float64  // bytes
+	AnomalyEntropyThreshold     float64  // bits per character
+	AnomalySuspiciousUserAgents []string // regexes, case-insensitive; nil keeps the default list
+
+	// Composite risk score
+	AnomalyRiskWeights  map[string]float64 // weight per severity; severities left out keep their default
+	AnomalyRiskHalfLife int                // seconds for an anomaly's weight to halve; 0 keeps 5 minutes
+
 	// Gemini settings
 	GeminiKey string
 	GeminiModel string
+	GeminiEscalate          bool    // analyze suspicious requests the proxy lets through
+	GeminiAnalysisThreshold int     // scoring mode: anomaly score from which allowed requests are analyzed
+	GeminiBlockConfidence   float64 // minimum confidence of a malicious verdict to block the client IP
+	GeminiBlockDuration     int     // seconds a client IP stays blocked after a malicious verdict
 
 	// Runtime flags
+	MaxRequests int // stop after handling this many requests; 0 means no limit
+	Calibrate   bool // observe traffic for a threshold calibration report
+
+	SummaryHalfLife int // half-life in seconds of the summary's decayed block rate; 0 disables it
 	DryRun      bool
 	Interactive bool
+	Explain     bool // print a per-request evaluation trace to stderr
 }
 
 // NewConfig creates a new default configuration
@@ -31,8 +131,9 @@ func NewConfig() *Config {
 	return &Config{
 		Port:              8080,
 		Timeout:           30,
+		HostRejectStatus:  400,
 		WAFAction:         "block",
-		AnomalyThreshold:  5,
+		AnomalyThreshold:  7,
 		LogFormat:         "json",
 		LogLevel:          "info",
 		GeminiModel:       "gemini-2.5-flash",
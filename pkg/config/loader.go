@@ -1,8 +1,12 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,11 +17,72 @@ type ConfigFile struct {
 		ListenPort int    `yaml:"listen_port"`
 		TargetURL  string `yaml:"target_url"`
 		Timeout    int    `yaml:"timeout"`
+
+		TLSCertFile string `yaml:"tls_cert_file"`
+		TLSKeyFile  string `yaml:"tls_key_file"`
+
+		UpstreamCAFile             string `yaml:"upstream_ca_file"`
+		UpstreamInsecureSkipVerify bool   `yaml:"upstream_insecure_skip_verify"`
+
+		ReadHeaderTimeout int `yaml:"read_header_timeout"`
+		ReadTimeout       int `yaml:"read_timeout"`
+		WriteTimeout      int `yaml:"write_timeout"`
+		IdleTimeout       int `yaml:"idle_timeout"`
+		ShutdownTimeout   int `yaml:"shutdown_timeout"`
+
+		AllowedHosts     []string `yaml:"allowed_hosts"`
+		HostRejectStatus int      `yaml:"host_reject_status"`
+
+		MaxConcurrentRequests int `yaml:"max_concurrent_requests"`
+
+		RateLimitPerSecond float64 `yaml:"rate_limit_requests_per_sec"`
+		RateLimitBurst     int     `yaml:"rate_limit_burst"`
+
+		PlaintextPolicy string `yaml:"plaintext_policy"`
+
+		StripHeaders   []string `yaml:"strip_headers"`
+		TrustedProxies []string `yaml:"trusted_proxies"`
+
+		ForwardedHeader bool `yaml:"forwarded_header"`
+
+		ConnFloodMaxConns           int     `yaml:"conn_flood_max_conns"`
+		ConnFloodWindowSeconds      int     `yaml:"conn_flood_window_seconds"`
+		ConnFloodMinRequestsPerConn float64 `yaml:"conn_flood_min_requests_per_conn"`
+
+		SlowClientMinBytesPerSec float64 `yaml:"slow_client_min_bytes_per_sec"`
+		SlowClientGraceSeconds   int     `yaml:"slow_client_grace_seconds"`
+
+		AdminAddr string `yaml:"admin_addr"`
 	} `yaml:"proxy"`
 
 	WAF struct {
 		DefaultAction string `yaml:"default_action"`
 		EnabledRules  []int  `yaml:"enabled_rules"`
+
+		BlockStatusCode  int    `yaml:"block_status_code"`
+		BlockBody        string `yaml:"block_body"`
+		BlockBodyFile    string `yaml:"block_body_file"`
+		BlockContentType string `yaml:"block_content_type"`
+
+		ControlFile   string `yaml:"control_file"`
+		HitsFile      string `yaml:"hits_file"`
+
+		SeverityBoosts []SeverityBoost `yaml:"severity_boosts"`
+
+		IPAllowlist []string `yaml:"ip_allowlist"`
+		IPBlocklist []string `yaml:"ip_blocklist"`
+
+		ScoringMode      bool `yaml:"scoring_mode"`
+		AnomalyThreshold int  `yaml:"anomaly_threshold"`
+
+		ParallelEvaluation bool `yaml:"parallel_evaluation"`
+		ParallelWorkers    int  `yaml:"parallel_workers"`
+
+		BodyInspectionWindowBytes int `yaml:"body_inspection_window_bytes"`
+
+		FailMode               string `yaml:"fail_mode"`
+		OverloadMaxLatencyMs   int    `yaml:"overload_max_latency_ms"`
+		OverloadMaxInFlight    int    `yaml:"overload_max_in_flight"`
 	} `yaml:"waf"`
 
 	Logging struct {
@@ -25,42 +90,158 @@ type ConfigFile struct {
 		TerminalLevel   string `yaml:"terminal_level"`
 		FilePath        string `yaml:"file_path"`
 		FileFormat      string `yaml:"file_format"`
+
+		StructuredEvents bool   `yaml:"structured_events"`
+		EventsMaxBytes   int64  `yaml:"events_max_file_bytes"`
+		EventsMaxBackups int    `yaml:"events_max_backups"`
+		StdoutFormat     string `yaml:"stdout_format"`
 	} `yaml:"logging"`
 
 	Gemini struct {
-		APIKey              string `yaml:"api_key"`
-		Model               string `yaml:"model"`
-		Enabled             bool   `yaml:"enabled"`
-		AnalysisThreshold   int    `yaml:"analysis_threshold"`
+		Provider             string  `yaml:"provider"`
+		BaseURL              string  `yaml:"base_url"`
+		APIKey               string  `yaml:"api_key"`
+		APIKeyFile           string  `yaml:"api_key_file"`
+		APIKeyEnv            string  `yaml:"api_key_env"`
+		Model                string  `yaml:"model"`
+		Enabled              bool    `yaml:"enabled"`
+		Escalate             bool    `yaml:"escalate"`
+		AnalysisThreshold    int     `yaml:"analysis_threshold"`
+		BlockConfidence      float64 `yaml:"block_confidence"`
+		BlockDurationSeconds int     `yaml:"block_duration_seconds"`
+		MaxChunkTokens       int     `yaml:"max_chunk_tokens"`
+		CacheSize            int     `yaml:"cache_size"`
+		CacheTTLSeconds      int     `yaml:"cache_ttl_seconds"`
 	} `yaml:"gemini"`
 
-	CustomRules []struct {
-		ID          int    `yaml:"id"`
-		Name        string `yaml:"name"`
-		Description string `yaml:"description"`
-		Phase       string `yaml:"phase"`
-		Operator    string `yaml:"operator"`
-		Pattern     string `yaml:"pattern"`
-		Target      string `yaml:"target"`
-		Action      string `yaml:"action"`
-		Severity    string `yaml:"severity"`
-		Enabled     bool   `yaml:"enabled"`
-	} `yaml:"custom_rules"`
+	Anomaly struct {
+		StateFile  string  `yaml:"state_file"`
+		Adaptive   bool    `yaml:"adaptive"`
+		ZThreshold float64 `yaml:"z_threshold"`
+
+		HeaderFingerprint bool `yaml:"header_fingerprint"`
+
+		SnapshotFile            string `yaml:"snapshot_file"`
+		SnapshotIntervalSeconds int    `yaml:"snapshot_interval_seconds"`
+
+		RequestRateThreshold float64  `yaml:"request_rate_threshold"`
+		PayloadSizeThreshold float64  `yaml:"payload_size_threshold"`
+		EntropyThreshold     float64  `yaml:"entropy_threshold"`
+		SuspiciousUserAgents []string `yaml:"suspicious_user_agents"`
+
+		RiskWeights struct {
+			Low      float64 `yaml:"low"`
+			Medium   float64 `yaml:"medium"`
+			High     float64 `yaml:"high"`
+			Critical float64 `yaml:"critical"`
+		} `yaml:"risk_weights"`
+		RiskHalfLifeSeconds int `yaml:"risk_half_life_seconds"`
+	} `yaml:"anomaly"`
+
+	CustomRules []CustomRule `yaml:"custom_rules"`
+}
+
+// CustomRule represents a rule defined in the custom_rules section
+type CustomRule struct {
+	ID              int      `yaml:"id" schema:"required"`
+	Name            string   `yaml:"name"`
+	Description     string   `yaml:"description"`
+	Phase           string   `yaml:"phase" schema:"required"`
+	Operator        string   `yaml:"operator"`
+	Pattern         string   `yaml:"pattern"`
+	Target          string   `yaml:"target"`
+	Action          string   `yaml:"action" schema:"required"`
+	Severity        string   `yaml:"severity"`
+	Enabled         bool     `yaml:"enabled"`
+	Requires        []int    `yaml:"requires,omitempty"`
+	Transformations []string `yaml:"transformations,omitempty"`
+	FullBody        bool     `yaml:"full_body,omitempty" mapstructure:"full_body"`
+
+	// Conditions must all match the same request along with the rule's own
+	// operator, if it has one
+	Conditions []CustomRuleCondition `yaml:"conditions,omitempty"`
 }
 
-// LoadConfigFile loads a YAML configuration file
+// CustomRuleCondition is a further condition of a custom rule, with a target,
+// operator and pattern of its own
+type CustomRuleCondition struct {
+	Target          string   `yaml:"target" schema:"required"`
+	Operator        string   `yaml:"operator" schema:"required"`
+	Pattern         string   `yaml:"pattern"`
+	Transformations []string `yaml:"transformations,omitempty"`
+}
+
+// SeverityBoost raises the severity of rule matches in a risky context. Every
+// condition that is set must hold for the boost to apply.
+type SeverityBoost struct {
+	Path            string   `yaml:"path,omitempty" mapstructure:"path"`                           // regex matched against the URL path
+	IPs             []string `yaml:"ips,omitempty" mapstructure:"ips"`                             // client IPs or CIDRs on a watchlist
+	MinAnomalyScore int      `yaml:"min_anomaly_score,omitempty" mapstructure:"min_anomaly_score"` // total score of the request's matches
+	Levels          int      `yaml:"levels,omitempty" mapstructure:"levels"`                       // severity levels to raise by; default 1
+}
+
+// LoadConfigFile loads a YAML configuration file. Unknown keys are ignored;
+// use LoadConfigFileChecked to report them.
 func LoadConfigFile(filePath string) (*ConfigFile, error) {
+	cfg, _, err := LoadConfigFileChecked(filePath, false)
+	return cfg, err
+}
+
+// LoadConfigFileChecked loads a YAML configuration file and checks it for
+// unknown keys, which usually are typos (e.g. "tagret_url") that would
+// otherwise silently leave a setting at its zero value. In strict mode an
+// unknown key is an error; otherwise the file is loaded without those keys and
+// they are returned as warnings.
+func LoadConfigFileChecked(filePath string, strict bool) (*ConfigFile, []string, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	warnings, err := unknownConfigKeys(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if strict && len(warnings) > 0 {
+		return nil, nil, fmt.Errorf("invalid config file %s:\n  %s", filePath, strings.Join(warnings, "\n  "))
 	}
 
 	var cfg ConfigFile
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	return &cfg, nil
+	return &cfg, warnings, nil
+}
+
+// unknownConfigKeys decodes data with known-field checking and returns a
+// message for every key that does not map to a ConfigFile field
+func unknownConfigKeys(data []byte) ([]string, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var cfg ConfigFile
+	err := decoder.Decode(&cfg)
+
+	var typeErr *yaml.TypeError
+	switch {
+	case err == nil, errors.Is(err, io.EOF):
+		return nil, nil
+	case errors.As(err, &typeErr):
+		// Messages read "line N: field X not found in type <struct>"; the
+		// struct dump is noise for the user
+		messages := make([]string, len(typeErr.Errors))
+		for i, msg := range typeErr.Errors {
+			if prefix, _, ok := strings.Cut(msg, " not found in type "); ok {
+				line, field, _ := strings.Cut(prefix, "field ")
+				msg = fmt.Sprintf("%sunknown key %q", line, field)
+			}
+			messages[i] = msg
+		}
+		return messages, nil
+	default:
+		return nil, err
+	}
 }
 
 // SaveConfigFile saves a configuration to a YAML file
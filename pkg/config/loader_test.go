@@ -0,0 +1,57 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFileChecked(t *testing.T) {
+	const data = `proxy:
+  listen_port: 8080
+  tagret_url: http://localhost:3000
+waf:
+  default_action: block
+custom_rules:
+  - id: 9000
+    name: Admin
+    patern: ^/admin
+`
+	path := filepath.Join(t.TempDir(), "shieldcli.yaml")
+	writeTestFile(t, path, data)
+
+	t.Run("lenient", func(t *testing.T) {
+		cfg, warnings, err := LoadConfigFileChecked(path, false)
+		if err != nil {
+			t.Fatalf("LoadConfigFileChecked: %v", err)
+		}
+		want := []string{`line 3: unknown key "tagret_url"`, `line 9: unknown key "patern"`}
+		if !reflect.DeepEqual(warnings, want) {
+			t.Errorf("warnings %q, want %q", warnings, want)
+		}
+		if cfg.Proxy.ListenPort != 8080 || cfg.WAF.DefaultAction != "block" || len(cfg.CustomRules) != 1 {
+			t.Errorf("known keys not loaded: %+v", cfg)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		cfg, _, err := LoadConfigFileChecked(path, true)
+		if err == nil || cfg != nil {
+			t.Fatalf("strict load returned %+v, %v, want an error", cfg, err)
+		}
+		for _, want := range []string{path, `unknown key "tagret_url"`, `unknown key "patern"`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q misses %q", err, want)
+			}
+		}
+	})
+
+	t.Run("no unknown keys", func(t *testing.T) {
+		clean := filepath.Join(t.TempDir(), "clean.yaml")
+		writeTestFile(t, clean, "proxy:\n  target_url: http://localhost:3000\n")
+		if _, warnings, err := LoadConfigFileChecked(clean, true); err != nil || len(warnings) > 0 {
+			t.Errorf("LoadConfigFileChecked = %q, %v, want no warnings", warnings, err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeConfigDir reads the config file at base, if not empty, then every
+// *.yaml file in dir in lexical order, and deep-merges them into a single
+// configuration map.
+//
+// Merge semantics:
+//   - nested sections (proxy, waf, ...) are merged key by key
+//   - scalars and lists in later files replace those in earlier files
+//   - custom_rules are merged by id: fields of a rule whose id was already seen
+//     override the earlier definition in place, new ids are appended
+//
+// Unknown keys in the fragments of dir are reported as warnings, as
+// LoadConfigFileChecked does, or fail the merge when strict is set; base is
+// left to LoadConfigFileChecked. It returns the merged map along with the
+// fragments that were read and the warnings.
+func MergeConfigDir(base, dir string, strict bool) (map[string]interface{}, []string, []string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to list config directory: %w", err)
+	}
+	if len(files) == 0 {
+		return nil, nil, nil, fmt.Errorf("no *.yaml files found in %s", dir)
+	}
+	sort.Strings(files)
+
+	merged := make(map[string]interface{})
+	if base != "" {
+		// The base file is merged first so its custom_rules are merged by id
+		// too, rather than replaced by the fragments' lists
+		if _, err := mergeConfigFile(merged, base); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var warnings []string
+	for _, file := range files {
+		data, err := mergeConfigFile(merged, file)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		unknown, err := unknownConfigKeys(data)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse config file %s: %w", file, err)
+		}
+		if strict && len(unknown) > 0 {
+			return nil, nil, nil, fmt.Errorf("invalid config file %s:\n  %s", file, strings.Join(unknown, "\n  "))
+		}
+		for _, warning := range unknown {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", file, warning))
+		}
+	}
+
+	return merged, files, warnings, nil
+}
+
+// mergeConfigFile reads a config file and merges it into merged, returning
+// the file's contents
+func mergeConfigFile(merged map[string]interface{}, file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+
+	var fragment map[string]interface{}
+	if err := yaml.Unmarshal(data, &fragment); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", file, err)
+	}
+
+	if err := mergeConfigMaps(merged, fragment); err != nil {
+		return nil, fmt.Errorf("failed to merge config file %s: %w", file, err)
+	}
+	return data, nil
+}
+
+// mergeConfigMaps merges src into dst following the MergeConfigDir semantics
+func mergeConfigMaps(dst, src map[string]interface{}) error {
+	for key, value := range src {
+		if key == "custom_rules" {
+			rules, err := mergeCustomRules(dst[key], value)
+			if err != nil {
+				return err
+			}
+			dst[key] = rules
+			continue
+		}
+
+		srcMap, srcIsMap := value.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			if err := mergeConfigMaps(dstMap, srcMap); err != nil {
+				return err
+			}
+			continue
+		}
+
+		dst[key] = value
+	}
+	return nil
+}
+
+// mergeCustomRules merges two custom_rules lists by rule id
+func mergeCustomRules(dst, src interface{}) ([]interface{}, error) {
+	existing, _ := dst.([]interface{})
+	incoming, ok := src.([]interface{})
+	if src != nil && !ok {
+		return nil, fmt.Errorf("custom_rules must be a list")
+	}
+
+	result := append([]interface{}{}, existing...)
+	index := make(map[int]int, len(result))
+	for i, rule := range result {
+		if id, ok := customRuleID(rule); ok {
+			index[id] = i
+		}
+	}
+
+	for _, rule := range incoming {
+		id, ok := customRuleID(rule)
+		if !ok {
+			return nil, fmt.Errorf("custom_rules entry without a numeric id")
+		}
+		if i, seen := index[id]; seen {
+			// Copy before merging so the earlier fragment's map is left untouched
+			updated := make(map[string]interface{})
+			for k, v := range result[i].(map[string]interface{}) {
+				updated[k] = v
+			}
+			if err := mergeConfigMaps(updated, rule.(map[string]interface{})); err != nil {
+				return nil, err
+			}
+			result[i] = updated
+			continue
+		}
+		index[id] = len(result)
+		result = append(result, rule)
+	}
+
+	return result, nil
+}
+
+// customRuleID extracts the id of a decoded custom rule entry
+func customRuleID(rule interface{}) (int, bool) {
+	fields, ok := rule.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	id, ok := fields["id"].(int)
+	return id, ok
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeConfigDir(t *testing.T) {
+	base := `proxy:
+  listen_port: 8080
+  target_url: http://localhost:3000
+custom_rules:
+  - id: 9100
+    name: Base Rule
+    action: log
+  - id: 9101
+    name: Kept Rule
+`
+
+	tests := []struct {
+		name         string
+		base         string
+		fragments    map[string]string
+		strict       bool
+		wantRules    []string // names of the merged custom rules, in order
+		wantActions  []interface{}
+		wantPort     int
+		wantWarnings int
+		wantErr      string
+	}{
+		{
+			name: "fragments merge rules by id on top of the base file",
+			base: base,
+			fragments: map[string]string{
+				"10-rules.yaml": "custom_rules:\n  - id: 9100\n    action: block\n  - id: 9200\n    name: Fragment Rule\n",
+				"90-env.yaml":   "proxy:\n  listen_port: 9090\n",
+			},
+			wantRules:   []string{"Base Rule", "Kept Rule", "Fragment Rule"},
+			wantActions: []interface{}{"block", nil, nil},
+			wantPort:    9090,
+		},
+		{
+			name: "later fragments win",
+			fragments: map[string]string{
+				"00-a.yaml": "custom_rules:\n  - id: 1\n    name: First\n",
+				"10-b.yaml": "custom_rules:\n  - id: 1\n    name: Second\n",
+			},
+			wantRules:   []string{"Second"},
+			wantActions: []interface{}{nil},
+		},
+		{
+			name: "unknown keys warn",
+			fragments: map[string]string{
+				"00-a.yaml": "proxy:\n  listen_prot: 9090\n",
+			},
+			wantWarnings: 1,
+		},
+		{
+			name: "unknown keys fail when strict",
+			fragments: map[string]string{
+				"00-a.yaml": "proxy:\n  listen_prot: 9090\n",
+			},
+			strict:  true,
+			wantErr: `unknown key "listen_prot"`,
+		},
+		{
+			name:    "empty directory",
+			wantErr: "no *.yaml files",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fragmentDir := filepath.Join(dir, "shieldcli.d")
+			if err := os.Mkdir(fragmentDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for name, data := range tt.fragments {
+				writeTestFile(t, filepath.Join(fragmentDir, name), data)
+			}
+			basePath := ""
+			if tt.base != "" {
+				basePath = filepath.Join(dir, "shieldcli.yaml")
+				writeTestFile(t, basePath, tt.base)
+			}
+
+			merged, files, warnings, err := MergeConfigDir(basePath, fragmentDir, tt.strict)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MergeConfigDir: %v", err)
+			}
+			if len(files) != len(tt.fragments) {
+				t.Errorf("read %d fragments, want %d", len(files), len(tt.fragments))
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("warnings %q, want %d", warnings, tt.wantWarnings)
+			}
+
+			var names, actions []interface{}
+			rules, _ := merged["custom_rules"].([]interface{})
+			for _, rule := range rules {
+				fields := rule.(map[string]interface{})
+				names = append(names, fields["name"])
+				actions = append(actions, fields["action"])
+			}
+			var wantNames []interface{}
+			for _, name := range tt.wantRules {
+				wantNames = append(wantNames, name)
+			}
+			if !reflect.DeepEqual(names, wantNames) || !reflect.DeepEqual(actions, tt.wantActions) {
+				t.Errorf("rules %v with actions %v, want %v with %v", names, actions, wantNames, tt.wantActions)
+			}
+			if tt.wantPort != 0 {
+				if port := merged["proxy"].(map[string]interface{})["listen_port"]; port != tt.wantPort {
+					t.Errorf("listen_port %v, want %d", port, tt.wantPort)
+				}
+			}
+		})
+	}
+}
+
+// writeTestFile writes data to path, failing the test on error
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,14 @@
+package config
+
+// RedactedValue replaces secret values when a configuration is printed or exported
+const RedactedValue = "********"
+
+// Redacted returns a copy of the configuration with secret fields masked.
+// Empty secrets stay empty so it remains clear that nothing was configured.
+func (c *ConfigFile) Redacted() *ConfigFile {
+	redacted := *c
+	if redacted.Gemini.APIKey != "" {
+		redacted.Gemini.APIKey = RedactedValue
+	}
+	return &redacted
+}
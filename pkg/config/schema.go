@@ -0,0 +1,106 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// schemaDraft is the JSON Schema dialect of generated schemas
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema generates a JSON Schema for the config file from the ConfigFile
+// struct, so it cannot drift from what the loader accepts. enums lists the
+// valid values of string keys by their dotted path, e.g.
+// "custom_rules.operator"; for a list of strings they apply to its items.
+// Fields tagged schema:"required" are required.
+func Schema(enums map[string][]string) map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(ConfigFile{}), "", enums)
+	schema["$schema"] = schemaDraft
+	schema["title"] = "ShieldCLI configuration"
+	return schema
+}
+
+// schemaFor describes a Go type found at a dotted config path
+func schemaFor(t reflect.Type, path string, enums map[string][]string) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem(), path, enums)
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := yamlName(field)
+			if name == "" {
+				continue
+			}
+			property := schemaFor(field.Type, joinPath(path, name), enums)
+			if field.Type.Kind() == reflect.Struct {
+				// A section whose keys are all commented out parses as null,
+				// which the loader reads as unset
+				property["type"] = []string{"object", "null"}
+			}
+			properties[name] = property
+			if field.Tag.Get("schema") == "required" {
+				required = append(required, name)
+			}
+		}
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem(), path, enums),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem(), path, enums),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		schema := map[string]interface{}{"type": "string"}
+		if values, ok := enums[path]; ok {
+			schema["enum"] = values
+		}
+		return schema
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// yamlName returns the key of a struct field in YAML, or "" if it is skipped
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// joinPath appends a key to a dotted config path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSecretTTL is how long a resolved secret reference is cached
+const DefaultSecretTTL = 5 * time.Minute
+
+// SecretProvider fetches the secret stored at a location. If field is set,
+// the secret is a JSON object and only that field is returned.
+type SecretProvider interface {
+	Fetch(location, field string) (string, error)
+}
+
+// cachedSecret is a resolved secret and when it must be fetched again
+type cachedSecret struct {
+	value   string
+	expires time.Time
+}
+
+// SecretResolver resolves secret references such as vault://path#field and
+// exec://command#field, caching each value for a TTL and fetching it again
+// once it expires. Values that are not references are returned unchanged.
+type SecretResolver struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	providers map[string]SecretProvider
+	cache     map[string]cachedSecret
+	now       func() time.Time
+}
+
+// NewSecretResolver creates a resolver with the vault:// and exec:// providers
+func NewSecretResolver(ttl time.Duration) *SecretResolver {
+	r := &SecretResolver{
+		ttl:       ttl,
+		providers: make(map[string]SecretProvider),
+		cache:     make(map[string]cachedSecret),
+		now:       time.Now,
+	}
+	r.Register("vault", NewVaultProvider())
+	r.Register("exec", &ExecProvider{Timeout: 10 * time.Second})
+	return r
+}
+
+// DefaultSecretResolver resolves secret references in configuration values
+var DefaultSecretResolver = NewSecretResolver(DefaultSecretTTL)
+
+// Register adds or replaces the provider for a reference scheme
+func (r *SecretResolver) Register(scheme string, provider SecretProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers[scheme] = provider
+}
+
+// Resolve returns the secret a reference points to, or value itself if it is
+// not a reference to a registered scheme
+func (r *SecretResolver) Resolve(value string) (string, error) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	if !ok {
+		return value, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider, ok := r.providers[scheme]
+	if !ok {
+		return value, nil
+	}
+
+	now := r.now()
+	if cached, ok := r.cache[value]; ok && now.Before(cached.expires) {
+		return cached.value, nil
+	}
+
+	location, field, _ := strings.Cut(rest, "#")
+	secret, err := provider.Fetch(location, field)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s:// secret: %w", scheme, err)
+	}
+
+	r.cache[value] = cachedSecret{value: secret, expires: now.Add(r.ttl)}
+	return secret, nil
+}
+
+// secretField extracts a string field from a JSON object secret
+func secretField(data []byte, field string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("secret field %q is not a string", field)
+	}
+	return s, nil
+}
+
+// VaultProvider reads secrets from HashiCorp Vault's HTTP API. Both KV v1 and
+// KV v2 mounts are supported, e.g. vault://secret/data/shieldcli#gemini_key.
+type VaultProvider struct {
+	Addr   string // Vault address, from VAULT_ADDR
+	Token  string // Vault token, from VAULT_TOKEN
+	Client *http.Client
+}
+
+// NewVaultProvider creates a Vault provider configured from VAULT_ADDR and VAULT_TOKEN
+func NewVaultProvider() *VaultProvider {
+	return &VaultProvider{
+		Addr:   os.Getenv("VAULT_ADDR"),
+		Token:  os.Getenv("VAULT_TOKEN"),
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Fetch reads a Vault secret and returns one of its fields
+func (v *VaultProvider) Fetch(location, field string) (string, error) {
+	if v.Addr == "" || v.Token == "" {
+		return "", fmt.Errorf("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+	if field == "" {
+		return "", fmt.Errorf("vault reference needs a #field")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(v.Addr, "/")+"/v1/"+strings.TrimLeft(location, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for %s", resp.Status, location)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid vault response: %w", err)
+	}
+
+	// KV v2 nests the secret under data.data
+	data, err := json.Marshal(body.Data)
+	if err != nil {
+		return "", err
+	}
+	if nested, ok := body.Data["data"]; ok && body.Data["metadata"] != nil {
+		data = nested
+	}
+	return secretField(data, field)
+}
+
+// ExecProvider runs a command and uses its output as the secret, e.g.
+// exec://pass show shieldcli/gemini. With a #field the output is parsed as a
+// JSON object and that field is returned.
+type ExecProvider struct {
+	Timeout time.Duration
+}
+
+// Fetch runs the command and returns its trimmed output or a field of it
+func (p *ExecProvider) Fetch(location, field string) (string, error) {
+	args := strings.Fields(location)
+	if len(args) == 0 {
+		return "", fmt.Errorf("exec reference has no command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.Timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", args[0], err)
+	}
+	if field != "" {
+		return secretField(out, field)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecretResolverVault(t *testing.T) {
+	fetches := 0
+	key := "key-v1"
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/shieldcli":
+			fetches++
+			fmt.Fprintf(w, `{"data": {"data": {"gemini_key": %q}, "metadata": {"version": %d}}}`, key, fetches)
+		case "/v1/kv/shieldcli":
+			fmt.Fprint(w, `{"data": {"hmac_secret": "kv1-secret"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(vault.Close)
+
+	now := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	resolver := NewSecretResolver(time.Minute)
+	resolver.now = func() time.Time { return now }
+	resolver.Register("vault", &VaultProvider{Addr: vault.URL, Token: "test-token", Client: vault.Client()})
+
+	resolve := func(ref, want string) {
+		t.Helper()
+		got, err := resolver.Resolve(ref)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", ref, err)
+		}
+		if got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", ref, got, want)
+		}
+	}
+
+	const ref = "vault://secret/data/shieldcli#gemini_key"
+	resolve(ref, "key-v1")
+	key = "key-v2"
+	now = now.Add(30 * time.Second)
+	resolve(ref, "key-v1")
+	if fetches != 1 {
+		t.Errorf("%d fetches within the TTL, want 1", fetches)
+	}
+
+	// Once the TTL passes, the rotated secret is fetched
+	now = now.Add(31 * time.Second)
+	resolve(ref, "key-v2")
+	if fetches != 2 {
+		t.Errorf("%d fetches after the TTL, want 2", fetches)
+	}
+
+	resolve("vault://kv/shieldcli#hmac_secret", "kv1-secret")
+	resolve("plain-key", "plain-key")
+	resolve("https://example.com/key", "https://example.com/key")
+
+	for _, bad := range []string{"vault://secret/data/missing#gemini_key", "vault://secret/data/shieldcli#other", "vault://secret/data/shieldcli"} {
+		if _, err := resolver.Resolve(bad); err == nil {
+			t.Errorf("Resolve(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestSecretResolverExec(t *testing.T) {
+	resolver := NewSecretResolver(time.Minute)
+
+	got, err := resolver.Resolve("exec://echo exec-secret")
+	if err != nil || got != "exec-secret" {
+		t.Errorf("Resolve = %q, %v, want exec-secret", got, err)
+	}
+	if _, err := resolver.Resolve("exec://false"); err == nil || !strings.Contains(err.Error(), "exec://") {
+		t.Errorf("failing command: error %v, want an exec:// error", err)
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultGeminiKeyEnv is the environment variable consulted for the Gemini API key
+// when no other source is configured
+const DefaultGeminiKeyEnv = "GEMINI_API_KEY"
+
+// DefaultOpenAIKeyEnv takes the place of DefaultGeminiKeyEnv when the
+// configured provider is an OpenAI-compatible endpoint
+const DefaultOpenAIKeyEnv = "OPENAI_API_KEY"
+
+// GeminiKeySources lists every place the Gemini API key can come from
+type GeminiKeySources struct {
+	Flag    string // value of the --gemini-key flag
+	KeyFile string // gemini.api_key_file: path to a file holding the key
+	KeyEnv  string // gemini.api_key_env: name of an environment variable holding the key
+	Literal string // gemini.api_key: the key written directly in the config file
+
+	// DefaultEnv is the environment variable checked after KeyEnv,
+	// DefaultGeminiKeyEnv if empty
+	DefaultEnv string
+}
+
+// ResolveGeminiKey returns the Gemini API key from the first source that provides one.
+// Sources are checked in order: the command-line flag, api_key_file, api_key_env,
+// the GEMINI_API_KEY (or DefaultEnv) environment variable, and finally the
+// literal api_key.
+// An empty string is returned when no source is set. A key that is a secret
+// reference (vault://path#field or exec://command) is resolved through
+// DefaultSecretResolver.
+func ResolveGeminiKey(src GeminiKeySources) (string, error) {
+	key, err := geminiKeyFromSources(src)
+	if err != nil || key == "" {
+		return key, err
+	}
+	return DefaultSecretResolver.Resolve(key)
+}
+
+// geminiKeyFromSources returns the Gemini API key, or secret reference, from
+// the first source that provides one
+func geminiKeyFromSources(src GeminiKeySources) (string, error) {
+	if key := strings.TrimSpace(src.Flag); key != "" {
+		return key, nil
+	}
+
+	if src.KeyFile != "" {
+		data, err := os.ReadFile(src.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read Gemini API key file: %w", err)
+		}
+		if key := strings.TrimSpace(string(data)); key != "" {
+			return key, nil
+		}
+	}
+
+	if src.KeyEnv != "" {
+		if key := strings.TrimSpace(os.Getenv(src.KeyEnv)); key != "" {
+			return key, nil
+		}
+	}
+
+	defaultEnv := src.DefaultEnv
+	if defaultEnv == "" {
+		defaultEnv = DefaultGeminiKeyEnv
+	}
+	if key := strings.TrimSpace(os.Getenv(defaultEnv)); key != "" {
+		return key, nil
+	}
+
+	return strings.TrimSpace(src.Literal), nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveGeminiKeyPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "gemini.key")
+	writeTestFile(t, keyFile, "  file-key\n")
+	emptyFile := filepath.Join(dir, "empty.key")
+	writeTestFile(t, emptyFile, "\n")
+
+	all := GeminiKeySources{Flag: "flag-key", KeyFile: keyFile, KeyEnv: "SHIELDCLI_TEST_KEY", Literal: "literal-key"}
+
+	tests := []struct {
+		name    string
+		src     GeminiKeySources
+		env     map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "flag first",
+			src:  all,
+			env:  map[string]string{"SHIELDCLI_TEST_KEY": "env-key", DefaultGeminiKeyEnv: "default-env-key"},
+			want: "flag-key",
+		},
+		{
+			name: "then the key file, trimmed",
+			src:  GeminiKeySources{KeyFile: keyFile, KeyEnv: "SHIELDCLI_TEST_KEY", Literal: "literal-key"},
+			env:  map[string]string{"SHIELDCLI_TEST_KEY": "env-key", DefaultGeminiKeyEnv: "default-env-key"},
+			want: "file-key",
+		},
+		{
+			name: "then api_key_env",
+			src:  GeminiKeySources{KeyFile: emptyFile, KeyEnv: "SHIELDCLI_TEST_KEY", Literal: "literal-key"},
+			env:  map[string]string{"SHIELDCLI_TEST_KEY": "env-key", DefaultGeminiKeyEnv: "default-env-key"},
+			want: "env-key",
+		},
+		{
+			name: "then GEMINI_API_KEY",
+			src:  GeminiKeySources{KeyEnv: "SHIELDCLI_TEST_KEY", Literal: "literal-key"},
+			env:  map[string]string{DefaultGeminiKeyEnv: "default-env-key"},
+			want: "default-env-key",
+		},
+		{
+			name: "OPENAI_API_KEY in its place",
+			src:  GeminiKeySources{Literal: "literal-key", DefaultEnv: DefaultOpenAIKeyEnv},
+			env:  map[string]string{DefaultGeminiKeyEnv: "default-env-key", DefaultOpenAIKeyEnv: "openai-key"},
+			want: "openai-key",
+		},
+		{
+			name: "literal api_key last",
+			src:  GeminiKeySources{Literal: " literal-key "},
+			want: "literal-key",
+		},
+		{name: "no source", want: ""},
+		{
+			name:    "unreadable key file",
+			src:     GeminiKeySources{KeyFile: filepath.Join(dir, "missing.key"), Literal: "literal-key"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"SHIELDCLI_TEST_KEY", DefaultGeminiKeyEnv, DefaultOpenAIKeyEnv} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			key, err := ResolveGeminiKey(tt.src)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveGeminiKey error %v, want error %v", err, tt.wantErr)
+			}
+			if key != tt.want {
+				t.Errorf("ResolveGeminiKey = %q, want %q", key, tt.want)
+			}
+		})
+	}
+}
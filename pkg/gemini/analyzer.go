@@ -0,0 +1,237 @@
+package gemini
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// Provider names accepted by NewAnalyzer
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+)
+
+// Analyzer is an AI provider for payload analysis and log summaries. *Client
+// talks to Gemini, *OpenAIClient to any OpenAI-compatible endpoint; both send
+// the same prompts.
+type Analyzer interface {
+	PayloadAnalyzer
+	SummarizeAttacks(logData string) (string, error)
+	SummarizeEventBrief(brief string) (string, error)
+	SummarizeAttacksChunked(chunker *LogChunker, maxTokens int, progress func(chunk int)) (string, error)
+	Close() error
+}
+
+// AnalyzerConfig selects and configures the provider NewAnalyzer creates
+type AnalyzerConfig struct {
+	Provider string // ProviderGemini (the default) or ProviderOpenAI
+	APIKey   string
+	Model    string
+	BaseURL  string // OpenAI-compatible endpoints only, DefaultOpenAIBaseURL if empty
+}
+
+// NewAnalyzer creates the Analyzer of the configured provider
+func NewAnalyzer(cfg AnalyzerConfig, logger *logging.Logger) (Analyzer, error) {
+	// Return a nil interface rather than a nil client on error
+	switch strings.ToLower(cfg.Provider) {
+	case "", ProviderGemini:
+		client, err := NewClient(cfg.APIKey, cfg.Model, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case ProviderOpenAI:
+		client, err := NewOpenAIClient(cfg.BaseURL, cfg.APIKey, cfg.Model, logger)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	default:
+		return nil, fmt.Errorf("unknown AI provider %q (use %s or %s)", cfg.Provider, ProviderGemini, ProviderOpenAI)
+	}
+}
+
+// generator sends a single-turn prompt to a model and returns the response
+// text. The Analyzer implementations differ only in this.
+type generator interface {
+	generate(prompt string) (string, error)
+}
+
+// analyzePayload asks the model whether a payload is malicious
+func analyzePayload(g generator, payload string) (*AnalysisResult, error) {
+	prompt := fmt.Sprintf(`Analyze the following HTTP payload for potential security threats. 
+Respond with ONLY a JSON object in this format (no markdown, no extra text):
+{
+  "is_malicious": true/false,
+  "confidence": 0.0-1.0,
+  "verdict": "malicious/suspicious/safe",
+  "explanation": "brief explanation",
+  "suggested_rule": "optional suggested WAF rule pattern"
+}
+
+Payload:
+%s`, payload)
+
+	result, err := g.generate(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze payload: %w", err)
+	}
+
+	// Parse the JSON response
+	analysisResult := parseAnalysisResult(result)
+	return analysisResult, nil
+}
+
+// summarizeAttacks generates a summary of attack trends from logs
+func summarizeAttacks(g generator, logData string) (string, error) {
+	prompt := fmt.Sprintf(`Analyze the following WAF logs and provide a brief summary of attack trends, 
+common attack patterns, and recommendations for improving security rules.
+
+WAF Logs:
+%s
+
+Provide a concise summary (2-3 paragraphs).`, logData)
+
+	result, err := g.generate(prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize attacks: %w", err)
+	}
+
+	return result, nil
+}
+
+// summarizeEventBrief generates a summary of attack trends from a brief of
+// aggregated event figures, as built by BuildEventBrief
+func summarizeEventBrief(g generator, brief string) (string, error) {
+	prompt := fmt.Sprintf(`The following figures were aggregated from a WAF's structured event log.
+Based on them, provide a brief summary of attack trends, common attack patterns,
+and recommendations for improving security rules.
+
+%s
+Provide a concise summary (2-3 paragraphs).`, brief)
+
+	result, err := g.generate(prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize attacks: %w", err)
+	}
+
+	return result, nil
+}
+
+// summarizeAttacksChunked summarizes a log of any size using map-reduce:
+// each chunk produced by the chunker is summarized on its own, then the
+// partial summaries are combined into a single summary. progress, if not nil,
+// is called after each chunk is summarized.
+func summarizeAttacksChunked(g generator, chunker *LogChunker, maxTokens int, progress func(chunk int)) (string, error) {
+	first, ok := chunker.Next()
+	if !ok {
+		if err := chunker.Err(); err != nil {
+			return "", fmt.Errorf("failed to read log: %w", err)
+		}
+		return "", fmt.Errorf("log is empty")
+	}
+
+	// A log that fits in one chunk needs no reduce step
+	next, ok := chunker.Next()
+	if !ok {
+		if err := chunker.Err(); err != nil {
+			return "", fmt.Errorf("failed to read log: %w", err)
+		}
+		summary, err := summarizeAttacks(g, first)
+		if err == nil && progress != nil {
+			progress(1)
+		}
+		return summary, err
+	}
+
+	var summaries []string
+	chunk := first
+	for {
+		prompt := fmt.Sprintf(`The following is part %d of a larger WAF log. Summarize the attack activity
+in this part: attack types, source IPs, targeted paths, rules that fired, and the time span.
+Be factual and concise; this summary will be combined with summaries of the other parts.
+
+WAF Logs:
+%s`, len(summaries)+1, chunk)
+
+		summary, err := g.generate(prompt)
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize log chunk %d: %w", len(summaries)+1, err)
+		}
+		summaries = append(summaries, summary)
+
+		if progress != nil {
+			progress(len(summaries))
+		}
+
+		if next == "" {
+			break
+		}
+		// Read ahead one chunk so the loop knows when the log is exhausted
+		chunk = next
+		next, _ = chunker.Next()
+	}
+	if err := chunker.Err(); err != nil {
+		return "", fmt.Errorf("failed to read log: %w", err)
+	}
+
+	return reduceSummaries(g, summaries, maxTokens)
+}
+
+// reduceSummaries combines partial summaries into one, merging them in
+// groups that fit the token budget until a single summary remains.
+func reduceSummaries(g generator, summaries []string, maxTokens int) (string, error) {
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxChunkTokens
+	}
+
+	for len(summaries) > 1 {
+		var reduced []string
+		var group []string
+		groupTokens := 0
+
+		flush := func() error {
+			if len(group) == 0 {
+				return nil
+			}
+			prompt := fmt.Sprintf(`The following are summaries of consecutive parts of one WAF log.
+Combine them into a single summary of attack trends, common attack patterns, and
+recommendations for improving security rules. Provide a concise summary (2-3 paragraphs).
+
+%s`, strings.Join(group, "\n\n---\n\n"))
+
+			combined, err := g.generate(prompt)
+			if err != nil {
+				return fmt.Errorf("failed to combine summaries: %w", err)
+			}
+			reduced = append(reduced, combined)
+			group = nil
+			groupTokens = 0
+			return nil
+		}
+
+		for _, summary := range summaries {
+			tokens := EstimateTokens(summary)
+			if len(group) > 0 && groupTokens+tokens > maxTokens {
+				if err := flush(); err != nil {
+					return "", err
+				}
+			}
+			group = append(group, summary)
+			groupTokens += tokens
+		}
+		if err := flush(); err != nil {
+			return "", err
+		}
+
+		// Guard against a budget too small to make progress
+		if len(reduced) >= len(summaries) {
+			return strings.Join(reduced, "\n\n"), nil
+		}
+		summaries = reduced
+	}
+
+	return summaries[0], nil
+}
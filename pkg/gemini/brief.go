@@ -0,0 +1,220 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// briefTopN is how many entries each ranking of an event brief lists
+const briefTopN = 10
+
+// ErrNotEventLog is returned by BuildEventBrief for logs that are not
+// structured event files, which are summarized as raw text instead
+var ErrNotEventLog = errors.New("not a structured event log")
+
+// eventBrief aggregates structured events into the figures a summary needs
+type eventBrief struct {
+	events   int
+	blocked  int
+	dryRun   int
+	first    time.Time
+	last     time.Time
+	rules    map[string]int
+	attacks  map[string]int
+	ips      map[string]*ipCounts
+	paths    map[string]int
+	statuses map[int]int
+
+	anomalies map[string]int // anomaly events by type and severity
+}
+
+// ipCounts are the requests of one source IP
+type ipCounts struct {
+	total   int
+	blocked int
+}
+
+// BuildEventBrief reads a structured event file (see logging.ScanEvents) and
+// aggregates it into a compact text brief: time span, block counts, and the
+// top rules, attack types, source IPs, blocked paths and status codes, plus
+// counts of anomaly events by type. The model gets the salient figures in a fraction of the tokens of the raw
+// events. Files that are not event logs yield ErrNotEventLog.
+func BuildEventBrief(path string) (string, error) {
+	b := &eventBrief{
+		rules:    make(map[string]int),
+		attacks:  make(map[string]int),
+		ips:      make(map[string]*ipCounts),
+		paths:    make(map[string]int),
+		statuses: make(map[int]int),
+
+		anomalies: make(map[string]int),
+	}
+
+	err := logging.ScanEvents(path, func(event logging.StructuredEvent) error {
+		if event.EventType == logging.EventTypeAnomaly {
+			b.anomalies[fmt.Sprintf("%s (%s)", event.AnomalyType, event.Severity)]++
+			return nil
+		}
+		// JSON lines of other kinds, such as JSON log lines, decode to events
+		// without request fields
+		if event.Method == "" && event.SourceIP == "" {
+			return ErrNotEventLog
+		}
+		b.add(event)
+		return nil
+	})
+	if errors.Is(err, ErrNotEventLog) {
+		return "", err
+	}
+	if err != nil {
+		// Text logs fail to decode on their first line
+		if b.events == 0 && len(b.anomalies) == 0 {
+			return "", fmt.Errorf("%w: %v", ErrNotEventLog, err)
+		}
+		return "", err
+	}
+	if b.events == 0 && len(b.anomalies) == 0 {
+		return "", fmt.Errorf("log is empty")
+	}
+
+	return b.String(), nil
+}
+
+// add counts an event
+func (b *eventBrief) add(event logging.StructuredEvent) {
+	b.events++
+	if !event.Timestamp.IsZero() {
+		if b.first.IsZero() || event.Timestamp.Before(b.first) {
+			b.first = event.Timestamp
+		}
+		if event.Timestamp.After(b.last) {
+			b.last = event.Timestamp
+		}
+	}
+	b.statuses[event.Status]++
+
+	ip := b.ips[event.SourceIP]
+	if ip == nil {
+		ip = &ipCounts{}
+		b.ips[event.SourceIP] = ip
+	}
+	ip.total++
+
+	if !event.Blocked {
+		return
+	}
+	b.blocked++
+	ip.blocked++
+	if event.DryRun {
+		b.dryRun++
+	}
+
+	path, _, _ := strings.Cut(event.URL, "?")
+	b.paths[path]++
+
+	if event.RuleID != 0 {
+		b.rules[fmt.Sprintf("%d %s", event.RuleID, event.RuleName)]++
+		b.attacks[attackType(event.RuleName)]++
+	} else {
+		// Blocks outside the rules, e.g. the IP blocklist or host checks
+		b.attacks[event.Reason]++
+	}
+}
+
+// attackType is the category of a rule name, e.g. "SQL Injection" for
+// "SQL Injection - Common Patterns"
+func attackType(ruleName string) string {
+	category, _, _ := strings.Cut(ruleName, " - ")
+	return category
+}
+
+// String renders the brief
+func (b *eventBrief) String() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "WAF event log brief, aggregated from %d request events\n", b.events)
+	if !b.first.IsZero() {
+		fmt.Fprintf(&sb, "Time span: %s to %s (%s)\n",
+			b.first.Format(time.RFC3339), b.last.Format(time.RFC3339), b.last.Sub(b.first).Round(time.Second))
+	}
+	blockRate := 0.0
+	if b.events > 0 {
+		blockRate = float64(b.blocked) / float64(b.events) * 100
+	}
+	fmt.Fprintf(&sb, "Requests: %d, blocked: %d (%.1f%%)", b.events, b.blocked, blockRate)
+	if b.dryRun > 0 {
+		fmt.Fprintf(&sb, ", of which %d only logged (dry run)", b.dryRun)
+	}
+	sb.WriteString("\n")
+
+	writeRanking(&sb, "Top rules (blocks)", b.rules)
+	writeRanking(&sb, "Attack types (blocks)", b.attacks)
+
+	ips := make([]string, 0, len(b.ips))
+	for ip := range b.ips {
+		ips = append(ips, ip)
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		a, c := b.ips[ips[i]], b.ips[ips[j]]
+		if a.blocked != c.blocked {
+			return a.blocked > c.blocked
+		}
+		if a.total != c.total {
+			return a.total > c.total
+		}
+		return ips[i] < ips[j]
+	})
+	if len(ips) > 0 {
+		sb.WriteString("Top source IPs (blocked/total requests):\n")
+	}
+	for i, ip := range ips {
+		if i == briefTopN {
+			fmt.Fprintf(&sb, "  ... %d more\n", len(ips)-briefTopN)
+			break
+		}
+		fmt.Fprintf(&sb, "  %s: %d/%d\n", ip, b.ips[ip].blocked, b.ips[ip].total)
+	}
+
+	writeRanking(&sb, "Top blocked paths", b.paths)
+
+	statuses := make(map[string]int, len(b.statuses))
+	for status, count := range b.statuses {
+		statuses[fmt.Sprint(status)] = count
+	}
+	writeRanking(&sb, "Status codes", statuses)
+	writeRanking(&sb, "Traffic anomalies", b.anomalies)
+
+	return sb.String()
+}
+
+// writeRanking writes the top counts under a heading, highest first
+func writeRanking(sb *strings.Builder, heading string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	fmt.Fprintf(sb, "%s:\n", heading)
+	for i, key := range keys {
+		if i == briefTopN {
+			fmt.Fprintf(sb, "  ... %d more\n", len(keys)-briefTopN)
+			break
+		}
+		fmt.Fprintf(sb, "  %s: %d\n", key, counts[key])
+	}
+}
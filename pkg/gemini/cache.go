@@ -0,0 +1,130 @@
+package gemini
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Defaults for the payload analysis cache
+const (
+	DefaultCacheSize = 1000
+	DefaultCacheTTL  = time.Hour
+)
+
+// CachedAnalyzer wraps an Analyzer with an in-memory LRU cache of payload
+// analyses, so a payload seen again, e.g. a duplicated attack in a replay,
+// costs no API call. Summaries pass through uncached.
+type CachedAnalyzer struct {
+	Analyzer
+
+	model string
+	size  int
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+// cacheEntry is a cached analysis
+type cacheEntry struct {
+	key     string
+	result  AnalysisResult
+	expires time.Time // zero if the entry never expires
+}
+
+// NewCachedAnalyzer caches up to size analyses of the wrapped analyzer,
+// keyed by a hash of the model and the payload. Entries older than ttl are
+// analyzed again; a ttl of 0 keeps them until evicted. A size below 1
+// defaults to DefaultCacheSize.
+func NewCachedAnalyzer(analyzer Analyzer, model string, size int, ttl time.Duration) *CachedAnalyzer {
+	if size < 1 {
+		size = DefaultCacheSize
+	}
+	return &CachedAnalyzer{
+		Analyzer: analyzer,
+		model:    model,
+		size:     size,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// AnalyzePayload returns the cached analysis of a payload, or analyzes it
+// with the wrapped analyzer and caches the result. Results that had to be
+// recovered from a malformed response are not cached.
+func (c *CachedAnalyzer) AnalyzePayload(payload string) (*AnalysisResult, error) {
+	key := c.key(payload)
+	if result, ok := c.get(key); ok {
+		return result, nil
+	}
+
+	result, err := c.Analyzer.AnalyzePayload(payload)
+	if err != nil || result.ParseError != nil {
+		return result, err
+	}
+	c.put(key, result)
+	return result, nil
+}
+
+// Len returns the number of cached analyses
+func (c *CachedAnalyzer) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// key hashes the model and payload, so the cache holds no payload text
+func (c *CachedAnalyzer) key(payload string) string {
+	sum := sha256.Sum256([]byte(c.model + "\x00" + payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns a copy of a live cached result
+func (c *CachedAnalyzer) get(key string) (*AnalysisResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+
+	result := entry.result
+	return &result, true
+}
+
+// put caches a copy of a result, evicting the least recently used entries
+// beyond the size limit
+func (c *CachedAnalyzer) put(key string, result *AnalysisResult) {
+	entry := &cacheEntry{key: key, result: *result}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
@@ -0,0 +1,103 @@
+package gemini
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingAnalyzer counts the payload analyses that reach it. Results for
+// payloads in unparsable come back with a ParseError.
+type countingAnalyzer struct {
+	Analyzer
+	calls      map[string]int
+	unparsable map[string]bool
+}
+
+func (a *countingAnalyzer) AnalyzePayload(payload string) (*AnalysisResult, error) {
+	a.calls[payload]++
+	result := &AnalysisResult{IsMalicious: true, Verdict: "malicious", Explanation: payload}
+	if a.unparsable[payload] {
+		result.ParseError = errors.New("invalid JSON")
+	}
+	return result, nil
+}
+
+func TestCachedAnalyzer(t *testing.T) {
+	inner := &countingAnalyzer{calls: make(map[string]int)}
+	cache := NewCachedAnalyzer(inner, "gemini-2.5-flash", 10, time.Hour)
+
+	first, err := cache.AnalyzePayload("' OR 1=1--")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Verdict = "changed by the caller"
+	second, err := cache.AnalyzePayload("' OR 1=1--")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls["' OR 1=1--"] != 1 {
+		t.Errorf("%d analyses of an identical payload, want 1", inner.calls["' OR 1=1--"])
+	}
+	if second.Verdict != "malicious" {
+		t.Errorf("cached verdict %q, want the original unaffected by callers", second.Verdict)
+	}
+
+	if _, err := cache.AnalyzePayload("<script>alert(1)</script>"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls["<script>alert(1)</script>"] != 1 || cache.Len() != 2 {
+		t.Errorf("differing payload: %d analyses, %d cached, want 1 and 2", inner.calls["<script>alert(1)</script>"], cache.Len())
+	}
+
+	// The model is part of the key
+	other := NewCachedAnalyzer(inner, "gemini-2.5-pro", 10, time.Hour)
+	other.AnalyzePayload("' OR 1=1--")
+	if inner.calls["' OR 1=1--"] != 2 {
+		t.Errorf("%d analyses after a model change, want 2", inner.calls["' OR 1=1--"])
+	}
+}
+
+func TestCachedAnalyzerEviction(t *testing.T) {
+	tests := []struct {
+		name      string
+		size      int
+		ttl       time.Duration
+		payloads  []string
+		wantCalls int // analyses of "a" after the payloads and "a" once more
+	}{
+		{name: "least recently used evicted", size: 2, payloads: []string{"a", "b", "c"}, wantCalls: 2},
+		{name: "recent use keeps an entry", size: 2, payloads: []string{"a", "b", "a", "c"}, wantCalls: 1},
+		{name: "expired entry analyzed again", size: 10, ttl: time.Nanosecond, payloads: []string{"a"}, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &countingAnalyzer{calls: make(map[string]int)}
+			cache := NewCachedAnalyzer(inner, "model", tt.size, tt.ttl)
+			for _, payload := range tt.payloads {
+				cache.AnalyzePayload(payload)
+			}
+			time.Sleep(time.Millisecond)
+			cache.AnalyzePayload("a")
+
+			if inner.calls["a"] != tt.wantCalls {
+				t.Errorf("%d analyses of a, want %d", inner.calls["a"], tt.wantCalls)
+			}
+			if cache.Len() > tt.size {
+				t.Errorf("%d cached analyses, want at most %d", cache.Len(), tt.size)
+			}
+		})
+	}
+}
+
+func TestCachedAnalyzerSkipsParseErrors(t *testing.T) {
+	inner := &countingAnalyzer{calls: make(map[string]int), unparsable: map[string]bool{"x": true}}
+	cache := NewCachedAnalyzer(inner, "model", 10, 0)
+
+	cache.AnalyzePayload("x")
+	cache.AnalyzePayload("x")
+	if inner.calls["x"] != 2 || cache.Len() != 0 {
+		t.Errorf("%d analyses, %d cached, want an unparsable result analyzed again and not cached", inner.calls["x"], cache.Len())
+	}
+}
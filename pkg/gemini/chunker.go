@@ -0,0 +1,160 @@
+package gemini
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// charsPerToken is a rough estimate of how many characters make up one model token
+const charsPerToken = 4
+
+// EstimateTokens approximates the number of model tokens in text
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// LogChunker splits a log stream into entry-aligned windows that each fit
+// within a token budget, so arbitrarily large logs can be summarized without
+// loading them into memory at once. An entry is a line plus the continuation
+// lines after it, such as the frames of a stack trace, so a chunk boundary
+// does not separate an error from its trace.
+type LogChunker struct {
+	scanner   *bufio.Scanner
+	maxChars  int
+	pending   string
+	lookahead string // line read past the end of the previous entry
+	entryMark byte   // class of the first character of the log's first line
+	err       error
+}
+
+// NewLogChunker creates a chunker reading from r with at most maxTokens per chunk
+func NewLogChunker(r io.Reader, maxTokens int) *LogChunker {
+	if maxTokens <= 0 {
+		maxTokens = DefaultMaxChunkTokens
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &LogChunker{
+		scanner:  scanner,
+		maxChars: maxTokens * charsPerToken,
+	}
+}
+
+// Next returns the next chunk, or false once the stream is exhausted.
+// Entries longer than the chunk budget are split across chunks, at a line
+// boundary where possible.
+func (lc *LogChunker) Next() (string, bool) {
+	var sb strings.Builder
+
+	for {
+		entry := lc.pending
+		lc.pending = ""
+		if entry == "" {
+			var ok bool
+			if entry, ok = lc.nextEntry(); !ok {
+				break
+			}
+		}
+
+		if sb.Len()+len(entry) > lc.maxChars {
+			if sb.Len() == 0 {
+				// A single entry larger than the budget: emit a budget-sized slice of it
+				cut := lc.maxChars
+				if i := strings.LastIndexByte(entry[:cut], '\n'); i > 0 {
+					cut = i + 1
+				}
+				sb.WriteString(entry[:cut])
+				lc.pending = entry[cut:]
+			} else {
+				lc.pending = entry
+			}
+			break
+		}
+		sb.WriteString(entry)
+	}
+
+	if sb.Len() == 0 {
+		return "", false
+	}
+	return sb.String(), true
+}
+
+// nextEntry reads the next entry. Continuation lines are only collected up
+// to the chunk budget, so a log that never starts a new entry is still read
+// in bounded pieces.
+func (lc *LogChunker) nextEntry() (string, bool) {
+	line, ok := lc.nextLine()
+	if !ok {
+		return "", false
+	}
+	if lc.entryMark == 0 {
+		lc.entryMark = charClass(line[0])
+	}
+
+	var sb strings.Builder
+	sb.WriteString(line)
+	for sb.Len() < lc.maxChars {
+		line, ok := lc.nextLine()
+		if !ok {
+			break
+		}
+		if lc.startsEntry(line) {
+			lc.lookahead = line
+			break
+		}
+		sb.WriteString(line)
+	}
+	return sb.String(), true
+}
+
+// nextLine returns the next line including its newline
+func (lc *LogChunker) nextLine() (string, bool) {
+	if lc.lookahead != "" {
+		line := lc.lookahead
+		lc.lookahead = ""
+		return line, true
+	}
+	if !lc.scanner.Scan() {
+		lc.err = lc.scanner.Err()
+		return "", false
+	}
+	return lc.scanner.Text() + "\n", true
+}
+
+// startsEntry reports whether a line begins a new entry rather than
+// continuing the previous one. Entries start the way the log's first line
+// does: with "[" as in "[2006-01-02 15:04:05] INFO", a digit of a timestamp,
+// or "{" for JSON lines. Indented and blank lines always continue an entry.
+// In a log whose first line starts otherwise, every other line is an entry.
+func (lc *LogChunker) startsEntry(line string) bool {
+	class := charClass(line[0])
+	switch {
+	case class == ' ':
+		return false
+	case lc.entryMark == 'a':
+		return true
+	default:
+		return class == lc.entryMark
+	}
+}
+
+// charClass groups the first characters of log lines: '0' for digits, ' '
+// for whitespace, the character itself for '[' and '{', and 'a' for the rest
+func charClass(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return '0'
+	case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		return ' '
+	case c == '[' || c == '{':
+		return c
+	default:
+		return 'a'
+	}
+}
+
+// Err returns the first read error encountered, if any
+func (lc *LogChunker) Err() error {
+	return lc.err
+}
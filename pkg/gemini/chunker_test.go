@@ -0,0 +1,100 @@
+package gemini
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// markerGenerator is a model that answers every prompt with the request
+// markers it contains, so a summary shows which log lines reached it
+type markerGenerator struct {
+	prompts int
+}
+
+var markerPattern = regexp.MustCompile(`req-\d{4}`)
+
+func (g *markerGenerator) generate(prompt string) (string, error) {
+	g.prompts++
+	return strings.Join(markerPattern.FindAllString(prompt, -1), " "), nil
+}
+
+func TestSummarizeAttacksChunked(t *testing.T) {
+	const entries = 400
+	var log strings.Builder
+	for i := 0; i < entries; i++ {
+		fmt.Fprintf(&log, "[2026-01-02 15:04:05] BLOCK Request req-%04d blocked: Rule 1001: SQL Injection\n", i)
+	}
+
+	g := &markerGenerator{}
+	var progress []int
+	summary, err := summarizeAttacksChunked(g, NewLogChunker(strings.NewReader(log.String()), 500), 500,
+		func(chunk int) { progress = append(progress, chunk) })
+	if err != nil {
+		t.Fatalf("summarizeAttacksChunked: %v", err)
+	}
+
+	if len(progress) < 2 {
+		t.Fatalf("log summarized in %d chunks, want several", len(progress))
+	}
+	for i, chunk := range progress {
+		if chunk != i+1 {
+			t.Fatalf("progress %v, want 1, 2, ...", progress)
+		}
+	}
+	if g.prompts <= len(progress) {
+		t.Errorf("%d prompts for %d chunks, want a reduce step", g.prompts, len(progress))
+	}
+	for i := 0; i < entries; i++ {
+		if marker := fmt.Sprintf("req-%04d", i); !strings.Contains(summary, marker) {
+			t.Fatalf("summary misses %s of chunk-split log", marker)
+		}
+	}
+}
+
+func TestSummarizeAttacksChunkedSmallLog(t *testing.T) {
+	g := &markerGenerator{}
+	summary, err := summarizeAttacksChunked(g, NewLogChunker(strings.NewReader("[x] req-0001 blocked\n"), 500), 500, nil)
+	if err != nil {
+		t.Fatalf("summarizeAttacksChunked: %v", err)
+	}
+	if summary != "req-0001" || g.prompts != 1 {
+		t.Errorf("summary %q after %d prompts, want req-0001 after 1", summary, g.prompts)
+	}
+
+	if _, err := summarizeAttacksChunked(g, NewLogChunker(strings.NewReader(""), 500), 500, nil); err == nil {
+		t.Error("empty log summarized without error")
+	}
+}
+
+func TestLogChunkerKeepsEntriesTogether(t *testing.T) {
+	entry := "[2026-01-02 15:04:05] ERROR Proxy error: upstream failed\n" +
+		"    goroutine 1 [running]:\n" +
+		"    main.main()\n"
+	log := strings.Repeat(entry, 20)
+
+	chunker := NewLogChunker(strings.NewReader(log), EstimateTokens(entry)*3)
+	var joined strings.Builder
+	chunks := 0
+	for {
+		chunk, ok := chunker.Next()
+		if !ok {
+			break
+		}
+		chunks++
+		if len(chunk)%len(entry) != 0 || !strings.HasPrefix(chunk, "[") {
+			t.Errorf("chunk %d splits an entry: %q", chunks, chunk)
+		}
+		joined.WriteString(chunk)
+	}
+	if chunker.Err() != nil {
+		t.Fatal(chunker.Err())
+	}
+	if chunks < 2 {
+		t.Errorf("%d chunks, want several", chunks)
+	}
+	if joined.String() != log {
+		t.Error("chunks do not add up to the log")
+	}
+}
@@ -2,6 +2,7 @@ package gemini
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,7 +10,10 @@ import (
 	"google.golang.org/genai"
 )
 
-// Client represents the Gemini AI client
+// DefaultMaxChunkTokens is the default token budget for one chunk of a log sent for summarization
+const DefaultMaxChunkTokens = 8000
+
+// Client is the Analyzer for Google's Gemini API
 type Client struct {
 	client *genai.Client
 	model  string
@@ -24,6 +28,10 @@ type AnalysisResult struct {
 	Explanation string
 	Verdict     string
 	SuggestedRule string
+
+	// ParseError is set when the response was not valid JSON and the fields
+	// above come from a best-effort scan of the text
+	ParseError error
 }
 
 // NewClient creates a new Gemini client
@@ -54,58 +62,28 @@ func NewClient(apiKey, model string, logger *logging.Logger) (*Client, error) {
 
 // AnalyzePayload sends a payload to Gemini for analysis
 func (c *Client) AnalyzePayload(payload string) (*AnalysisResult, error) {
-	prompt := fmt.Sprintf(`Analyze the following HTTP payload for potential security threats. 
-Respond with ONLY a JSON object in this format (no markdown, no extra text):
-{
-  "is_malicious": true/false,
-  "confidence": 0.0-1.0,
-  "verdict": "malicious/suspicious/safe",
-  "explanation": "brief explanation",
-  "suggested_rule": "optional suggested WAF rule pattern"
-}
-
-Payload:
-%s`, payload)
-
-	resp, err := c.client.Models.GenerateContent(c.ctx, c.model, []*genai.Content{
-		{
-			Role: "user",
-			Parts: []*genai.Part{
-				{Text: prompt},
-			},
-		},
-	}, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to analyze payload: %w", err)
-	}
-
-	if len(resp.Candidates) == 0 {
-		return nil, fmt.Errorf("no response from Gemini")
-	}
-
-	// Extract text from response
-	var result string
-	for _, part := range resp.Candidates[0].Content.Parts {
-		if part.Text != "" {
-			result += part.Text
-		}
-	}
-
-	// Parse the JSON response
-	analysisResult := parseAnalysisResult(result)
-	return analysisResult, nil
+	return analyzePayload(c, payload)
 }
 
 // SummarizeAttacks generates a summary of attack trends from logs
 func (c *Client) SummarizeAttacks(logData string) (string, error) {
-	prompt := fmt.Sprintf(`Analyze the following WAF logs and provide a brief summary of attack trends, 
-common attack patterns, and recommendations for improving security rules.
+	return summarizeAttacks(c, logData)
+}
 
-WAF Logs:
-%s
+// SummarizeEventBrief generates a summary of attack trends from a brief of
+// aggregated event figures, as built by BuildEventBrief
+func (c *Client) SummarizeEventBrief(brief string) (string, error) {
+	return summarizeEventBrief(c, brief)
+}
 
-Provide a concise summary (2-3 paragraphs).`, logData)
+// SummarizeAttacksChunked summarizes a log of any size, see
+// summarizeAttacksChunked
+func (c *Client) SummarizeAttacksChunked(chunker *LogChunker, maxTokens int, progress func(chunk int)) (string, error) {
+	return summarizeAttacksChunked(c, chunker, maxTokens, progress)
+}
 
+// generate sends a single-turn prompt to the model and returns the response text
+func (c *Client) generate(prompt string) (string, error) {
 	resp, err := c.client.Models.GenerateContent(c.ctx, c.model, []*genai.Content{
 		{
 			Role: "user",
@@ -115,10 +93,10 @@ Provide a concise summary (2-3 paragraphs).`, logData)
 		},
 	}, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to summarize attacks: %w", err)
+		return "", err
 	}
 
-	if len(resp.Candidates) == 0 {
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
 		return "", fmt.Errorf("no response from Gemini")
 	}
 
@@ -139,8 +117,55 @@ func (c *Client) Close() error {
 	return nil
 }
 
-// parseAnalysisResult parses the JSON response from Gemini
-func parseAnalysisResult(jsonStr string) *AnalysisResult {
+// analysisResponse is the JSON object the analysis prompt asks Gemini for
+type analysisResponse struct {
+	IsMalicious   bool    `json:"is_malicious"`
+	Confidence    float64 `json:"confidence"`
+	Verdict       string  `json:"verdict"`
+	Explanation   string  `json:"explanation"`
+	SuggestedRule string  `json:"suggested_rule"`
+}
+
+// parseAnalysisResult parses the JSON response from Gemini. Markdown code
+// fences and prose around the object are ignored. If the object does not
+// unmarshal, the heuristic parser is used instead and ParseError is set.
+func parseAnalysisResult(response string) *AnalysisResult {
+	var parsed analysisResponse
+	err := json.Unmarshal([]byte(extractJSONObject(response)), &parsed)
+	if err != nil {
+		result := parseAnalysisHeuristic(response)
+		result.ParseError = fmt.Errorf("invalid JSON in analysis response: %w", err)
+		return result
+	}
+
+	return &AnalysisResult{
+		IsMalicious:   parsed.IsMalicious,
+		Confidence:    parsed.Confidence,
+		Explanation:   parsed.Explanation,
+		Verdict:       strings.ToLower(strings.TrimSpace(parsed.Verdict)),
+		SuggestedRule: parsed.SuggestedRule,
+	}
+}
+
+// extractJSONObject strips markdown code fences and returns the span from the
+// first '{' to the last '}', or the trimmed response if there is none
+func extractJSONObject(response string) string {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start == -1 || end < start {
+		return strings.TrimSpace(response)
+	}
+	return response[start : end+1]
+}
+
+// parseAnalysisHeuristic scans a response that is not valid JSON for the
+// expected fields, so a malformed reply still yields a best-effort result
+func parseAnalysisHeuristic(jsonStr string) *AnalysisResult {
 	result := &AnalysisResult{
 		IsMalicious: false,
 		Confidence:  0.0,
@@ -148,7 +173,6 @@ func parseAnalysisResult(jsonStr string) *AnalysisResult {
 		Explanation: "Failed to parse response",
 	}
 
-	// Simple JSON parsing (avoiding external dependencies)
 	if strings.Contains(jsonStr, `"is_malicious": true`) {
 		result.IsMalicious = true
 	}
@@ -0,0 +1,50 @@
+package gemini
+
+import "testing"
+
+func TestParseAnalysisResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		response     string
+		want         AnalysisResult
+		wantParseErr bool
+	}{
+		{
+			name: "fenced JSON with reordered keys",
+			response: "```json\n{\"verdict\": \"Malicious\", \"suggested_rule\": \"(?i)union\\\\s+select\", " +
+				"\"confidence\": 9.5e-1, \"explanation\": \"UNION-based \\\"SQL\\\" injection\", \"is_malicious\": true}\n```",
+			want: AnalysisResult{IsMalicious: true, Confidence: 0.95, Verdict: "malicious",
+				Explanation: `UNION-based "SQL" injection`, SuggestedRule: `(?i)union\s+select`},
+		},
+		{
+			name:     "prose around the object",
+			response: "Here is my analysis:\n{\"is_malicious\": false, \"confidence\": 0.1, \"verdict\": \"safe\", \"explanation\": \"A search term\"}\nHope this helps.",
+			want:     AnalysisResult{Confidence: 0.1, Verdict: "safe", Explanation: "A search term"},
+		},
+		{
+			name:         "malformed JSON falls back to the heuristic",
+			response:     `{"is_malicious": true, "confidence": 0.8, "verdict": "suspicious", "explanation": "Odd encoding",}`,
+			want:         AnalysisResult{IsMalicious: true, Confidence: 0.8, Verdict: "suspicious", Explanation: "Odd encoding"},
+			wantParseErr: true,
+		},
+		{
+			name:         "no JSON at all",
+			response:     "I cannot analyze this payload.",
+			want:         AnalysisResult{Verdict: "unknown", Explanation: "Failed to parse response"},
+			wantParseErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseAnalysisResult(tt.response)
+			if (got.ParseError != nil) != tt.wantParseErr {
+				t.Errorf("ParseError %v, want error: %t", got.ParseError, tt.wantParseErr)
+			}
+			got.ParseError = nil
+			if *got != tt.want {
+				t.Errorf("parseAnalysisResult = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package gemini
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PayloadAnalyzer analyzes a single payload; every Analyzer implements it
+type PayloadAnalyzer interface {
+	AnalyzePayload(payload string) (*AnalysisResult, error)
+}
+
+// VerdictDiff is a payload on which two analyzers disagree
+type VerdictDiff struct {
+	Payload         string
+	A               *AnalysisResult
+	B               *AnalysisResult
+	VerdictChanged  bool    // verdict or malicious flag differs
+	ConfidenceDelta float64 // B's confidence minus A's
+}
+
+// DiffAnalyses analyzes every payload with both analyzers and returns the
+// payloads whose verdicts differ or whose confidences differ by at least
+// minConfidenceDelta, in input order
+func DiffAnalyses(a, b PayloadAnalyzer, payloads []string, minConfidenceDelta float64) ([]VerdictDiff, error) {
+	var diffs []VerdictDiff
+	for i, payload := range payloads {
+		resultA, err := a.AnalyzePayload(payload)
+		if err != nil {
+			return nil, fmt.Errorf("payload %d: model A: %w", i+1, err)
+		}
+		resultB, err := b.AnalyzePayload(payload)
+		if err != nil {
+			return nil, fmt.Errorf("payload %d: model B: %w", i+1, err)
+		}
+
+		diff := VerdictDiff{
+			Payload:         payload,
+			A:               resultA,
+			B:               resultB,
+			VerdictChanged:  resultA.IsMalicious != resultB.IsMalicious || !strings.EqualFold(resultA.Verdict, resultB.Verdict),
+			ConfidenceDelta: resultB.Confidence - resultA.Confidence,
+		}
+		if diff.VerdictChanged || math.Abs(diff.ConfidenceDelta) >= minConfidenceDelta {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
@@ -0,0 +1,63 @@
+package gemini
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// stubAnalyzer answers with a fixed result per payload
+type stubAnalyzer map[string]AnalysisResult
+
+func (s stubAnalyzer) AnalyzePayload(payload string) (*AnalysisResult, error) {
+	result, ok := s[payload]
+	if !ok {
+		return nil, errors.New("quota exceeded")
+	}
+	return &result, nil
+}
+
+func TestDiffAnalyses(t *testing.T) {
+	payloads := []string{"' OR 1=1--", "<b>bold</b>", "../../etc/passwd", "hello"}
+	a := stubAnalyzer{
+		"' OR 1=1--":       {IsMalicious: true, Verdict: "malicious", Confidence: 0.95},
+		"<b>bold</b>":      {IsMalicious: true, Verdict: "suspicious", Confidence: 0.6},
+		"../../etc/passwd": {IsMalicious: true, Verdict: "malicious", Confidence: 0.9},
+		"hello":            {Verdict: "safe", Confidence: 0.9},
+	}
+	b := stubAnalyzer{
+		"' OR 1=1--":       {IsMalicious: true, Verdict: "Malicious", Confidence: 0.97},
+		"<b>bold</b>":      {Verdict: "safe", Confidence: 0.8},
+		"../../etc/passwd": {IsMalicious: true, Verdict: "malicious", Confidence: 0.5},
+		"hello":            {Verdict: "safe", Confidence: 0.85},
+	}
+
+	diffs, err := DiffAnalyses(a, b, payloads, 0.2)
+	if err != nil {
+		t.Fatalf("DiffAnalyses: %v", err)
+	}
+
+	type summary struct {
+		payload string
+		changed bool
+	}
+	var got []summary
+	for _, diff := range diffs {
+		got = append(got, summary{diff.Payload, diff.VerdictChanged})
+	}
+	want := []summary{{"<b>bold</b>", true}, {"../../etc/passwd", false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffs %+v, want %+v", got, want)
+	}
+	if delta := diffs[1].ConfidenceDelta; delta > -0.39 || delta < -0.41 {
+		t.Errorf("confidence delta %v, want -0.4", delta)
+	}
+}
+
+func TestDiffAnalysesError(t *testing.T) {
+	a := stubAnalyzer{"x": {Verdict: "safe"}}
+	_, err := DiffAnalyses(a, stubAnalyzer{}, []string{"x"}, 0)
+	if err == nil || err.Error() != "payload 1: model B: quota exceeded" {
+		t.Errorf("error %v, want the failing model and payload", err)
+	}
+}
@@ -0,0 +1,155 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// DefaultOpenAIBaseURL is the API base URL used when none is configured
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// openAITimeout bounds one chat completion; summaries of large chunks can
+// take a while on slower self-hosted models
+const openAITimeout = 2 * time.Minute
+
+// OpenAIClient is the Analyzer for any endpoint speaking the OpenAI chat
+// completions API: OpenAI itself, or a compatible gateway or self-hosted
+// server such as vLLM, Ollama or LiteLLM
+type OpenAIClient struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+	logger  *logging.Logger
+}
+
+// NewOpenAIClient creates a client for the chat completions API under
+// baseURL, e.g. http://localhost:11434/v1. An empty baseURL defaults to
+// DefaultOpenAIBaseURL. The API key may be empty for self-hosted endpoints
+// that need none.
+func NewOpenAIClient(baseURL, apiKey, model string, logger *logging.Logger) (*OpenAIClient, error) {
+	if model == "" {
+		return nil, fmt.Errorf("a model is required for an OpenAI-compatible provider")
+	}
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	if !strings.HasPrefix(baseURL, "http://") && !strings.HasPrefix(baseURL, "https://") {
+		return nil, fmt.Errorf("invalid base URL %q: must start with http:// or https://", baseURL)
+	}
+
+	return &OpenAIClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		client:  &http.Client{Timeout: openAITimeout},
+		logger:  logger,
+	}, nil
+}
+
+// AnalyzePayload sends a payload to the model for analysis
+func (c *OpenAIClient) AnalyzePayload(payload string) (*AnalysisResult, error) {
+	return analyzePayload(c, payload)
+}
+
+// SummarizeAttacks generates a summary of attack trends from logs
+func (c *OpenAIClient) SummarizeAttacks(logData string) (string, error) {
+	return summarizeAttacks(c, logData)
+}
+
+// SummarizeEventBrief generates a summary of attack trends from a brief of
+// aggregated event figures, as built by BuildEventBrief
+func (c *OpenAIClient) SummarizeEventBrief(brief string) (string, error) {
+	return summarizeEventBrief(c, brief)
+}
+
+// SummarizeAttacksChunked summarizes a log of any size, see
+// summarizeAttacksChunked
+func (c *OpenAIClient) SummarizeAttacksChunked(chunker *LogChunker, maxTokens int, progress func(chunk int)) (string, error) {
+	return summarizeAttacksChunked(c, chunker, maxTokens, progress)
+}
+
+// Close releases idle connections
+func (c *OpenAIClient) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// chatMessage is one message of a chat completions request or response
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the body of a chat completions request
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+// chatResponse is the part of a chat completions response the client reads.
+// Error is set instead of Choices when the request failed.
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// generate sends a single-turn prompt to the chat completions endpoint and
+// returns the response text
+func (c *OpenAIClient) generate(prompt string) (string, error) {
+	body, err := json.Marshal(chatRequest{
+		Model:    c.model,
+		Messages: []chatMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var parsed chatResponse
+	decodeErr := json.Unmarshal(data, &parsed)
+	if resp.StatusCode != http.StatusOK {
+		if decodeErr == nil && parsed.Error != nil && parsed.Error.Message != "" {
+			return "", fmt.Errorf("%s returned %s: %s", c.baseURL, resp.Status, parsed.Error.Message)
+		}
+		return "", fmt.Errorf("%s returned %s", c.baseURL, resp.Status)
+	}
+	if decodeErr != nil {
+		return "", fmt.Errorf("invalid response from %s: %w", c.baseURL, decodeErr)
+	}
+
+	if len(parsed.Choices) == 0 || parsed.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("no response from %s", c.baseURL)
+	}
+	return parsed.Choices[0].Message.Content, nil
+}
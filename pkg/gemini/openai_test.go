@@ -0,0 +1,117 @@
+package gemini
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// newFakeOpenAI starts a server emulating the chat completions endpoint that
+// answers every prompt with reply, recording the requests it got
+func newFakeOpenAI(t *testing.T, reply string) (*httptest.Server, *[]chatRequest) {
+	t.Helper()
+
+	var requests []chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"message": "Incorrect API key provided", "type": "invalid_request_error"}}`))
+			return
+		}
+
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     "chatcmpl-1",
+			"object": "chat.completion",
+			"model":  req.Model,
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": reply},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestOpenAIClientAnalyzePayload(t *testing.T) {
+	server, requests := newFakeOpenAI(t,
+		`{"is_malicious": true, "confidence": 0.9, "verdict": "malicious", "explanation": "SQL injection"}`)
+
+	analyzer, err := NewAnalyzer(AnalyzerConfig{Provider: "OpenAI", APIKey: "test-key", Model: "gpt-4o-mini", BaseURL: server.URL + "/v1/"}, logging.NewLogger(""))
+	if err != nil {
+		t.Fatalf("NewAnalyzer: %v", err)
+	}
+	defer analyzer.Close()
+
+	result, err := analyzer.AnalyzePayload("id=1' OR '1'='1")
+	if err != nil {
+		t.Fatalf("AnalyzePayload: %v", err)
+	}
+	if !result.IsMalicious || result.Confidence != 0.9 || result.Verdict != "malicious" || result.ParseError != nil {
+		t.Errorf("result %+v, want a parsed malicious verdict", result)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("%d requests to the endpoint, want 1", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Model != "gpt-4o-mini" || len(req.Messages) != 1 || req.Messages[0].Role != "user" ||
+		!strings.Contains(req.Messages[0].Content, "id=1' OR '1'='1") {
+		t.Errorf("request %+v, want one user message with the payload for gpt-4o-mini", req)
+	}
+
+	summary, err := analyzer.SummarizeAttacks("[x] BLOCK Request blocked")
+	if err != nil || summary == "" {
+		t.Errorf("SummarizeAttacks = %q, %v", summary, err)
+	}
+}
+
+func TestOpenAIClientErrors(t *testing.T) {
+	server, _ := newFakeOpenAI(t, "")
+
+	tests := []struct {
+		name    string
+		apiKey  string
+		baseURL string
+		wantErr string
+	}{
+		{name: "API error message", apiKey: "wrong-key", baseURL: server.URL + "/v1", wantErr: "Incorrect API key provided"},
+		{name: "empty choice", apiKey: "test-key", baseURL: server.URL + "/v1", wantErr: "no response from"},
+		{name: "not found", apiKey: "test-key", baseURL: server.URL, wantErr: "404 Not Found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewOpenAIClient(tt.baseURL, tt.apiKey, "gpt-4o-mini", logging.NewLogger(""))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.AnalyzePayload("x"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewOpenAIClient("localhost:11434", "", "llama3", nil); err == nil {
+		t.Error("NewOpenAIClient accepted a base URL without a scheme")
+	}
+	if _, err := NewAnalyzer(AnalyzerConfig{Provider: "watson"}, nil); err == nil {
+		t.Error("NewAnalyzer accepted an unknown provider")
+	}
+}
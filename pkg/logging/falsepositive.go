@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Labels analysts set on reviewed events
+const (
+	EventLabelBenign    = "benign"
+	EventLabelMalicious = "malicious"
+)
+
+// FalsePositive is a blocked event selected for false-positive review
+type FalsePositive struct {
+	Event StructuredEvent
+
+	// Suspected is set for unlabeled events blocked by the same rule on the
+	// same path as an event labeled benign
+	Suspected bool
+}
+
+// ReadEvents reads every event of an event file. The file may be JSON lines
+// or a JSON array, optionally gzip-compressed.
+func ReadEvents(path string) ([]StructuredEvent, error) {
+	var events []StructuredEvent
+	err := ScanEvents(path, func(event StructuredEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ScanEvents calls fn with each event of an event file in order, without
+// holding the whole file in memory, and stops at the first error fn returns
+func ScanEvents(path string, fn func(StructuredEvent) error) error {
+	s, err := openEventStream(path, 0)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	for {
+		raw, err := s.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var event StructuredEvent
+		if err := json.Unmarshal(raw.raw, &event); err != nil {
+			return fmt.Errorf("%s: invalid event: %w", path, err)
+		}
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+}
+
+// SelectFalsePositives returns the blocked events labeled benign, in input
+// order. With includeSuspected, unlabeled blocked events that share a rule
+// and URL path with one of them are returned too, marked Suspected.
+func SelectFalsePositives(events []StructuredEvent, includeSuspected bool) []FalsePositive {
+	type ruleOnPath struct {
+		ruleID int
+		path   string
+	}
+
+	benign := make(map[ruleOnPath]bool)
+	for _, event := range events {
+		if event.Blocked && event.Label == EventLabelBenign {
+			benign[ruleOnPath{event.RuleID, eventPath(event.URL)}] = true
+		}
+	}
+
+	var selected []FalsePositive
+	for _, event := range events {
+		if !event.Blocked {
+			continue
+		}
+		switch {
+		case event.Label == EventLabelBenign:
+			selected = append(selected, FalsePositive{Event: event})
+		case event.Label == "" && includeSuspected && benign[ruleOnPath{event.RuleID, eventPath(event.URL)}]:
+			selected = append(selected, FalsePositive{Event: event, Suspected: true})
+		}
+	}
+	return selected
+}
+
+// eventPath returns the path of an event URL without its query
+func eventPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Path
+}
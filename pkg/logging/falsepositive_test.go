@@ -0,0 +1,48 @@
+package logging
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSelectFalsePositives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labeled.jsonl")
+	writeEvents(t, path, `{"event_id": "fp-1", "url": "/search?q=O'Brien", "rule_id": 1001, "blocked": true, "label": "benign"}
+{"event_id": "attack", "url": "/search?q=1' OR 1=1--", "rule_id": 1001, "blocked": true, "label": "malicious"}
+{"event_id": "same-rule-and-path", "url": "/search?q=D'Angelo", "rule_id": 1001, "blocked": true}
+{"event_id": "other-path", "url": "/login", "rule_id": 1001, "blocked": true}
+{"event_id": "other-rule", "url": "/search?q=../", "rule_id": 1003, "blocked": true}
+{"event_id": "allowed", "url": "/search?q=shoes", "blocked": false, "label": "benign"}
+{"event_id": "fp-2", "url": "/upload", "rule_id": 1006, "blocked": true, "label": "benign"}
+`)
+	events, err := ReadEvents(path)
+	if err != nil {
+		t.Fatalf("ReadEvents: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		includeSuspected bool
+		want             []string // event IDs, with suspected ones marked by a "?" suffix
+	}{
+		{name: "labeled only", want: []string{"fp-1", "fp-2"}},
+		{name: "with suspected", includeSuspected: true, want: []string{"fp-1", "same-rule-and-path?", "fp-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, fp := range SelectFalsePositives(events, tt.includeSuspected) {
+				id := fp.Event.EventID
+				if fp.Suspected {
+					id += "?"
+				}
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
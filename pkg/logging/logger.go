@@ -1,14 +1,29 @@
 package logging
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
 )
 
+// Stdout formats
+const (
+	StdoutPretty = "pretty" // color-coded lines for terminals
+	StdoutJSON   = "json"   // one JSON object per line for container log collectors
+)
+
 // Logger provides structured logging with color-coded severity
 type Logger struct {
-	file *os.File
+	file         *os.File
+	stdoutFormat string
+}
+
+// stdoutLine is a log line printed in the JSON stdout format
+type stdoutLine struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Message   string    `json:"message"`
 }
 
 // Color codes for terminal output
@@ -36,6 +51,20 @@ func NewLogger(filePath string) *Logger {
 	return logger
 }
 
+// SetStdoutFormat selects how log lines are printed to stdout: StdoutPretty
+// (the default) or StdoutJSON
+func (l *Logger) SetStdoutFormat(format string) error {
+	switch format {
+	case "", StdoutPretty:
+		l.stdoutFormat = StdoutPretty
+	case StdoutJSON:
+		l.stdoutFormat = StdoutJSON
+	default:
+		return fmt.Errorf("invalid stdout format %q (expected %q or %q)", format, StdoutPretty, StdoutJSON)
+	}
+	return nil
+}
+
 // Close closes the log file if it's open
 func (l *Logger) Close() error {
 	if l.file != nil {
@@ -71,12 +100,19 @@ func (l *Logger) Block(format string, args ...interface{}) {
 
 // log is the internal logging function
 func (l *Logger) log(level, color, format string, args ...interface{}) {
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
+	now := time.Now()
+	timestamp := now.Format("2006-01-02 15:04:05")
 	message := fmt.Sprintf(format, args...)
 
-	// Terminal output with color
-	coloredOutput := fmt.Sprintf("%s[%s] %s%s %s\n", color, timestamp, level, colorReset, message)
-	fmt.Fprint(os.Stdout, coloredOutput)
+	if l.stdoutFormat == StdoutJSON {
+		// Terminal output as one JSON object per line, without colors
+		line, _ := json.Marshal(stdoutLine{Timestamp: now, Level: level, Message: message})
+		fmt.Fprintf(os.Stdout, "%s\n", line)
+	} else {
+		// Terminal output with color
+		coloredOutput := fmt.Sprintf("%s[%s] %s%s %s\n", color, timestamp, level, colorReset, message)
+		fmt.Fprint(os.Stdout, coloredOutput)
+	}
 
 	// File output (plain text)
 	if l.file != nil {
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout returns what run prints to stdout
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	run()
+	os.Stdout = stdout
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestJSONStdoutFormat(t *testing.T) {
+	logger := NewLogger("")
+	if err := logger.SetStdoutFormat(StdoutJSON); err != nil {
+		t.Fatalf("SetStdoutFormat: %v", err)
+	}
+	events := NewStdoutStructuredLogger()
+
+	out := captureStdout(t, func() {
+		logger.Info("ShieldCLI starting...")
+		logger.Block("Request %s blocked: %s", "req-1", `Rule 1002: "<script>" in body`)
+		events.Log(StructuredEvent{RequestID: "req-1", Method: "POST", URL: "/login", Blocked: true, Status: 403})
+	})
+
+	if strings.Contains(out, "\033[") {
+		t.Errorf("JSON stdout contains ANSI escapes:\n%q", out)
+	}
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("stdout line %q is not a JSON object: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("%d stdout lines, want 3:\n%s", len(lines), out)
+	}
+
+	if lines[0]["level"] != "INFO" || lines[0]["message"] != "ShieldCLI starting..." || lines[0]["timestamp"] == nil {
+		t.Errorf("log line %v, want level, message and timestamp", lines[0])
+	}
+	if lines[1]["level"] != "BLOCK" || lines[1]["message"] != `Request req-1 blocked: Rule 1002: "<script>" in body` {
+		t.Errorf("block line %v, want the message intact", lines[1])
+	}
+	if lines[2]["request_id"] != "req-1" || lines[2]["blocked"] != true || lines[2]["event_id"] == "" {
+		t.Errorf("event line %v, want the request event", lines[2])
+	}
+}
+
+func TestPrettyStdoutFormat(t *testing.T) {
+	logger := NewLogger("")
+	out := captureStdout(t, func() { logger.Warn("Running in DRY-RUN mode") })
+	if !strings.HasPrefix(out, colorYellow+"[") || !strings.HasSuffix(out, "WARN"+colorReset+" Running in DRY-RUN mode\n") {
+		t.Errorf("pretty output %q, want a colored line", out)
+	}
+	if json.Valid(bytes.TrimSpace([]byte(out))) {
+		t.Error("pretty output is JSON")
+	}
+
+	if err := logger.SetStdoutFormat("yaml"); err == nil {
+		t.Error("SetStdoutFormat accepted an unknown format")
+	}
+}
@@ -0,0 +1,204 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// MergeStats counts the events seen while merging event files
+type MergeStats struct {
+	Read       int
+	Written    int
+	Duplicates int
+}
+
+// rawEvent is an event kept verbatim along with the fields merging needs
+type rawEvent struct {
+	id     string
+	ts     time.Time
+	raw    json.RawMessage
+	source int // index of the input file, to break timestamp ties stably
+}
+
+// eventStream decodes events one at a time from a JSON lines or JSON array
+// file, gzip-compressed or not
+type eventStream struct {
+	path   string
+	file   *os.File
+	gz     *gzip.Reader
+	dec    *json.Decoder
+	array  bool
+	source int
+}
+
+// openEventStream opens an event file and detects its compression and format
+func openEventStream(path string, source int) (*eventStream, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	s := &eventStream{path: path, file: file, source: source}
+
+	br := bufio.NewReader(file)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		if s.gz, err = gzip.NewReader(br); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		r = s.gz
+	}
+
+	// A JSON array starts with '[' after optional whitespace
+	zr := bufio.NewReader(r)
+	for {
+		b, err := zr.ReadByte()
+		if err != nil {
+			break
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		zr.UnreadByte()
+		s.array = b == '['
+		break
+	}
+
+	s.dec = json.NewDecoder(zr)
+	if s.array {
+		if _, err := s.dec.Token(); err != nil {
+			s.close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+// next returns the next event of the stream, or io.EOF once it is exhausted
+func (s *eventStream) next() (*rawEvent, error) {
+	if s.array && !s.dec.More() {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+
+	var fields struct {
+		EventID   string    `json:"event_id"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("%s: invalid event: %w", s.path, err)
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, fmt.Errorf("%s: invalid event: %w", s.path, err)
+	}
+	return &rawEvent{id: fields.EventID, ts: fields.Timestamp, raw: compact.Bytes(), source: s.source}, nil
+}
+
+// close closes the underlying file
+func (s *eventStream) close() {
+	if s.gz != nil {
+		s.gz.Close()
+	}
+	s.file.Close()
+}
+
+// eventHeap orders the next event of each stream by timestamp
+type eventHeap []*rawEvent
+
+func (h eventHeap) Len() int { return len(h) }
+func (h eventHeap) Less(i, j int) bool {
+	if h[i].ts.Equal(h[j].ts) {
+		return h[i].source < h[j].source
+	}
+	return h[i].ts.Before(h[j].ts)
+}
+func (h eventHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *eventHeap) Push(x interface{}) { *h = append(*h, x.(*rawEvent)) }
+func (h *eventHeap) Pop() interface{} {
+	old := *h
+	event := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return event
+}
+
+// MergeEventFiles merges structured event files into w as JSON lines sorted by
+// timestamp, dropping events whose event_id was already written. Inputs may
+// be JSON lines or JSON arrays, optionally gzip-compressed, and are streamed;
+// each is expected in timestamp order, as written by the proxy.
+func MergeEventFiles(w io.Writer, paths []string) (MergeStats, error) {
+	var stats MergeStats
+
+	streams := make([]*eventStream, 0, len(paths))
+	defer func() {
+		for _, s := range streams {
+			s.close()
+		}
+	}()
+
+	h := &eventHeap{}
+	for i, path := range paths {
+		s, err := openEventStream(path, i)
+		if err != nil {
+			return stats, err
+		}
+		streams = append(streams, s)
+
+		event, err := s.next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		stats.Read++
+		heap.Push(h, event)
+	}
+
+	bw := bufio.NewWriter(w)
+	seen := make(map[string]bool)
+	for h.Len() > 0 {
+		event := heap.Pop(h).(*rawEvent)
+
+		if event.id != "" && seen[event.id] {
+			stats.Duplicates++
+		} else {
+			if event.id != "" {
+				seen[event.id] = true
+			}
+			bw.Write(event.raw)
+			bw.WriteByte('\n')
+			stats.Written++
+		}
+
+		following, err := streams[event.source].next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return stats, err
+		}
+		stats.Read++
+		heap.Push(h, following)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to write merged events: %w", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,75 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeEventFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// Two instances whose files overlap: evt-2 was shipped by both
+	first := filepath.Join(dir, "a.events.jsonl")
+	writeEvents(t, first, `{"event_id": "evt-1", "timestamp": "2026-01-02T15:00:01Z", "method": "GET"}
+{"event_id": "evt-2", "timestamp": "2026-01-02T15:00:03Z", "method": "POST"}
+
+{"event_id": "evt-5", "timestamp": "2026-01-02T15:00:06Z", "method": "GET"}
+`)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(`[
+  {"timestamp": "2026-01-02T15:00:02Z", "method": "HEAD"},
+  {"event_id": "evt-2", "timestamp": "2026-01-02T15:00:03Z", "method": "POST"},
+  {"event_id": "evt-3", "timestamp": "2026-01-02T17:00:04+02:00", "method": "PUT"},
+  {"event_id": "evt-4", "timestamp": "2026-01-02T15:00:05Z", "method": "DELETE"}
+]`))
+	zw.Close()
+	second := filepath.Join(dir, "b.events.json.gz")
+	writeEvents(t, second, gz.String())
+
+	var out bytes.Buffer
+	stats, err := MergeEventFiles(&out, []string{first, second})
+	if err != nil {
+		t.Fatalf("MergeEventFiles: %v", err)
+	}
+
+	var methods []string
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		var event struct {
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("merged line %q is not an event: %v", line, err)
+		}
+		methods = append(methods, event.Method)
+	}
+	if want := []string{"GET", "HEAD", "POST", "PUT", "DELETE", "GET"}; !reflect.DeepEqual(methods, want) {
+		t.Errorf("merged methods %q, want %q", methods, want)
+	}
+	if want := (MergeStats{Read: 7, Written: 6, Duplicates: 1}); stats != want {
+		t.Errorf("stats %+v, want %+v", stats, want)
+	}
+}
+
+func TestMergeEventFilesInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.jsonl")
+	writeEvents(t, path, "{\"event_id\": \"evt-1\", \"timestamp\": \"2026-01-02T15:00:01Z\"}\n{not json\n")
+
+	if _, err := MergeEventFiles(&bytes.Buffer{}, []string{path}); err == nil {
+		t.Error("MergeEventFiles accepted a corrupt file")
+	}
+}
+
+func writeEvents(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,178 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	EventTypeRequest = "request" // the outcome of a proxied request
+	EventTypeAnomaly = "anomaly" // a traffic anomaly found by the anomaly detector
+)
+
+// StructuredEvent records the outcome of one proxied request, or a traffic
+// anomaly, which has only the type, severity, reason and source IP fields set
+type StructuredEvent struct {
+	EventID        string    `json:"event_id"`
+	Timestamp      time.Time `json:"timestamp"`
+	EventType      string    `json:"event_type,omitempty"` // empty in files written before event types
+	RequestID      string    `json:"request_id,omitempty"` // X-Request-ID of the request
+	Method         string    `json:"method"`
+	URL            string    `json:"url"`
+	SourceIP       string    `json:"source_ip"`
+	RuleID         int       `json:"rule_id,omitempty"`
+	RuleName       string    `json:"rule_name,omitempty"`
+	Blocked        bool      `json:"blocked"`
+	DryRun         bool      `json:"dry_run,omitempty"` // blocked, but only logged
+	Reason         string    `json:"reason,omitempty"`
+	Severity       string    `json:"severity,omitempty"`     // of an anomaly or the blocking rule, raised by severity boosts: low, medium, high or critical
+	AnomalyType    string    `json:"anomaly_type,omitempty"` // e.g. ip_address or payload_size
+	ResponseTimeMs float64   `json:"response_time_ms"`
+	Status         int       `json:"status"`
+	Label          string    `json:"label,omitempty"` // set by analysts reviewing events: benign or malicious
+}
+
+// StructuredLogger writes StructuredEvents as JSON lines to a file, stdout,
+// or both
+type StructuredLogger struct {
+	mu     sync.Mutex
+	file   *os.File
+	stdout bool
+
+	// Size-based rotation of the event file
+	path       string
+	size       int64
+	maxBytes   int64 // rotate before a write would exceed this; 0 disables rotation
+	maxBackups int   // rotated files kept; 0 keeps all
+}
+
+// NewStructuredLogger opens (or creates) an event file for appending
+func NewStructuredLogger(filePath string) (*StructuredLogger, error) {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open event file: %w", err)
+	}
+	return &StructuredLogger{file: file, path: filePath, size: info.Size()}, nil
+}
+
+// SetRotation rotates the event file once a write would grow it past
+// maxBytes, keeping at most maxBackups rotated files (0 keeps all). Rotated
+// files are renamed with a timestamp suffix, e.g. waf.events-20260102T150405.000000000.jsonl.
+func (l *StructuredLogger) SetRotation(maxBytes int64, maxBackups int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.maxBytes = maxBytes
+	l.maxBackups = maxBackups
+}
+
+// NewStdoutStructuredLogger creates a logger that only prints events to stdout
+func NewStdoutStructuredLogger() *StructuredLogger {
+	return &StructuredLogger{stdout: true}
+}
+
+// EnableStdout also prints every event to stdout, one JSON object per line
+func (l *StructuredLogger) EnableStdout() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.stdout = true
+}
+
+// Log writes an event, filling in its ID and timestamp if unset
+func (l *StructuredLogger) Log(event StructuredEvent) error {
+	if event.EventID == "" {
+		event.EventID = newEventID()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stdout {
+		os.Stdout.Write(line)
+	}
+	if l.file != nil {
+		var rotateErr error
+		if l.maxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+			rotateErr = l.rotate()
+		}
+		n, err := l.file.Write(line)
+		l.size += int64(n)
+		if err != nil {
+			return err
+		}
+		return rotateErr
+	}
+	return nil
+}
+
+// rotate renames the current event file with a timestamp suffix, opens a
+// fresh one and removes the oldest backups; callers must hold l.mu
+func (l *StructuredLogger) rotate() error {
+	l.file.Close()
+
+	ext := filepath.Ext(l.path)
+	stem := strings.TrimSuffix(l.path, ext)
+	backup := fmt.Sprintf("%s-%s%s", stem, time.Now().UTC().Format("20060102T150405.000000000"), ext)
+	renameErr := os.Rename(l.path, backup)
+
+	// Keep logging even if the rename failed
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to reopen event file: %w", err)
+	}
+	l.file = file
+	l.size = 0
+	if renameErr != nil {
+		return fmt.Errorf("failed to rotate event file: %w", renameErr)
+	}
+
+	if l.maxBackups > 0 {
+		backups, _ := filepath.Glob(stem + "-[0-9]*T*" + ext)
+		// Timestamp suffixes sort chronologically
+		sort.Strings(backups)
+		for len(backups) > l.maxBackups {
+			os.Remove(backups[0])
+			backups = backups[1:]
+		}
+	}
+	return nil
+}
+
+// Close closes the event file, if any
+func (l *StructuredLogger) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// newEventID returns a random 128-bit hex event ID
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestStructuredLoggerRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "waf.events.jsonl")
+
+	l, err := NewStructuredLogger(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const maxBytes = 700
+	l.SetRotation(maxBytes, 2)
+	for i := 1; i <= 10; i++ {
+		if err := l.Log(StructuredEvent{RequestID: fmt.Sprintf("req-%02d", i), Method: "GET", URL: "/", Status: 200}); err != nil {
+			t.Fatalf("Log: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, "waf.events-*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("%d rotated files, want 2 kept: %q", len(backups), backups)
+	}
+	sort.Strings(backups)
+
+	// The kept files hold the latest events, in order, each under the limit
+	var ids []string
+	for _, file := range append(backups, path) {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > maxBytes {
+			t.Errorf("%s is %d bytes, want at most %d", filepath.Base(file), info.Size(), maxBytes)
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var event StructuredEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Fatalf("%s: invalid line %q: %v", filepath.Base(file), scanner.Text(), err)
+			}
+			ids = append(ids, event.RequestID)
+		}
+		f.Close()
+	}
+
+	if len(ids) == 0 || len(ids) >= 10 || ids[len(ids)-1] != "req-10" {
+		t.Fatalf("kept events %q, want the latest ones with the oldest dropped", ids)
+	}
+	first := 10 - len(ids) + 1
+	for i, id := range ids {
+		if want := fmt.Sprintf("req-%02d", first+i); id != want {
+			t.Fatalf("kept events %q, want req-%02d to req-10 in order", ids, first)
+		}
+	}
+}
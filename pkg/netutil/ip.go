@@ -0,0 +1,110 @@
+// Package netutil parses IP lists and resolves the client IP of a request,
+// for the WAF engine and the proxy alike
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ParseCIDRs parses a list of IPs and CIDRs into networks. A bare IP is
+// treated as a single-address network; blank entries are skipped.
+func ParseCIDRs(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ContainsIP reports whether any of the networks contains ip
+func ContainsIP(networks []*net.IPNet, ip net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// PeerIP returns the IP of the request's direct peer, or nil if its address
+// does not hold one
+func PeerIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(strings.Trim(host, "[]"))
+}
+
+// IsTrustedPeer reports whether the request's direct peer is a trusted proxy
+func IsTrustedPeer(r *http.Request, trusted []*net.IPNet) bool {
+	ip := PeerIP(r)
+	return ip != nil && ContainsIP(trusted, ip)
+}
+
+// ClientIP returns the client IP of a request. X-Forwarded-For is only
+// honoured when the direct peer is a trusted proxy, since anyone else can
+// put any IP in it. The chain is then walked from the right, skipping
+// trusted proxies, and the first other hop is the client: entries further
+// left were written by the client itself and cannot be trusted either. It
+// returns nil if the peer address holds no IP.
+func ClientIP(r *http.Request, trusted []*net.IPNet) net.IP {
+	peer := PeerIP(r)
+	if peer == nil || !ContainsIP(trusted, peer) {
+		return peer
+	}
+
+	hops := forwardedHops(r)
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			// A malformed hop ends what can be followed
+			break
+		}
+		if !ContainsIP(trusted, ip) {
+			return ip
+		}
+		peer = ip
+	}
+	// Every hop is a trusted proxy, or the chain is broken: the last hop
+	// that could be followed is as close to the client as is known
+	return peer
+}
+
+// forwardedHops returns the X-Forwarded-For entries of a request in order,
+// across repeated headers
+func forwardedHops(r *http.Request) []string {
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
@@ -0,0 +1,83 @@
+package netutil
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "bare IPv4", entries: []string{"203.0.113.7"}, want: []string{"203.0.113.7/32"}},
+		{name: "bare IPv6", entries: []string{"2001:db8::1"}, want: []string{"2001:db8::1/128"}},
+		{name: "CIDR", entries: []string{"10.0.0.0/8", "fd00::/8"}, want: []string{"10.0.0.0/8", "fd00::/8"}},
+		{name: "blank entries skipped", entries: []string{"", "  ", " 10.0.0.1 "}, want: []string{"10.0.0.1/32"}},
+		{name: "invalid IP", entries: []string{"10.0.0.300"}, wantErr: true},
+		{name: "invalid CIDR", entries: []string{"10.0.0.0/33"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			networks, err := ParseCIDRs(tt.entries)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseCIDRs(%q) succeeded, want error", tt.entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCIDRs(%q): %v", tt.entries, err)
+			}
+			if len(networks) != len(tt.want) {
+				t.Fatalf("ParseCIDRs(%q) = %v, want %v", tt.entries, networks, tt.want)
+			}
+			for i, network := range networks {
+				if network.String() != tt.want[i] {
+					t.Errorf("network %d = %s, want %s", i, network, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseCIDRs([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "no header", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "untrusted peer ignores header", remoteAddr: "203.0.113.7:5000", forwarded: []string{"10.1.1.1"}, want: "203.0.113.7"},
+		{name: "trusted peer", remoteAddr: "10.0.0.1:5000", forwarded: []string{"198.51.100.9"}, want: "198.51.100.9"},
+		{name: "rightmost untrusted hop", remoteAddr: "10.0.0.1:5000", forwarded: []string{"1.2.3.4, 198.51.100.9, 10.0.0.2"}, want: "198.51.100.9"},
+		{name: "repeated headers", remoteAddr: "10.0.0.1:5000", forwarded: []string{"1.2.3.4", "198.51.100.9"}, want: "198.51.100.9"},
+		{name: "all hops trusted", remoteAddr: "10.0.0.1:5000", forwarded: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "malformed hop", remoteAddr: "10.0.0.1:5000", forwarded: []string{"1.2.3.4, junk, 10.0.0.2"}, want: "10.0.0.2"},
+		{name: "trusted peer without header", remoteAddr: "10.0.0.1:5000", want: "10.0.0.1"},
+		{name: "IPv6 peer", remoteAddr: "[2001:db8::1]:5000", want: "2001:db8::1"},
+		{name: "peer without IP", remoteAddr: "pipe", want: "<nil>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := ClientIP(r, trusted).String(); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
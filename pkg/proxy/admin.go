@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// adminHandler serves the admin API. It has no authentication, so the admin
+// address should only be reachable by operators.
+//
+//	POST /stats/reset         reset the anomaly statistics, answering with
+//	                          the snapshot of what they held
+//	POST /rules/{id}/disable  stop evaluating a rule until it is enabled
+//	POST /rules/{id}/enable   evaluate a disabled rule again
+func (p *Proxy) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /stats/reset", p.handleStatsReset)
+	mux.HandleFunc("POST /rules/{id}/disable", p.handleSetRuleEnabled(false))
+	mux.HandleFunc("POST /rules/{id}/enable", p.handleSetRuleEnabled(true))
+	return mux
+}
+
+// handleSetRuleEnabled returns a handler that enables or disables the rule
+// in the request path, answering with its new state
+func (p *Proxy) handleSetRuleEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid rule ID %q", r.PathValue("id")), http.StatusBadRequest)
+			return
+		}
+		if err := p.wafEngine.SetRuleEnabled(id, enabled); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		p.logger.Info("Rule %d set enabled=%t through the admin API from %s", id, enabled, r.RemoteAddr)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "enabled": enabled})
+	}
+}
+
+// handleStatsReset resets the anomaly detector's statistics, the session
+// rule match counts and the block tally, and answers with a snapshot of the
+// anomaly statistics
+func (p *Proxy) handleStatsReset(w http.ResponseWriter, r *http.Request) {
+	snapshot := p.detector.SnapshotAndReset()
+	p.wafEngine.ResetSessionStats()
+	p.tally.reset()
+	p.logger.Info("Statistics reset through the admin API from %s", r.RemoteAddr)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+// startAdmin starts serving the admin API on the configured address
+func (p *Proxy) startAdmin() error {
+	listener, err := net.Listen("tcp", p.config.AdminAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on admin address %s: %w", p.config.AdminAddr, err)
+	}
+
+	p.adminServer = &http.Server{
+		Handler:           p.adminHandler(),
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+	}
+	go func() {
+		if err := p.adminServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			p.logger.Error("Admin API error: %v", err)
+		}
+	}()
+	return nil
+}
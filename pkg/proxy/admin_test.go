@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/anomaly"
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestAdminStatsReset(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantReset  bool
+	}{
+		{name: "reset", method: "POST", path: "/stats/reset", wantStatus: http.StatusOK, wantReset: true},
+		{name: "wrong method", method: "GET", path: "/stats/reset", wantStatus: http.StatusMethodNotAllowed},
+		{name: "unknown path", method: "POST", path: "/stats", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProxy(t, &config.Config{})
+			for i := 0; i < 3; i++ {
+				serve(p, newRequest("GET", "/", "", "192.0.2.1:4000", ""))
+			}
+
+			w := httptest.NewRecorder()
+			p.adminHandler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			total := p.AnomalyDetector().GetStatistics()["total_requests"]
+			if !tt.wantReset {
+				if total != int64(3) {
+					t.Errorf("total_requests %v, want 3 untouched", total)
+				}
+				return
+			}
+			if total != int64(0) {
+				t.Errorf("total_requests %v after reset, want 0", total)
+			}
+			var snapshot anomaly.Snapshot
+			if err := json.NewDecoder(w.Body).Decode(&snapshot); err != nil {
+				t.Fatalf("invalid snapshot: %v", err)
+			}
+			if snapshot.Statistics["total_requests"] != 3.0 {
+				t.Errorf("snapshot total_requests %v, want 3", snapshot.Statistics["total_requests"])
+			}
+		})
+	}
+}
+
+func TestAdminSetRuleEnabled(t *testing.T) {
+	attack := "q=' OR 1=1--"
+
+	tests := []struct {
+		name       string
+		paths      []string // admin calls, in order
+		wantStatus int      // status of the last call
+		wantAttack int      // status of the attack after the calls
+	}{
+		{name: "disable", paths: []string{"/rules/1001/disable"}, wantStatus: http.StatusOK, wantAttack: http.StatusOK},
+		{name: "enable again", paths: []string{"/rules/1001/disable", "/rules/1001/enable"}, wantStatus: http.StatusOK, wantAttack: http.StatusForbidden},
+		{name: "unknown rule", paths: []string{"/rules/4242/disable"}, wantStatus: http.StatusNotFound, wantAttack: http.StatusForbidden},
+		{name: "invalid rule ID", paths: []string{"/rules/sqli/disable"}, wantStatus: http.StatusBadRequest, wantAttack: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProxy(t, &config.Config{})
+			if w := serve(p, newRequest("POST", "/search", attack, "192.0.2.1:4000", "")); w.Code != http.StatusForbidden {
+				t.Fatalf("attack before the calls: status %d, want 403", w.Code)
+			}
+
+			var w *httptest.ResponseRecorder
+			for _, path := range tt.paths {
+				w = httptest.NewRecorder()
+				p.adminHandler().ServeHTTP(w, httptest.NewRequest("POST", path, nil))
+			}
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if w := serve(p, newRequest("POST", "/search", attack, "192.0.2.1:4000", "")); w.Code != tt.wantAttack {
+				t.Errorf("attack after the calls: status %d, want %d", w.Code, tt.wantAttack)
+			}
+		})
+	}
+}
+
+func TestAdminStatsResetKeepsRules(t *testing.T) {
+	p := newTestProxy(t, &config.Config{CustomRules: []config.CustomRule{{
+		ID: 9100, Name: "Probe", Phase: "request_uri", Operator: "contains",
+		Target: "REQUEST_URI", Pattern: "/probe", Action: "block", Severity: "high", Enabled: true,
+	}}})
+	engine := p.Engine()
+	if err := engine.SetRuleEnabled(1005, false); err != nil {
+		t.Fatal(err)
+	}
+	rules := len(engine.GetRules())
+
+	serve(p, newRequest("GET", "/probe", "", "192.0.2.1:4000", ""))
+	serve(p, newRequest("POST", "/search", "q=' OR 1=1--", "192.0.2.1:4000", ""))
+
+	w := httptest.NewRecorder()
+	p.adminHandler().ServeHTTP(w, httptest.NewRequest("POST", "/stats/reset", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", w.Code)
+	}
+
+	if stats := engine.GetRuleStats(); len(stats) != 0 {
+		t.Errorf("rule stats %v after reset, want none", stats)
+	}
+	if summary := p.Summary(); summary.Total != 0 || summary.Blocked != 0 || len(summary.ByRule) != 0 {
+		t.Errorf("summary %+v after reset, want zero counts", summary)
+	}
+	if hits, _ := engine.RuleHits(); hits[9100].Count != 1 || hits[1001].Count != 1 {
+		t.Errorf("cumulative rule hits %v after reset, want 9100 and 1001 kept", hits)
+	}
+
+	if got := len(engine.GetRules()); got != rules {
+		t.Errorf("%d rules after reset, want %d", got, rules)
+	}
+	for _, rule := range engine.GetRules() {
+		if rule.ID == 1005 && rule.Enabled {
+			t.Error("rule 1005 enabled again by the reset")
+		}
+	}
+	if w := serve(p, newRequest("GET", "/probe", "", "192.0.2.1:4000", "")); w.Code != http.StatusForbidden {
+		t.Errorf("custom rule after reset: status %d, want 403", w.Code)
+	}
+	if stats := engine.GetRuleStats(); stats[9100] != 1 {
+		t.Errorf("rule stats %v, want 9100 counted once since the reset", stats)
+	}
+}
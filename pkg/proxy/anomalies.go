@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/anomaly"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// The proxy's anomaly detector judges request rates and per-IP volume over
+// anomalyWindow; new anomalies are logged every anomalyLogInterval
+const (
+	anomalyWindow      = time.Minute
+	anomalyLogInterval = 10 * time.Second
+)
+
+// recordTraffic feeds a request to the anomaly detector, which judges it
+// against both the global and its endpoint's baseline, and, when calibrating,
+// the calibrator. body is the inspected part of the request body.
+func (p *Proxy) recordTraffic(r *http.Request, body []byte) {
+	size := int64(len(body))
+	if r.ContentLength > size {
+		// Only the inspection window was read
+		size = r.ContentLength
+	}
+	clientIP := p.clientIP(r)
+	entropy := waf.Entropy(string(body))
+
+	p.detector.RecordPathRequest(requestID(r), r.URL.Path, clientIP, r.UserAgent(), size, entropy)
+	if p.config.AnomalyHeaderFingerprint {
+		p.detector.RecordHeaderFingerprint(requestID(r), clientIP, r.Header)
+	}
+	if p.calibrator != nil {
+		p.calibrator.Record(clientIP, r.UserAgent(), size, entropy)
+	}
+}
+
+// clientIP returns the client IP the rules see, or the peer host if it is
+// not an IP. X-Forwarded-For only counts from trusted proxies.
+func (p *Proxy) clientIP(r *http.Request) string {
+	return rateLimitKey(r, p.trustedProxies)
+}
+
+// reportAnomalies logs new anomalies every anomalyLogInterval until stop is
+// closed
+func (p *Proxy) reportAnomalies(stop <-chan struct{}) {
+	ticker := time.NewTicker(anomalyLogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.logAnomalies()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// logAnomalies logs the anomalies detected since the last call, one line per
+// type and severity, so a flood of requests does not become a flood of log
+// lines. The anomalies also become structured events, so they reach the SIEM
+// alongside rule blocks: one per client IP and type, for the latest anomaly,
+// with the number of repeats in the reason.
+func (p *Proxy) logAnomalies() {
+	type kind struct {
+		anomalyType string
+		severity    string
+	}
+	type source struct {
+		ip          string
+		anomalyType string
+	}
+
+	var kinds []kind
+	counts := make(map[kind]int)
+	latest := make(map[kind]string)
+
+	var sources []source
+	sourceCounts := make(map[source]int)
+	sourceLatest := make(map[source]anomaly.Anomaly)
+
+	for _, a := range p.detector.DrainAnomalies() {
+		k := kind{a.Type, a.Severity}
+		if counts[k] == 0 {
+			kinds = append(kinds, k)
+		}
+		counts[k]++
+		latest[k] = a.Description
+
+		s := source{a.IP, a.Type}
+		if sourceCounts[s] == 0 {
+			sources = append(sources, s)
+		}
+		sourceCounts[s]++
+		sourceLatest[s] = a
+	}
+
+	if p.events != nil {
+		for _, s := range sources {
+			event := anomalyEvent(sourceLatest[s])
+			if n := sourceCounts[s]; n > 1 {
+				event.Reason = fmt.Sprintf("%s (%d times since the last report)", event.Reason, n)
+			}
+			if err := p.events.Log(event); err != nil {
+				p.logger.Warn("Failed to write structured event: %v", err)
+			}
+		}
+	}
+
+	for _, k := range kinds {
+		if counts[k] == 1 {
+			p.logger.Warn("[ANOMALY] %s (%s): %s", k.anomalyType, k.severity, latest[k])
+		} else {
+			p.logger.Warn("[ANOMALY] %s (%s), %d times, latest: %s", k.anomalyType, k.severity, counts[k], latest[k])
+		}
+	}
+}
+
+// anomalyEvent converts an anomaly to its structured event
+func anomalyEvent(a anomaly.Anomaly) logging.StructuredEvent {
+	return logging.StructuredEvent{
+		Timestamp:   a.Timestamp,
+		EventType:   logging.EventTypeAnomaly,
+		RequestID:   a.RequestID,
+		SourceIP:    a.IP,
+		Reason:      a.Description,
+		Severity:    a.Severity,
+		AnomalyType: a.Type,
+	}
+}
+
+// PersistAnomalyState saves the anomaly detector's state to path every
+// interval until stop is closed, and once more on the way out, so a
+// restarted proxy can resume from its learned baseline
+func (p *Proxy) PersistAnomalyState(path string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.detector.SaveState(path); err != nil {
+				p.logger.Error("Failed to persist anomaly state: %v", err)
+			}
+		case <-stop:
+			if err := p.detector.SaveState(path); err != nil {
+				p.logger.Error("Failed to persist anomaly state: %v", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+func TestAnomalyEventsCarryRequestID(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.Config
+		body        string
+		wantAnomaly string
+	}{
+		{
+			name:        "payload size",
+			cfg:         config.Config{AnomalyPayloadSizeThreshold: 8},
+			body:        "a large enough payload",
+			wantAnomaly: "payload_size",
+		},
+		{
+			name:        "header fingerprint",
+			cfg:         config.Config{AnomalyHeaderFingerprint: true},
+			wantAnomaly: "header_fingerprint",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.EventsFile = filepath.Join(t.TempDir(), "events.jsonl")
+			p := newTestProxy(t, &cfg)
+
+			w := serve(p, newRequest("POST", "/api/items", tt.body, "192.0.2.1:4000", ""))
+			id := w.Header().Get(RequestIDHeader)
+			if id == "" {
+				t.Fatal("response has no request ID")
+			}
+			p.logAnomalies()
+
+			data, err := os.ReadFile(cfg.EventsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var event logging.StructuredEvent
+				if err := json.Unmarshal([]byte(line), &event); err != nil {
+					t.Fatalf("invalid event %q: %v", line, err)
+				}
+				if event.EventType != logging.EventTypeAnomaly || event.AnomalyType != tt.wantAnomaly {
+					continue
+				}
+				found = true
+				if event.RequestID != id {
+					t.Errorf("anomaly event request ID %q, want %q", event.RequestID, id)
+				}
+			}
+			if !found {
+				t.Errorf("no %s anomaly event in %s", tt.wantAnomaly, data)
+			}
+		})
+	}
+}
+
+func TestRecordTrafficBuildsPathProfiles(t *testing.T) {
+	p := newTestProxy(t, &config.Config{})
+	for _, target := range []string{"/users/1", "/users/2", "/users/3?page=2", "/login"} {
+		serve(p, newRequest("GET", target, "", "192.0.2.1:4000", ""))
+	}
+
+	profiles := p.AnomalyDetector().GetPathProfiles()
+	want := map[string]int64{"/users/:id": 3, "/login": 1}
+	if len(profiles) != len(want) {
+		t.Fatalf("got profiles %+v, want %v", profiles, want)
+	}
+	for _, profile := range profiles {
+		if profile.Requests != want[profile.Path] {
+			t.Errorf("%s: %d requests, want %d", profile.Path, profile.Requests, want[profile.Path])
+		}
+	}
+}
+
+func TestAnomalyEventsPerSource(t *testing.T) {
+	cfg := config.Config{AnomalyPayloadSizeThreshold: 8}
+	cfg.EventsFile = filepath.Join(t.TempDir(), "events.jsonl")
+	p := newTestProxy(t, &cfg)
+
+	send := func(remoteAddr string, n int) {
+		for i := 0; i < n; i++ {
+			serve(p, newRequest("POST", "/api/items", "a large enough payload", remoteAddr, ""))
+		}
+	}
+	events := func() map[string]string {
+		t.Helper()
+		data, err := os.ReadFile(cfg.EventsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reasons := make(map[string]string)
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var event logging.StructuredEvent
+			if err := json.Unmarshal([]byte(line), &event); err != nil {
+				t.Fatalf("invalid event %q: %v", line, err)
+			}
+			if event.AnomalyType != "payload_size" {
+				continue
+			}
+			if _, dup := reasons[event.SourceIP]; dup {
+				t.Errorf("second payload_size event for %s: %s", event.SourceIP, line)
+			}
+			reasons[event.SourceIP] = event.Reason
+		}
+		return reasons
+	}
+
+	send("192.0.2.1:4000", 50)
+	send("192.0.2.2:4000", 1)
+	p.logAnomalies()
+
+	reasons := events()
+	if len(reasons) != 2 {
+		t.Fatalf("payload_size events for %v, want one for each of 2 IPs", reasons)
+	}
+	if want := "(50 times since the last report)"; !strings.HasSuffix(reasons["192.0.2.1"], want) {
+		t.Errorf("reason %q for the flooding IP, want it to end in %q", reasons["192.0.2.1"], want)
+	}
+	if strings.Contains(reasons["192.0.2.2"], "times") {
+		t.Errorf("reason %q for a single anomaly has a repeat count", reasons["192.0.2.2"])
+	}
+
+	// The next report starts over
+	os.Truncate(cfg.EventsFile, 0)
+	send("192.0.2.1:4000", 3)
+	p.logAnomalies()
+	if reasons := events(); len(reasons) != 1 || !strings.HasSuffix(reasons["192.0.2.1"], "(3 times since the last report)") {
+		t.Errorf("payload_size events %v in the next report, want one for 192.0.2.1 with 3 repeats", reasons)
+	}
+}
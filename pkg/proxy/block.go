@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+// Default block response
+const (
+	defaultBlockBody        = "Forbidden"
+	defaultBlockContentType = "text/plain; charset=utf-8"
+)
+
+// blockResponse is what clients get for a request the WAF blocks
+type blockResponse struct {
+	status      int
+	contentType string
+	body        blockTemplate
+}
+
+// blockTemplate is a parsed block body: an html/template for HTML content
+// types, so request data is escaped, else a text/template
+type blockTemplate interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// blockPageData is the data a block body template is rendered with
+type blockPageData struct {
+	Status    int
+	Reason    string
+	RequestID string // as sent in the X-Request-ID response header
+}
+
+// blockTemplateFuncs are the functions available to block body templates in
+// addition to the built-in html and js escapers
+var blockTemplateFuncs = template.FuncMap{
+	// json renders a value as a JSON literal, e.g. a quoted and escaped string
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// newBlockResponse builds the block response from the configuration. The body
+// comes from BlockBodyFile, else BlockBody, and is a template, parsed with
+// html/template when served as HTML. Without BlockContentType, a body file is
+// served with the type of its extension, e.g. text/html for a branded block
+// page.
+func newBlockResponse(cfg *config.Config) (*blockResponse, error) {
+	status := cfg.BlockStatusCode
+	if status == 0 {
+		status = http.StatusForbidden
+	}
+	if status < 100 || status > 599 {
+		return nil, fmt.Errorf("invalid block status code %d", status)
+	}
+
+	contentType := cfg.BlockContentType
+	if contentType == "" && cfg.BlockBodyFile != "" {
+		contentType = mime.TypeByExtension(filepath.Ext(cfg.BlockBodyFile))
+	}
+	if contentType == "" {
+		contentType = defaultBlockContentType
+	}
+
+	body := cfg.BlockBody
+	if cfg.BlockBodyFile != "" {
+		data, err := os.ReadFile(cfg.BlockBodyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read block body file: %w", err)
+		}
+		body = string(data)
+	} else if body == "" {
+		body = defaultBlockBody
+	}
+
+	var tmpl blockTemplate
+	var err error
+	if isHTML(contentType) {
+		tmpl, err = htmltemplate.New("block").Funcs(htmltemplate.FuncMap(blockTemplateFuncs)).Parse(body)
+	} else {
+		tmpl, err = template.New("block").Funcs(blockTemplateFuncs).Parse(body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid block body template: %w", err)
+	}
+
+	// Catch references to unknown fields now rather than on the first block
+	if err := tmpl.Execute(&bytes.Buffer{}, blockPageData{}); err != nil {
+		return nil, fmt.Errorf("invalid block body template: %w", err)
+	}
+
+	return &blockResponse{status: status, contentType: contentType, body: tmpl}, nil
+}
+
+// isHTML reports whether contentType is an HTML media type
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// ReloadBlockResponse rebuilds the block response from the block settings of
+// cfg, re-reading the body file, e.g. after a branded block page was edited.
+// On error the current block response stays in use.
+func (p *Proxy) ReloadBlockResponse(cfg *config.Config) error {
+	block, err := newBlockResponse(cfg)
+	if err != nil {
+		return err
+	}
+	p.block.Store(block)
+	return nil
+}
+
+// write sends the block response for a request blocked for reason
+func (b *blockResponse) write(w http.ResponseWriter, r *http.Request, reason string) {
+	data := blockPageData{Status: b.status, Reason: reason, RequestID: requestID(r)}
+
+	var body bytes.Buffer
+	if err := b.body.Execute(&body, data); err != nil {
+		// Validated at startup, so only a failing function gets here
+		body.Reset()
+		body.WriteString(http.StatusText(b.status))
+	}
+
+	w.Header().Set("Content-Type", b.contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+	w.WriteHeader(b.status)
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestBlockResponseJSON(t *testing.T) {
+	p := newTestProxy(t, &config.Config{
+		BlockStatusCode:  http.StatusNotAcceptable,
+		BlockContentType: "application/json",
+		BlockBody:        `{"error": "blocked", "reason": {{json .Reason}}, "request_id": {{json .RequestID}}}`,
+	})
+
+	w := serve(p, newRequest("POST", "/login", "username=admin' OR 1=1--", "192.0.2.1:4000", ""))
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusNotAcceptable)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type %q, want application/json", got)
+	}
+
+	var body struct {
+		Error     string `json:"error"`
+		Reason    string `json:"reason"`
+		RequestID string `json:"request_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body %q is not JSON: %v", w.Body.String(), err)
+	}
+	if body.Reason != "Rule 1001: SQL Injection - Common Patterns" {
+		t.Errorf("reason %q, want the blocking rule", body.Reason)
+	}
+	if id := w.Header().Get(RequestIDHeader); body.RequestID == "" || body.RequestID != id {
+		t.Errorf("request_id %q, want the %s header %q", body.RequestID, RequestIDHeader, id)
+	}
+
+	if w := serve(p, newRequest("GET", "/", "", "192.0.2.1:4000", "")); w.Code != http.StatusOK {
+		t.Errorf("allowed request got %d, want 200", w.Code)
+	}
+}
+
+func TestBlockResponseInvalid(t *testing.T) {
+	for _, cfg := range []*config.Config{
+		{BlockStatusCode: 99},
+		{BlockStatusCode: 600},
+		{BlockBody: "{{.Reason"},
+		{BlockBodyFile: filepath.Join(t.TempDir(), "missing.html")},
+	} {
+		if _, err := newBlockResponse(cfg); err == nil {
+			t.Errorf("newBlockResponse accepted %+v", *cfg)
+		}
+	}
+}
+
+func TestBlockPageFile(t *testing.T) {
+	page := filepath.Join(t.TempDir(), "blocked.html")
+	if err := os.WriteFile(page, []byte("<p>{{.Reason}}</p><p>Reference: {{.RequestID}}</p>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := newTestProxy(t, &config.Config{BlockBodyFile: page})
+
+	w := serve(p, newRequest("POST", "/comment", "text=<script>alert(1)</script>", "192.0.2.1:4000", ""))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if got, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+		t.Errorf("Content-Type %q, want %q", got, want)
+	}
+	id := w.Header().Get(RequestIDHeader)
+	want := "<p>Rule 1002: Cross-Site Scripting (XSS)</p><p>Reference: " + id + "</p>"
+	if got := w.Body.String(); got != want {
+		t.Errorf("body %q, want %q", got, want)
+	}
+}
+
+func TestBlockPageEscaping(t *testing.T) {
+	const hostile = `"><script>alert(1)</script>`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+	}{
+		{
+			name:        "HTML is escaped",
+			contentType: "text/html; charset=utf-8",
+			body:        "<p>Reference: {{.RequestID}}</p>",
+			want:        "<p>Reference: &#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;</p>",
+		},
+		{
+			name:        "JSON is quoted",
+			contentType: "application/json",
+			body:        `{"request_id": {{json .RequestID}}}`,
+			want:        `{"request_id": "\"\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := newBlockResponse(&config.Config{BlockContentType: tt.contentType, BlockBody: tt.body})
+			if err != nil {
+				t.Fatalf("newBlockResponse: %v", err)
+			}
+
+			// An ID that got past validation must still not break out of the page
+			r := httptest.NewRequest("GET", "/", nil)
+			r = r.WithContext(context.WithValue(r.Context(), requestIDKey{}, hostile))
+			w := httptest.NewRecorder()
+			block.write(w, r, "Rule 1002: Cross-Site Scripting (XSS)")
+			if got := w.Body.String(); got != tt.want {
+				t.Errorf("body %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// Defaults for the connection-flood guard
+const (
+	defaultConnFloodWindow             = 10 * time.Second
+	defaultConnFloodMinRequestsPerConn = 0.5
+)
+
+// connFloodGuard tracks per-IP new-connection and request rates and refuses
+// connections from IPs that open connections far faster than they send
+// requests over them, a pattern of connection-churn DoS. A flagged IP is
+// refused for one window; refused connections do not extend the ban.
+type connFloodGuard struct {
+	maxConns           int           // new connections per window before an IP is judged
+	window             time.Duration // counting window
+	minRequestsPerConn float64       // below this ratio the IP is flooding
+	logger             *logging.Logger
+
+	mu      sync.Mutex
+	clients map[string]*connFloodStats
+}
+
+// connFloodStats are the counts of one client IP in the current window
+type connFloodStats struct {
+	windowStart  time.Time
+	conns        int
+	requests     int
+	blockedUntil time.Time
+}
+
+// newConnFloodGuard creates a guard; zero window and ratio use the defaults
+func newConnFloodGuard(maxConns int, window time.Duration, minRequestsPerConn float64, logger *logging.Logger) *connFloodGuard {
+	if window <= 0 {
+		window = defaultConnFloodWindow
+	}
+	if minRequestsPerConn <= 0 {
+		minRequestsPerConn = defaultConnFloodMinRequestsPerConn
+	}
+	return &connFloodGuard{
+		maxConns:           maxConns,
+		window:             window,
+		minRequestsPerConn: minRequestsPerConn,
+		logger:             logger,
+		clients:            make(map[string]*connFloodStats),
+	}
+}
+
+// stats returns the counts for ip, starting a new window when the current
+// one has passed; callers must hold g.mu
+func (g *connFloodGuard) stats(ip string, now time.Time) *connFloodStats {
+	s, ok := g.clients[ip]
+	if !ok {
+		s = &connFloodStats{windowStart: now}
+		g.clients[ip] = s
+	} else if now.Sub(s.windowStart) >= g.window {
+		s.windowStart = now
+		s.conns = 0
+		s.requests = 0
+	}
+	return s
+}
+
+// allowConn records a new connection from ip and reports whether it may proceed
+func (g *connFloodGuard) allowConn(ip string) bool {
+	now := time.Now()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.prune(now)
+
+	s := g.stats(ip, now)
+	if now.Before(s.blockedUntil) {
+		return false
+	}
+
+	s.conns++
+	if s.conns > g.maxConns && float64(s.requests)/float64(s.conns) < g.minRequestsPerConn {
+		s.blockedUntil = now.Add(g.window)
+		g.logger.Block("Connection flood from %s: %d new connections but %d requests in %v, refusing connections for %v",
+			ip, s.conns, s.requests, g.window, g.window)
+		return false
+	}
+	return true
+}
+
+// recordRequest records a request received from ip
+func (g *connFloodGuard) recordRequest(ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.stats(ip, time.Now()).requests++
+}
+
+// prune drops idle clients so the table does not grow without bound; callers must hold g.mu
+func (g *connFloodGuard) prune(now time.Time) {
+	if len(g.clients) < 10000 {
+		return
+	}
+	for ip, s := range g.clients {
+		if now.Sub(s.windowStart) >= 2*g.window && now.After(s.blockedUntil) {
+			delete(g.clients, ip)
+		}
+	}
+}
+
+// connFloodListener refuses connections rejected by the guard right after accepting them
+type connFloodListener struct {
+	net.Listener
+	guard *connFloodGuard
+}
+
+// Accept waits for the next connection the guard allows
+func (l *connFloodListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.guard.allowConn(hostWithoutPort(conn.RemoteAddr().String())) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+}
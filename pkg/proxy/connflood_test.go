@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+func TestConnFloodGuard(t *testing.T) {
+	const flooder, client = "192.0.2.1", "192.0.2.2"
+	g := newConnFloodGuard(5, 50*time.Millisecond, 0, logging.NewLogger(""))
+
+	// Up to maxConns connections are allowed before the ratio is judged
+	for i := 1; i <= 5; i++ {
+		if !g.allowConn(flooder) {
+			t.Fatalf("connection %d refused, want the first 5 allowed", i)
+		}
+	}
+	if g.allowConn(flooder) {
+		t.Fatal("connection 6 without requests allowed, want it refused")
+	}
+	if g.allowConn(flooder) {
+		t.Error("connection allowed during the ban")
+	}
+
+	// A client sending a request on most connections is not flooding
+	for i := 1; i <= 10; i++ {
+		if !g.allowConn(client) {
+			t.Fatalf("client connection %d refused", i)
+		}
+		g.recordRequest(client)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if !g.allowConn(flooder) {
+		t.Error("connection refused after the ban window")
+	}
+}
+
+func TestConnFloodListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &connFloodListener{Listener: inner, guard: newConnFloodGuard(3, time.Minute, 0, logging.NewLogger(""))}
+	t.Cleanup(func() { l.Close() })
+
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	// The listener closes refused connections; accepted ones stay open
+	var refused int
+	for i := 0; i < 5; i++ {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, err := conn.Read(make([]byte, 1)); err == io.EOF {
+			refused++
+		}
+	}
+
+	if refused != 2 {
+		t.Errorf("%d connections refused, want the 2 past the limit", refused)
+	}
+	waitFor(t, func() bool { return len(accepted) == 3 })
+	for len(accepted) > 0 {
+		(<-accepted).Close()
+	}
+}
@@ -0,0 +1,162 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/gemini"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// Escalation defaults and limits
+const (
+	DefaultEscalationConfidence    = 0.8
+	DefaultEscalationBlockDuration = 10 * time.Minute
+
+	escalationQueueSize    = 100  // requests waiting for analysis; more are dropped
+	escalationPayloadLimit = 4096 // bytes of a request sent for analysis
+)
+
+// escalation sends requests the rules found suspicious but let through to an
+// AI analyzer in the background. A malicious verdict of high enough
+// confidence blocks the request's client IP for a while.
+type escalation struct {
+	analyzer      gemini.PayloadAnalyzer
+	minScore      int // scoring mode: escalate allowed requests from this score
+	minConfidence float64
+	blockFor      time.Duration
+	logger        *logging.Logger
+	queue         chan escalationJob
+
+	mu     sync.Mutex
+	blocks map[string]time.Time // client IP to the end of its block
+}
+
+// escalationJob is a request waiting for analysis
+type escalationJob struct {
+	requestID string
+	clientIP  string
+	payload   string
+	reason    string // why the request was escalated
+}
+
+// EnableEscalation makes the proxy send requests that are suspicious but not
+// blocked to analyzer, without delaying them. A request is suspicious when it
+// matches a log rule of medium severity or higher or, in scoring mode, when
+// its anomaly score reaches minScore. A malicious verdict with at least
+// minConfidence blocks the client IP for blockFor; zero values use the
+// defaults. Call before Start.
+func (p *Proxy) EnableEscalation(analyzer gemini.PayloadAnalyzer, minScore int, minConfidence float64, blockFor time.Duration) {
+	if minConfidence <= 0 {
+		minConfidence = DefaultEscalationConfidence
+	}
+	if blockFor <= 0 {
+		blockFor = DefaultEscalationBlockDuration
+	}
+	p.escalation = &escalation{
+		analyzer:      analyzer,
+		minScore:      minScore,
+		minConfidence: minConfidence,
+		blockFor:      blockFor,
+		logger:        p.logger,
+		queue:         make(chan escalationJob, escalationQueueSize),
+		blocks:        make(map[string]time.Time),
+	}
+}
+
+// suspiciousMatch returns the first matched log rule of medium severity or
+// higher, or "" if there is none
+func suspiciousMatch(result *waf.CheckResult) string {
+	for _, eval := range result.Evaluations {
+		if eval.Matched && eval.Action == waf.ActionLog && waf.SeverityScore(eval.Severity) >= waf.SeverityScore("medium") {
+			return fmt.Sprintf("Rule %d: %s", eval.RuleID, eval.RuleName)
+		}
+	}
+	return ""
+}
+
+// enqueue queues a request for analysis. It never waits: when the queue is
+// full the request is not analyzed.
+func (e *escalation) enqueue(r *http.Request, clientIP string, body []byte, reason string) {
+	payload := fmt.Sprintf("%s %s\n\n%s", r.Method, r.RequestURI, body)
+	if len(payload) > escalationPayloadLimit {
+		payload = payload[:escalationPayloadLimit]
+	}
+
+	job := escalationJob{
+		requestID: requestID(r),
+		clientIP:  clientIP,
+		payload:   payload,
+		reason:    reason,
+	}
+	select {
+	case e.queue <- job:
+		e.logger.Debug("Escalated request %s for AI analysis: %s", job.requestID, reason)
+	default:
+		e.logger.Debug("AI analysis queue full, not escalating request %s", job.requestID)
+	}
+}
+
+// run analyzes queued requests until stop is closed
+func (e *escalation) run(stop <-chan struct{}) {
+	for {
+		select {
+		case job := <-e.queue:
+			e.analyze(job)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// analyze analyzes one request and blocks its client IP on a confident
+// malicious verdict
+func (e *escalation) analyze(job escalationJob) {
+	result, err := e.analyzer.AnalyzePayload(job.payload)
+	if err != nil {
+		e.logger.Warn("AI analysis of request %s failed: %v", job.requestID, err)
+		return
+	}
+
+	if !result.IsMalicious || result.Confidence < e.minConfidence {
+		e.logger.Debug("AI analysis of request %s: %s (confidence %.2f)", job.requestID, result.Verdict, result.Confidence)
+		return
+	}
+
+	e.block(job.clientIP)
+	e.logger.Block("AI analysis found request %s (%s) malicious with confidence %.2f, blocking %s for %s: %s",
+		job.requestID, job.reason, result.Confidence, job.clientIP, e.blockFor, result.Explanation)
+}
+
+// block blocks a client IP for blockFor, dropping expired blocks
+func (e *escalation) block(clientIP string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	for ip, until := range e.blocks {
+		if now.After(until) {
+			delete(e.blocks, ip)
+		}
+	}
+	e.blocks[clientIP] = now.Add(e.blockFor)
+}
+
+// blocked reports whether a client IP is blocked, dropping expired blocks
+func (e *escalation) blocked(clientIP string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	until, ok := e.blocks[clientIP]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(e.blocks, clientIP)
+		return false
+	}
+	return true
+}
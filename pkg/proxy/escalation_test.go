@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/gemini"
+)
+
+// maliciousAnalyzer finds every payload malicious
+type maliciousAnalyzer struct{}
+
+func (maliciousAnalyzer) AnalyzePayload(payload string) (*gemini.AnalysisResult, error) {
+	return &gemini.AnalysisResult{IsMalicious: true, Confidence: 1, Verdict: "malicious"}, nil
+}
+
+func TestEscalationBlocksTrustedClientIP(t *testing.T) {
+	tests := []struct {
+		name              string
+		remoteAddr        string
+		forwarded         string
+		nextAddr, nextFwd string
+		wantStatus        int
+	}{
+		{
+			name:       "changing X-Forwarded-For does not dodge the block",
+			remoteAddr: "203.0.113.7:4000", forwarded: "192.0.2.1",
+			nextAddr: "203.0.113.7:4001", nextFwd: "192.0.2.2",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "spoofed X-Forwarded-For does not block another client",
+			remoteAddr: "203.0.113.7:4000", forwarded: "198.51.100.9",
+			nextAddr:   "198.51.100.9:4000",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "client behind a trusted proxy is blocked",
+			remoteAddr: "10.0.0.1:4000", forwarded: "198.51.100.9",
+			nextAddr: "10.0.0.1:4001", nextFwd: "198.51.100.9",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "other clients of a trusted proxy pass",
+			remoteAddr: "10.0.0.1:4000", forwarded: "198.51.100.9",
+			nextAddr: "10.0.0.1:4001", nextFwd: "198.51.100.10",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProxy(t, &config.Config{
+				TrustedProxies: []string{"10.0.0.1"},
+				CustomRules: []config.CustomRule{{
+					ID: 9100, Name: "Probe", Phase: "request_uri", Operator: "contains",
+					Target: "REQUEST_URI", Pattern: "probe", Action: "log", Severity: "high", Enabled: true,
+				}},
+			})
+			p.EnableEscalation(maliciousAnalyzer{}, 0, 0, 0)
+
+			if w := serve(p, newRequest("GET", "/probe", "", tt.remoteAddr, tt.forwarded)); w.Code != http.StatusOK {
+				t.Fatalf("suspicious request: status %d, want %d", w.Code, http.StatusOK)
+			}
+			select {
+			case job := <-p.escalation.queue:
+				p.escalation.analyze(job)
+			default:
+				t.Fatal("suspicious request was not escalated")
+			}
+
+			if w := serve(p, newRequest("GET", "/", "", tt.nextAddr, tt.nextFwd)); w.Code != tt.wantStatus {
+				t.Errorf("next request: status %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// requestOutcome collects the block decision for a request while it is
+// handled, including blocks in the response phase, for its structured event
+type requestOutcome struct {
+	mu       sync.Mutex
+	blocked  bool
+	reason   string
+	severity string // effective severity of the blocking rule, if a rule blocked
+	rules    []string
+}
+
+// outcomeKey is the request context key of the requestOutcome
+type outcomeKey struct{}
+
+// recordBlock counts a blocked request against the rules that caused it in
+// the session tally and notes the block in the request's outcome. Without
+// rules the reason itself is counted. severity is that of the blocking rule,
+// or empty for blocks by other checks.
+func (p *Proxy) recordBlock(r *http.Request, reason, severity string, rules ...string) {
+	if len(rules) == 0 {
+		rules = []string{reason}
+	}
+	p.tally.block(rules...)
+
+	if outcome, ok := r.Context().Value(outcomeKey{}).(*requestOutcome); ok {
+		outcome.mu.Lock()
+		if !outcome.blocked {
+			outcome.blocked = true
+			outcome.reason = reason
+			outcome.severity = severity
+			outcome.rules = rules
+		}
+		outcome.mu.Unlock()
+	}
+}
+
+// withOutcome attaches a fresh requestOutcome to a request
+func withOutcome(r *http.Request) (*http.Request, *requestOutcome) {
+	outcome := &requestOutcome{}
+	return r.WithContext(context.WithValue(r.Context(), outcomeKey{}, outcome)), outcome
+}
+
+// logEvent writes the structured event of a finished request
+func (p *Proxy) logEvent(r *http.Request, sw *statusRecorder, outcome *requestOutcome, start time.Time) {
+	event := logging.StructuredEvent{
+		Timestamp:      start,
+		EventType:      logging.EventTypeRequest,
+		RequestID:      requestID(r),
+		Method:         r.Method,
+		URL:            r.RequestURI,
+		SourceIP:       p.clientIP(r),
+		ResponseTimeMs: float64(time.Since(start).Microseconds()) / 1000,
+		Status:         sw.status,
+	}
+
+	outcome.mu.Lock()
+	if outcome.blocked {
+		event.Blocked = true
+		event.DryRun = p.config.DryRun
+		event.Reason = outcome.reason
+		event.Severity = outcome.severity
+		event.RuleID, event.RuleName = ruleFromReason(outcome.rules[0])
+	}
+	outcome.mu.Unlock()
+
+	if err := p.events.Log(event); err != nil {
+		p.logger.Warn("Failed to write structured event: %v", err)
+	}
+}
+
+// ruleFromReason extracts the rule ID and name from a "Rule <id>: <name>"
+// reason, as produced by the WAF engine. Other reasons yield no rule.
+func ruleFromReason(reason string) (int, string) {
+	var id int
+	if _, err := fmt.Sscanf(reason, "Rule %d:", &id); err != nil {
+		return 0, ""
+	}
+
+	_, name, _ := strings.Cut(reason, ": ")
+	// Strip annotations such as "(+5)" or "(severity raised ...)"
+	if i := strings.Index(name, " ("); i != -1 {
+		name = name[:i]
+	}
+	return id, name
+}
+
+// statusRecorder records the status code written to a client
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code
+func (sw *statusRecorder) WriteHeader(statusCode int) {
+	sw.status = statusCode
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Flush passes flushes through to the underlying writer
+func (sw *statusRecorder) Flush() {
+	if flusher, ok := sw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (sw *statusRecorder) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+func TestRequestEventSeverity(t *testing.T) {
+	probe := config.CustomRule{
+		ID: 9100, Name: "Probe", Phase: "request_uri", Operator: "contains",
+		Target: "REQUEST_URI", Pattern: "probe", Action: "block", Severity: "high", Enabled: true,
+	}
+	adminBoost := []config.SeverityBoost{{Path: "^/admin"}}
+
+	tests := []struct {
+		name         string
+		cfg          config.Config
+		target       string
+		wantBlocked  bool
+		wantSeverity string
+	}{
+		{
+			name:         "rule severity",
+			cfg:          config.Config{SeverityBoosts: adminBoost},
+			target:       "/shop/probe",
+			wantBlocked:  true,
+			wantSeverity: "high",
+		},
+		{
+			name:         "boosted on an admin path",
+			cfg:          config.Config{SeverityBoosts: adminBoost},
+			target:       "/admin/probe",
+			wantBlocked:  true,
+			wantSeverity: "critical",
+		},
+		{
+			name:         "without boosts",
+			target:       "/admin/probe",
+			wantBlocked:  true,
+			wantSeverity: "high",
+		},
+		{
+			name:         "boosted in scoring mode",
+			cfg:          config.Config{SeverityBoosts: adminBoost, ScoringMode: true, AnomalyThreshold: 4},
+			target:       "/admin/probe",
+			wantBlocked:  true,
+			wantSeverity: "critical",
+		},
+		{
+			name:   "allowed",
+			cfg:    config.Config{SeverityBoosts: adminBoost},
+			target: "/admin/users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			cfg.CustomRules = []config.CustomRule{probe}
+			cfg.EventsFile = filepath.Join(t.TempDir(), "events.jsonl")
+			p := newTestProxy(t, &cfg)
+
+			serve(p, newRequest("GET", tt.target, "", "192.0.2.1:4000", ""))
+
+			data, err := os.ReadFile(cfg.EventsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var event logging.StructuredEvent
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				if err := json.Unmarshal([]byte(line), &event); err != nil {
+					t.Fatalf("invalid event %q: %v", line, err)
+				}
+				if event.EventType == logging.EventTypeRequest {
+					break
+				}
+			}
+			if event.EventType != logging.EventTypeRequest {
+				t.Fatalf("no request event in %s", data)
+			}
+			if event.Blocked != tt.wantBlocked || event.Severity != tt.wantSeverity {
+				t.Errorf("event blocked=%t severity %q, want blocked=%t severity %q",
+					event.Blocked, event.Severity, tt.wantBlocked, tt.wantSeverity)
+			}
+		})
+	}
+}
+
+// readEvents returns the structured events written to path
+func readEvents(t *testing.T, path string) []logging.StructuredEvent {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []logging.StructuredEvent
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var event logging.StructuredEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
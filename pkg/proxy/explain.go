@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// explain writes the --explain trace of a request
+func (p *Proxy) explain(r *http.Request, result *waf.CheckResult) {
+	fmt.Fprint(p.explainOut, formatExplanation(r, result))
+}
+
+// formatExplanation renders a human-readable trace of how the WAF reached its
+// decision for a request: a one-line rationale followed by one line per
+// evaluated rule.
+func formatExplanation(r *http.Request, result *waf.CheckResult) string {
+	var sb strings.Builder
+
+	matched := result.MatchedRules()
+	rationale := "no blocking rule matched"
+	if result.Decision == waf.DecisionBlock {
+		rationale = result.Reason
+	} else if len(matched) > 0 {
+		ids := make([]string, 0, len(matched))
+		for _, eval := range matched {
+			ids = append(ids, fmt.Sprintf("%d", eval.RuleID))
+		}
+		rationale = fmt.Sprintf("matched non-blocking rules %s", strings.Join(ids, ", "))
+	}
+
+	fmt.Fprintf(&sb, "[EXPLAIN] %s %s from %s -> %s (%s; %d rules evaluated, %d matched)\n",
+		r.Method, r.RequestURI, r.RemoteAddr, strings.ToUpper(result.Decision.String()),
+		rationale, len(result.Evaluations), len(matched))
+
+	for _, eval := range result.Evaluations {
+		status := "no match"
+		if eval.Matched {
+			status = "MATCH"
+		} else if len(eval.UnmetRequires) > 0 {
+			status = fmt.Sprintf("chain unmet %v", eval.UnmetRequires)
+		}
+		fmt.Fprintf(&sb, "  rule %-6d %-16s %-8s %-6s %s\n",
+			eval.RuleID, eval.Phase, status, eval.Action, eval.RuleName)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         config.Config
+		body        string
+		wantContain []string
+	}{
+		{
+			name:        "rule mode",
+			cfg:         config.Config{Explain: true},
+			body:        "q=' OR 1=1--",
+			wantContain: []string{"[EXPLAIN] POST", "-> BLOCK", "rule 1001   request_body     MATCH    block"},
+		},
+		{
+			name:        "rule mode allow",
+			cfg:         config.Config{Explain: true},
+			body:        "q=shoes",
+			wantContain: []string{"[EXPLAIN] POST /search", "-> ALLOW", "rule 1001   request_body     no match block"},
+		},
+		{
+			name:        "scoring mode block",
+			cfg:         config.Config{Explain: true, ScoringMode: true},
+			body:        "q=' OR 1=1--&c=<script>alert(1)</script>",
+			wantContain: []string{"-> BLOCK (anomaly score", "MATCH"},
+		},
+		{
+			name:        "scoring mode allow",
+			cfg:         config.Config{Explain: true, ScoringMode: true},
+			body:        "q=shoes",
+			wantContain: []string{"-> ALLOW (no blocking rule matched"},
+		},
+		{
+			name: "explain off",
+			cfg:  config.Config{ScoringMode: true},
+			body: "q=' OR 1=1--",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			p := newTestProxy(t, &cfg)
+			var out bytes.Buffer
+			p.explainOut = &out
+
+			serve(p, newRequest("POST", "/search", tt.body, "192.0.2.1:4000", ""))
+
+			if len(tt.wantContain) == 0 && out.Len() > 0 {
+				t.Fatalf("explain output %q, want none", out.String())
+			}
+			for _, want := range tt.wantContain {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("explain output %q does not contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/netutil"
+)
+
+// setForwardedHeaders describes the original request to the upstream. The
+// client IP is appended to X-Forwarded-For by the reverse proxy itself.
+// X-Forwarded-Proto, X-Forwarded-Host and Forwarded elements set by a
+// trusted proxy are kept, since they describe the request as the client sent
+// it; from any other peer they are replaced, as a client could claim https
+// or another host. With rfc7239 an element for this hop is appended to the
+// Forwarded header.
+func setForwardedHeaders(req *http.Request, rfc7239 bool, trusted []*net.IPNet) {
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+
+	if !netutil.IsTrustedPeer(req, trusted) {
+		req.Header.Del("X-Forwarded-Proto")
+		req.Header.Del("X-Forwarded-Host")
+		req.Header.Del("Forwarded")
+	}
+
+	if req.Header.Get("X-Forwarded-Proto") == "" {
+		req.Header.Set("X-Forwarded-Proto", proto)
+	}
+	if req.Header.Get("X-Forwarded-Host") == "" && req.Host != "" {
+		req.Header.Set("X-Forwarded-Host", req.Host)
+	}
+
+	if rfc7239 {
+		element := "for=" + forwardedNode(hostWithoutPort(req.RemoteAddr))
+		if req.Host != "" {
+			element += ";host=" + forwardedValue(req.Host)
+		}
+		element += ";proto=" + proto
+
+		if prior := req.Header.Values("Forwarded"); len(prior) > 0 {
+			element = strings.Join(prior, ", ") + ", " + element
+		}
+		req.Header.Set("Forwarded", element)
+	}
+}
+
+// forwardedNode formats a client IP as an RFC 7239 node; IPv6 addresses are
+// bracketed and quoted
+func forwardedNode(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return `"[` + ip + `]"`
+	}
+	return forwardedValue(ip)
+}
+
+// forwardedValue returns value as an RFC 7239 token, quoting it when it
+// contains characters a token may not, such as the colon of host:port
+func forwardedValue(value string) string {
+	for _, c := range value {
+		if !isTokenChar(c) {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+		}
+	}
+	return value
+}
+
+// isTokenChar reports whether c may appear in an HTTP token (RFC 7230 tchar)
+func isTokenChar(c rune) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", c)
+}
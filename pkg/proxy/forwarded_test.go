@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestForwardedHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantProto  string
+		wantHost   string
+		wantFwd    string
+	}{
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:4000",
+			wantProto:  "https",
+			wantHost:   "shop.example.com",
+			wantFwd:    "for=203.0.113.7;proto=https, for=10.0.0.1;host=example.com;proto=http",
+		},
+		{
+			name:       "untrusted peer",
+			remoteAddr: "192.0.2.1:4000",
+			wantProto:  "http",
+			wantHost:   "example.com",
+			wantFwd:    "for=192.0.2.1;host=example.com;proto=http",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			cfg := &config.Config{TrustedProxies: []string{"10.0.0.0/8"}, ForwardedHeader: true}
+			p := newTestProxyFor(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+			})
+
+			r := newRequest("GET", "/", "", tt.remoteAddr, "")
+			r.Header.Set("X-Forwarded-Proto", "https")
+			r.Header.Set("X-Forwarded-Host", "shop.example.com")
+			r.Header.Set("Forwarded", "for=203.0.113.7;proto=https")
+			serve(p, r)
+
+			if v := got.Get("X-Forwarded-Proto"); v != tt.wantProto {
+				t.Errorf("X-Forwarded-Proto %q, want %q", v, tt.wantProto)
+			}
+			if v := got.Get("X-Forwarded-Host"); v != tt.wantHost {
+				t.Errorf("X-Forwarded-Host %q, want %q", v, tt.wantHost)
+			}
+			if v := got.Get("Forwarded"); v != tt.wantFwd {
+				t.Errorf("Forwarded %q, want %q", v, tt.wantFwd)
+			}
+		})
+	}
+}
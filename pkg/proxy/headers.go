@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// headerInjectionMarkers are raw and percent-encoded line breaks that must
+// never appear in an outbound header value
+var headerInjectionMarkers = []string{"\r", "\n", "%0d", "%0a"}
+
+// sanitizeResponseHeaders removes response-splitting payloads from header
+// values before they are written to the client. A value containing a raw or
+// percent-encoded CR/LF is truncated at the first line break so that any
+// injected headers or body that follow it are dropped. It returns the names of
+// the headers that were modified.
+func sanitizeResponseHeaders(h http.Header) []string {
+	var modified []string
+	for name, values := range h {
+		changed := false
+		for i, value := range values {
+			if cut := headerInjectionIndex(value); cut >= 0 {
+				values[i] = strings.TrimSpace(value[:cut])
+				changed = true
+			}
+		}
+		if changed {
+			modified = append(modified, name)
+		}
+	}
+	sort.Strings(modified)
+	return modified
+}
+
+// headerInjectionIndex returns the index of the first line break marker in
+// value, or -1 if there is none
+func headerInjectionIndex(value string) int {
+	lower := strings.ToLower(value)
+	cut := -1
+	for _, marker := range headerInjectionMarkers {
+		if i := strings.Index(lower, marker); i >= 0 && (cut < 0 || i < cut) {
+			cut = i
+		}
+	}
+	return cut
+}
@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+func TestSanitizeResponseHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		want         string
+		wantModified bool
+	}{
+		{name: "CRLF", value: "/welcome\r\nSet-Cookie: session=attacker", want: "/welcome", wantModified: true},
+		{name: "bare LF", value: "/welcome\nSet-Cookie: session=attacker", want: "/welcome", wantModified: true},
+		{name: "bare CR", value: "/welcome \rSet-Cookie: session=attacker", want: "/welcome", wantModified: true},
+		{name: "encoded CRLF", value: "/welcome%0d%0aSet-Cookie:%20session=attacker", want: "/welcome", wantModified: true},
+		{name: "upper-case encoding", value: "/welcome%0D%0ASet-Cookie:%20session=attacker", want: "/welcome", wantModified: true},
+		{name: "legitimate location", value: "https://example.com/search?q=a%20b&page=2", want: "https://example.com/search?q=a%20b&page=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{"Location": {tt.value}, "Content-Type": {"text/html"}}
+			modified := sanitizeResponseHeaders(h)
+
+			if got := h.Get("Location"); got != tt.want {
+				t.Errorf("Location %q, want %q", got, tt.want)
+			}
+			if got := h.Get("Content-Type"); got != "text/html" {
+				t.Errorf("Content-Type %q, want it untouched", got)
+			}
+			var want []string
+			if tt.wantModified {
+				want = []string{"Location"}
+			}
+			if !reflect.DeepEqual(modified, want) {
+				t.Errorf("modified %q, want %q", modified, want)
+			}
+		})
+	}
+}
+
+func TestResponseWriterStripsInjectedHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := &responseWriter{
+		ResponseWriter: rec,
+		body:           &bytes.Buffer{},
+		logger:         logging.NewLogger(""),
+		request:        httptest.NewRequest("GET", "/login?next=x", nil),
+	}
+	rw.Header().Set("Location", "/home\r\nSet-Cookie: session=attacker")
+	rw.Header().Add("X-Trace", "a")
+	rw.Header().Add("X-Trace", "b%0aSet-Cookie: admin=1")
+	rw.WriteHeader(http.StatusFound)
+
+	if got := rec.Header().Get("Location"); got != "/home" {
+		t.Errorf("Location %q, want %q", got, "/home")
+	}
+	if got := rec.Header().Values("X-Trace"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("X-Trace %q, want [a b]", got)
+	}
+	if got := rec.Header().Values("Set-Cookie"); len(got) > 0 {
+		t.Errorf("injected Set-Cookie %q reached the client", got)
+	}
+	if rec.Code != http.StatusFound {
+		t.Errorf("status %d, want %d", rec.Code, http.StatusFound)
+	}
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// checkHost validates the Host header of a request against the allowed hosts.
+// It returns a non-empty reason when the request should be rejected: the Host
+// header is missing, does not match any allowed pattern, or disagrees with the
+// TLS server name (SNI) the client negotiated.
+func checkHost(r *http.Request, allowed []string) string {
+	host := hostWithoutPort(r.Host)
+	if host == "" {
+		return "missing Host header"
+	}
+
+	if r.TLS != nil && r.TLS.ServerName != "" && !strings.EqualFold(host, r.TLS.ServerName) {
+		return "Host " + host + " does not match TLS server name " + r.TLS.ServerName
+	}
+
+	if !hostAllowed(host, allowed) {
+		return "Host " + host + " is not allowed"
+	}
+
+	return ""
+}
+
+// hostAllowed reports whether host matches one of the allowed patterns.
+// Patterns are case-insensitive and may use shell-style wildcards,
+// e.g. "*.example.com".
+func hostAllowed(host string, allowed []string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(hostWithoutPort(pattern))
+		if pattern == host {
+			return true
+		}
+		if matched, err := path.Match(pattern, host); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// hostWithoutPort strips an optional port from a Host header value
+func hostWithoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return strings.Trim(host, "[]")
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestHostCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		serverName string // TLS SNI, if any
+		dryRun     bool
+		wantStatus int
+		wantReason string
+	}{
+		{name: "allowed host", host: "example.com", wantStatus: http.StatusOK},
+		{name: "allowed host with port", host: "example.com:8080", wantStatus: http.StatusOK},
+		{name: "wildcard match", host: "API.example.org", wantStatus: http.StatusOK},
+		{name: "matching SNI", host: "example.com", serverName: "example.com", wantStatus: http.StatusOK},
+		{name: "disallowed host", host: "evil.test", wantStatus: http.StatusMisdirectedRequest, wantReason: "Host evil.test is not allowed"},
+		{name: "wildcard does not match the apex", host: "example.org", wantStatus: http.StatusMisdirectedRequest, wantReason: "Host example.org is not allowed"},
+		{name: "missing host", wantStatus: http.StatusMisdirectedRequest, wantReason: "missing Host header"},
+		{
+			name:       "SNI mismatch",
+			host:       "example.com",
+			serverName: "api.example.org",
+			wantStatus: http.StatusMisdirectedRequest,
+			wantReason: "Host example.com does not match TLS server name api.example.org",
+		},
+		{name: "dry run", host: "evil.test", dryRun: true, wantStatus: http.StatusOK, wantReason: "Host evil.test is not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				AllowedHosts:     []string{"example.com", "*.example.org"},
+				HostRejectStatus: http.StatusMisdirectedRequest,
+				DryRun:           tt.dryRun,
+				EventsFile:       filepath.Join(t.TempDir(), "events.jsonl"),
+			}
+			p := newTestProxy(t, cfg)
+
+			r := newRequest("GET", "/", "", "192.0.2.1:4000", "")
+			r.Host = tt.host
+			if tt.serverName != "" {
+				r.TLS = &tls.ConnectionState{ServerName: tt.serverName}
+			}
+			if w := serve(p, r); w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			events := readEvents(t, cfg.EventsFile)
+			if len(events) != 1 {
+				t.Fatalf("%d events, want 1", len(events))
+			}
+			if tt.wantReason != "" && (!events[0].Blocked || events[0].Reason != tt.wantReason) {
+				t.Errorf("event blocked %v with reason %q, want blocked with %q", events[0].Blocked, events[0].Reason, tt.wantReason)
+			}
+			if tt.wantReason == "" && events[0].Blocked {
+				t.Errorf("allowed request logged as blocked: %q", events[0].Reason)
+			}
+		})
+	}
+}
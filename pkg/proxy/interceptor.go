@@ -1,33 +1,32 @@
 package proxy
 
 import (
-	"bytes"
-	"io"
 	"net/http"
+
+	"github.com/shieldcli/shieldcli/pkg/waf"
 )
 
 // RequestInterceptor intercepts and modifies HTTP requests
 type RequestInterceptor struct {
+	engine       *waf.Engine // decides how much of the body to read; nil buffers all of it
 	originalBody []byte
 }
 
-// InterceptRequest captures the request body for analysis
+// InterceptRequest captures the request body for analysis. The body is
+// buffered on the request, so the WAF engine inspects the same bytes without
+// reading it again and the reverse proxy can still forward it. Chunked bodies
+// with no declared length are captured too. With a body inspection window
+// only the window is captured and the rest is streamed.
 func (ri *RequestInterceptor) InterceptRequest(r *http.Request) error {
-	if r.Body != nil && r.ContentLength > 0 {
-		// Read the body
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			return err
-		}
-
-		// Store the original body
-		ri.originalBody = body
-
-		// Reset the body so it can be read again
-		r.Body = io.NopCloser(bytes.NewReader(body))
+	var body []byte
+	var err error
+	if ri.engine != nil {
+		body, err = ri.engine.ReadRequestBody(r)
+	} else {
+		body, err = waf.BufferRequestBody(r)
 	}
-
-	return nil
+	ri.originalBody = body
+	return err
 }
 
 // GetBody returns the intercepted request body
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"sync/atomic"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// concurrencyLimiter caps the number of requests the proxy handles at once,
+// so a flood cannot spawn unbounded goroutines reading bodies and evaluating
+// rules. Requests over the cap are rejected instead of queued.
+type concurrencyLimiter struct {
+	slots  chan struct{}
+	logger *logging.Logger
+
+	saturated atomic.Bool
+	rejected  atomic.Int64
+}
+
+// newConcurrencyLimiter creates a limiter allowing max concurrent requests
+func newConcurrencyLimiter(max int, logger *logging.Logger) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		slots:  make(chan struct{}, max),
+		logger: logger,
+	}
+}
+
+// acquire takes a slot without waiting and reports whether one was free. The
+// first rejection while saturated is logged as an anomaly; release logs the
+// total once a slot frees up again.
+func (l *concurrencyLimiter) acquire() bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	l.rejected.Add(1)
+	if l.saturated.CompareAndSwap(false, true) {
+		l.logger.Warn("[ANOMALY] Concurrency limit of %d requests reached: rejecting new requests with 503", cap(l.slots))
+	}
+	return false
+}
+
+// release frees a slot taken by acquire
+func (l *concurrencyLimiter) release() {
+	<-l.slots
+	if l.saturated.CompareAndSwap(true, false) {
+		l.logger.Info("Concurrency below limit again after rejecting %d requests", l.rejected.Swap(0))
+	}
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestMaxConcurrentRequests(t *testing.T) {
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	p := newTestProxyFor(t, &config.Config{MaxConcurrentRequests: 2}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			arrived <- struct{}{}
+			<-release
+		}
+		w.Write([]byte("ok"))
+	})
+
+	// Fill both slots with requests held at the upstream
+	var wg sync.WaitGroup
+	slow := make([]*httptest.ResponseRecorder, 2)
+	for i := range slow {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slow[i] = serve(p, newRequest("GET", "/slow", "", "192.0.2.1:4000", ""))
+		}()
+		<-arrived
+	}
+
+	w := serve(p, newRequest("GET", "/", "", "192.0.2.2:4000", ""))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("request over the limit got %d, want 503", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After %q, want \"1\"", got)
+	}
+
+	close(release)
+	wg.Wait()
+	for i, w := range slow {
+		if w.Code != http.StatusOK {
+			t.Errorf("request %d within the limit got %d, want 200", i+1, w.Code)
+		}
+	}
+
+	if w := serve(p, newRequest("GET", "/", "", "192.0.2.2:4000", "")); w.Code != http.StatusOK {
+		t.Errorf("request after the slots freed got %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// Fail modes applied while the proxy is overloaded
+const (
+	FailOpen   = "open"   // skip WAF checks and forward everything
+	FailClosed = "closed" // reject everything with 503
+)
+
+// latencySmoothing is the weight given to each new sample in the latency moving average
+const latencySmoothing = 0.2
+
+// probeInterval is how often a request is still handled normally while the
+// fail mode is engaged, so evaluation latency keeps being measured and the
+// guard can recover
+const probeInterval = 10
+
+// overloadGuard watches WAF evaluation latency and in-flight request count and
+// switches the proxy into its configured fail mode when either crosses its
+// threshold. It switches back once both drop below half their thresholds, so
+// the mode doesn't flap around the limit.
+type overloadGuard struct {
+	mode        string
+	maxLatency  time.Duration
+	maxInFlight int64
+	logger      *logging.Logger
+
+	inFlight   atomic.Int64
+	overloaded atomic.Bool
+	skipped    atomic.Int64
+
+	mu         sync.Mutex
+	avgLatency time.Duration
+}
+
+// newOverloadGuard creates a guard; a zero threshold disables that signal
+func newOverloadGuard(mode string, maxLatency time.Duration, maxInFlight int, logger *logging.Logger) *overloadGuard {
+	return &overloadGuard{
+		mode:        mode,
+		maxLatency:  maxLatency,
+		maxInFlight: int64(maxInFlight),
+		logger:      logger,
+	}
+}
+
+// enter registers an in-flight request and returns a function to call when it finishes
+func (g *overloadGuard) enter() func() {
+	g.inFlight.Add(1)
+	g.evaluate()
+	return func() {
+		g.inFlight.Add(-1)
+		g.evaluate()
+	}
+}
+
+// observe records the time a WAF evaluation took
+func (g *overloadGuard) observe(latency time.Duration) {
+	g.mu.Lock()
+	if g.avgLatency == 0 {
+		g.avgLatency = latency
+	} else {
+		g.avgLatency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(g.avgLatency))
+	}
+	g.mu.Unlock()
+	g.evaluate()
+}
+
+// Overloaded reports whether the fail mode is currently engaged
+func (g *overloadGuard) Overloaded() bool {
+	return g.overloaded.Load()
+}
+
+// shed reports whether the fail mode applies to a request. While overloaded
+// every probeInterval-th request is still handled normally.
+func (g *overloadGuard) shed() bool {
+	if !g.overloaded.Load() {
+		return false
+	}
+	return g.skipped.Add(1)%probeInterval != 0
+}
+
+// evaluate updates the overload state from the current signals
+func (g *overloadGuard) evaluate() {
+	g.mu.Lock()
+	avg := g.avgLatency
+	g.mu.Unlock()
+	inFlight := g.inFlight.Load()
+
+	latencyHigh := g.maxLatency > 0 && avg > g.maxLatency
+	inFlightHigh := g.maxInFlight > 0 && inFlight > g.maxInFlight
+
+	if latencyHigh || inFlightHigh {
+		if g.overloaded.CompareAndSwap(false, true) {
+			g.logger.Warn("Overload detected (avg WAF latency %v, %d in flight): switching to fail-%s", avg, inFlight, g.mode)
+		}
+		return
+	}
+
+	latencyCalm := g.maxLatency <= 0 || avg <= g.maxLatency/2
+	inFlightCalm := g.maxInFlight <= 0 || inFlight <= g.maxInFlight/2
+	if latencyCalm && inFlightCalm {
+		if g.overloaded.CompareAndSwap(true, false) {
+			g.logger.Info("Load subsided (avg WAF latency %v, %d in flight): resuming normal WAF checks", avg, inFlight)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+func TestOverloadFailMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		body       string
+		wantStatus int // while overloaded, for requests not probed
+	}{
+		{name: "fail-open forwards attacks", mode: FailOpen, body: "q=' OR 1=1--", wantStatus: http.StatusOK},
+		{name: "fail-closed rejects clean requests", mode: FailClosed, body: "q=shoes", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProxy(t, &config.Config{FailMode: tt.mode, OverloadMaxLatency: 50})
+			normal := http.StatusOK
+			if tt.mode == FailOpen {
+				normal = http.StatusForbidden
+			}
+			send := func() int {
+				return serve(p, newRequest("POST", "/search", tt.body, "192.0.2.1:4000", "")).Code
+			}
+
+			if code := send(); code != normal {
+				t.Fatalf("before overload: status %d, want %d", code, normal)
+			}
+
+			// Rule evaluation slows down past the latency threshold
+			p.overload.observe(time.Second)
+			if !p.overload.Overloaded() {
+				t.Fatal("fail mode not engaged")
+			}
+			shed, probed := 0, 0
+			for i := 0; i < 2*probeInterval; i++ {
+				switch code := send(); code {
+				case tt.wantStatus:
+					shed++
+				case normal:
+					probed++
+				default:
+					t.Fatalf("status %d while overloaded", code)
+				}
+			}
+			if probed == 0 || shed < 2*probeInterval-2 {
+				t.Errorf("while overloaded %d requests shed and %d probed, want all but every %dth shed", shed, probed, probeInterval)
+			}
+
+			// Evaluation is fast again
+			for p.overload.Overloaded() {
+				p.overload.observe(time.Millisecond)
+			}
+			if code := send(); code != normal {
+				t.Errorf("after recovery: status %d, want %d", code, normal)
+			}
+		})
+	}
+}
+
+func TestOverloadGuardHysteresis(t *testing.T) {
+	g := newOverloadGuard(FailOpen, 0, 4, logging.NewLogger(""))
+
+	var done []func()
+	for i := 0; i < 5; i++ {
+		done = append(done, g.enter())
+	}
+	if !g.Overloaded() {
+		t.Fatal("5 in flight with a limit of 4: not overloaded")
+	}
+
+	// Dropping below the limit is not enough; load must fall to half of it
+	done[4]()
+	done[3]()
+	if !g.Overloaded() {
+		t.Error("3 in flight: recovered before reaching half the limit")
+	}
+	done[2]()
+	if g.Overloaded() {
+		t.Error("2 in flight: still overloaded")
+	}
+	done[1]()
+	done[0]()
+}
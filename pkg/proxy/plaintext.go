@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/netutil"
+)
+
+// Policies for plaintext HTTP requests on an HTTPS-only deployment
+const (
+	PlaintextAllow    = "allow"    // forward, only flagging downgrades
+	PlaintextRedirect = "redirect" // 308 redirect to the https:// URL
+	PlaintextBlock    = "block"    // reject with 403
+)
+
+// isPlaintext reports whether a request reached the client-facing edge over
+// plain HTTP. A request that did not arrive over TLS itself counts as
+// encrypted only when a trusted proxy says so with X-Forwarded-Proto.
+func isPlaintext(r *http.Request, trusted []*net.IPNet) bool {
+	if r.TLS != nil {
+		return false
+	}
+	if netutil.IsTrustedPeer(r, trusted) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		return false
+	}
+	return true
+}
+
+// downgradeSource returns the https:// Referer or Origin of a plaintext
+// request for the same host, which suggests a page served over HTTPS was
+// downgraded (e.g. by SSL stripping). It returns "" if there is none.
+func downgradeSource(r *http.Request) string {
+	host := hostWithoutPort(r.Host)
+	for _, name := range []string{"Origin", "Referer"} {
+		value := r.Header.Get(name)
+		u, err := url.Parse(value)
+		if err != nil || !strings.EqualFold(u.Scheme, "https") {
+			continue
+		}
+		if strings.EqualFold(u.Hostname(), host) {
+			return name + " " + value
+		}
+	}
+	return ""
+}
+
+// httpsURL returns the https:// URL of a request on the default HTTPS port
+func httpsURL(r *http.Request) string {
+	host := hostWithoutPort(r.Host)
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return "https://" + host + r.URL.RequestURI()
+}
+
+// enforcePlaintextPolicy applies the plaintext policy to a request and
+// reports whether it was answered (redirected or blocked) and must not be
+// forwarded
+func (p *Proxy) enforcePlaintextPolicy(w http.ResponseWriter, r *http.Request) bool {
+	if !isPlaintext(r, p.trustedProxies) {
+		return false
+	}
+
+	if source := downgradeSource(r); source != "" {
+		p.logger.Warn("[ANOMALY] Possible protocol downgrade: plaintext %s %s from %s with %s",
+			r.Method, r.RequestURI, r.RemoteAddr, source)
+	}
+
+	switch p.config.PlaintextPolicy {
+	case PlaintextRedirect:
+		if hostWithoutPort(r.Host) == "" {
+			break
+		}
+		p.logger.Info("Redirecting plaintext request %s %s from %s to HTTPS", r.Method, r.RequestURI, r.RemoteAddr)
+		http.Redirect(w, r, httpsURL(r), http.StatusPermanentRedirect)
+		return true
+	case PlaintextBlock:
+	default:
+		return false
+	}
+
+	// Blocking, or redirecting without a Host to redirect to
+	p.logger.Block("Plaintext request rejected from %s: %s %s", r.RemoteAddr, r.Method, r.RequestURI)
+	p.recordBlock(r, "Plaintext HTTP", "")
+	if p.config.DryRun {
+		return false
+	}
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("HTTPS Required"))
+	return true
+}
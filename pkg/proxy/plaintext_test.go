@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestPlaintextPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		dryRun       bool
+		remoteAddr   string
+		tls          bool
+		proto        string // X-Forwarded-Proto
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "redirect", policy: PlaintextRedirect, wantStatus: http.StatusPermanentRedirect, wantLocation: "https://shop.example.com/cart?item=1"},
+		{name: "block", policy: PlaintextBlock, wantStatus: http.StatusForbidden},
+		{name: "block in dry-run", policy: PlaintextBlock, dryRun: true, wantStatus: http.StatusOK},
+		{name: "allow", policy: PlaintextAllow, wantStatus: http.StatusOK},
+		{name: "over TLS", policy: PlaintextBlock, tls: true, wantStatus: http.StatusOK},
+		{name: "TLS ended at a trusted proxy", policy: PlaintextBlock, remoteAddr: "10.0.0.1:4000", proto: "https", wantStatus: http.StatusOK},
+		{name: "https claimed by an untrusted peer", policy: PlaintextBlock, proto: "https", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProxy(t, &config.Config{PlaintextPolicy: tt.policy, DryRun: tt.dryRun, TrustedProxies: []string{"10.0.0.0/8"}})
+
+			remoteAddr := tt.remoteAddr
+			if remoteAddr == "" {
+				remoteAddr = "192.0.2.1:4000"
+			}
+			r := newRequest("GET", "http://shop.example.com:8080/cart?item=1", "", remoteAddr, "")
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			w := serve(p, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestDowngradeSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{name: "https referer for the same host", headers: map[string]string{"Referer": "https://shop.example.com/login"}, want: "Referer https://shop.example.com/login"},
+		{name: "https origin", headers: map[string]string{"Origin": "https://SHOP.example.com"}, want: "Origin https://SHOP.example.com"},
+		{name: "plaintext referer", headers: map[string]string{"Referer": "http://shop.example.com/login"}},
+		{name: "other host", headers: map[string]string{"Referer": "https://search.example.net/?q=shop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRequest("GET", "http://shop.example.com/cart", "", "192.0.2.1:4000", "")
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			if got := downgradeSource(r); got != tt.want {
+				t.Errorf("downgradeSource = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
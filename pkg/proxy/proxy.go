@@ -2,18 +2,37 @@ package proxy
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"math"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/shieldcli/shieldcli/pkg/anomaly"
 	"github.com/shieldcli/shieldcli/pkg/config"
 	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/netutil"
 	"github.com/shieldcli/shieldcli/pkg/waf"
 )
 
+// Default server timeouts
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultRequestTimeout    = 30 * time.Second // read and write
+	defaultIdleTimeout       = 120 * time.Second
+	defaultShutdownTimeout   = 5 * time.Second
+)
+
 // Proxy represents the ShieldCLI reverse proxy with WAF
 type Proxy struct {
 	config       *config.Config
@@ -22,6 +41,25 @@ type Proxy struct {
 	reverseProxy *httputil.ReverseProxy
 	listener     net.Listener
 	server       *http.Server
+	adminServer  *http.Server // nil unless AdminAddr is set
+	tlsConfig    *tls.Config  // nil serves plain HTTP
+	overload     *overloadGuard
+	block        atomic.Pointer[blockResponse] // replaced by ReloadBlockResponse
+
+	stopOnce sync.Once
+	stopped  chan struct{} // closed once Stop has drained or closed the server
+
+	trustedProxies []*net.IPNet
+	connFlood      *connFloodGuard
+	slowClients    *slowClientGuard
+	limiter        *concurrencyLimiter
+	rateLimiter    *rateLimiter
+	events         *logging.StructuredLogger
+	tally          *sessionTally
+	detector       *anomaly.AnomalyDetector
+	escalation     *escalation         // nil unless EnableEscalation was called
+	calibrator     *anomaly.Calibrator // observes traffic for run --learn-and-report
+	explainOut     io.Writer           // where --explain traces go, os.Stderr by default
 }
 
 // NewProxy creates a new proxy instance
@@ -38,14 +76,25 @@ func NewProxy(cfg *config.Config, logger *logging.Logger) (*Proxy, error) {
 		return nil, fmt.Errorf("failed to create WAF engine: %w", err)
 	}
 
+	trustedProxies, err := netutil.ParseCIDRs(cfg.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create reverse proxy
 	rp := httputil.NewSingleHostReverseProxy(targetURL)
 
 	// Customize the reverse proxy
+	director := rp.Director
 	rp.Director = func(req *http.Request) {
-		req.Header.Add("X-Forwarded-For", req.RemoteAddr)
-		req.Header.Add("X-Forwarded-Proto", "http")
-		req.Header.Add("X-Forwarded-Host", req.Header.Get("Host"))
+		director(req)
+		setForwardedHeaders(req, cfg.ForwardedHeader, trustedProxies)
+	}
+
+	if cfg.UpstreamCAFile != "" || cfg.UpstreamInsecureSkipVerify {
+		if rp.Transport, err = upstreamTransport(cfg.UpstreamCAFile, cfg.UpstreamInsecureSkipVerify); err != nil {
+			return nil, err
+		}
 	}
 
 	// Add error handling
@@ -56,15 +105,127 @@ func NewProxy(cfg *config.Config, logger *logging.Logger) (*Proxy, error) {
 	}
 
 	proxy := &Proxy{
-		config:       cfg,
-		logger:       logger,
-		wafEngine:    wafEngine,
-		reverseProxy: rp,
+		config:         cfg,
+		logger:         logger,
+		wafEngine:      wafEngine,
+		reverseProxy:   rp,
+		trustedProxies: trustedProxies,
+		tally:          newSessionTally(time.Duration(cfg.SummaryHalfLife) * time.Second),
+		stopped:        make(chan struct{}),
+		explainOut:     os.Stderr,
+	}
+
+	rp.ModifyResponse = func(resp *http.Response) error {
+		// The client already has the request ID header; an upstream echoing
+		// it back would add a duplicate
+		resp.Header.Del(RequestIDHeader)
+		return proxy.inspectResponse(resp)
+	}
+
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		if proxy.tlsConfig, err = loadTLSConfig(cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.ConnFloodMaxConns > 0 {
+		proxy.connFlood = newConnFloodGuard(cfg.ConnFloodMaxConns,
+			time.Duration(cfg.ConnFloodWindow)*time.Second, cfg.ConnFloodMinRequestsPerConn, logger)
+	}
+
+	if cfg.SlowClientMinRate > 0 {
+		proxy.slowClients = newSlowClientGuard(cfg.SlowClientMinRate,
+			time.Duration(cfg.SlowClientGrace)*time.Second, logger)
+	}
+
+	if err := proxy.ReloadBlockResponse(cfg); err != nil {
+		return nil, err
+	}
+
+	proxy.detector = anomaly.NewAnomalyDetector(anomalyWindow)
+	err = proxy.detector.Configure(anomaly.DetectorConfig{
+		RequestRateThreshold: cfg.AnomalyRequestRateThreshold,
+		PayloadSizeThreshold: cfg.AnomalyPayloadSizeThreshold,
+		EntropyThreshold:     cfg.AnomalyEntropyThreshold,
+		SuspiciousUserAgents: cfg.AnomalySuspiciousUserAgents,
+		RiskWeights:          cfg.AnomalyRiskWeights,
+		RiskHalfLife:         time.Duration(cfg.AnomalyRiskHalfLife) * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AnomalyAdaptive {
+		z := cfg.AnomalyZThreshold
+		if z <= 0 {
+			z = anomaly.DefaultAdaptiveZThreshold
+		}
+		proxy.detector.SetAdaptiveThresholds(z, anomaly.DefaultAdaptiveMinSamples)
+	}
+	if proxy.slowClients != nil {
+		// Drops surface as slowloris anomalies with the rest
+		proxy.slowClients.onDrop = proxy.detector.RecordSlowClient
+	}
+
+	if cfg.Calibrate {
+		proxy.calibrator = anomaly.NewCalibrator()
+	}
+
+	if cfg.RateLimitPerSecond > 0 {
+		proxy.rateLimiter = newRateLimiter(cfg.RateLimitPerSecond, cfg.RateLimitBurst, logger)
+	}
+
+	if cfg.MaxConcurrentRequests > 0 {
+		proxy.limiter = newConcurrencyLimiter(cfg.MaxConcurrentRequests, logger)
+	}
+
+	if cfg.EventsFile != "" {
+		if proxy.events, err = logging.NewStructuredLogger(cfg.EventsFile); err != nil {
+			return nil, err
+		}
+		proxy.events.SetRotation(cfg.EventsMaxFileBytes, cfg.EventsMaxBackups)
+	}
+	if cfg.StdoutFormat == logging.StdoutJSON {
+		if proxy.events == nil {
+			proxy.events = logging.NewStdoutStructuredLogger()
+		} else {
+			proxy.events.EnableStdout()
+		}
+	}
+
+	switch cfg.PlaintextPolicy {
+	case "", PlaintextAllow, PlaintextRedirect, PlaintextBlock:
+	default:
+		return nil, fmt.Errorf("invalid plaintext policy %q (expected %q, %q or %q)",
+			cfg.PlaintextPolicy, PlaintextAllow, PlaintextRedirect, PlaintextBlock)
+	}
+
+	switch cfg.FailMode {
+	case "":
+	case FailOpen, FailClosed:
+		proxy.overload = newOverloadGuard(cfg.FailMode,
+			time.Duration(cfg.OverloadMaxLatency)*time.Millisecond, cfg.OverloadMaxInFlight, logger)
+	default:
+		return nil, fmt.Errorf("invalid fail mode %q (expected %q or %q)", cfg.FailMode, FailOpen, FailClosed)
 	}
 
 	return proxy, nil
 }
 
+// Engine returns the WAF engine used by the proxy
+func (p *Proxy) Engine() *waf.Engine {
+	return p.wafEngine
+}
+
+// AnomalyDetector returns the detector fed with the proxy's traffic
+func (p *Proxy) AnomalyDetector() *anomaly.AnomalyDetector {
+	return p.detector
+}
+
+// Calibrator returns the traffic calibrator, or nil unless calibrating
+func (p *Proxy) Calibrator() *anomaly.Calibrator {
+	return p.calibrator
+}
+
 // Start starts the proxy server
 func (p *Proxy) Start() error {
 	// Create HTTP handler
@@ -79,55 +240,286 @@ func (p *Proxy) Start() error {
 		return fmt.Errorf("failed to listen on %s: %w", addr, err)
 	}
 
+	if p.connFlood != nil {
+		listener = &connFloodListener{Listener: listener, guard: p.connFlood}
+	}
+
+	if p.slowClients != nil {
+		listener = &slowClientListener{Listener: listener}
+	}
+
 	p.listener = listener
 
+	if p.config.AdminAddr != "" {
+		if err := p.startAdmin(); err != nil {
+			listener.Close()
+			return err
+		}
+		defer p.adminServer.Close()
+	}
+
 	// Create server
-	p.server = &http.Server{
-		Handler:      handler,
-		ReadTimeout:  time.Duration(p.config.Timeout) * time.Second,
-		WriteTimeout: time.Duration(p.config.Timeout) * time.Second,
+	p.server = p.newServer(handler)
+	p.server.TLSConfig = p.tlsConfig
+
+	if p.slowClients != nil {
+		p.server.ConnState = p.slowClients.connState
+		p.server.ConnContext = p.slowClients.connContext
+		stop := make(chan struct{})
+		defer close(stop)
+		go p.slowClients.watch(stop)
+	}
+
+	stopReports := make(chan struct{})
+	go p.reportAnomalies(stopReports)
+	if p.escalation != nil {
+		go p.escalation.run(stopReports)
 	}
+	defer func() {
+		close(stopReports)
+		// Report what the last interval detected
+		p.logAnomalies()
+	}()
 
-	// Start server
-	return p.server.Serve(listener)
+	// Start server; a server stopped by Stop or --max-requests is a clean exit
+	if p.tlsConfig != nil {
+		// The certificate is already in TLSConfig
+		err = p.server.ServeTLS(listener, "", "")
+	} else {
+		err = p.server.Serve(listener)
+	}
+	if err == http.ErrServerClosed {
+		// Serve returns as soon as shutdown begins; wait for in-flight
+		// requests so the caller does not release what they still use
+		<-p.stopped
+		return nil
+	}
+	return err
+}
+
+// newServer creates the HTTP server with the configured timeouts. Headers get
+// a short window of their own so slow clients cannot hold connections open,
+// while the read and write windows can be raised for large transfers.
+func (p *Proxy) newServer(handler http.Handler) *http.Server {
+	requestTimeout := secondsOr(p.config.Timeout, defaultRequestTimeout)
+
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: secondsOr(p.config.ReadHeaderTimeout, defaultReadHeaderTimeout),
+		ReadTimeout:       secondsOr(p.config.ReadTimeout, requestTimeout),
+		WriteTimeout:      secondsOr(p.config.WriteTimeout, requestTimeout),
+		IdleTimeout:       secondsOr(p.config.IdleTimeout, defaultIdleTimeout),
+	}
 }
 
-// Stop stops the proxy server
-func (p *Proxy) Stop() error {
-	if p.server != nil {
-		return p.server.Close()
+// secondsOr converts seconds to a duration, using def when seconds is not positive
+func secondsOr(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Stop stops the proxy server gracefully: it stops accepting connections at
+// once and lets in-flight requests finish until ctx is done, then closes the
+// remaining connections. Later calls do nothing.
+func (p *Proxy) Stop(ctx context.Context) error {
+	if p.server == nil {
+		return nil
+	}
+
+	var err error
+	p.stopOnce.Do(func() {
+		defer close(p.stopped)
+		if err = p.server.Shutdown(ctx); err != nil {
+			p.logger.Warn("Graceful shutdown did not complete: %v", err)
+			err = p.server.Close()
+		}
+	})
+	return err
+}
+
+// ShutdownTimeout returns how long Stop should let in-flight requests drain
+func (p *Proxy) ShutdownTimeout() time.Duration {
+	return secondsOr(p.config.ShutdownTimeout, defaultShutdownTimeout)
+}
+
+// shutdown stops the server gracefully within the shutdown timeout
+func (p *Proxy) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), p.ShutdownTimeout())
+	defer cancel()
+
+	p.Stop(ctx)
+}
+
+// Close releases the proxy's event file once the server has stopped
+func (p *Proxy) Close() error {
+	if p.events != nil {
+		return p.events.Close()
 	}
 	return nil
 }
 
 // handleRequest handles incoming HTTP requests
 func (p *Proxy) handleRequest(w http.ResponseWriter, r *http.Request) {
+	// Drop client-controlled headers unless a trusted proxy set them
+	if len(p.config.StripHeaders) > 0 {
+		if stripped := stripUntrustedHeaders(r, p.config.StripHeaders, p.trustedProxies); len(stripped) > 0 {
+			p.logger.Debug("Stripped client-controlled headers from %s: %s", r.RemoteAddr, strings.Join(stripped, ", "))
+		}
+	}
+
+	// Correlate logs, events and responses; an inbound ID is kept unless
+	// stripped above
+	r = withRequestID(w, r)
+
 	// Log incoming request
-	p.logger.Debug("Incoming request: %s %s from %s", r.Method, r.RequestURI, r.RemoteAddr)
+	p.logger.Debug("Incoming request %s: %s %s from %s", requestID(r), r.Method, r.RequestURI, r.RemoteAddr)
+
+	if p.events != nil {
+		sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		var outcome *requestOutcome
+		r, outcome = withOutcome(r)
+		w = sw
+		defer p.logEvent(r, sw, outcome, time.Now())
+	}
+
+	if p.connFlood != nil {
+		p.connFlood.recordRequest(hostWithoutPort(r.RemoteAddr))
+	}
+
+	if p.slowClients != nil {
+		defer p.slowClients.watchBody(r)()
+	}
+
+	if total := p.tally.request(); p.config.MaxRequests > 0 && total == int64(p.config.MaxRequests) {
+		p.logger.Info("Reached the maximum of %d requests, shutting down", p.config.MaxRequests)
+		go p.shutdown()
+	}
+
+	if p.rateLimiter != nil {
+		if ok, wait := p.rateLimiter.allow(rateLimitKey(r, p.trustedProxies)); !ok && !p.config.DryRun {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("Too Many Requests"))
+			return
+		}
+	}
+
+	if p.limiter != nil {
+		if !p.limiter.acquire() {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Service Unavailable"))
+			return
+		}
+		defer p.limiter.release()
+	}
+
+	shed := false
+	if p.overload != nil {
+		defer p.overload.enter()()
+
+		shed = p.overload.shed()
+		if shed && p.overload.mode == FailClosed {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("Service Unavailable"))
+			return
+		}
+	}
+
+	// Validate the Host header before doing any further work
+	if len(p.config.AllowedHosts) > 0 {
+		if reason := checkHost(r, p.config.AllowedHosts); reason != "" {
+			p.logger.Block("Request %s rejected from %s: %s", requestID(r), r.RemoteAddr, reason)
+			p.recordBlock(r, reason, "", "Host check")
+			if !p.config.DryRun {
+				status := p.config.HostRejectStatus
+				if status == 0 {
+					status = http.StatusBadRequest
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(http.StatusText(status)))
+				return
+			}
+		}
+	}
+
+	// Redirect or reject plaintext HTTP on an HTTPS-only deployment
+	if p.config.PlaintextPolicy != "" && p.enforcePlaintextPolicy(w, r) {
+		return
+	}
 
 	// Intercept request body
-	interceptor := &RequestInterceptor{}
+	interceptor := &RequestInterceptor{engine: p.wafEngine}
 	if err := interceptor.InterceptRequest(r); err != nil {
 		p.logger.Error("Failed to intercept request: %v", err)
 	}
 
+	p.recordTraffic(r, interceptor.GetBody())
+
 	// Check WAF rules
-	decision, reason := p.wafEngine.Check(r)
+	var decision waf.Decision
+	var reason, severity string
+	var blockedBy []string
+	var suspicious string // why an allowed request is escalated for AI analysis
+	checkStart := time.Now()
+	if p.escalation != nil && p.escalation.blocked(p.clientIP(r)) {
+		// Blocked after AI analysis of an earlier request
+		decision = waf.DecisionBlock
+		reason = "AI escalation block"
+	} else if shed {
+		// Failing open: forward without evaluating rules until load subsides
+		decision = waf.DecisionAllow
+	} else if p.config.ScoringMode {
+		result, score, contributing := p.wafEngine.CheckDetailedWithScore(r)
+		decision, reason, severity = result.Decision, result.Reason, result.Severity
+		if p.config.Explain {
+			p.explain(r, result)
+		}
+		if decision == waf.DecisionBlock {
+			blockedBy = contributing
+		} else if score > 0 {
+			p.logger.Debug("Anomaly score %d below threshold (%s)", score, strings.Join(contributing, ", "))
+			if p.escalation != nil && score >= p.escalation.minScore {
+				suspicious = fmt.Sprintf("anomaly score %d", score)
+			}
+		}
+	} else if p.config.Explain || p.escalation != nil {
+		// Escalation needs the log rules that matched, which Check skips
+		result := p.wafEngine.CheckDetailed(r)
+		decision, reason, severity = result.Decision, result.Reason, result.Severity
+		if p.config.Explain {
+			p.explain(r, result)
+		}
+		if p.escalation != nil && decision != waf.DecisionBlock {
+			suspicious = suspiciousMatch(result)
+		}
+	} else {
+		decision, reason, severity = p.wafEngine.CheckWithSeverity(r)
+	}
+	if p.overload != nil && !shed {
+		p.overload.observe(time.Since(checkStart))
+	}
+
+	if suspicious != "" {
+		p.escalation.enqueue(r, p.clientIP(r), interceptor.GetBody(), suspicious)
+	}
 
 	if decision == waf.DecisionBlock {
-		p.logger.Block("Request blocked: %s", reason)
+		p.logger.Block("Request %s blocked: %s", requestID(r), reason)
+		p.recordBlock(r, reason, severity, blockedBy...)
 
 		if p.config.Interactive {
 			// In interactive mode, ask user
 			if !p.askUser(reason) {
-				w.WriteHeader(http.StatusForbidden)
-				w.Write([]byte("Forbidden"))
+				p.block.Load().write(w, r, reason)
 				return
 			}
 		} else if !p.config.DryRun {
 			// In normal mode, block the request
-			w.WriteHeader(http.StatusForbidden)
-			w.Write([]byte("Forbidden"))
+			p.block.Load().write(w, r, reason)
 			return
 		}
 		// In dry-run mode, log but continue
@@ -138,6 +530,8 @@ func (p *Proxy) handleRequest(w http.ResponseWriter, r *http.Request) {
 		ResponseWriter: w,
 		statusCode:     http.StatusOK,
 		body:           &bytes.Buffer{},
+		logger:         p.logger,
+		request:        r,
 	}
 
 	// Forward to target
@@ -164,13 +558,22 @@ type responseWriter struct {
 	statusCode int
 	body       *bytes.Buffer
 	written    bool
+	streaming  bool // body is passed through without being captured
+	logger     *logging.Logger
+	request    *http.Request
 }
 
-// WriteHeader captures the status code
+// WriteHeader captures the status code and strips response-splitting
+// payloads from the upstream headers before they reach the client
 func (rw *responseWriter) WriteHeader(statusCode int) {
 	if !rw.written {
 		rw.statusCode = statusCode
 		rw.written = true
+		if names := sanitizeResponseHeaders(rw.Header()); len(names) > 0 && rw.logger != nil {
+			rw.logger.Error("[CRITICAL] Response splitting attempt: CR/LF stripped from upstream header(s) %s for %s %s from %s",
+				strings.Join(names, ", "), rw.request.Method, rw.request.RequestURI, rw.request.RemoteAddr)
+		}
+		rw.streaming = isStreamingContentType(rw.Header().Get("Content-Type"))
 		rw.ResponseWriter.WriteHeader(statusCode)
 	}
 }
@@ -180,6 +583,33 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	if !rw.written {
 		rw.WriteHeader(http.StatusOK)
 	}
-	rw.body.Write(b)
+	if !rw.streaming {
+		rw.body.Write(b)
+	}
 	return rw.ResponseWriter.Write(b)
 }
+
+// Flush sends any buffered data to the client. The reverse proxy flushes
+// streamed responses and forces chunked encoding when the upstream declares
+// trailers, so this must reach the underlying writer for trailers to survive.
+func (rw *responseWriter) Flush() {
+	if !rw.written {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// isStreamingContentType reports whether responses of a content type are
+// streamed (gRPC, gRPC-Web, server-sent events) and must not be buffered
+func isStreamingContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return strings.HasPrefix(contentType, "application/grpc") ||
+		strings.HasPrefix(contentType, "text/event-stream")
+}
@@ -0,0 +1,329 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// newTestProxy returns a proxy for cfg in front of an upstream that answers
+// every request with 200 "ok"
+func newTestProxy(t *testing.T, cfg *config.Config) *Proxy {
+	t.Helper()
+	return newTestProxyFor(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+}
+
+// newTestProxyFor returns a proxy for cfg in front of an upstream served by
+// handler
+func newTestProxyFor(t *testing.T, cfg *config.Config, handler http.HandlerFunc) *Proxy {
+	t.Helper()
+
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+
+	cfg.ProxyTo = upstream.URL
+	p, err := NewProxy(cfg, logging.NewLogger(""))
+	if err != nil {
+		t.Fatalf("NewProxy: %v", err)
+	}
+	return p
+}
+
+// serve sends a request through the proxy and returns the response
+func serve(p *Proxy, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	p.handleRequest(w, r)
+	return w
+}
+
+// newRequest returns a request from remoteAddr, with an X-Forwarded-For
+// header unless forwarded is empty
+func newRequest(method, target, body, remoteAddr, forwarded string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.RemoteAddr = remoteAddr
+	if forwarded != "" {
+		r.Header.Set("X-Forwarded-For", forwarded)
+	}
+	return r
+}
+
+// startProxy starts p on a free local port and returns its address once it
+// accepts connections. The proxy is stopped when the test ends.
+func startProxy(t *testing.T, p *Proxy) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.config.Port = l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- p.Start() }()
+	t.Cleanup(func() {
+		p.Stop(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("Start: %v", err)
+		}
+	})
+
+	addr := fmt.Sprintf("127.0.0.1:%d", p.config.Port)
+	waitFor(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	})
+	return addr
+}
+
+func TestRequestBodyInspection(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "SQL injection", body: "username=admin' OR 1=1--", wantStatus: http.StatusForbidden},
+		{name: "benign form", body: "username=alice&remember=1", wantStatus: http.StatusOK},
+		{name: "empty body", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded string
+			p := newTestProxyFor(t, &config.Config{}, func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				forwarded = string(body)
+			})
+
+			w := serve(p, newRequest("POST", "/login", tt.body, "192.0.2.1:4000", ""))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && forwarded != tt.body {
+				t.Errorf("upstream got body %q, want %q", forwarded, tt.body)
+			}
+		})
+	}
+}
+
+func TestStreamedRequestBody(t *testing.T) {
+	clean := bytes.Repeat([]byte("field=value&"), 100_000)
+	tests := []struct {
+		name       string
+		body       []byte
+		wantStatus int
+	}{
+		{name: "attack in the first chunk", body: append([]byte("q=' OR 1=1--&"), clean...), wantStatus: http.StatusForbidden},
+		{name: "clean stream", body: clean, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var forwarded []byte
+			p := newTestProxyFor(t, &config.Config{BodyInspectionWindow: 1024}, func(w http.ResponseWriter, r *http.Request) {
+				forwarded, _ = io.ReadAll(r.Body)
+			})
+
+			r := httptest.NewRequest("POST", "/upload", bytes.NewReader(tt.body))
+			r.RemoteAddr = "192.0.2.1:4000"
+			w := serve(p, r)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && !bytes.Equal(forwarded, tt.body) {
+				t.Errorf("upstream got %d bytes, want the %d sent unchanged", len(forwarded), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestResponseTrailers(t *testing.T) {
+	const frame = "\x00\x00\x00\x00\x02ok"
+	tests := []struct {
+		name        string
+		contentType string
+	}{
+		{name: "gRPC-Web", contentType: "application/grpc-web+proto"},
+		{name: "plain response", contentType: "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			stalled := false
+			p := newTestProxyFor(t, &config.Config{}, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+				w.Write([]byte(frame))
+				w.(http.Flusher).Flush()
+				// The client reads the first frame before the stream ends
+				select {
+				case <-release:
+				case <-time.After(2 * time.Second):
+					stalled = true
+				}
+				w.Write([]byte(frame))
+				w.Header().Set("Grpc-Status", "0")
+				w.Header().Set("Grpc-Message", "done")
+			})
+			front := httptest.NewServer(http.HandlerFunc(p.handleRequest))
+			t.Cleanup(front.Close)
+
+			resp, err := http.Post(front.URL+"/echo.Echo/Say", tt.contentType, strings.NewReader("\x00\x00\x00\x00\x00"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			first := make([]byte, len(frame))
+			if _, err := io.ReadFull(resp.Body, first); err != nil {
+				t.Fatal(err)
+			}
+			close(release)
+			rest, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stalled {
+				t.Error("first frame held back until the upstream finished")
+			}
+			if body := string(first) + string(rest); body != frame+frame {
+				t.Errorf("body %q, want the upstream frames unchanged", body)
+			}
+			if got := resp.Trailer.Get("Grpc-Status"); got != "0" {
+				t.Errorf("Grpc-Status trailer %q, want \"0\"", got)
+			}
+			if got := resp.Trailer.Get("Grpc-Message"); got != "done" {
+				t.Errorf("Grpc-Message trailer %q, want \"done\"", got)
+			}
+		})
+	}
+}
+
+func TestServerTimeouts(t *testing.T) {
+	tests := []struct {
+		name                                          string
+		cfg                                           config.Config
+		wantReadHeader, wantRead, wantWrite, wantIdle time.Duration
+	}{
+		{
+			name:           "defaults",
+			wantReadHeader: 10 * time.Second, wantRead: 30 * time.Second, wantWrite: 30 * time.Second, wantIdle: 120 * time.Second,
+		},
+		{
+			name:           "timeout sets read and write",
+			cfg:            config.Config{Timeout: 60},
+			wantReadHeader: 10 * time.Second, wantRead: time.Minute, wantWrite: time.Minute, wantIdle: 120 * time.Second,
+		},
+		{
+			name:           "distinct values",
+			cfg:            config.Config{Timeout: 60, ReadHeaderTimeout: 5, ReadTimeout: 300, WriteTimeout: 900, IdleTimeout: 30},
+			wantReadHeader: 5 * time.Second, wantRead: 5 * time.Minute, wantWrite: 15 * time.Minute, wantIdle: 30 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestProxy(t, &tt.cfg).newServer(http.NotFoundHandler())
+
+			got := [4]time.Duration{server.ReadHeaderTimeout, server.ReadTimeout, server.WriteTimeout, server.IdleTimeout}
+			want := [4]time.Duration{tt.wantReadHeader, tt.wantRead, tt.wantWrite, tt.wantIdle}
+			if got != want {
+				t.Errorf("read header, read, write, idle timeouts %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	tests := []struct {
+		name      string
+		drain     time.Duration
+		wantDrain bool
+	}{
+		{name: "in-flight request drains", drain: 5 * time.Second, wantDrain: true},
+		{name: "drain deadline elapses", drain: 50 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entered, release := make(chan struct{}), make(chan struct{})
+			p := newTestProxyFor(t, &config.Config{}, func(w http.ResponseWriter, r *http.Request) {
+				close(entered)
+				<-release
+				w.Write([]byte("done"))
+			})
+			addr := startProxy(t, p)
+			releaseOnce := sync.OnceFunc(func() { close(release) })
+			t.Cleanup(releaseOnce)
+
+			type result struct {
+				body string
+				err  error
+			}
+			results := make(chan result, 1)
+			go func() {
+				client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+				resp, err := client.Get("http://" + addr + "/slow")
+				if err != nil {
+					results <- result{err: err}
+					return
+				}
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				results <- result{body: string(body), err: err}
+			}()
+			<-entered
+
+			stopped := make(chan error, 1)
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), tt.drain)
+				defer cancel()
+				stopped <- p.Stop(ctx)
+			}()
+
+			// New connections are refused while the slow request is in flight
+			waitFor(t, func() bool {
+				conn, err := net.Dial("tcp", addr)
+				if err == nil {
+					conn.Close()
+				}
+				return err != nil
+			})
+
+			if !tt.wantDrain {
+				if err := <-stopped; err != nil {
+					t.Errorf("Stop: %v", err)
+				}
+				if res := <-results; res.err == nil {
+					t.Errorf("in-flight request completed with %q after the drain deadline", res.body)
+				}
+				return
+			}
+
+			releaseOnce()
+			if res := <-results; res.err != nil || res.body != "done" {
+				t.Errorf("in-flight request got %q, %v, want \"done\"", res.body, res.err)
+			}
+			if err := <-stopped; err != nil {
+				t.Errorf("Stop: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/netutil"
+)
+
+// rateLimitPruneInterval is how often idle buckets are dropped
+const rateLimitPruneInterval = time.Minute
+
+// rateLimiter throttles each client IP with a token bucket: a client may send
+// burst requests at once and rate requests per second after that. Requests
+// without a token are rejected, so brute-force and scraping clients are
+// slowed down however their requests fare against the rules.
+type rateLimiter struct {
+	rate   float64 // tokens added per second
+	burst  float64 // bucket capacity
+	logger *logging.Logger
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+// tokenBucket is the bucket of one client IP
+type tokenBucket struct {
+	tokens  float64
+	last    time.Time // when tokens was last brought up to date
+	limited bool      // the client is being rejected; logged once per episode
+}
+
+// newRateLimiter creates a limiter allowing rate requests per second per IP
+// with bursts of up to burst requests; a burst below 1 defaults to the rate
+// rounded up
+func newRateLimiter(rate float64, burst int, logger *logging.Logger) *rateLimiter {
+	if burst < 1 {
+		burst = int(math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:      rate,
+		burst:     float64(burst),
+		logger:    logger,
+		buckets:   make(map[string]*tokenBucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// allow takes a token from ip's bucket and reports whether there was one. A
+// rejected request also gets the time until the next token.
+func (l *rateLimiter) allow(ip string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[ip] = b
+	} else {
+		b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.limited = false
+		return true, 0
+	}
+
+	if !b.limited {
+		b.limited = true
+		l.logger.Block("Rate limit of %g requests/s (burst %g) exceeded by %s, rejecting with 429", l.rate, l.burst, ip)
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// prune drops the buckets of clients idle long enough for their bucket to be
+// full again, which is the state a new bucket starts in; callers must hold l.mu
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for ip, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, ip)
+		}
+	}
+}
+
+// rateLimitKey returns the IP a request is rate limited by: the client IP
+// reported by trusted proxies, otherwise the peer address, since
+// X-Forwarded-For from anyone else could dodge the limit with made-up IPs
+func rateLimitKey(r *http.Request, trusted []*net.IPNet) string {
+	if ip := netutil.ClientIP(r, trusted); ip != nil {
+		return ip.String()
+	}
+	return hostWithoutPort(r.RemoteAddr)
+}
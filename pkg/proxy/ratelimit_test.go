@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestRateLimit(t *testing.T) {
+	p := newTestProxy(t, &config.Config{RateLimitPerSecond: 2, RateLimitBurst: 3})
+	send := func(remoteAddr string) int {
+		return serve(p, newRequest("GET", "/", "", remoteAddr, "")).Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := send("192.0.2.1:4000"); code != http.StatusOK {
+			t.Fatalf("request %d of the burst got %d, want 200", i+1, code)
+		}
+	}
+	w := serve(p, newRequest("GET", "/", "", "192.0.2.1:4001", ""))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the burst got %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After %q, want \"1\"", got)
+	}
+	if code := send("192.0.2.2:4000"); code != http.StatusOK {
+		t.Errorf("another client got %d, want 200", code)
+	}
+
+	// A second at 2 requests/s refills two tokens
+	limiter := p.rateLimiter
+	limiter.mu.Lock()
+	limiter.buckets["192.0.2.1"].last = limiter.buckets["192.0.2.1"].last.Add(-time.Second)
+	limiter.mu.Unlock()
+	for i := 0; i < 2; i++ {
+		if code := send("192.0.2.1:4000"); code != http.StatusOK {
+			t.Errorf("request %d after the refill got %d, want 200", i+1, code)
+		}
+	}
+	if code := send("192.0.2.1:4000"); code != http.StatusTooManyRequests {
+		t.Errorf("request past the refill got %d, want 429", code)
+	}
+}
+
+func TestRateLimiterPrunesIdleBuckets(t *testing.T) {
+	limiter := newRateLimiter(2, 3, nil)
+	limiter.allow("192.0.2.1")
+	limiter.allow("192.0.2.2")
+
+	// 192.0.2.1 has been idle long enough to refill its bucket
+	limiter.lastPrune = limiter.lastPrune.Add(-rateLimitPruneInterval)
+	limiter.buckets["192.0.2.1"].last = limiter.buckets["192.0.2.1"].last.Add(-2 * time.Second)
+	limiter.allow("192.0.2.2")
+
+	if _, ok := limiter.buckets["192.0.2.1"]; ok {
+		t.Error("idle bucket was not pruned")
+	}
+	if _, ok := limiter.buckets["192.0.2.2"]; !ok {
+		t.Error("active bucket was pruned")
+	}
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// RequestIDHeader carries the request ID to the upstream and back to the client
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength caps inbound request IDs kept as they are
+const maxRequestIDLength = 128
+
+// requestIDKey is the request context key of the request ID
+type requestIDKey struct{}
+
+// withRequestID assigns a request its ID: a well-formed inbound X-Request-ID,
+// e.g. from a load balancer, or else a new UUID. The ID is set on the request
+// for the upstream and on the response, and stored in the request context.
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+
+	r.Header.Set(RequestIDHeader, id)
+	w.Header().Set(RequestIDHeader, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+}
+
+// requestID returns the ID assigned to a request, or "" if none was
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// validRequestID reports whether an inbound request ID is safe to log and
+// echo into headers and block pages: non-empty, not too long, and only
+// letters, digits, '.', '_', ':' and '-'
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '.', c == '_', c == ':', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random version 4 UUID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestInboundRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		inbound  string
+		wantKept bool
+	}{
+		{name: "UUID", inbound: "8ece0ad3-36ff-4dc9-856b-90aa99c00b9f", wantKept: true},
+		{name: "load balancer trace", inbound: "1-67891233-abcdef0123.lb_1:80", wantKept: true},
+		{name: "markup", inbound: `"><script>alert(1)</script>`},
+		{name: "quotes", inbound: `id"onmouseover="x`},
+		{name: "spaces", inbound: "a b"},
+		{name: "too long", inbound: string(make([]byte, maxRequestIDLength+1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var upstreamID string
+			p := newTestProxyFor(t, &config.Config{}, func(w http.ResponseWriter, r *http.Request) {
+				upstreamID = r.Header.Get(RequestIDHeader)
+			})
+
+			r := newRequest("GET", "/", "", "192.0.2.1:4000", "")
+			r.Header.Set(RequestIDHeader, tt.inbound)
+			w := serve(p, r)
+
+			id := w.Header().Get(RequestIDHeader)
+			if kept := id == tt.inbound; kept != tt.wantKept {
+				t.Errorf("response ID %q, kept %v, want %v", id, kept, tt.wantKept)
+			}
+			if !validRequestID(id) {
+				t.Errorf("response ID %q is not a valid request ID", id)
+			}
+			if upstreamID != id {
+				t.Errorf("upstream got ID %q, want %q", upstreamID, id)
+			}
+		})
+	}
+}
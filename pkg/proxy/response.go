@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// maxResponseInspectBytes caps how much of a response body is buffered for
+// response-phase rules; the rest is streamed through uninspected
+const maxResponseInspectBytes = 1 << 20
+
+// blockedResponsePage replaces a response blocked by a response-phase rule,
+// so none of the upstream content reaches the client
+const blockedResponsePage = `<!DOCTYPE html>
+<html>
+<head><title>Error</title></head>
+<body>
+<h1>Something went wrong</h1>
+<p>The server could not complete your request.</p>
+</body>
+</html>
+`
+
+// inspectResponse runs the response-phase rules against an upstream response
+// and replaces it with a safe error page when one blocks. It is installed as
+// the reverse proxy's ModifyResponse hook.
+func (p *Proxy) inspectResponse(resp *http.Response) error {
+	if !p.wafEngine.HasResponseRules() {
+		return nil
+	}
+
+	var body []byte
+	if resp.Body != nil && resp.Body != http.NoBody && !isStreamingContentType(resp.Header.Get("Content-Type")) {
+		buffered, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseInspectBytes))
+		if err != nil {
+			return err
+		}
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buffered), resp.Body), resp.Body}
+		body = decodedResponseBody(resp.Header.Get("Content-Encoding"), buffered)
+	}
+
+	decision, reason, severity := p.wafEngine.CheckResponse(resp, body)
+	if decision != waf.DecisionBlock {
+		return nil
+	}
+
+	r := resp.Request
+	p.logger.Block("Response blocked for request %s (%s %s from %s): %s", requestID(r), r.Method, r.RequestURI, r.RemoteAddr, reason)
+	p.recordBlock(resp.Request, reason, severity)
+	if p.config.DryRun {
+		return nil
+	}
+
+	resp.Body.Close()
+	resp.StatusCode = http.StatusInternalServerError
+	resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+	resp.Header = http.Header{"Content-Type": {"text/html; charset=utf-8"}}
+	resp.Trailer = nil
+	resp.TransferEncoding = nil
+	resp.ContentLength = int64(len(blockedResponsePage))
+	resp.Header.Set("Content-Length", strconv.FormatInt(resp.ContentLength, 10))
+	resp.Body = io.NopCloser(strings.NewReader(blockedResponsePage))
+	return nil
+}
+
+// decodedResponseBody returns the buffered body as the rules should see it,
+// inflating gzip-encoded bodies. A body that cannot be decoded is inspected as is.
+func decodedResponseBody(encoding string, body []byte) []byte {
+	if !strings.EqualFold(strings.TrimSpace(encoding), "gzip") || len(body) == 0 {
+		return body
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	defer zr.Close()
+
+	// The buffer may hold a truncated stream; inspect whatever inflates
+	decoded, _ := io.ReadAll(io.LimitReader(zr, maxResponseInspectBytes))
+	return decoded
+}
@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestResponseRuleBlocks(t *testing.T) {
+	leak := "You have an error in your SQL syntax error near 'users'"
+	sqlErrorRule := config.CustomRule{
+		ID: 9300, Name: "SQL Error Leak", Phase: "response_body", Operator: "contains",
+		Target: "RESPONSE_BODY", Pattern: "SQL syntax error", Action: "block", Severity: "high", Enabled: true,
+	}
+
+	tests := []struct {
+		name        string
+		dryRun      bool
+		body        string
+		gzip        bool
+		contentType string
+		wantStatus  int
+		wantLeak    bool // the upstream body reaches the client, not the error page
+	}{
+		{name: "leaked error", body: leak, wantStatus: http.StatusInternalServerError},
+		{name: "gzip-encoded leaked error", body: leak, gzip: true, wantStatus: http.StatusInternalServerError},
+		{name: "benign response", body: "<h1>Users</h1>", wantStatus: http.StatusOK, wantLeak: true},
+		{name: "dry run", dryRun: true, body: leak, wantStatus: http.StatusOK, wantLeak: true},
+		{name: "streaming response is not buffered", body: leak, contentType: "text/event-stream", wantStatus: http.StatusOK, wantLeak: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DryRun: tt.dryRun, CustomRules: []config.CustomRule{sqlErrorRule}}
+			p := newTestProxyFor(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if !tt.gzip {
+					w.Write([]byte(tt.body))
+					return
+				}
+				var buf bytes.Buffer
+				gz := gzip.NewWriter(&buf)
+				gz.Write([]byte(tt.body))
+				gz.Close()
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(buf.Bytes())
+			})
+
+			w := serve(p, newRequest("GET", "/users", "", "192.0.2.1:4000", ""))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			got := w.Body.String()
+			if tt.gzip && w.Header().Get("Content-Encoding") == "gzip" {
+				zr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var buf bytes.Buffer
+				buf.ReadFrom(zr)
+				got = buf.String()
+			}
+			want := blockedResponsePage
+			if tt.wantLeak {
+				want = tt.body
+			}
+			if got != want {
+				t.Errorf("body %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// defaultSlowClientGrace is how long a request may deliver before its rate
+// is judged
+const defaultSlowClientGrace = 5 * time.Second
+
+// Delivery phases of a tracked connection
+const (
+	phaseIdle     = iota // waiting for the next request
+	phaseHeaders         // request headers are arriving
+	phaseBody            // the request body is being read
+	phaseResponse        // the response is being written
+)
+
+// slowConn counts the bytes read from and written to a connection, and the
+// phase of the request currently being delivered over it
+type slowConn struct {
+	net.Conn
+	bytesRead atomic.Int64
+
+	mu           sync.Mutex
+	phase        int
+	phaseStart   time.Time
+	phaseBytes   int64 // bytes transferred in the phase's direction when it started
+	bytesWritten int64
+	writeTime    time.Duration // time blocked in the response phase's completed writes
+	writeStart   time.Time     // start of the pending write, zero if none
+}
+
+// Read counts the bytes read from the connection. The first bytes arriving
+// on an idle connection start the headers of its next request.
+func (c *slowConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.mu.Lock()
+		if c.phase == phaseIdle {
+			c.setPhase(phaseHeaders)
+		}
+		c.bytesRead.Add(int64(n))
+		c.mu.Unlock()
+	}
+	return n, err
+}
+
+// Write counts the bytes written to the connection and the time spent
+// blocked writing them. The first write on an idle connection starts the
+// response phase.
+func (c *slowConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	if c.phase == phaseIdle {
+		c.setPhase(phaseResponse)
+	}
+	start := time.Now()
+	c.writeStart = start
+	c.mu.Unlock()
+
+	n, err := c.Conn.Write(b)
+
+	c.mu.Lock()
+	c.bytesWritten += int64(n)
+	if c.phase == phaseResponse {
+		c.writeTime += time.Since(start)
+	}
+	c.writeStart = time.Time{}
+	c.mu.Unlock()
+	return n, err
+}
+
+// enterPhase starts measuring delivery for a phase
+func (c *slowConn) enterPhase(phase int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setPhase(phase)
+}
+
+// setPhase starts a phase; callers must hold c.mu
+func (c *slowConn) setPhase(phase int) {
+	c.phase = phase
+	c.phaseStart = time.Now()
+	c.phaseBytes = c.bytesRead.Load()
+	if phase == phaseResponse {
+		c.phaseBytes = c.bytesWritten
+		c.writeTime = 0
+	}
+}
+
+// progress returns the phase of the connection, the bytes transferred in it
+// and how long the transfer took. A response is only timed while writes are
+// blocked, since the upstream may take its time producing it.
+func (c *slowConn) progress(now time.Time) (phase int, transferred int64, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.phase != phaseResponse {
+		return c.phase, c.bytesRead.Load() - c.phaseBytes, now.Sub(c.phaseStart)
+	}
+	elapsed = c.writeTime
+	if !c.writeStart.IsZero() {
+		elapsed += now.Sub(c.writeStart)
+	}
+	return c.phase, c.bytesWritten - c.phaseBytes, elapsed
+}
+
+// slowClientGuard drops connections that deliver request headers or bodies,
+// or read responses, slower than a minimum byte rate (Slowloris, slow POST,
+// slow read). Server timeouts only bound the whole request, so a client
+// trickling bytes just fast enough can otherwise hold a connection for the
+// full timeout.
+type slowClientGuard struct {
+	minRate float64       // bytes per second
+	grace   time.Duration // delivery time before the rate is judged
+	logger  *logging.Logger
+	onDrop  func(ip, what string, rate, minRate float64) // records a drop; may be nil
+
+	mu    sync.Mutex
+	conns map[*slowConn]struct{}
+}
+
+// newSlowClientGuard creates a guard; a zero grace uses the default
+func newSlowClientGuard(minRate float64, grace time.Duration, logger *logging.Logger) *slowClientGuard {
+	if grace <= 0 {
+		grace = defaultSlowClientGrace
+	}
+	return &slowClientGuard{
+		minRate: minRate,
+		grace:   grace,
+		logger:  logger,
+		conns:   make(map[*slowConn]struct{}),
+	}
+}
+
+// connState tracks connections as the server reports their state. A new
+// connection owes the headers of its first request; the server reports a
+// connection active once those headers are complete.
+func (g *slowClientGuard) connState(conn net.Conn, state http.ConnState) {
+	sc, ok := unwrapSlowConn(conn)
+	if !ok {
+		return
+	}
+
+	switch state {
+	case http.StateNew:
+		sc.enterPhase(phaseHeaders)
+		g.mu.Lock()
+		g.conns[sc] = struct{}{}
+		g.mu.Unlock()
+	case http.StateActive, http.StateIdle:
+		sc.enterPhase(phaseIdle)
+	case http.StateHijacked, http.StateClosed:
+		g.mu.Lock()
+		delete(g.conns, sc)
+		g.mu.Unlock()
+	}
+}
+
+// check drops every connection delivering slower than the minimum rate
+func (g *slowClientGuard) check(now time.Time) {
+	g.mu.Lock()
+	conns := make([]*slowConn, 0, len(g.conns))
+	for sc := range g.conns {
+		conns = append(conns, sc)
+	}
+	g.mu.Unlock()
+
+	for _, sc := range conns {
+		phase, transferred, elapsed := sc.progress(now)
+		if phase == phaseIdle || elapsed < g.grace {
+			continue
+		}
+		rate := float64(transferred) / elapsed.Seconds()
+		if rate >= g.minRate {
+			continue
+		}
+
+		what := "sent request headers"
+		switch phase {
+		case phaseBody:
+			what = "sent the request body"
+		case phaseResponse:
+			what = "read the response"
+		}
+		g.logger.Info("Dropping connection from %s: %s at %.1f bytes/s (minimum %.0f) over %v",
+			sc.RemoteAddr(), what, rate, g.minRate, elapsed.Round(time.Millisecond))
+		sc.Close()
+		if g.onDrop != nil {
+			g.onDrop(connIP(sc), what, rate, g.minRate)
+		}
+	}
+}
+
+// connIP returns the IP of a connection's peer, or its whole address if it
+// holds no port
+func connIP(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// watch runs check periodically until stop is closed
+func (g *slowClientGuard) watch(stop <-chan struct{}) {
+	interval := g.grace / 2
+	if interval <= 0 || interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			g.check(now)
+		}
+	}
+}
+
+// slowConnKey is the request context key of the request's slowConn
+type slowConnKey struct{}
+
+// connContext stores the connection in the context of its requests
+func (g *slowClientGuard) connContext(ctx context.Context, conn net.Conn) context.Context {
+	if sc, ok := unwrapSlowConn(conn); ok {
+		return context.WithValue(ctx, slowConnKey{}, sc)
+	}
+	return ctx
+}
+
+// watchBody measures the delivery of a request's body from now until it is
+// fully read. It returns a function to call once the handler is done.
+func (g *slowClientGuard) watchBody(r *http.Request) func() {
+	sc, ok := r.Context().Value(slowConnKey{}).(*slowConn)
+	if !ok || r.Body == nil || r.Body == http.NoBody {
+		return func() {}
+	}
+
+	sc.enterPhase(phaseBody)
+	done := func() {
+		sc.mu.Lock()
+		if sc.phase == phaseBody {
+			sc.phase = phaseIdle
+		}
+		sc.mu.Unlock()
+	}
+	r.Body = &eofNotifyingBody{ReadCloser: r.Body, onEOF: done}
+	return done
+}
+
+// eofNotifyingBody calls onEOF once the body has been read to the end
+type eofNotifyingBody struct {
+	io.ReadCloser
+	onEOF func()
+	once  sync.Once
+}
+
+// Read reads from the body and reports reaching its end
+func (b *eofNotifyingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.onEOF)
+	}
+	return n, err
+}
+
+// unwrapSlowConn returns the slowConn under a connection, which the server
+// wraps in a *tls.Conn when serving TLS
+func unwrapSlowConn(conn net.Conn) (*slowConn, bool) {
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = tc.NetConn()
+	}
+	sc, ok := conn.(*slowConn)
+	return sc, ok
+}
+
+// slowClientListener wraps accepted connections so the guard can measure them
+type slowClientListener struct {
+	net.Listener
+}
+
+// Accept wraps the next connection in a slowConn
+func (l *slowClientListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &slowConn{Conn: conn}, nil
+}
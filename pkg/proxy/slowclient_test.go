@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/anomaly"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+func TestSlowClientGuard(t *testing.T) {
+	const grace = time.Second
+
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, sc *slowConn, client net.Conn)
+		wantDrop string // what the dropped client did, or "" to keep it
+	}{
+		{
+			name:     "slow headers",
+			setup:    func(t *testing.T, sc *slowConn, client net.Conn) { sc.enterPhase(phaseHeaders) },
+			wantDrop: "sent request headers",
+		},
+		{
+			name: "fast headers",
+			setup: func(t *testing.T, sc *slowConn, client net.Conn) {
+				sc.enterPhase(phaseHeaders)
+				go client.Write(make([]byte, 4096))
+				if _, err := sc.Read(make([]byte, 4096)); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:     "slow body",
+			setup:    func(t *testing.T, sc *slowConn, client net.Conn) { sc.enterPhase(phaseBody) },
+			wantDrop: "sent the request body",
+		},
+		{
+			name:  "idle",
+			setup: func(t *testing.T, sc *slowConn, client net.Conn) { sc.enterPhase(phaseIdle) },
+		},
+		{
+			name: "slow read",
+			setup: func(t *testing.T, sc *slowConn, client net.Conn) {
+				sc.enterPhase(phaseIdle)
+				// Nothing reads the client end, so the write blocks
+				go sc.Write(make([]byte, 4096))
+				waitFor(t, func() bool {
+					sc.mu.Lock()
+					defer sc.mu.Unlock()
+					return !sc.writeStart.IsZero()
+				})
+			},
+			wantDrop: "read the response",
+		},
+		{
+			name: "response waiting on the upstream",
+			setup: func(t *testing.T, sc *slowConn, client net.Conn) {
+				sc.enterPhase(phaseIdle)
+				go client.Read(make([]byte, 4096))
+				if _, err := sc.Write(make([]byte, 4096)); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := net.Pipe()
+			t.Cleanup(func() { server.Close(); client.Close() })
+			sc := &slowConn{Conn: server}
+
+			guard := newSlowClientGuard(100, grace, logging.NewLogger(""))
+			var dropped string
+			guard.onDrop = func(ip, what string, rate, minRate float64) { dropped = what }
+			guard.connState(sc, http.StateNew)
+			tt.setup(t, sc, client)
+
+			guard.check(time.Now().Add(2 * grace))
+			if dropped != tt.wantDrop {
+				t.Errorf("dropped %q, want %q", dropped, tt.wantDrop)
+			}
+		})
+	}
+}
+
+func TestSlowClientAnomaly(t *testing.T) {
+	server, client := net.Pipe()
+	t.Cleanup(func() { server.Close(); client.Close() })
+	sc := &slowConn{Conn: server}
+
+	detector := anomaly.NewAnomalyDetector(time.Minute)
+	guard := newSlowClientGuard(100, time.Second, logging.NewLogger(""))
+	guard.onDrop = detector.RecordSlowClient
+	guard.connState(sc, http.StateNew)
+	guard.check(time.Now().Add(2 * time.Second))
+
+	anomalies := detector.GetAnomalies()
+	if len(anomalies) != 1 || anomalies[0].Type != "slowloris" || anomalies[0].Severity != "high" {
+		t.Fatalf("anomalies %+v, want one high slowloris anomaly", anomalies)
+	}
+	if !strings.Contains(anomalies[0].Description, "sent request headers") {
+		t.Errorf("description %q does not say what was slow", anomalies[0].Description)
+	}
+	if len(detector.RecentAnomalies()) != 1 {
+		t.Error("slowloris anomaly missing from the risk history")
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Summary is the blocked tally of a proxy session
+type Summary struct {
+	Start   time.Time        `json:"start"`
+	End     time.Time        `json:"end"`
+	DryRun  bool             `json:"dry_run"` // blocked requests were only logged
+	Total   int64            `json:"total"`
+	Blocked int64            `json:"blocked"`
+	ByRule  map[string]int64 `json:"by_rule"`
+
+	BlockRate float64 `json:"block_rate"` // Blocked / Total
+	// DecayedBlockRate weights each request by 2^(-age/half-life), so recent
+	// requests dominate; only set when a half-life is configured
+	DecayedBlockRate *float64 `json:"decayed_block_rate,omitempty"`
+}
+
+// sessionTally counts requests and block decisions for the session summary
+type sessionTally struct {
+	mu      sync.Mutex
+	start   time.Time
+	total   int64
+	blocked int64
+	byRule  map[string]int64
+
+	// Exponentially decayed request and block counts as of decayedAt
+	halfLife       time.Duration // 0 disables decay tracking
+	decayedTotal   float64
+	decayedBlocked float64
+	decayedAt      time.Time
+}
+
+// newSessionTally creates an empty tally starting now, also tracking a
+// decayed block rate when halfLife is positive
+func newSessionTally(halfLife time.Duration) *sessionTally {
+	now := time.Now()
+	return &sessionTally{start: now, byRule: make(map[string]int64), halfLife: halfLife, decayedAt: now}
+}
+
+// decay ages the decayed counts to now. Callers hold t.mu.
+func (t *sessionTally) decay(now time.Time) {
+	if t.halfLife <= 0 || !now.After(t.decayedAt) {
+		return
+	}
+	factor := math.Exp2(-float64(now.Sub(t.decayedAt)) / float64(t.halfLife))
+	t.decayedTotal *= factor
+	t.decayedBlocked *= factor
+	t.decayedAt = now
+}
+
+// request counts a handled request and returns the running total
+func (t *sessionTally) request() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.total++
+	if t.halfLife > 0 {
+		t.decay(time.Now())
+		t.decayedTotal++
+	}
+	return t.total
+}
+
+// block counts a blocked request against the rules that caused it
+func (t *sessionTally) block(rules ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.blocked++
+	if t.halfLife > 0 {
+		t.decay(time.Now())
+		t.decayedBlocked++
+	}
+	for _, rule := range rules {
+		t.byRule[rule]++
+	}
+}
+
+// reset zeroes the tally, starting a new one now
+func (t *sessionTally) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.start = now
+	t.total = 0
+	t.blocked = 0
+	t.byRule = make(map[string]int64)
+	t.decayedTotal = 0
+	t.decayedBlocked = 0
+	t.decayedAt = now
+}
+
+// Summary returns the blocked tally of the session so far
+func (p *Proxy) Summary() Summary {
+	t := p.tally
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	byRule := make(map[string]int64, len(t.byRule))
+	for rule, count := range t.byRule {
+		byRule[rule] = count
+	}
+	summary := Summary{
+		Start:   t.start,
+		End:     time.Now(),
+		DryRun:  p.config.DryRun,
+		Total:   t.total,
+		Blocked: t.blocked,
+		ByRule:  byRule,
+	}
+	if t.total > 0 {
+		summary.BlockRate = float64(t.blocked) / float64(t.total)
+	}
+	if t.halfLife > 0 {
+		// Both counts decay alike, so their ratio needs no aging to End
+		rate := 0.0
+		if t.decayedTotal > 0 {
+			rate = t.decayedBlocked / t.decayedTotal
+		}
+		summary.DecayedBlockRate = &rate
+	}
+	return summary
+}
+
+// WriteSummary writes a session summary to path as JSON
+func WriteSummary(path string, summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal summary: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write summary file: %w", err)
+	}
+	return nil
+}
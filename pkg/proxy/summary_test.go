@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestSummaryDecayedBlockRate(t *testing.T) {
+	p := newTestProxy(t, &config.Config{SummaryHalfLife: 60})
+	tally := p.tally
+
+	for i := 0; i < 90; i++ {
+		tally.request()
+	}
+	// Age the allowed requests by ten half-lives
+	tally.mu.Lock()
+	tally.decayedAt = tally.decayedAt.Add(-10 * time.Minute)
+	tally.mu.Unlock()
+	for i := 0; i < 10; i++ {
+		tally.request()
+		tally.block("Rule 1001: SQL Injection - Common Patterns")
+	}
+
+	summary := p.Summary()
+	if summary.BlockRate != 0.1 {
+		t.Errorf("block rate %v, want 0.1", summary.BlockRate)
+	}
+	if summary.DecayedBlockRate == nil {
+		t.Fatal("no decayed block rate with a half-life configured")
+	}
+	if rate := *summary.DecayedBlockRate; rate < 0.99 || rate > 1 {
+		t.Errorf("decayed block rate %v, want the recent blocks to dominate (about 0.991)", rate)
+	}
+
+	flat := newTestProxy(t, &config.Config{})
+	flat.tally.request()
+	if summary := flat.Summary(); summary.DecayedBlockRate != nil {
+		t.Errorf("decayed block rate %v without a half-life, want none", *summary.DecayedBlockRate)
+	}
+}
@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and its
+// key to dir, returning the file paths and the certificate
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "shieldcli test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestServeTLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+
+	p := newTestProxy(t, &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+	addr := startProxy(t, p)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		DisableKeepAlives: true,
+	}}
+
+	resp, err := client.Get("https://" + addr + "/")
+	if err != nil {
+		t.Fatalf("HTTPS request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("HTTPS response %d %q, want 200 \"ok\" from the backend", resp.StatusCode, body)
+	}
+	if resp.TLS == nil {
+		t.Error("response was not served over TLS")
+	}
+
+	plain := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	if resp, err := plain.Get("http://" + addr + "/"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Error("plain HTTP request reached the backend of a TLS listener")
+		}
+	}
+}
+
+func TestLoadTLSConfigErrors(t *testing.T) {
+	certFile, _, _ := writeSelfSignedCert(t, t.TempDir())
+
+	if _, err := loadTLSConfig(certFile, ""); err == nil {
+		t.Error("loadTLSConfig accepted a certificate without a key")
+	}
+	if _, err := loadTLSConfig(certFile, filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("loadTLSConfig accepted a missing key file")
+	}
+}
+
+func TestUpstreamTLS(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "upstream-ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: upstream.Certificate().Raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	otherCAFile, _, _ := writeSelfSignedCert(t, dir)
+
+	tests := []struct {
+		name       string
+		caFile     string
+		skipVerify bool
+		wantStatus int
+	}{
+		{name: "CA bundle", caFile: caFile, wantStatus: http.StatusOK},
+		{name: "skip verify", skipVerify: true, wantStatus: http.StatusOK},
+		{name: "system roots", wantStatus: http.StatusBadGateway},
+		{name: "other CA", caFile: otherCAFile, wantStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ProxyTo: upstream.URL, UpstreamCAFile: tt.caFile, UpstreamInsecureSkipVerify: tt.skipVerify}
+			p, err := NewProxy(cfg, logging.NewLogger(""))
+			if err != nil {
+				t.Fatalf("NewProxy: %v", err)
+			}
+
+			w := serve(p, newRequest("GET", "/", "", "192.0.2.1:4000", ""))
+			if w.Code != tt.wantStatus {
+				t.Errorf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	if _, err := NewProxy(&config.Config{ProxyTo: upstream.URL, UpstreamCAFile: filepath.Join(dir, "missing.pem")}, logging.NewLogger("")); err == nil {
+		t.Error("NewProxy accepted a missing upstream CA bundle")
+	}
+}
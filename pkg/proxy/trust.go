@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/shieldcli/shieldcli/pkg/netutil"
+)
+
+// stripUntrustedHeaders removes client-controlled headers from a request whose
+// peer is not a trusted proxy, so they cannot spoof the client IP or
+// correlation IDs seen by rules and logs. It returns the headers removed.
+func stripUntrustedHeaders(r *http.Request, headers []string, trusted []*net.IPNet) []string {
+	if netutil.IsTrustedPeer(r, trusted) {
+		return nil
+	}
+
+	var stripped []string
+	for _, name := range headers {
+		if _, ok := r.Header[http.CanonicalHeaderKey(name)]; ok {
+			r.Header.Del(name)
+			stripped = append(stripped, http.CanonicalHeaderKey(name))
+		}
+	}
+	return stripped
+}
@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestStripUntrustedHeaders(t *testing.T) {
+	// Blocks a client by the IP it claims, so it fires only if X-Real-IP
+	// survives stripping
+	claimedIP := config.CustomRule{
+		ID: 9600, Name: "Claimed IP", Phase: "request_headers", Operator: "equals",
+		Target: "REQUEST_HEADERS:X-Real-IP", Pattern: "203.0.113.7", Action: "block", Severity: "high", Enabled: true,
+	}
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		wantStatus    int
+		wantForwarded string
+	}{
+		{
+			name:          "untrusted peer",
+			remoteAddr:    "192.0.2.1:4000",
+			wantStatus:    http.StatusOK,
+			wantForwarded: "192.0.2.1",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:4000",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			cfg := &config.Config{
+				StripHeaders:   []string{"x-forwarded-for", "X-Real-IP", "X-Request-Id"},
+				TrustedProxies: []string{"10.0.0.0/8"},
+				CustomRules:    []config.CustomRule{claimedIP},
+			}
+			p := newTestProxyFor(t, cfg, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Clone()
+			})
+
+			r := newRequest("GET", "/", "", tt.remoteAddr, "203.0.113.7")
+			r.Header.Set("X-Real-IP", "203.0.113.7")
+			r.Header.Set("X-Request-Id", "spoofed-id")
+			w := serve(p, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if v := got.Get("X-Forwarded-For"); v != tt.wantForwarded {
+				t.Errorf("upstream X-Forwarded-For %q, want %q", v, tt.wantForwarded)
+			}
+			if v := got.Get("X-Real-IP"); v != "" {
+				t.Errorf("upstream X-Real-IP %q, want it stripped", v)
+			}
+			if v := got.Get("X-Request-Id"); v == "spoofed-id" {
+				t.Error("spoofed X-Request-Id reached the upstream")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package replay
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// WAFComparisonResult holds the decisions of two WAF engines for one recorded request
+type WAFComparisonResult struct {
+	Request   RecordedRequest
+	DecisionA waf.Decision
+	ReasonA   string
+	DecisionB waf.Decision
+	ReasonB   string
+	Changed   bool
+}
+
+// WAFComparison is the outcome of running recorded traffic through two WAF engines
+type WAFComparison struct {
+	Results      []WAFComparisonResult
+	NewlyBlocked int // allowed by A, blocked by B
+	NewlyAllowed int // blocked by A, allowed by B
+}
+
+// Divergent returns the results whose decision differs between the two engines
+func (c *WAFComparison) Divergent() []WAFComparisonResult {
+	var divergent []WAFComparisonResult
+	for _, result := range c.Results {
+		if result.Changed {
+			divergent = append(divergent, result)
+		}
+	}
+	return divergent
+}
+
+// CompareWAF runs every recorded request through engines a and b offline and
+// reports where their decisions differ. No target server is contacted.
+func CompareWAF(records []TrafficRecord, a, b *waf.Engine) (*WAFComparison, error) {
+	comparison := &WAFComparison{
+		Results: make([]WAFComparisonResult, 0, len(records)),
+	}
+
+	for _, record := range records {
+		// Each engine gets its own request so body reads do not interfere
+		reqA, err := record.Request.HTTPRequest()
+		if err != nil {
+			return nil, fmt.Errorf("request %s: %w", record.Request.ID, err)
+		}
+		reqB, err := record.Request.HTTPRequest()
+		if err != nil {
+			return nil, fmt.Errorf("request %s: %w", record.Request.ID, err)
+		}
+
+		result := WAFComparisonResult{Request: record.Request}
+		result.DecisionA, result.ReasonA = a.Check(reqA)
+		result.DecisionB, result.ReasonB = b.Check(reqB)
+		result.Changed = result.DecisionA != result.DecisionB
+
+		if result.Changed {
+			if result.DecisionB == waf.DecisionBlock {
+				comparison.NewlyBlocked++
+			} else if result.DecisionA == waf.DecisionBlock {
+				comparison.NewlyAllowed++
+			}
+		}
+
+		comparison.Results = append(comparison.Results, result)
+	}
+
+	return comparison, nil
+}
+
+// HTTPRequest rebuilds the recorded request as an inbound server request, as
+// the proxy would have received it
+func (rr RecordedRequest) HTTPRequest() (*http.Request, error) {
+	req, err := http.NewRequest(rr.Method, rr.URL, strings.NewReader(rr.Body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for key, value := range rr.Headers {
+		req.Header.Set(key, value)
+	}
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+		req.Header.Del("Host")
+	}
+	req.RequestURI = rr.URL
+	req.RemoteAddr = rr.RemoteAddr
+
+	return req, nil
+}
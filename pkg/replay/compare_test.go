@@ -0,0 +1,67 @@
+package replay
+
+import (
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+func newEngine(t *testing.T, cfg *config.Config) *waf.Engine {
+	t.Helper()
+	engine, err := waf.NewEngine(cfg, logging.NewLogger(""))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestCompareWAF(t *testing.T) {
+	records := []TrafficRecord{
+		{Request: RecordedRequest{ID: "home", Method: "GET", URL: "/", RemoteAddr: "192.0.2.1:4000"}},
+		{Request: RecordedRequest{ID: "internal", Method: "GET", URL: "/internal/metrics", RemoteAddr: "192.0.2.1:4000"}},
+		{Request: RecordedRequest{
+			ID: "sqli", Method: "POST", URL: "/login", Body: "user=admin' OR 1=1--", RemoteAddr: "192.0.2.2:4000",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		}},
+		{Request: RecordedRequest{
+			ID: "internal with host", Method: "GET", URL: "/internal/debug", RemoteAddr: "192.0.2.3:4000",
+			Headers: map[string]string{"Host": "shop.example.com"},
+		}},
+	}
+
+	a := newEngine(t, &config.Config{})
+	b := newEngine(t, &config.Config{CustomRules: []config.CustomRule{{
+		ID: 9500, Name: "Internal Paths", Phase: "request_uri", Operator: "startswith",
+		Target: "REQUEST_URI", Pattern: "/internal/", Action: "block", Severity: "high", Enabled: true,
+	}}})
+
+	comparison, err := CompareWAF(records, a, b)
+	if err != nil {
+		t.Fatalf("CompareWAF: %v", err)
+	}
+
+	if len(comparison.Results) != len(records) {
+		t.Fatalf("%d results, want one per record (%d)", len(comparison.Results), len(records))
+	}
+	if comparison.NewlyBlocked != 2 || comparison.NewlyAllowed != 0 {
+		t.Errorf("newly blocked %d, newly allowed %d, want 2 and 0", comparison.NewlyBlocked, comparison.NewlyAllowed)
+	}
+
+	var divergent []string
+	for _, result := range comparison.Divergent() {
+		divergent = append(divergent, result.Request.ID)
+		if result.DecisionA != waf.DecisionAllow || result.DecisionB != waf.DecisionBlock {
+			t.Errorf("%s: decisions %v then %v, want allow then block", result.Request.ID, result.DecisionA, result.DecisionB)
+		}
+	}
+	if len(divergent) != 2 || divergent[0] != "internal" || divergent[1] != "internal with host" {
+		t.Errorf("divergent requests %q, want [internal, internal with host]", divergent)
+	}
+
+	sqli := comparison.Results[2]
+	if sqli.Changed || sqli.DecisionA != waf.DecisionBlock || sqli.DecisionB != waf.DecisionBlock {
+		t.Errorf("sqli: %+v, want blocked by both engines", sqli)
+	}
+}
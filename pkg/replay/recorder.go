@@ -1,11 +1,13 @@
 package replay
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -72,9 +74,15 @@ func (r *Recorder) RecordTraffic(req *http.Request, statusCode int, responseBody
 		}
 	}
 
+	// Keep the proxy's request ID so recordings match logs and events
+	id := req.Header.Get("X-Request-ID")
+	if id == "" {
+		id = fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+
 	// Create recorded request
 	recordedReq := RecordedRequest{
-		ID:         fmt.Sprintf("%d", time.Now().UnixNano()),
+		ID:         id,
 		Timestamp:  time.Now(),
 		Method:     req.Method,
 		URL:        req.RequestURI,
@@ -179,7 +187,8 @@ func (r *Recorder) FilterRecordsByBlocked(blocked bool) []TrafficRecord {
 	return filtered
 }
 
-// ExportToCSV exports recorded traffic to a CSV file
+// ExportToCSV exports recorded traffic to a CSV file. Fields are quoted as
+// needed, so URLs and reasons may contain commas, quotes or newlines.
 func (r *Recorder) ExportToCSV(filePath string) error {
 	file, err := os.Create(filePath)
 	if err != nil {
@@ -187,28 +196,29 @@ func (r *Recorder) ExportToCSV(filePath string) error {
 	}
 	defer file.Close()
 
+	w := csv.NewWriter(file)
+
 	// Write header
-	_, err = file.WriteString("ID,Timestamp,Method,URL,Status,Blocked,Reason\n")
-	if err != nil {
+	if err := w.Write([]string{"ID", "Timestamp", "Method", "URL", "Status", "Blocked", "Reason"}); err != nil {
 		return err
 	}
 
 	// Write records
 	for _, record := range r.records {
-		line := fmt.Sprintf("%s,%s,%s,%s,%d,%v,%s\n",
+		err := w.Write([]string{
 			record.Request.ID,
 			record.Request.Timestamp.Format(time.RFC3339),
 			record.Request.Method,
 			record.Request.URL,
-			record.Response.StatusCode,
-			record.Blocked,
+			strconv.Itoa(record.Response.StatusCode),
+			strconv.FormatBool(record.Blocked),
 			record.Reason,
-		)
-		_, err := file.WriteString(line)
+		})
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	w.Flush()
+	return w.Error()
 }
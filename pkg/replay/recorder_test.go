@@ -0,0 +1,68 @@
+package replay
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExportToCSV(t *testing.T) {
+	recorder := NewRecorder("", 10)
+
+	tests := []struct {
+		id      string
+		target  string
+		status  int
+		blocked bool
+		reason  string
+		wantRow []string // without the timestamp
+	}{
+		{
+			id: "req-1", target: "/search?q=a,b&sort=asc", status: 200,
+			wantRow: []string{"req-1", "GET", "/search?q=a,b&sort=asc", "200", "false", ""},
+		},
+		{
+			id: "req-2", target: `/items?name="quoted"`, status: 403, blocked: true, reason: "Rule 9100: Probe, \"quoted\"\nsecond line",
+			wantRow: []string{"req-2", "GET", `/items?name="quoted"`, "403", "true", "Rule 9100: Probe, \"quoted\"\nsecond line"},
+		},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		r.Header.Set("X-Request-ID", tt.id)
+		if err := recorder.RecordTraffic(r, tt.status, nil, tt.blocked, tt.reason); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "traffic.csv")
+	if err := recorder.ExportToCSV(path); err != nil {
+		t.Fatalf("ExportToCSV: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("export is not valid CSV: %v", err)
+	}
+
+	if len(rows) != 3 {
+		t.Fatalf("%d rows, want a header and 2 records", len(rows))
+	}
+	if want := []string{"ID", "Timestamp", "Method", "URL", "Status", "Blocked", "Reason"}; !reflect.DeepEqual(rows[0], want) {
+		t.Errorf("header %q, want %q", rows[0], want)
+	}
+	for i, tt := range tests {
+		row := rows[i+1]
+		got := append([]string{row[0]}, row[2:]...)
+		if !reflect.DeepEqual(got, tt.wantRow) {
+			t.Errorf("record %d read back as %q, want %q", i+1, got, tt.wantRow)
+		}
+	}
+}
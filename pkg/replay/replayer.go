@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 )
 
@@ -14,7 +15,9 @@ type Replayer struct {
 	client    *http.Client
 	targetURL string
 	records   []TrafficRecord
-	results   []ReplayResult
+
+	mu      sync.Mutex
+	results []ReplayResult
 }
 
 // ReplayResult represents the result of replaying a single request
@@ -64,20 +67,90 @@ func (r *Replayer) ReplayAll() error {
 	return nil
 }
 
+// ReplayAllConcurrent replays all recorded requests with up to workers
+// requests in flight. Results are stored in record order. On the first
+// error no further records are dispatched and only the results of records
+// before the failing one are kept.
+func (r *Replayer) ReplayAllConcurrent(workers int) error {
+	if workers <= 1 {
+		return r.ReplayAll()
+	}
+
+	results := make([]ReplayResult, len(r.records))
+	errs := make([]error, len(r.records))
+	indexes := make(chan int)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indexes {
+				results[idx], errs[idx] = r.replay(r.records[idx])
+				if errs[idx] != nil {
+					stopOnce.Do(func() { close(stop) })
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for idx := range r.records {
+		select {
+		case indexes <- idx:
+		case <-stop:
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for idx, err := range errs {
+		if err != nil {
+			return err
+		}
+		if results[idx].Timestamp.IsZero() {
+			// Never dispatched because an earlier record failed
+			break
+		}
+		r.results = append(r.results, results[idx])
+	}
+	return nil
+}
+
 // ReplayRequest replays a single recorded request
 func (r *Replayer) ReplayRequest(record TrafficRecord) error {
+	result, err := r.replay(record)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.results = append(r.results, result)
+	r.mu.Unlock()
+
+	return nil
+}
+
+// replay sends a single recorded request and compares the response
+func (r *Replayer) replay(record TrafficRecord) (ReplayResult, error) {
 	startTime := time.Now()
 
 	// Parse the URL
 	parsedURL, err := url.Parse(r.targetURL + record.Request.URL)
 	if err != nil {
-		return fmt.Errorf("failed to parse URL: %w", err)
+		return ReplayResult{}, fmt.Errorf("failed to parse URL: %w", err)
 	}
 
 	// Create a new request
 	req, err := http.NewRequest(record.Request.Method, parsedURL.String(), bytes.NewBufferString(record.Request.Body))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return ReplayResult{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Copy headers from recorded request
@@ -121,13 +194,14 @@ func (r *Replayer) ReplayRequest(record TrafficRecord) error {
 		BodyMatch:        bodyMatch,
 	}
 
-	r.results = append(r.results, result)
-
-	return nil
+	return result, nil
 }
 
 // GetResults returns all replay results
 func (r *Replayer) GetResults() []ReplayResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	return r.results
 }
 
@@ -170,6 +244,9 @@ func (r *Replayer) GetResultSummary() map[string]interface{} {
 
 // ClearResults clears all replay results
 func (r *Replayer) ClearResults() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	r.results = make([]ReplayResult, 0)
 }
 
@@ -0,0 +1,89 @@
+package replay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newRecords returns n GET records of /item/<i> whose recorded response is
+// 200 "item-<i>"
+func newRecords(n int) []TrafficRecord {
+	records := make([]TrafficRecord, n)
+	for i := range records {
+		records[i] = TrafficRecord{
+			Request:  RecordedRequest{ID: fmt.Sprintf("req-%d", i), Method: "GET", URL: fmt.Sprintf("/item/%d", i)},
+			Response: RecordedResponse{StatusCode: http.StatusOK, Body: fmt.Sprintf("item-%d", i)},
+		}
+	}
+	return records
+}
+
+func TestReplayAllConcurrent(t *testing.T) {
+	const workers = 10
+	var inFlight, maxInFlight atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("item-" + strings.TrimPrefix(r.URL.Path, "/item/")))
+	}))
+	t.Cleanup(server.Close)
+
+	records := newRecords(50)
+	replayer := NewReplayer(server.URL)
+	replayer.LoadRecords(records)
+	if err := replayer.ReplayAllConcurrent(workers); err != nil {
+		t.Fatalf("ReplayAllConcurrent: %v", err)
+	}
+
+	results := replayer.GetResults()
+	if len(results) != len(records) {
+		t.Fatalf("%d results, want %d", len(results), len(records))
+	}
+	for i, result := range results {
+		if result.OriginalRequest.ID != records[i].Request.ID {
+			t.Fatalf("result %d is for %s, want results in record order", i, result.OriginalRequest.ID)
+		}
+		if !result.Success || !result.StatusMatch || !result.BodyMatch {
+			t.Errorf("result %d %+v, want a successful, matching replay", i, result)
+		}
+	}
+
+	if peak := maxInFlight.Load(); peak > workers || peak < 2 {
+		t.Errorf("%d requests in flight at most, want 2 to %d", peak, workers)
+	}
+}
+
+func TestReplayAllConcurrentError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	records := newRecords(20)
+	records[5].Request.Method = "BAD METHOD"
+	replayer := NewReplayer(server.URL)
+	replayer.LoadRecords(records)
+
+	if err := replayer.ReplayAllConcurrent(4); err == nil {
+		t.Fatal("ReplayAllConcurrent succeeded with an invalid record")
+	}
+	results := replayer.GetResults()
+	if len(results) > 5 {
+		t.Errorf("%d results, want only those of records before the invalid one", len(results))
+	}
+	for i, result := range results {
+		if result.OriginalRequest.ID != records[i].Request.ID {
+			t.Errorf("result %d is for %s, want %s", i, result.OriginalRequest.ID, records[i].Request.ID)
+		}
+	}
+}
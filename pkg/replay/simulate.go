@@ -0,0 +1,50 @@
+package replay
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+// PatternSimulationResult holds the matches of a candidate pattern in one recorded request
+type PatternSimulationResult struct {
+	Request RecordedRequest
+	Matches []waf.PatternMatch
+}
+
+// SimulatePattern runs a candidate regex pattern against the given request
+// target of every recorded request offline and returns the requests it
+// matches. Nothing is sent and no configuration is changed.
+func SimulatePattern(records []TrafficRecord, pattern, target string) ([]PatternSimulationResult, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	target, err = waf.ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(target, "RESPONSE_") {
+		return nil, fmt.Errorf("target %s cannot be simulated: recorded traffic holds requests only", target)
+	}
+
+	var results []PatternSimulationResult
+	for _, record := range records {
+		req, err := record.Request.HTTPRequest()
+		if err != nil {
+			return nil, fmt.Errorf("request %s: %w", record.Request.ID, err)
+		}
+
+		matches, err := waf.MatchPattern(re, target, req)
+		if err != nil {
+			return nil, fmt.Errorf("request %s: %w", record.Request.ID, err)
+		}
+		if len(matches) > 0 {
+			results = append(results, PatternSimulationResult{Request: record.Request, Matches: matches})
+		}
+	}
+
+	return results, nil
+}
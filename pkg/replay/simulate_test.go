@@ -0,0 +1,67 @@
+package replay
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/waf"
+)
+
+func TestSimulatePattern(t *testing.T) {
+	records := []TrafficRecord{
+		{Request: RecordedRequest{ID: "search", Method: "GET", URL: "/search?q=shoes&sort=price"}},
+		{Request: RecordedRequest{ID: "union", Method: "GET", URL: "/search?q=x%27+UNION+SELECT+password+FROM+users--&sort=price"}},
+		{Request: RecordedRequest{ID: "two args", Method: "GET", URL: "/report?a=union+select+1&b=" + strings.Repeat("z", 30) + "union+select+2"}},
+		{Request: RecordedRequest{
+			ID: "body only", Method: "POST", URL: "/search", Body: "q=union select",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+		}},
+	}
+
+	results, err := SimulatePattern(records, `(?i)union\s+select`, "args")
+	if err != nil {
+		t.Fatalf("SimulatePattern: %v", err)
+	}
+
+	got := map[string][]waf.PatternMatch{}
+	var ids []string
+	for _, result := range results {
+		ids = append(ids, result.Request.ID)
+		got[result.Request.ID] = result.Matches
+	}
+	if want := []string{"union", "two args"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("matched requests %q, want %q", ids, want)
+	}
+
+	want := map[string][]waf.PatternMatch{
+		"union": {{Location: "ARGS:q", Match: "UNION SELECT", Snippet: "x' UNION SELECT password FROM users..."}},
+		"two args": {
+			{Location: "ARGS:a", Match: "union select", Snippet: "union select 1"},
+			{Location: "ARGS:b", Match: "union select", Snippet: "...zzzzzzzzzzzzzzzzzzzzunion select 2"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("matches %+v, want %+v", got, want)
+	}
+}
+
+func TestSimulatePatternErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		target  string
+	}{
+		{name: "invalid pattern", pattern: "(unclosed", target: "ARGS"},
+		{name: "unknown target", pattern: "x", target: "COOKIES"},
+		{name: "response target", pattern: "x", target: "RESPONSE_BODY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := SimulatePattern(nil, tt.pattern, tt.target); err == nil {
+				t.Error("SimulatePattern succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package waf
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+)
+
+// Limits above which a pattern is considered expensive to evaluate
+const (
+	maxSafeProgramSize  = 2000
+	maxSafeAlternations = 64
+	maxSafeRepeatDepth  = 3
+)
+
+// PatternBenchmark holds the result of benchmarking a regex pattern
+type PatternBenchmark struct {
+	Pattern      string
+	ProgramSize  int // instructions in the compiled program
+	Alternations int // total alternation branches in the pattern
+	RepeatDepth  int // deepest nesting of repetition operators
+	Inputs       []InputBenchmark
+	Warnings     []string
+}
+
+// InputBenchmark holds the timing of a pattern against one generated input
+type InputBenchmark struct {
+	Name     string
+	Size     int
+	Duration time.Duration // average time per match
+	Matched  bool
+}
+
+// Slowest returns the input with the highest per-match time
+func (pb *PatternBenchmark) Slowest() InputBenchmark {
+	var slowest InputBenchmark
+	for _, input := range pb.Inputs {
+		if input.Duration > slowest.Duration {
+			slowest = input
+		}
+	}
+	return slowest
+}
+
+// BenchmarkPattern compiles a regex pattern and times it against generated
+// worst-case inputs of the given size. Go's RE2 engine runs in linear time, so
+// this mainly surfaces patterns whose per-byte cost is high on large inputs and
+// patterns whose structure (alternation breadth, nested repetition) is expensive.
+// A warning is added for every input whose per-match time exceeds maxDuration.
+func BenchmarkPattern(pattern string, inputSize int, maxDuration time.Duration) (*PatternBenchmark, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile pattern: %w", err)
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pattern: %w", err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile pattern program: %w", err)
+	}
+
+	result := &PatternBenchmark{
+		Pattern:      pattern,
+		ProgramSize:  len(prog.Inst),
+		Alternations: countAlternations(parsed),
+		RepeatDepth:  repeatDepth(parsed),
+	}
+
+	if result.ProgramSize > maxSafeProgramSize {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("compiled program is large (%d instructions); matching cost per byte will be high", result.ProgramSize))
+	}
+	if result.Alternations > maxSafeAlternations {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("pattern has %d alternation branches; consider splitting it into several rules", result.Alternations))
+	}
+	if result.RepeatDepth > maxSafeRepeatDepth {
+		result.Warnings = append(result.Warnings,
+			fmt.Sprintf("repetition operators are nested %d levels deep", result.RepeatDepth))
+	}
+
+	for _, generated := range generateBenchmarkInputs(parsed, inputSize) {
+		name, input := generated.name, generated.data
+		const iterations = 3
+		matched := false
+		start := time.Now()
+		for i := 0; i < iterations; i++ {
+			matched = re.MatchString(input)
+		}
+		perMatch := time.Since(start) / iterations
+
+		result.Inputs = append(result.Inputs, InputBenchmark{
+			Name:     name,
+			Size:     len(input),
+			Duration: perMatch,
+			Matched:  matched,
+		})
+
+		if maxDuration > 0 && perMatch > maxDuration {
+			result.Warnings = append(result.Warnings,
+				fmt.Sprintf("input %q took %v per match (limit %v)", name, perMatch, maxDuration))
+		}
+	}
+
+	return result, nil
+}
+
+// benchmarkInput is a named generated input
+type benchmarkInput struct {
+	name string
+	data string
+}
+
+// generateBenchmarkInputs builds adversarial inputs of roughly the given size
+func generateBenchmarkInputs(re *syntax.Regexp, size int) []benchmarkInput {
+	literals := collectLiterals(re)
+	if literals == "" {
+		literals = "a"
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, size)
+	for i := range random {
+		random[i] = byte(32 + rng.Intn(95))
+	}
+
+	// A near miss repeats the pattern's literal characters but ends with a
+	// byte the pattern is unlikely to accept, forcing a full scan.
+	nearMiss := strings.Repeat(literals, size/len(literals)+1)[:size]
+	if size > 0 {
+		nearMiss = nearMiss[:size-1] + "\x00"
+	}
+
+	return []benchmarkInput{
+		{"repeated-char", strings.Repeat("a", size)},
+		{"pattern-literals", strings.Repeat(literals, size/len(literals)+1)[:size]},
+		{"near-miss", nearMiss},
+		{"random-printable", string(random)},
+		{"whitespace-padded", strings.Repeat(" \t", size/2)},
+	}
+}
+
+// collectLiterals returns the literal characters appearing in a pattern
+func collectLiterals(re *syntax.Regexp) string {
+	var sb strings.Builder
+	var walk func(*syntax.Regexp)
+	walk = func(node *syntax.Regexp) {
+		if node.Op == syntax.OpLiteral {
+			sb.WriteString(string(node.Rune))
+		}
+		for _, sub := range node.Sub {
+			walk(sub)
+		}
+	}
+	walk(re)
+	return sb.String()
+}
+
+// countAlternations returns the total number of alternation branches in a pattern
+func countAlternations(re *syntax.Regexp) int {
+	count := 0
+	if re.Op == syntax.OpAlternate {
+		count += len(re.Sub)
+	}
+	for _, sub := range re.Sub {
+		count += countAlternations(sub)
+	}
+	return count
+}
+
+// repeatDepth returns the deepest nesting of repetition operators in a pattern
+func repeatDepth(re *syntax.Regexp) int {
+	depth := 0
+	for _, sub := range re.Sub {
+		if d := repeatDepth(sub); d > depth {
+			depth = d
+		}
+	}
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		depth++
+	}
+	return depth
+}
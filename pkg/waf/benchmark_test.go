@@ -0,0 +1,59 @@
+package waf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBenchmarkPattern(t *testing.T) {
+	var words []string
+	for i := 0; i < 100; i++ {
+		// Varied letters, so the parser cannot factor the branches into a shared prefix
+		words = append(words, fmt.Sprintf("%c%c%c", 'a'+i%26, 'a'+i*7%26, 'a'+i*3%26))
+	}
+
+	tests := []struct {
+		name         string
+		pattern      string
+		maxDuration  time.Duration
+		wantWarnings []string
+	}{
+		{name: "simple", pattern: `^/admin`},
+		{name: "nested repetition", pattern: `((((a+)+)+)+)+b`, wantWarnings: []string{"nested 5 levels deep"}},
+		{name: "alternation breadth", pattern: strings.Join(words, "|"), wantWarnings: []string{"100 alternation branches"}},
+		{name: "large program", pattern: `[a-z]{1,1000}[0-9]{1,500}`, wantWarnings: []string{"compiled program is large"}},
+		{name: "per-match cost", pattern: `(a|aa)*c`, maxDuration: time.Nanosecond, wantWarnings: []string{"per match (limit 1ns)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := BenchmarkPattern(tt.pattern, 10_000, tt.maxDuration)
+			if err != nil {
+				t.Fatalf("BenchmarkPattern: %v", err)
+			}
+			if len(result.Inputs) == 0 || result.Slowest().Size == 0 {
+				t.Errorf("no timed inputs in %+v", result)
+			}
+			if len(tt.wantWarnings) == 0 && len(result.Warnings) > 0 {
+				t.Errorf("warnings %q, want none", result.Warnings)
+			}
+			for _, want := range tt.wantWarnings {
+				found := false
+				for _, warning := range result.Warnings {
+					found = found || strings.Contains(warning, want)
+				}
+				if !found {
+					t.Errorf("warnings %q, want one containing %q", result.Warnings, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBenchmarkPatternInvalid(t *testing.T) {
+	if _, err := BenchmarkPattern(`(unclosed`, 10, 0); err == nil {
+		t.Error("BenchmarkPattern accepted an invalid pattern")
+	}
+}
@@ -0,0 +1,92 @@
+package waf
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// bufferedBody is a request body that has already been read into memory. It
+// lets the body be inspected and still be forwarded, without reading the
+// underlying stream twice.
+type bufferedBody struct {
+	*bytes.Reader
+	data []byte
+}
+
+// Close implements io.Closer
+func (b *bufferedBody) Close() error {
+	return nil
+}
+
+// newBufferedBody wraps data as a fresh, unread request body
+func newBufferedBody(data []byte) *bufferedBody {
+	return &bufferedBody{Reader: bytes.NewReader(data), data: data}
+}
+
+// peekedBody is a request body whose first bytes were read for inspection,
+// followed by the unread remainder of the original stream
+type peekedBody struct {
+	io.Reader
+	closer io.Closer
+	data   []byte
+}
+
+// Close closes the original body
+func (b *peekedBody) Close() error {
+	return b.closer.Close()
+}
+
+// PeekRequestBody reads at most limit bytes of the request body for
+// inspection and re-attaches them in front of the unread remainder, so the
+// rest streams to the upstream without being held in memory. Later calls
+// return the same bytes. A nil or empty body yields nil.
+func PeekRequestBody(r *http.Request, limit int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	switch body := r.Body.(type) {
+	case *peekedBody:
+		return body.data, nil
+	case *bufferedBody:
+		if int64(len(body.data)) > limit {
+			return body.data[:limit], nil
+		}
+		return body.data, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, limit))
+	r.Body = &peekedBody{
+		Reader: io.MultiReader(bytes.NewReader(data), r.Body),
+		closer: r.Body,
+		data:   data,
+	}
+	return data, err
+}
+
+// BufferRequestBody reads the request body once and caches it on the request,
+// re-attaching it to r.Body so it can still be forwarded. Later calls return
+// the cached body without reading again. A nil or empty body yields nil.
+func BufferRequestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if buffered, ok := r.Body.(*bufferedBody); ok {
+		return buffered.data, nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		// Re-attach what was read so the request is not silently truncated further
+		r.Body = newBufferedBody(data)
+		return data, err
+	}
+
+	r.Body = newBufferedBody(data)
+	r.GetBody = func() (io.ReadCloser, error) {
+		return newBufferedBody(data), nil
+	}
+	r.ContentLength = int64(len(data))
+	return data, nil
+}
@@ -0,0 +1,107 @@
+package waf
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Defaults for the shared compiled-pattern cache
+const (
+	defaultPatternCacheSize = 4096
+	defaultPatternCacheTTL  = time.Hour
+)
+
+// compiledPatterns caches compiled regexes by pattern string so that
+// recompiling an unchanged rule (on reload, or when several engines load the
+// same rule set) reuses the existing *regexp.Regexp. A compiled Regexp is safe
+// for concurrent use, so sharing it between rules is fine.
+var compiledPatterns = newPatternCache(defaultPatternCacheSize, defaultPatternCacheTTL)
+
+// patternCache is a size-bounded LRU cache of compiled regexes whose entries
+// also expire when they have not been used for ttl
+type patternCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	order      *list.List // most recently used at the front
+	entries    map[string]*list.Element
+}
+
+// patternCacheEntry is a single cached compiled pattern
+type patternCacheEntry struct {
+	pattern  string
+	regex    *regexp.Regexp
+	lastUsed time.Time
+}
+
+// newPatternCache creates an empty cache
+func newPatternCache(maxEntries int, ttl time.Duration) *patternCache {
+	return &patternCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// compile returns the cached compiled form of pattern, compiling and caching it on a miss
+func (c *patternCache) compile(pattern string) (*regexp.Regexp, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if elem, ok := c.entries[pattern]; ok {
+		entry := elem.Value.(*patternCacheEntry)
+		if c.ttl <= 0 || now.Sub(entry.lastUsed) <= c.ttl {
+			entry.lastUsed = now
+			c.order.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry.regex, nil
+		}
+		c.removeElement(elem)
+	}
+	c.mu.Unlock()
+
+	// Compile outside the lock; a concurrent miss on the same pattern just compiles twice
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[pattern]; ok {
+		return elem.Value.(*patternCacheEntry).regex, nil
+	}
+	c.entries[pattern] = c.order.PushFront(&patternCacheEntry{pattern: pattern, regex: re, lastUsed: now})
+	c.evict(now)
+
+	return re, nil
+}
+
+// evict drops expired entries and then the least recently used ones until
+// the cache is within its size bound; callers must hold c.mu
+func (c *patternCache) evict(now time.Time) {
+	for elem := c.order.Back(); elem != nil && c.ttl > 0; {
+		prev := elem.Prev()
+		if now.Sub(elem.Value.(*patternCacheEntry).lastUsed) > c.ttl {
+			c.removeElement(elem)
+		} else {
+			// Entries are ordered by last use, so the rest are fresher
+			break
+		}
+		elem = prev
+	}
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// removeElement removes an entry; callers must hold c.mu
+func (c *patternCache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*patternCacheEntry).pattern)
+}
@@ -0,0 +1,82 @@
+package waf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPatternCache(t *testing.T) {
+	c := newPatternCache(2, time.Hour)
+
+	first, err := c.compile(`^/admin`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := c.compile(`^/admin`); again != first {
+		t.Error("unchanged pattern was compiled again")
+	}
+	if _, err := c.compile(`(unclosed`); err == nil {
+		t.Error("invalid pattern compiled")
+	}
+
+	// Over the size bound the least recently used pattern goes
+	c.compile(`^/login`)
+	c.compile(`^/admin`)
+	c.compile(`^/search`)
+	if _, ok := c.entries[`^/login`]; ok || len(c.entries) != 2 {
+		t.Errorf("cached %d patterns with ^/login, want 2 without it", len(c.entries))
+	}
+
+	// An entry unused for longer than the TTL is compiled afresh
+	c.entries[`^/admin`].Value.(*patternCacheEntry).lastUsed = time.Now().Add(-2 * time.Hour)
+	if again, _ := c.compile(`^/admin`); again == first {
+		t.Error("expired pattern was reused")
+	}
+}
+
+// reloadRules returns n regex rules, as a large imported rule set would have
+func reloadRules(n int) []*Rule {
+	rules := make([]*Rule, n)
+	for i := range rules {
+		rules[i] = &Rule{
+			ID:       100000 + i,
+			Phase:    PhaseRequestURI,
+			Operator: OpRegex,
+			Target:   "REQUEST_URI",
+			Pattern:  fmt.Sprintf(`(?i)/(admin|api|internal)/v%d/[a-z0-9_-]{1,64}\.(php|aspx?|jsp)`, i),
+		}
+	}
+	return rules
+}
+
+// BenchmarkReload compiles a 500-rule set as a reload does, with every
+// pattern new ("cold") and with the patterns unchanged since the last load
+// ("unchanged")
+func BenchmarkReload(b *testing.B) {
+	saved := compiledPatterns
+	b.Cleanup(func() { compiledPatterns = saved })
+
+	compileAll := func(b *testing.B) {
+		for _, rule := range reloadRules(500) {
+			if err := rule.Compile(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("cold", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			compiledPatterns = newPatternCache(defaultPatternCacheSize, defaultPatternCacheTTL)
+			compileAll(b)
+		}
+	})
+	b.Run("unchanged", func(b *testing.B) {
+		compiledPatterns = newPatternCache(defaultPatternCacheSize, defaultPatternCacheTTL)
+		compileAll(b)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			compileAll(b)
+		}
+	})
+}
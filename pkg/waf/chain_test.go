@@ -0,0 +1,62 @@
+package waf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestChainedRule(t *testing.T) {
+	engine := newTestEngine(t, &config.Config{CustomRules: []config.CustomRule{
+		{
+			ID: 9100, Name: "Scripted Client", Phase: "request_headers", Operator: "contains",
+			Target: "REQUEST_HEADERS:User-Agent", Pattern: "curl/", Action: "log", Severity: "low", Enabled: true,
+		},
+		{
+			ID: 9101, Name: "Admin Path", Phase: "request_uri", Operator: "startswith",
+			Target: "REQUEST_URI", Pattern: "/admin", Action: "log", Severity: "low", Enabled: true,
+		},
+		{
+			ID: 9102, Name: "Scripted Admin Access", Phase: "request_uri",
+			Requires: []int{9100, 9101}, Action: "block", Severity: "high", Enabled: true,
+		},
+	}})
+
+	tests := []struct {
+		name         string
+		target       string
+		userAgent    string
+		wantDecision Decision
+		wantUnmet    []int
+	}{
+		{name: "neither", target: "/shop", userAgent: "Mozilla/5.0", wantDecision: DecisionAllow, wantUnmet: nil},
+		{name: "scripted client only", target: "/shop", userAgent: "curl/8.5.0", wantDecision: DecisionAllow, wantUnmet: []int{9101}},
+		{name: "admin path only", target: "/admin/users", userAgent: "Mozilla/5.0", wantDecision: DecisionAllow, wantUnmet: []int{9100}},
+		{name: "both", target: "/admin/users", userAgent: "curl/8.5.0", wantDecision: DecisionBlock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			r.Header.Set("User-Agent", tt.userAgent)
+
+			result := engine.CheckDetailed(r)
+			if result.Decision != tt.wantDecision {
+				t.Fatalf("decision %v (%s), want %v", result.Decision, result.Reason, tt.wantDecision)
+			}
+			if tt.wantDecision == DecisionBlock && result.Reason != "Rule 9102: Scripted Admin Access" {
+				t.Errorf("reason %q, want the chained rule", result.Reason)
+			}
+
+			for _, eval := range result.Evaluations {
+				if eval.RuleID != 9102 {
+					continue
+				}
+				if len(tt.wantUnmet) > 0 && (len(eval.UnmetRequires) != len(tt.wantUnmet) || eval.UnmetRequires[0] != tt.wantUnmet[0]) {
+					t.Errorf("unmet requires %v, want %v", eval.UnmetRequires, tt.wantUnmet)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package waf
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+// RuleFromConfig validates a custom_rules entry and converts it into a rule.
+// A chained rule (one with requires) or a rule with conditions may omit its
+// operator, pattern and target.
+func RuleFromConfig(cr config.CustomRule) (*Rule, error) {
+	phase, err := ParsePhase(cr.Phase)
+	if err != nil {
+		return nil, fmt.Errorf("rule %d: %w", cr.ID, err)
+	}
+	action, err := ParseAction(cr.Action)
+	if err != nil {
+		return nil, fmt.Errorf("rule %d: %w", cr.ID, err)
+	}
+
+	severity := "medium"
+	if cr.Severity != "" {
+		if severity, err = ParseSeverity(cr.Severity); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", cr.ID, err)
+		}
+	}
+
+	rule := &Rule{
+		ID:          cr.ID,
+		Name:        cr.Name,
+		Description: cr.Description,
+		Phase:       phase,
+		Pattern:     cr.Pattern,
+		Action:      action,
+		Severity:    severity,
+		Enabled:     cr.Enabled,
+		Requires:    cr.Requires,
+
+		Transformations: cr.Transformations,
+		FullBody:        cr.FullBody,
+	}
+
+	for i, cc := range cr.Conditions {
+		cond := RuleCondition{
+			Pattern:         cc.Pattern,
+			Transformations: cc.Transformations,
+		}
+		if cond.Operator, err = ParseOperator(cc.Operator); err != nil {
+			return nil, fmt.Errorf("rule %d: condition %d: %w", cr.ID, i+1, err)
+		}
+		if cond.Target, err = ParseTarget(cc.Target); err != nil {
+			return nil, fmt.Errorf("rule %d: condition %d: %w", cr.ID, i+1, err)
+		}
+		rule.Conditions = append(rule.Conditions, cond)
+	}
+
+	if cr.Operator == "" && (len(cr.Requires) > 0 || len(cr.Conditions) > 0) {
+		return rule, nil
+	}
+	if rule.Operator, err = ParseOperator(cr.Operator); err != nil {
+		return nil, fmt.Errorf("rule %d: %w", cr.ID, err)
+	}
+	if rule.Target, err = ParseTarget(cr.Target); err != nil {
+		return nil, fmt.Errorf("rule %d: %w", cr.ID, err)
+	}
+
+	return rule, nil
+}
+
+// RuleToConfig converts a rule into its custom_rules entry, the inverse of
+// RuleFromConfig
+func RuleToConfig(rule *Rule) config.CustomRule {
+	var conditions []config.CustomRuleCondition
+	for _, cond := range rule.Conditions {
+		conditions = append(conditions, config.CustomRuleCondition{
+			Target:          cond.Target,
+			Operator:        string(cond.Operator),
+			Pattern:         cond.Pattern,
+			Transformations: append([]string(nil), cond.Transformations...),
+		})
+	}
+
+	return config.CustomRule{
+		ID:              rule.ID,
+		Name:            rule.Name,
+		Description:     rule.Description,
+		Phase:           string(rule.Phase),
+		Operator:        string(rule.Operator),
+		Pattern:         rule.Pattern,
+		Target:          rule.Target,
+		Action:          string(rule.Action),
+		Severity:        rule.Severity,
+		Enabled:         rule.Enabled,
+		Requires:        append([]int(nil), rule.Requires...),
+		Transformations: append([]string(nil), rule.Transformations...),
+		FullBody:        rule.FullBody,
+		Conditions:      conditions,
+	}
+}
+
+// ExportRules returns every rule of the engine, built-in and custom, as
+// custom_rules entries in engine order
+func (e *Engine) ExportRules() []config.CustomRule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]config.CustomRule, len(e.rules))
+	for i, rule := range e.rules {
+		rules[i] = RuleToConfig(rule)
+	}
+	return rules
+}
+
+// ImportRules validates and compiles custom_rules entries, such as those of
+// ExportRules, and loads them into the engine. Each rule replaces the rule
+// with its ID or is added after the others. Nothing is loaded unless every
+// rule is valid. It returns how many rules were added and replaced.
+func (e *Engine) ImportRules(rules []config.CustomRule) (added, replaced int, err error) {
+	compiled := make([]*Rule, 0, len(rules))
+	seen := make(map[int]bool, len(rules))
+	for _, cr := range rules {
+		if seen[cr.ID] {
+			return 0, 0, fmt.Errorf("rule %d: duplicate rule ID", cr.ID)
+		}
+		seen[cr.ID] = true
+
+		rule, err := RuleFromConfig(cr)
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := rule.Compile(); err != nil {
+			return 0, 0, fmt.Errorf("rule %d: failed to compile rule: %w", cr.ID, err)
+		}
+		compiled = append(compiled, rule)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, rule := range compiled {
+		if e.putRule(rule) {
+			replaced++
+		} else {
+			added++
+		}
+	}
+	return added, replaced, nil
+}
+
+// ConfigEnums returns the valid values of the config file keys that hold a
+// rule field, keyed by their dotted path, for config.Schema
+func ConfigEnums() map[string][]string {
+	transforms := make([]string, 0, len(transformations))
+	for name := range transformations {
+		transforms = append(transforms, name)
+	}
+	sort.Strings(transforms)
+
+	return map[string][]string{
+		"waf.default_action":           stringValues(validActions),
+		"custom_rules.phase":           stringValues(validPhases),
+		"custom_rules.operator":        stringValues(validOperators),
+		"custom_rules.action":          stringValues(validActions),
+		"custom_rules.severity":        validSeverities,
+		"custom_rules.transformations": transforms,
+
+		"custom_rules.conditions.operator":        stringValues(validOperators),
+		"custom_rules.conditions.transformations": transforms,
+	}
+}
+
+// stringValues converts a list of string-typed values to strings
+func stringValues[T ~string](values []T) []string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = string(v)
+	}
+	return strs
+}
+
+// addCustomRules adds the custom_rules entries of the configuration. An
+// invalid rule is logged and skipped so one bad entry cannot stop startup.
+func (e *Engine) addCustomRules(rules []config.CustomRule) {
+	loaded := 0
+	for _, cr := range rules {
+		rule, err := RuleFromConfig(cr)
+		if err != nil {
+			e.logger.Warn("Skipping invalid custom rule: %v", err)
+			continue
+		}
+		if err := e.AddRule(rule); err != nil {
+			e.logger.Warn("Skipping invalid custom rule: rule %d: %v", cr.ID, err)
+			continue
+		}
+		loaded++
+	}
+	e.logger.Info("Loaded %d of %d custom rules", loaded, len(rules))
+}
+
+// LoadConfigFile applies the rule settings of a configuration file to the
+// engine. When enabled_rules is set, built-in rules not listed there are
+// disabled; custom_rules are then validated and added.
+func (e *Engine) LoadConfigFile(cf *config.ConfigFile) error {
+	if len(cf.WAF.EnabledRules) > 0 {
+		enabled := make(map[int]bool, len(cf.WAF.EnabledRules))
+		for _, id := range cf.WAF.EnabledRules {
+			enabled[id] = true
+		}
+
+		e.mu.Lock()
+		for _, rule := range e.rules {
+			rule.Enabled = enabled[rule.ID]
+		}
+		e.mu.Unlock()
+	}
+
+	for _, cr := range cf.CustomRules {
+		rule, err := RuleFromConfig(cr)
+		if err != nil {
+			return err
+		}
+		if err := e.AddRule(rule); err != nil {
+			return fmt.Errorf("rule %d: %w", cr.ID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package waf
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestCustomRulesFromConfigFile(t *testing.T) {
+	const data = `custom_rules:
+  - id: 9800
+    name: WordPress Probe
+    phase: request_uri
+    operator: regex
+    target: REQUEST_URI
+    pattern: ^/wp-(admin|login)
+    action: block
+    severity: high
+    enabled: true
+  - id: 9801
+    name: Broken Regex
+    phase: request_uri
+    operator: regex
+    target: REQUEST_URI
+    pattern: "(unclosed"
+    action: block
+    enabled: true
+  - id: 9802
+    name: Unknown Operator
+    phase: request_uri
+    operator: glob
+    target: REQUEST_URI
+    pattern: "*"
+    action: block
+    enabled: true
+`
+	path := filepath.Join(t.TempDir(), "shieldcli.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := config.LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile: %v", err)
+	}
+
+	engine := newTestEngine(t, &config.Config{CustomRules: file.CustomRules})
+
+	found := map[int]bool{}
+	for _, rule := range engine.GetRules() {
+		found[rule.ID] = true
+	}
+	if !found[9800] {
+		t.Error("custom rule 9800 not loaded")
+	}
+	if found[9801] || found[9802] {
+		t.Error("invalid custom rules loaded, want them skipped")
+	}
+	if !found[1001] {
+		t.Error("default rules missing next to custom rules")
+	}
+
+	if decision, reason := engine.Check(httptest.NewRequest("GET", "/wp-login.php", nil)); decision != DecisionBlock || reason != "Rule 9800: WordPress Probe" {
+		t.Errorf("/wp-login.php: %v (%s), want blocked by rule 9800", decision, reason)
+	}
+	if decision, _ := engine.Check(httptest.NewRequest("GET", "/blog/wp-login", nil)); decision != DecisionAllow {
+		t.Errorf("/blog/wp-login: %v, want allowed", decision)
+	}
+}
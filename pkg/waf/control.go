@@ -0,0 +1,67 @@
+package waf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ApplyControlFile reads rule toggles from a control file and applies them.
+// Each non-empty line has the form "enable <id>" or "disable <id>"; lines
+// starting with '#' are ignored. All valid lines are applied even if others
+// fail, and the errors for invalid lines are returned together.
+func (e *Engine) ApplyControlFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open control file: %w", err)
+	}
+	defer file.Close()
+
+	var errs []string
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			errs = append(errs, fmt.Sprintf("line %d: expected \"enable|disable <id>\"", lineNum))
+			continue
+		}
+
+		id, err := strconv.Atoi(fields[1])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: invalid rule ID %q", lineNum, fields[1]))
+			continue
+		}
+
+		var enabled bool
+		switch strings.ToLower(fields[0]) {
+		case "enable":
+			enabled = true
+		case "disable":
+			enabled = false
+		default:
+			errs = append(errs, fmt.Sprintf("line %d: unknown command %q", lineNum, fields[0]))
+			continue
+		}
+
+		if err := e.SetRuleEnabled(id, enabled); err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %v", lineNum, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read control file: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("control file errors: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package waf
+
+// RuleEvalResult is the outcome of evaluating one rule against an input
+type RuleEvalResult struct {
+	RuleID          int
+	RuleName        string
+	Operator        RuleOperator
+	Target          string
+	Transformations []string
+	Enabled         bool
+	Matched         bool
+	MatchedText     string // the text a regex rule matched
+}
+
+// MatchAll evaluates every rule of a phase against data, in engine order, and
+// reports each rule's outcome. Disabled rules are evaluated too, so a rule
+// that would have matched can be told apart from one that is switched off.
+// Targets are not extracted from a request: data stands for whichever part
+// of it each rule inspects, after which the rule's transformations apply.
+// A rule with conditions matches when its own operator, if it has one, and
+// every condition match data. Chained rules without an operator or
+// conditions of their own never match here, since they only combine the
+// rules they require.
+func (e *Engine) MatchAll(data string, phase RulePhase) []RuleEvalResult {
+	var results []RuleEvalResult
+	for _, rule := range e.GetRules() {
+		if rule.Phase != phase {
+			continue
+		}
+
+		e.mu.RLock()
+		evaluated := *rule
+		e.mu.RUnlock()
+
+		enabled := evaluated.Enabled
+		evaluated.Enabled = true
+
+		result := RuleEvalResult{
+			RuleID:          rule.ID,
+			RuleName:        rule.Name,
+			Operator:        rule.Operator,
+			Target:          rule.Target,
+			Transformations: rule.Transformations,
+			Enabled:         enabled,
+			Matched:         matchesData(&evaluated, data),
+		}
+		if result.Matched && evaluated.Operator != "" {
+			result.MatchedText, _ = evaluated.FindMatch(data)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// matchesData reports whether a rule's own operator and conditions all match
+// data
+func matchesData(rule *Rule, data string) bool {
+	if rule.Operator == "" && len(rule.Conditions) == 0 {
+		return false
+	}
+	if rule.Operator != "" && !rule.Match(data) {
+		return false
+	}
+	for i := range rule.Conditions {
+		if !rule.Conditions[i].Match(data) {
+			return false
+		}
+	}
+	return true
+}
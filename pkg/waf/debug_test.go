@@ -0,0 +1,42 @@
+package waf
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestMatchAll(t *testing.T) {
+	engine := newTestEngine(t, &config.Config{})
+	if err := engine.SetRuleEnabled(1004, false); err != nil {
+		t.Fatal(err)
+	}
+
+	results := engine.MatchAll("name=x; cat /etc/passwd", PhaseRequestBody)
+
+	var ids []int
+	matched := make(map[int]RuleEvalResult)
+	for _, result := range results {
+		ids = append(ids, result.RuleID)
+		if result.Matched {
+			matched[result.RuleID] = result
+		}
+	}
+	if want := []int{1001, 1002, 1004, 1006}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("evaluated rules %v, want the request_body rules %v", ids, want)
+	}
+	if len(matched) != 1 {
+		t.Fatalf("matched rules %v, want only 1004", matched)
+	}
+
+	got := matched[1004]
+	if got.Operator != OpRegex || got.Enabled || got.MatchedText != "; cat" {
+		t.Errorf("rule 1004 result %+v, want a disabled regex match of \"; cat\"", got)
+	}
+
+	uri := engine.MatchAll("/static/../../etc/passwd", PhaseRequestURI)
+	if len(uri) != 1 || uri[0].RuleID != 1003 || !uri[0].Matched || !uri[0].Enabled {
+		t.Errorf("request_uri results %+v, want a match of the enabled rule 1003", uri)
+	}
+}
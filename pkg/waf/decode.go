@@ -0,0 +1,196 @@
+package waf
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encodings understood by the layered decoder
+const (
+	EncodingURL    = "url"
+	EncodingBase64 = "base64"
+	EncodingHex    = "hex"
+	EncodingHTML   = "html"
+)
+
+// DefaultMaxDecodeLayers caps automatic decoding so crafted input cannot loop
+const DefaultMaxDecodeLayers = 10
+
+// autoDecodeOrder is the order encodings are tried in for each layer. Hex is
+// tried before base64 because every hex string is also valid base64.
+var autoDecodeOrder = []string{EncodingURL, EncodingHTML, EncodingHex, EncodingBase64}
+
+// DecodeStage is the result of removing one encoding layer
+type DecodeStage struct {
+	Encoding string
+	Output   string
+}
+
+var (
+	percentEscapePattern = regexp.MustCompile(`%[0-9a-fA-F]{2}`)
+	htmlEntityPattern    = regexp.MustCompile(`&(#[0-9]+|#[xX][0-9a-fA-F]+|[a-zA-Z]+);`)
+	hexPattern           = regexp.MustCompile(`^(0x)?([0-9a-fA-F]{2})+$`)
+	hexEscapePattern     = regexp.MustCompile(`^(\\x[0-9a-fA-F]{2})+$`)
+	base64Pattern        = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+)
+
+// Decode removes a single layer of the given encoding. It returns false when
+// the input does not look encoded that way or does not decode to text.
+func Decode(encoding, input string) (string, bool) {
+	switch encoding {
+	case EncodingURL:
+		return decodeURL(input)
+	case EncodingHTML:
+		return decodeHTML(input)
+	case EncodingHex:
+		return decodeHex(input)
+	case EncodingBase64:
+		return decodeBase64(input)
+	default:
+		return "", false
+	}
+}
+
+// DecodeLayers repeatedly removes encoding layers from input, detecting the
+// encoding of each layer, until nothing more decodes or maxLayers is reached.
+// It returns every intermediate stage; the last one holds the final payload.
+func DecodeLayers(input string, maxLayers int) []DecodeStage {
+	if maxLayers <= 0 {
+		maxLayers = DefaultMaxDecodeLayers
+	}
+
+	var stages []DecodeStage
+	current := input
+	for len(stages) < maxLayers {
+		decoded := false
+		for _, encoding := range autoDecodeOrder {
+			if output, ok := Decode(encoding, current); ok {
+				stages = append(stages, DecodeStage{Encoding: encoding, Output: output})
+				current = output
+				decoded = true
+				break
+			}
+		}
+		if !decoded {
+			break
+		}
+	}
+	return stages
+}
+
+// DecodeSequence removes the given encodings from input in order, failing on
+// the first layer that does not decode
+func DecodeSequence(input string, encodings []string) ([]DecodeStage, error) {
+	var stages []DecodeStage
+	current := input
+	for _, encoding := range encodings {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		output, ok := Decode(encoding, current)
+		if !ok {
+			if !isKnownEncoding(encoding) {
+				return stages, fmt.Errorf("unknown encoding %q (valid: %s)", encoding, strings.Join(autoDecodeOrder, ", "))
+			}
+			return stages, fmt.Errorf("layer %d is not valid %s", len(stages)+1, encoding)
+		}
+		stages = append(stages, DecodeStage{Encoding: encoding, Output: output})
+		current = output
+	}
+	return stages, nil
+}
+
+// isKnownEncoding reports whether encoding is supported by Decode
+func isKnownEncoding(encoding string) bool {
+	for _, known := range autoDecodeOrder {
+		if encoding == known {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeURL removes one layer of percent-encoding
+func decodeURL(input string) (string, bool) {
+	if !percentEscapePattern.MatchString(input) {
+		return "", false
+	}
+	output, err := url.QueryUnescape(input)
+	if err != nil {
+		// Fall back to decoding only the valid escapes
+		output = percentEscapePattern.ReplaceAllStringFunc(input, func(escape string) string {
+			b, _ := hex.DecodeString(escape[1:])
+			return string(b)
+		})
+	}
+	return output, output != input
+}
+
+// decodeHTML removes one layer of HTML entity encoding
+func decodeHTML(input string) (string, bool) {
+	if !htmlEntityPattern.MatchString(input) {
+		return "", false
+	}
+	output := html.UnescapeString(input)
+	return output, output != input
+}
+
+// decodeHex decodes a plain hex string (optionally 0x-prefixed) or \xNN escapes
+func decodeHex(input string) (string, bool) {
+	trimmed := strings.TrimSpace(input)
+	var digits string
+	switch {
+	case hexEscapePattern.MatchString(trimmed):
+		digits = strings.ReplaceAll(trimmed, `\x`, "")
+	case len(trimmed) >= 4 && hexPattern.MatchString(trimmed):
+		digits = strings.TrimPrefix(trimmed, "0x")
+	default:
+		return "", false
+	}
+
+	b, err := hex.DecodeString(digits)
+	if err != nil || !isText(b) {
+		return "", false
+	}
+	return string(b), true
+}
+
+// decodeBase64 decodes standard or URL-safe base64, padded or not
+func decodeBase64(input string) (string, bool) {
+	trimmed := strings.TrimSpace(input)
+	if len(trimmed) < 8 || !base64Pattern.MatchString(trimmed) {
+		return "", false
+	}
+
+	for _, encoding := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if b, err := encoding.DecodeString(trimmed); err == nil && isText(b) {
+			return string(b), true
+		}
+	}
+	return "", false
+}
+
+// isText reports whether b is valid UTF-8 made up almost entirely of
+// printable characters, which tells real decoded payloads apart from input
+// that merely happens to be valid hex or base64
+func isText(b []byte) bool {
+	if len(b) == 0 || !utf8.Valid(b) {
+		return false
+	}
+
+	printable, total := 0, 0
+	for _, r := range string(b) {
+		total++
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+	return float64(printable)/float64(total) >= 0.95
+}
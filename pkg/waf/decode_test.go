@@ -0,0 +1,73 @@
+package waf
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeLayers(t *testing.T) {
+	const sqli = "' OR 1=1--"
+	b64 := base64.StdEncoding.EncodeToString
+
+	tests := []struct {
+		name      string
+		input     string
+		maxLayers int
+		want      []DecodeStage
+	}{
+		{
+			name:  "base64 of url-encoded SQLi",
+			input: b64([]byte("%27%20OR%201%3D1--")),
+			want: []DecodeStage{
+				{Encoding: EncodingBase64, Output: "%27%20OR%201%3D1--"},
+				{Encoding: EncodingURL, Output: sqli},
+			},
+		},
+		{
+			name:  "hex of HTML entities",
+			input: "3c7363726970743e26233132303b",
+			want: []DecodeStage{
+				{Encoding: EncodingHex, Output: "<script>&#120;"},
+				{Encoding: EncodingHTML, Output: "<script>x"},
+			},
+		},
+		{
+			name:      "layers capped",
+			input:     b64([]byte(b64([]byte(b64([]byte(sqli)))))),
+			maxLayers: 2,
+			want: []DecodeStage{
+				{Encoding: EncodingBase64, Output: b64([]byte(b64([]byte(sqli))))},
+				{Encoding: EncodingBase64, Output: b64([]byte(sqli))},
+			},
+		},
+		{name: "plain text", input: sqli},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecodeLayers(tt.input, tt.maxLayers); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeLayers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeSequence(t *testing.T) {
+	input := base64.StdEncoding.EncodeToString([]byte("%3Cscript%3E"))
+
+	stages, err := DecodeSequence(input, []string{"Base64", " url "})
+	if err != nil {
+		t.Fatalf("DecodeSequence: %v", err)
+	}
+	if len(stages) != 2 || stages[1].Output != "<script>" {
+		t.Errorf("stages %q, want <script> after base64 and url", stages)
+	}
+
+	if _, err := DecodeSequence(input, []string{"url"}); err == nil || err.Error() != "layer 1 is not valid url" {
+		t.Errorf("error %v, want layer 1 is not valid url", err)
+	}
+	if _, err := DecodeSequence(input, []string{"rot13"}); err == nil || err.Error() != `unknown encoding "rot13" (valid: url, html, hex, base64)` {
+		t.Errorf("error %v, want unknown encoding", err)
+	}
+}
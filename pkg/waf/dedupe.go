@@ -0,0 +1,88 @@
+package waf
+
+import (
+	"net/http"
+	"regexp/syntax"
+	"sort"
+	"strings"
+)
+
+// DuplicateRule is a rule that matches exactly what a rule with a lower ID
+// matches
+type DuplicateRule struct {
+	Rule        *Rule
+	DuplicateOf *Rule
+}
+
+// FindDuplicateRules groups rules by their phase and normalized operator,
+// target, transformations and pattern and returns every rule but the lowest
+// ID of each group, ordered by ID. Regex patterns are compared after
+// simplification, so e.g. "[0-9]+" and "\d+" are duplicates. Chained rules
+// and rules another rule requires are left out, since removing them changes
+// what the chain matches.
+func FindDuplicateRules(rules []*Rule) []DuplicateRule {
+	required := make(map[int]bool)
+	for _, rule := range rules {
+		for _, id := range rule.Requires {
+			required[id] = true
+		}
+	}
+
+	sorted := make([]*Rule, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule.Requires) == 0 && !required[rule.ID] {
+			sorted = append(sorted, rule)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	var duplicates []DuplicateRule
+	kept := make(map[string]*Rule)
+	for _, rule := range sorted {
+		key := matchKey(rule)
+		if first, ok := kept[key]; ok {
+			duplicates = append(duplicates, DuplicateRule{Rule: rule, DuplicateOf: first})
+			continue
+		}
+		kept[key] = rule
+	}
+	return duplicates
+}
+
+// matchKey normalizes what a rule matches into a comparable key. Conditions
+// are sorted, since their order does not change what a rule matches.
+func matchKey(rule *Rule) string {
+	key := conditionKey(rule.Target, rule.Operator, rule.Transformations, rule.Pattern)
+
+	conditions := make([]string, len(rule.Conditions))
+	for i, cond := range rule.Conditions {
+		conditions[i] = conditionKey(cond.Target, cond.Operator, cond.Transformations, cond.Pattern)
+	}
+	sort.Strings(conditions)
+
+	return strings.Join(append([]string{string(rule.Phase), key}, conditions...), "\x00")
+}
+
+// conditionKey normalizes a target, operator and pattern into a comparable key
+func conditionKey(target string, operator RuleOperator, transformations []string, pattern string) string {
+	if name, header, ok := strings.Cut(target, ":"); ok {
+		target = name + ":" + http.CanonicalHeaderKey(header)
+	}
+
+	switch operator {
+	case OpRegex, OpNotRegex:
+		if re, err := syntax.Parse(pattern, syntax.Perl); err == nil {
+			pattern = re.Simplify().String()
+		}
+	case OpHighEntropy, OpSQLi, OpXSS:
+		// These operators ignore the pattern
+		pattern = ""
+	}
+
+	return strings.Join([]string{
+		string(operator),
+		target,
+		strings.Join(transformations, ","),
+		pattern,
+	}, "\x01")
+}
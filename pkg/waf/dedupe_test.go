@@ -0,0 +1,36 @@
+package waf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicateRules(t *testing.T) {
+	rule := func(id int, operator RuleOperator, target, pattern string) *Rule {
+		return &Rule{ID: id, Phase: PhaseRequestURI, Operator: operator, Target: target, Pattern: pattern}
+	}
+
+	chained := rule(9106, OpContains, "REQUEST_URI", "/admin")
+	chained.Requires = []int{9107}
+
+	rules := []*Rule{
+		rule(9101, OpContains, "REQUEST_URI", "/admin"),
+		rule(9100, OpContains, "REQUEST_URI", "/admin"),
+		rule(9102, OpContains, "ARGS", "/admin"),
+		rule(9103, OpRegex, "REQUEST_HEADERS:x-api-key", `[0-9]+`),
+		rule(9104, OpRegex, "REQUEST_HEADERS:X-Api-Key", `\d+`),
+		rule(9105, OpSQLi, "ARGS", "ignored"),
+		rule(9108, OpSQLi, "ARGS", ""),
+		chained,
+		rule(9107, OpContains, "REQUEST_URI", "/admin"),
+	}
+
+	got := make(map[int]int)
+	for _, d := range FindDuplicateRules(rules) {
+		got[d.Rule.ID] = d.DuplicateOf.ID
+	}
+	want := map[int]int{9101: 9100, 9104: 9103, 9108: 9105}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("duplicates %v, want %v", got, want)
+	}
+}
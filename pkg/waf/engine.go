@@ -2,11 +2,15 @@ package waf
 
 import (
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/shieldcli/shieldcli/pkg/config"
 	"github.com/shieldcli/shieldcli/pkg/logging"
+	"github.com/shieldcli/shieldcli/pkg/netutil"
 )
 
 // Decision represents the WAF decision
@@ -18,11 +22,72 @@ const (
 	DecisionLog
 )
 
+// String returns the lowercase name of the decision
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "allow"
+	case DecisionBlock:
+		return "block"
+	case DecisionLog:
+		return "log"
+	default:
+		return "unknown"
+	}
+}
+
+// RuleEvaluation records the outcome of evaluating a single rule
+type RuleEvaluation struct {
+	RuleID   int
+	RuleName string
+	Phase    RulePhase
+	Action   RuleAction
+	Severity string
+	Matched  bool
+
+	// UnmetRequires lists required rules that did not match, which kept a
+	// chained rule from matching even though its own condition did
+	UnmetRequires []int
+}
+
+// CheckResult contains the decision for a request along with every rule evaluation
+type CheckResult struct {
+	Decision    Decision
+	Reason      string
+	Severity    string // effective severity of the rule that blocked the request
+	Evaluations []RuleEvaluation
+}
+
+// MatchedRules returns the evaluations that matched the request
+func (cr *CheckResult) MatchedRules() []RuleEvaluation {
+	var matched []RuleEvaluation
+	for _, eval := range cr.Evaluations {
+		if eval.Matched {
+			matched = append(matched, eval)
+		}
+	}
+	return matched
+}
+
 // Engine represents the custom WAF engine
 type Engine struct {
+	mu     sync.RWMutex
 	config *config.Config
 	logger *logging.Logger
 	rules  []*Rule
+
+	hasChains      bool         // true once any rule with Requires is loaded
+	ipFilter       *IPFilter    // checked before any rule; nil when no IP lists are configured
+	trustedProxies []*net.IPNet // peers whose X-Forwarded-For gives the client IP
+
+	severityBoosts []*severityBoost // contexts that raise the severity of matches
+
+	// Cumulative match counts, guarded separately so checks holding the
+	// read lock can record hits
+	hitsMu      sync.Mutex
+	hits        map[int]*RuleHits
+	hitsSince   time.Time
+	sessionHits map[int]int64
 }
 
 // NewEngine creates a new WAF engine
@@ -31,11 +96,39 @@ func NewEngine(cfg *config.Config, logger *logging.Logger) (*Engine, error) {
 		config: cfg,
 		logger: logger,
 		rules:  make([]*Rule, 0),
+
+		hits:        make(map[int]*RuleHits),
+		hitsSince:   time.Now(),
+		sessionHits: make(map[int]int64),
 	}
 
+	trusted, err := netutil.ParseCIDRs(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxy: %w", err)
+	}
+	engine.trustedProxies = trusted
+
+	if len(cfg.IPAllowlist) > 0 || len(cfg.IPBlocklist) > 0 {
+		filter, err := NewIPFilter(cfg.IPAllowlist, cfg.IPBlocklist, trusted)
+		if err != nil {
+			return nil, err
+		}
+		engine.ipFilter = filter
+	}
+
+	boosts, err := newSeverityBoosts(cfg.SeverityBoosts)
+	if err != nil {
+		return nil, err
+	}
+	engine.severityBoosts = boosts
+
 	// Add default OWASP-style rules
 	engine.addDefaultRules()
 
+	if len(cfg.CustomRules) > 0 {
+		engine.addCustomRules(cfg.CustomRules)
+	}
+
 	return engine, nil
 }
 
@@ -130,96 +223,278 @@ func (e *Engine) addDefaultRules() {
 	e.logger.Debug("Loaded %d default WAF rules", len(e.rules))
 }
 
-// AddRule adds a custom rule to the engine
+// AddRule adds a custom rule to the engine. A rule with the ID of a rule
+// already loaded replaces it in place, so a custom rule can redefine a
+// built-in one.
 func (e *Engine) AddRule(rule *Rule) error {
 	if err := rule.Compile(); err != nil {
 		return fmt.Errorf("failed to compile rule: %w", err)
 	}
-	e.rules = append(e.rules, rule)
+	e.mu.Lock()
+	e.putRule(rule)
+	e.mu.Unlock()
 	e.logger.Debug("Added custom rule: %s (ID: %d)", rule.Name, rule.ID)
 	return nil
 }
 
+// putRule adds a compiled rule or replaces the rule with its ID, and reports
+// whether one was replaced. Callers must hold e.mu for writing.
+func (e *Engine) putRule(rule *Rule) bool {
+	if len(rule.Requires) > 0 {
+		e.hasChains = true
+	}
+	for i, existing := range e.rules {
+		if existing.ID == rule.ID {
+			e.rules[i] = rule
+			return true
+		}
+	}
+	e.rules = append(e.rules, rule)
+	return false
+}
+
+// requestPhases lists the request phases in evaluation order
+var requestPhases = []RulePhase{PhaseRequestHeaders, PhaseRequestURI, PhaseRequestBody}
+
 // Check checks an HTTP request against all WAF rules
 func (e *Engine) Check(r *http.Request) (Decision, string) {
-	// Check request headers phase rules
-	for _, rule := range e.rules {
-		if rule.Phase != PhaseRequestHeaders {
-			continue
+	decision, reason, _ := e.CheckWithSeverity(r)
+	return decision, reason
+}
+
+// CheckWithSeverity is Check that also returns the effective severity of the
+// rule that blocked the request, raised by any severity boosts that apply.
+// The severity is empty unless a rule blocked the request.
+func (e *Engine) CheckWithSeverity(r *http.Request) (Decision, string, string) {
+	if e.ipFilter != nil {
+		if decision, reason, ok := e.ipFilter.Check(r); ok {
+			return decision, reason, ""
 		}
+	}
 
-		if e.checkRule(rule, r) {
-			if rule.Action == ActionBlock {
-				return DecisionBlock, fmt.Sprintf("Rule %d: %s", rule.ID, rule.Name)
+	body := e.requestBody(r)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	// Chained rules depend on the matches of other rules, possibly in later
+	// phases, so every rule has to be evaluated before deciding
+	if e.hasChains || len(e.severityBoosts) > 0 {
+		result := e.evaluate(r, body)
+		return result.Decision, result.Reason, result.Severity
+	}
+
+	if e.config.ParallelEvaluation {
+		return e.checkParallel(r, body)
+	}
+
+	for _, phase := range requestPhases {
+		for _, rule := range e.rules {
+			if rule.Phase != phase {
+				continue
+			}
+
+			if e.checkRule(rule, r, body) {
+				e.recordHit(rule.ID)
+				if rule.Action == ActionBlock {
+					return DecisionBlock, fmt.Sprintf("Rule %d: %s", rule.ID, rule.Name), rule.Severity
+				}
 			}
 		}
 	}
 
-	// Check request URI phase rules
+	return DecisionAllow, "", ""
+}
+
+// CheckDetailed checks an HTTP request against all WAF rules and records
+// the outcome of every rule evaluation. Unlike Check it does not stop at the
+// first blocking rule, but the resulting decision is the same.
+func (e *Engine) CheckDetailed(r *http.Request) *CheckResult {
+	if e.ipFilter != nil {
+		if decision, reason, ok := e.ipFilter.Check(r); ok {
+			return &CheckResult{Decision: decision, Reason: reason}
+		}
+	}
+
+	body := e.requestBody(r)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.evaluate(r, body)
+}
+
+// requestBody returns the request body for body-target rules. It reads from
+// the client, so callers must not hold e.mu: a slow upload would keep rule
+// updates waiting, and every request queued behind them.
+func (e *Engine) requestBody(r *http.Request) string {
+	body, err := e.readBody(r)
+	if err != nil {
+		e.logger.Warn("Failed to read request body, inspecting %d bytes read: %v", len(body), err)
+	}
+	return string(body)
+}
+
+// ReadRequestBody reads the part of the request body the rules inspect: the
+// first BodyInspectionWindow bytes when a window is configured, else all of
+// it. The body stays readable for forwarding.
+func (e *Engine) ReadRequestBody(r *http.Request) ([]byte, error) {
+	return e.readBody(r)
+}
+
+// readBody streams the body past the inspection window unless an enabled
+// rule needs the full body, in which case it is buffered. The lock is only
+// held to look at the rules, not while reading.
+func (e *Engine) readBody(r *http.Request) ([]byte, error) {
+	window := e.inspectionWindow()
+	if window <= 0 {
+		return BufferRequestBody(r)
+	}
+	return PeekRequestBody(r, window)
+}
+
+// inspectionWindow returns how much of a request body the rules inspect, or
+// 0 to buffer all of it
+func (e *Engine) inspectionWindow() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	window := e.config.BodyInspectionWindow
+	if window <= 0 {
+		return 0
+	}
 	for _, rule := range e.rules {
-		if rule.Phase != PhaseRequestURI {
-			continue
+		if rule.Enabled && rule.FullBody && rule.inspects("REQUEST_BODY") {
+			return 0
 		}
+	}
+	return int64(window)
+}
 
-		if e.checkRule(rule, r) {
-			if rule.Action == ActionBlock {
-				return DecisionBlock, fmt.Sprintf("Rule %d: %s", rule.ID, rule.Name)
+// evaluate runs every enabled rule against the request, then resolves rule
+// chains and picks the first blocking rule in phase order. Callers must hold e.mu.
+func (e *Engine) evaluate(r *http.Request, body string) *CheckResult {
+	result := &CheckResult{
+		Decision:    DecisionAllow,
+		Evaluations: make([]RuleEvaluation, 0, len(e.rules)),
+	}
+
+	var ordered []*Rule
+	matched := make(map[int]bool)
+	for _, phase := range requestPhases {
+		for _, rule := range e.rules {
+			if rule.Phase != phase || !rule.Enabled {
+				continue
+			}
+
+			ordered = append(ordered, rule)
+			if e.checkRule(rule, r, body) {
+				matched[rule.ID] = true
 			}
 		}
 	}
 
-	// Check request body phase rules
-	for _, rule := range e.rules {
-		if rule.Phase != PhaseRequestBody {
-			continue
+	for _, rule := range ordered {
+		eval := RuleEvaluation{
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			Phase:    rule.Phase,
+			Action:   rule.Action,
+			Severity: rule.Severity,
+			Matched:  matched[rule.ID],
 		}
 
-		if e.checkRule(rule, r) {
-			if rule.Action == ActionBlock {
-				return DecisionBlock, fmt.Sprintf("Rule %d: %s", rule.ID, rule.Name)
+		// A chained rule only counts as matched when every rule it requires matched too
+		if eval.Matched && len(rule.Requires) > 0 {
+			for _, id := range rule.Requires {
+				if !matched[id] {
+					eval.Matched = false
+					eval.UnmetRequires = append(eval.UnmetRequires, id)
+				}
 			}
 		}
+		result.Evaluations = append(result.Evaluations, eval)
+		if eval.Matched {
+			e.recordHit(rule.ID)
+		}
+
+		if eval.Matched && rule.Action == ActionBlock && result.Decision != DecisionBlock {
+			result.Decision = DecisionBlock
+			result.Reason = fmt.Sprintf("Rule %d: %s", rule.ID, rule.Name)
+			result.Severity = rule.Severity
+		}
 	}
 
-	return DecisionAllow, ""
+	if len(e.severityBoosts) > 0 {
+		e.applySeverityBoosts(r, result)
+	}
+
+	return result
 }
 
-// checkRule checks if a rule matches the request
-func (e *Engine) checkRule(rule *Rule, r *http.Request) bool {
+// checkRule checks if a rule matches the request and its buffered body. A
+// rule with conditions matches only when its own operator, if it has one,
+// and every condition match.
+func (e *Engine) checkRule(rule *Rule, r *http.Request, body string) bool {
 	if !rule.Enabled {
 		return false
 	}
 
+	// A chained rule without an operator of its own is a pure combination of
+	// the rules it requires
+	if rule.Operator == "" && len(rule.Conditions) == 0 {
+		return len(rule.Requires) > 0
+	}
+
+	if rule.Operator != "" && !e.matchRequestTarget(rule.ID, rule.Target, r, body, rule.Match) {
+		return false
+	}
+	for i := range rule.Conditions {
+		if !e.matchRequestTarget(rule.ID, rule.Conditions[i].Target, r, body, rule.Conditions[i].Match) {
+			return false
+		}
+	}
+
+	e.logger.Debug("Rule %d matched: %s", rule.ID, rule.Name)
+	return true
+}
+
+// matchRequestTarget extracts a target from the request and its buffered
+// body and reports whether match accepts it. Targets that hold several
+// values match when any of them does.
+func (e *Engine) matchRequestTarget(ruleID int, target string, r *http.Request, body string, match func(string) bool) bool {
 	var data string
 
 	// Extract data based on target
 	switch {
-	case rule.Target == "REQUEST_URI":
+	case target == "REQUEST_URI":
 		data = r.RequestURI
-	case rule.Target == "REQUEST_BODY":
-		// For now, we'll skip body checking in this phase
-		// This will be enhanced later
-		return false
-	case strings.HasPrefix(rule.Target, "REQUEST_HEADERS:"):
-		headerName := strings.TrimPrefix(rule.Target, "REQUEST_HEADERS:")
+	case target == "REQUEST_BODY":
+		data = body
+	case target == "QUERY_STRING":
+		// The raw query verbatim, so malformed queries that the parser
+		// mangles or drops can still be matched
+		data = r.URL.RawQuery
+	case strings.HasPrefix(target, "REQUEST_HEADERS:"):
+		headerName := strings.TrimPrefix(target, "REQUEST_HEADERS:")
 		data = r.Header.Get(headerName)
-	case rule.Target == "REQUEST_HEADERS":
+	case target == "REQUEST_HEADERS":
 		// Check all headers
 		for name, values := range r.Header {
 			for _, value := range values {
-				if rule.Match(value) {
-					e.logger.Debug("Rule %d matched in header %s", rule.ID, name)
+				if match(value) {
+					e.logger.Debug("Rule %d matched in header %s", ruleID, name)
 					return true
 				}
 			}
 		}
 		return false
-	case rule.Target == "ARGS":
+	case target == "ARGS":
 		// Check query parameters
 		for key, values := range r.URL.Query() {
 			for _, value := range values {
-				if rule.Match(value) {
-					e.logger.Debug("Rule %d matched in argument %s", rule.ID, key)
+				if match(value) {
+					e.logger.Debug("Rule %d matched in argument %s", ruleID, key)
 					return true
 				}
 			}
@@ -229,15 +504,38 @@ func (e *Engine) checkRule(rule *Rule, r *http.Request) bool {
 		return false
 	}
 
-	if data != "" && rule.Match(data) {
-		e.logger.Debug("Rule %d matched: %s", rule.ID, rule.Name)
-		return true
-	}
-
-	return false
+	return data != "" && match(data)
 }
 
 // GetRules returns all rules in the engine
 func (e *Engine) GetRules() []*Rule {
-	return e.rules
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	rules := make([]*Rule, len(e.rules))
+	copy(rules, e.rules)
+	return rules
+}
+
+// SetRuleEnabled enables or disables a single rule at runtime. It is safe to
+// call while requests are being checked; the change applies to the next check.
+func (e *Engine) SetRuleEnabled(id int, enabled bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, rule := range e.rules {
+		if rule.ID == id {
+			if rule.Enabled != enabled {
+				rule.Enabled = enabled
+				state := "disabled"
+				if enabled {
+					state = "enabled"
+				}
+				e.logger.Warn("Rule %d (%s) %s at runtime", rule.ID, rule.Name, state)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("rule %d not found", id)
 }
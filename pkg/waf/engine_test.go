@@ -0,0 +1,240 @@
+package waf
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// newTestEngine returns an engine with the built-in rules and those of cfg
+func newTestEngine(t *testing.T, cfg *config.Config) *Engine {
+	t.Helper()
+	engine, err := NewEngine(cfg, logging.NewLogger(""))
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	return engine
+}
+
+func TestCheckRequestBody(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          config.Config
+		body         io.Reader // nil sends no body
+		wantDecision Decision
+		wantRule     string
+	}{
+		{name: "SQL injection", body: strings.NewReader("username=admin' OR 1=1--"), wantDecision: DecisionBlock, wantRule: "Rule 1001:"},
+		{name: "XSS", body: strings.NewReader(`comment=<script>alert(1)</script>`), wantDecision: DecisionBlock, wantRule: "Rule 1002:"},
+		{name: "entity-encoded XSS", body: strings.NewReader("comment=&#x3c;script&#x3e;alert(1)&#x3c;/script&#x3e;"), wantDecision: DecisionBlock, wantRule: "Rule 1002:"},
+		{name: "benign HTML", body: strings.NewReader(`comment=<img alt="svg diagram" src="chart.png">`), wantDecision: DecisionAllow},
+		{name: "command injection", body: strings.NewReader("host=example.com; cat /etc/passwd"), wantDecision: DecisionBlock, wantRule: "Rule 1004:"},
+		{name: "benign form", body: strings.NewReader("username=alice&remember=1"), wantDecision: DecisionAllow},
+		{name: "empty body", body: strings.NewReader(""), wantDecision: DecisionAllow},
+		{name: "nil body", wantDecision: DecisionAllow},
+		{
+			name:         "attack within the inspection window",
+			cfg:          config.Config{BodyInspectionWindow: 64},
+			body:         strings.NewReader("q=' OR 1=1--" + strings.Repeat("a", 1024)),
+			wantDecision: DecisionBlock,
+			wantRule:     "Rule 1001:",
+		},
+		{name: "parallel evaluation", cfg: config.Config{ParallelEvaluation: true}, body: strings.NewReader("q=' OR 1=1--"), wantDecision: DecisionBlock, wantRule: "Rule 1001:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			engine := newTestEngine(t, &cfg)
+
+			r := httptest.NewRequest("POST", "/login", tt.body)
+			var sent string
+			if tt.body == nil {
+				r.Body = nil
+			} else {
+				sent = readAll(t, r.Body)
+				r.Body = io.NopCloser(strings.NewReader(sent))
+			}
+
+			decision, reason := engine.Check(r)
+			if decision != tt.wantDecision || !strings.HasPrefix(reason, tt.wantRule) {
+				t.Errorf("Check = (%v, %q), want (%v, %q...)", decision, reason, tt.wantDecision, tt.wantRule)
+			}
+
+			// The upstream must still get the whole body
+			if r.Body != nil && r.Body != http.NoBody {
+				if got := readAll(t, r.Body); got != sent {
+					t.Errorf("body after Check %q, want %q", got, sent)
+				}
+			}
+		})
+	}
+}
+
+func TestSlowBodyDoesNotHoldRules(t *testing.T) {
+	engine := newTestEngine(t, &config.Config{BodyInspectionWindow: 64})
+
+	// A client that has sent nothing of its body yet
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	r := httptest.NewRequest("POST", "/upload", pr)
+	checked := make(chan Decision)
+	go func() {
+		decision, _ := engine.Check(r)
+		checked <- decision
+	}()
+
+	updated := make(chan error)
+	go func() { updated <- engine.SetRuleEnabled(1006, false) }()
+	select {
+	case err := <-updated:
+		if err != nil {
+			t.Fatalf("SetRuleEnabled: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SetRuleEnabled waited for a request body")
+	}
+
+	pw.Write([]byte("q=' OR 1=1--"))
+	pw.Close()
+	if decision := <-checked; decision != DecisionBlock {
+		t.Errorf("Check = %v, want block", decision)
+	}
+}
+
+// readAll reads a body to the end, failing the test on error
+func readAll(t *testing.T, body io.Reader) string {
+	t.Helper()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestQueryStringTarget(t *testing.T) {
+	// The semicolon makes the parser drop the pair, so ARGS never sees it
+	const target = "/items?id=1;DROP%20TABLE%20users&&page"
+
+	tests := []struct {
+		name         string
+		ruleTarget   string
+		wantDecision Decision
+	}{
+		{name: "QUERY_STRING", ruleTarget: "QUERY_STRING", wantDecision: DecisionBlock},
+		{name: "ARGS", ruleTarget: "ARGS", wantDecision: DecisionAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine(t, &config.Config{CustomRules: []config.CustomRule{{
+				ID: 9900, Name: "Stacked Query", Phase: "request_uri", Operator: "regex", Target: tt.ruleTarget,
+				Pattern: `(?i);\s*(%20)*drop`, Action: "block", Severity: "critical", Enabled: true,
+			}}})
+
+			r := httptest.NewRequest("GET", target, nil)
+			if _, ok := r.URL.Query()["id"]; ok {
+				t.Fatal("query parser surfaced the malformed pair")
+			}
+			if decision, reason := engine.Check(r); decision != tt.wantDecision {
+				t.Errorf("decision %v (%s), want %v", decision, reason, tt.wantDecision)
+			}
+		})
+	}
+}
+
+func TestRuleConditions(t *testing.T) {
+	rules := []config.CustomRule{
+		{
+			ID: 9910, Name: "Debug Admin Access", Phase: "request_uri", Operator: "startswith", Target: "REQUEST_URI",
+			Pattern: "/admin", Action: "block", Severity: "high", Enabled: true,
+			Conditions: []config.CustomRuleCondition{
+				{Target: "REQUEST_HEADERS:X-Debug", Operator: "equals", Pattern: "1"},
+			},
+		},
+		{
+			// Conditions only, no operator of its own
+			ID: 9911, Name: "Scripted Export", Phase: "request_uri", Target: "REQUEST_URI", Action: "block", Severity: "high", Enabled: true,
+			Conditions: []config.CustomRuleCondition{
+				{Target: "REQUEST_URI", Operator: "contains", Pattern: "/export"},
+				{Target: "REQUEST_HEADERS:User-Agent", Operator: "contains", Pattern: "python-requests", Transformations: []string{"lowercase"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		target       string
+		headers      map[string]string
+		wantDecision Decision
+		wantReason   string
+	}{
+		{name: "URI and header", target: "/admin/users", headers: map[string]string{"X-Debug": "1"}, wantDecision: DecisionBlock, wantReason: "Rule 9910: Debug Admin Access"},
+		{name: "URI only", target: "/admin/users", wantDecision: DecisionAllow},
+		{name: "header only", target: "/shop", headers: map[string]string{"X-Debug": "1"}, wantDecision: DecisionAllow},
+		{name: "header with another value", target: "/admin/users", headers: map[string]string{"X-Debug": "0"}, wantDecision: DecisionAllow},
+		{name: "all conditions", target: "/reports/export", headers: map[string]string{"User-Agent": "Python-Requests/2.31"}, wantDecision: DecisionBlock, wantReason: "Rule 9911: Scripted Export"},
+		{name: "one of two conditions", target: "/reports/export", headers: map[string]string{"User-Agent": "Mozilla/5.0"}, wantDecision: DecisionAllow},
+	}
+
+	engine := newTestEngine(t, &config.Config{CustomRules: rules})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.target, nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			decision, reason := engine.Check(r)
+			if decision != tt.wantDecision || (tt.wantReason != "" && reason != tt.wantReason) {
+				t.Errorf("decision %v (%s), want %v (%s)", decision, reason, tt.wantDecision, tt.wantReason)
+			}
+		})
+	}
+}
+
+// BenchmarkCheck checks a benign and a malicious request against the
+// default rule set
+func BenchmarkCheck(b *testing.B) {
+	engine, err := NewEngine(&config.Config{}, logging.NewLogger(""))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	// Rule matches are logged to stdout; keep them out of the results
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = devNull
+	b.Cleanup(func() {
+		os.Stdout = stdout
+		devNull.Close()
+	})
+
+	requests := map[string]func() *http.Request{
+		"benign": func() *http.Request {
+			r := httptest.NewRequest("POST", "/search?q=shoes&page=2", strings.NewReader("query=running+shoes&size=42"))
+			r.Header.Set("User-Agent", "Mozilla/5.0")
+			return r
+		},
+		"malicious": func() *http.Request {
+			return httptest.NewRequest("POST", "/login", strings.NewReader("username=admin' OR 1=1--&password=x"))
+		},
+	}
+
+	for _, name := range []string{"benign", "malicious"} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				engine.Check(requests[name]())
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package waf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RuleHits holds the cumulative match count of a rule
+type RuleHits struct {
+	Count       int64     `json:"count"`
+	LastMatched time.Time `json:"last_matched,omitempty"`
+}
+
+// ruleHitsState is the on-disk format of the hit counter state file
+type ruleHitsState struct {
+	Since time.Time         `json:"since"`
+	Rules map[int]*RuleHits `json:"rules"`
+}
+
+// recordHit counts a match of a rule
+func (e *Engine) recordHit(id int) {
+	e.hitsMu.Lock()
+	defer e.hitsMu.Unlock()
+
+	hits, ok := e.hits[id]
+	if !ok {
+		hits = &RuleHits{}
+		e.hits[id] = hits
+	}
+	hits.Count++
+	hits.LastMatched = time.Now()
+	e.sessionHits[id]++
+}
+
+// GetRuleStats returns how often each rule matched since the engine was
+// created. Unlike RuleHits these counts start at zero every session.
+func (e *Engine) GetRuleStats() map[int]int64 {
+	e.hitsMu.Lock()
+	defer e.hitsMu.Unlock()
+
+	stats := make(map[int]int64, len(e.sessionHits))
+	for id, count := range e.sessionHits {
+		stats[id] = count
+	}
+	return stats
+}
+
+// ResetSessionStats zeroes the session match counts of GetRuleStats. The
+// rules, their enabled state and the cumulative counts of RuleHits are kept.
+func (e *Engine) ResetSessionStats() {
+	e.hitsMu.Lock()
+	defer e.hitsMu.Unlock()
+
+	e.sessionHits = make(map[int]int64)
+}
+
+// RuleHits returns the cumulative match counts of every rule that has matched
+// along with the time counting started
+func (e *Engine) RuleHits() (map[int]RuleHits, time.Time) {
+	e.hitsMu.Lock()
+	defer e.hitsMu.Unlock()
+
+	hits := make(map[int]RuleHits, len(e.hits))
+	for id, h := range e.hits {
+		hits[id] = *h
+	}
+	return hits, e.hitsSince
+}
+
+// ColdRules returns the IDs of rules that matched at most maxHits times and not
+// at all within idle. Nothing is reported until counts have been collected for
+// at least idle, since a rule cannot be judged cold on a short history.
+func (e *Engine) ColdRules(maxHits int64, idle time.Duration) []int {
+	hits, since := e.RuleHits()
+	now := time.Now()
+	if now.Sub(since) < idle {
+		return nil
+	}
+
+	var cold []int
+	for _, rule := range e.GetRules() {
+		h := hits[rule.ID]
+		if h.Count <= maxHits && now.Sub(h.LastMatched) >= idle {
+			cold = append(cold, rule.ID)
+		}
+	}
+	sort.Ints(cold)
+	return cold
+}
+
+// LoadRuleHits restores hit counts from a state file written by SaveRuleHits.
+// A missing file is not an error; counting then starts from now.
+func (e *Engine) LoadRuleHits(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read hits file: %w", err)
+	}
+
+	var state ruleHitsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse hits file: %w", err)
+	}
+
+	e.hitsMu.Lock()
+	defer e.hitsMu.Unlock()
+
+	if !state.Since.IsZero() {
+		e.hitsSince = state.Since
+	}
+	e.hits = make(map[int]*RuleHits, len(state.Rules))
+	for id, h := range state.Rules {
+		if h != nil {
+			e.hits[id] = h
+		}
+	}
+	return nil
+}
+
+// SaveRuleHits writes the hit counts to a state file. The file is replaced
+// atomically so a crash mid-write does not lose the previous counts.
+func (e *Engine) SaveRuleHits(path string) error {
+	hits, since := e.RuleHits()
+	state := ruleHitsState{Since: since, Rules: make(map[int]*RuleHits, len(hits))}
+	for id, h := range hits {
+		h := h
+		state.Rules[id] = &h
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal hits: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write hits file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write hits file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write hits file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write hits file: %w", err)
+	}
+	return nil
+}
+
+// PersistRuleHits saves the hit counts to path every interval until stop is
+// closed, and once more on the way out
+func (e *Engine) PersistRuleHits(path string, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.SaveRuleHits(path); err != nil {
+				e.logger.Error("Failed to persist rule hits: %v", err)
+			}
+		case <-stop:
+			if err := e.SaveRuleHits(path); err != nil {
+				e.logger.Error("Failed to persist rule hits: %v", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package waf
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+// sendAttacks sends n requests that match the built-in SQL injection rule
+func sendAttacks(engine *Engine, n int) {
+	for i := 0; i < n; i++ {
+		engine.Check(httptest.NewRequest("POST", "/login", strings.NewReader("q=' OR 1=1--")))
+	}
+}
+
+func TestRuleHitsPersistAcrossRestart(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after int // attacks before and after the restart
+		want          int64
+	}{
+		{name: "counts survive a restart", before: 3, want: 3},
+		{name: "counting resumes after a restart", before: 2, after: 3, want: 5},
+		{name: "no hits", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hits.json")
+
+			first := newTestEngine(t, &config.Config{})
+			sendAttacks(first, tt.before)
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				first.PersistRuleHits(path, time.Hour, stop)
+				close(done)
+			}()
+			// Shutting down saves the counts once more
+			close(stop)
+			<-done
+			_, since := first.RuleHits()
+
+			second := newTestEngine(t, &config.Config{})
+			if err := second.LoadRuleHits(path); err != nil {
+				t.Fatalf("LoadRuleHits: %v", err)
+			}
+			sendAttacks(second, tt.after)
+
+			hits, gotSince := second.RuleHits()
+			if got := hits[1001].Count; got != tt.want {
+				t.Errorf("rule 1001 hits %d, want %d", got, tt.want)
+			}
+			if !gotSince.Equal(since) {
+				t.Errorf("counting since %v, want %v from before the restart", gotSince, since)
+			}
+			if stats := second.GetRuleStats()[1001]; stats != int64(tt.after) {
+				t.Errorf("session hits %d, want %d", stats, tt.after)
+			}
+		})
+	}
+}
+
+func TestLoadRuleHitsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string // file contents, or "" for no file
+		wantErr bool
+	}{
+		{name: "missing file starts from zero"},
+		{name: "corrupt file", data: "{not json", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hits.json")
+			if tt.data != "" {
+				if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := newTestEngine(t, &config.Config{}).LoadRuleHits(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadRuleHits error %v, want error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestColdRules(t *testing.T) {
+	now := time.Now()
+	month := 30 * 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		since    time.Time
+		hits     string // rules entry of the hits file
+		wantCold bool   // whether rule 1001 is cold
+	}{
+		{name: "never matched", since: now.Add(-2 * month), hits: `{}`, wantCold: true},
+		{name: "few old matches", since: now.Add(-2 * month), hits: `{"1001": {"count": 2, "last_matched": "` + now.Add(-6*7*24*time.Hour).Format(time.RFC3339) + `"}}`, wantCold: true},
+		{name: "recent match", since: now.Add(-2 * month), hits: `{"1001": {"count": 1, "last_matched": "` + now.Add(-time.Hour).Format(time.RFC3339) + `"}}`},
+		{name: "many old matches", since: now.Add(-2 * month), hits: `{"1001": {"count": 50, "last_matched": "` + now.Add(-6*7*24*time.Hour).Format(time.RFC3339) + `"}}`},
+		{name: "history too short to judge", since: now.Add(-time.Hour), hits: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hits.json")
+			data := `{"since": "` + tt.since.Format(time.RFC3339) + `", "rules": ` + tt.hits + `}`
+			if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			engine := newTestEngine(t, &config.Config{})
+			if err := engine.LoadRuleHits(path); err != nil {
+				t.Fatal(err)
+			}
+
+			cold := false
+			for _, id := range engine.ColdRules(3, month) {
+				cold = cold || id == 1001
+			}
+			if cold != tt.wantCold {
+				t.Errorf("rule 1001 cold: %t, want %t", cold, tt.wantCold)
+			}
+		})
+	}
+}
+
+func TestGetRuleStats(t *testing.T) {
+	engine := newTestEngine(t, &config.Config{})
+	sendAttacks(engine, 3)
+	for i := 0; i < 2; i++ {
+		engine.Check(httptest.NewRequest("GET", "/files/../../etc/passwd", nil))
+		engine.Check(httptest.NewRequest("GET", "/products?page=2", nil))
+	}
+
+	want := map[int]int64{1001: 3, 1003: 2}
+	if got := engine.GetRuleStats(); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetRuleStats = %v, want %v", got, want)
+	}
+
+	engine.ResetSessionStats()
+	if got := engine.GetRuleStats(); len(got) != 0 {
+		t.Errorf("GetRuleStats after reset = %v, want none", got)
+	}
+	if hits, _ := engine.RuleHits(); hits[1001].Count != 3 {
+		t.Errorf("cumulative rule 1001 hits %d after a session reset, want 3", hits[1001].Count)
+	}
+}
@@ -0,0 +1,51 @@
+package waf
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/shieldcli/shieldcli/pkg/netutil"
+)
+
+// IPFilter hard-allows and hard-blocks client IPs before any rule is
+// evaluated. An IP on both lists is allowed, so internal ranges can be
+// carved out of a broader blocklist.
+type IPFilter struct {
+	allow   []*net.IPNet
+	deny    []*net.IPNet
+	trusted []*net.IPNet // proxies whose X-Forwarded-For is honoured
+}
+
+// NewIPFilter parses allow and deny lists of CIDRs. A bare IP is treated as a
+// single-address network. The client IP is taken from X-Forwarded-For only
+// for requests from the trusted proxies, see netutil.ClientIP.
+func NewIPFilter(allow, deny []string, trusted []*net.IPNet) (*IPFilter, error) {
+	allowNets, err := netutil.ParseCIDRs(allow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP allowlist entry: %w", err)
+	}
+	denyNets, err := netutil.ParseCIDRs(deny)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IP blocklist entry: %w", err)
+	}
+	return &IPFilter{allow: allowNets, deny: denyNets, trusted: trusted}, nil
+}
+
+// Check returns the filter's decision for a request and whether the filter
+// decided it at all. Requests whose client IP cannot be parsed, or is on
+// neither list, are left to the rules.
+func (f *IPFilter) Check(r *http.Request) (Decision, string, bool) {
+	ip := netutil.ClientIP(r, f.trusted)
+	if ip == nil {
+		return DecisionAllow, "", false
+	}
+
+	if netutil.ContainsIP(f.allow, ip) {
+		return DecisionAllow, "", true
+	}
+	if netutil.ContainsIP(f.deny, ip) {
+		return DecisionBlock, "IP blocklist", true
+	}
+	return DecisionAllow, "", false
+}
@@ -0,0 +1,68 @@
+package waf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/netutil"
+)
+
+func TestIPFilterCheck(t *testing.T) {
+	trusted, err := netutil.ParseCIDRs([]string{"10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter, err := NewIPFilter([]string{"192.168.0.0/16"}, []string{"198.51.100.0/24", "192.168.9.9"}, trusted)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwarded    string
+		wantDecision Decision
+		wantDecided  bool
+	}{
+		{name: "allowlisted peer", remoteAddr: "192.168.1.5:4000", wantDecision: DecisionAllow, wantDecided: true},
+		{name: "blocklisted peer", remoteAddr: "198.51.100.7:4000", wantDecision: DecisionBlock, wantDecided: true},
+		{name: "allowlist wins on overlap", remoteAddr: "192.168.9.9:4000", wantDecision: DecisionAllow, wantDecided: true},
+		{name: "unlisted peer", remoteAddr: "203.0.113.7:4000", wantDecision: DecisionAllow},
+		{name: "spoofed allowlisted IP from untrusted peer", remoteAddr: "203.0.113.7:4000", forwarded: "192.168.1.5", wantDecision: DecisionAllow},
+		{name: "spoofed header cannot unblock", remoteAddr: "198.51.100.7:4000", forwarded: "192.168.1.5", wantDecision: DecisionBlock, wantDecided: true},
+		{name: "trusted proxy forwards allowlisted client", remoteAddr: "10.0.0.1:4000", forwarded: "192.168.1.5", wantDecision: DecisionAllow, wantDecided: true},
+		{name: "trusted proxy uses rightmost untrusted hop", remoteAddr: "10.0.0.1:4000", forwarded: "192.168.1.5, 198.51.100.7", wantDecision: DecisionBlock, wantDecided: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			decision, _, decided := filter.Check(r)
+			if decision != tt.wantDecision || decided != tt.wantDecided {
+				t.Errorf("Check = (%v, %v), want (%v, %v)", decision, decided, tt.wantDecision, tt.wantDecided)
+			}
+		})
+	}
+}
+
+func TestNewIPFilterInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		allow, deny []string
+	}{
+		{name: "allowlist", allow: []string{"not-an-ip"}},
+		{name: "blocklist", deny: []string{"10.0.0.0/99"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewIPFilter(tt.allow, tt.deny, nil); err == nil {
+				t.Error("NewIPFilter succeeded, want error")
+			}
+		})
+	}
+}
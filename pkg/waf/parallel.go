@@ -0,0 +1,84 @@
+package waf
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// checkParallel is the fast path of Check with rules evaluated concurrently
+// by a bounded pool of goroutines. Rules after the first known blocking rule
+// are skipped, and the reported rule and recorded hits are those of the
+// sequential path: the first blocking rule in phase order wins. Callers must
+// hold e.mu.
+func (e *Engine) checkParallel(r *http.Request, body string) (Decision, string, string) {
+	var ordered []*Rule
+	for _, phase := range requestPhases {
+		for _, rule := range e.rules {
+			if rule.Phase == phase && rule.Enabled {
+				ordered = append(ordered, rule)
+			}
+		}
+	}
+
+	workers := e.config.ParallelWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(ordered) {
+		workers = len(ordered)
+	}
+
+	matched := make([]bool, len(ordered))
+	var next atomic.Int64
+	var firstBlock atomic.Int64 // index of the first blocking match found so far
+	firstBlock.Store(int64(len(ordered)))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= int64(len(ordered)) || i > firstBlock.Load() {
+					return
+				}
+
+				rule := ordered[i]
+				if !e.checkRule(rule, r, body) {
+					continue
+				}
+				matched[i] = true
+				if rule.Action != ActionBlock {
+					continue
+				}
+				for {
+					current := firstBlock.Load()
+					if i >= current || firstBlock.CompareAndSwap(current, i) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	block := int(firstBlock.Load())
+	for i, rule := range ordered {
+		if i > block {
+			break
+		}
+		if matched[i] {
+			e.recordHit(rule.ID)
+		}
+	}
+
+	if block < len(ordered) {
+		rule := ordered[block]
+		return DecisionBlock, fmt.Sprintf("Rule %d: %s", rule.ID, rule.Name), rule.Severity
+	}
+	return DecisionAllow, "", ""
+}
@@ -0,0 +1,140 @@
+package waf
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/logging"
+)
+
+// largeRuleSet returns n request_uri rules that match "/probe-<id>"; every
+// tenth of them blocks, the others only log
+func largeRuleSet(n int) []config.CustomRule {
+	rules := make([]config.CustomRule, n)
+	for i := range rules {
+		id := 20000 + i
+		action := "log"
+		if i%10 == 0 {
+			action = "block"
+		}
+		rules[i] = config.CustomRule{
+			ID: id, Name: fmt.Sprintf("Probe %d", id), Phase: "request_uri", Operator: "regex", Target: "REQUEST_URI",
+			Pattern: fmt.Sprintf(`/probe-%d(/|$)`, id), Action: action, Severity: "high", Enabled: true,
+		}
+	}
+	return rules
+}
+
+func TestParallelEvaluationReason(t *testing.T) {
+	rules := largeRuleSet(200)
+	sequential := newTestEngine(t, &config.Config{CustomRules: rules})
+	parallel := newTestEngine(t, &config.Config{CustomRules: rules, ParallelEvaluation: true, ParallelWorkers: 8})
+
+	// Matches the blocking rules 20050, 20120 and 20190 and the logging rule 20045
+	const target = "/probe-20190/probe-20045/probe-20120/probe-20050/"
+	_, want := sequential.Check(httptest.NewRequest("GET", target, nil))
+	if want != "Rule 20050: Probe 20050" {
+		t.Fatalf("sequential reason %q, want the first blocking rule 20050", want)
+	}
+
+	for i := 0; i < 50; i++ {
+		decision, reason := parallel.Check(httptest.NewRequest("GET", target, nil))
+		if decision != DecisionBlock || reason != want {
+			t.Fatalf("parallel check %d: %v (%s), want block (%s)", i, decision, reason, want)
+		}
+	}
+	if got := parallel.GetRuleStats(); got[20045] != 50 || got[20050] != 50 || got[20120] != 0 || got[20190] != 0 {
+		t.Errorf("parallel hits %v, want the rules up to the block counted and those after it not", got)
+	}
+}
+
+func TestConcurrentCheckAndAddRule(t *testing.T) {
+	for _, parallel := range []bool{false, true} {
+		t.Run(fmt.Sprintf("parallel=%v", parallel), func(t *testing.T) {
+			engine := newTestEngine(t, &config.Config{CustomRules: largeRuleSet(50), ParallelEvaluation: parallel})
+
+			var wg sync.WaitGroup
+			for w := 0; w < 4; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < 100; i++ {
+						r := httptest.NewRequest("POST", "/probe-20010", strings.NewReader("username=alice"))
+						if decision, reason := engine.Check(r); decision != DecisionBlock || reason != "Rule 20010: Probe 20010" {
+							t.Errorf("check during AddRule: %v (%s), want block by rule 20010", decision, reason)
+							return
+						}
+					}
+				}()
+			}
+			for i := 0; i < 50; i++ {
+				rule := &Rule{
+					ID: 30000 + i, Name: "Added", Phase: PhaseRequestBody, Operator: OpContains, Target: "REQUEST_BODY",
+					Pattern: fmt.Sprintf("added-%d", i), Action: ActionBlock, Enabled: true,
+				}
+				if err := engine.AddRule(rule); err != nil {
+					t.Fatal(err)
+				}
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestAddRuleDuringBodyRead(t *testing.T) {
+	engine := newTestEngine(t, &config.Config{})
+
+	// A client still sending its body must not hold up rule changes
+	body, w := io.Pipe()
+	done := make(chan Decision, 1)
+	go func() {
+		decision, _ := engine.Check(httptest.NewRequest("POST", "/comment", body))
+		done <- decision
+	}()
+
+	added := make(chan error, 1)
+	go func() {
+		added <- engine.AddRule(&Rule{
+			ID: 9920, Name: "Spam", Phase: PhaseRequestBody, Operator: OpContains, Target: "REQUEST_BODY",
+			Pattern: "buy now", Action: ActionBlock, Enabled: true,
+		})
+	}()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AddRule waited for a request body to arrive")
+	}
+
+	w.Write([]byte("text=buy now"))
+	w.Close()
+	if decision := <-done; decision != DecisionBlock {
+		t.Errorf("decision %v, want the added rule to block", decision)
+	}
+}
+
+// BenchmarkParallelEvaluation checks a request matching no rule against 500
+// regex rules, sequentially and in parallel
+func BenchmarkParallelEvaluation(b *testing.B) {
+	for _, parallel := range []bool{false, true} {
+		b.Run(map[bool]string{false: "sequential", true: "parallel"}[parallel], func(b *testing.B) {
+			engine, err := NewEngine(&config.Config{CustomRules: largeRuleSet(500), ParallelEvaluation: parallel}, logging.NewLogger(""))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				engine.Check(httptest.NewRequest(http.MethodGet, "/catalog/shoes?page=2", nil))
+			}
+		})
+	}
+}
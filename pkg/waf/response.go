@@ -0,0 +1,121 @@
+package waf
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// responsePhases lists the response phases in evaluation order
+var responsePhases = []RulePhase{PhaseResponseHeaders, PhaseResponseBody}
+
+// CheckResponse checks an upstream response and its body against the
+// response-phase rules, to catch leaked stack traces, database errors or
+// server banners before they reach the client. A chained response rule only
+// matches when the response rules it requires matched too. It returns the
+// decision, its reason and the severity of the rule that blocked.
+func (e *Engine) CheckResponse(resp *http.Response, body []byte) (Decision, string, string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var ordered []*Rule
+	matched := make(map[int]bool)
+	for _, phase := range responsePhases {
+		for _, rule := range e.rules {
+			if rule.Phase != phase || !rule.Enabled {
+				continue
+			}
+
+			ordered = append(ordered, rule)
+			if e.checkResponseRule(rule, resp, body) {
+				matched[rule.ID] = true
+			}
+		}
+	}
+
+	decision, reason, severity := DecisionAllow, "", ""
+	for _, rule := range ordered {
+		if !matched[rule.ID] || !requiresMet(rule, matched) {
+			continue
+		}
+
+		e.recordHit(rule.ID)
+		if rule.Action == ActionBlock && decision != DecisionBlock {
+			decision = DecisionBlock
+			reason = fmt.Sprintf("Rule %d: %s", rule.ID, rule.Name)
+			severity = rule.Severity
+		}
+	}
+
+	return decision, reason, severity
+}
+
+// requiresMet reports whether every rule a chained rule requires matched
+func requiresMet(rule *Rule, matched map[int]bool) bool {
+	for _, id := range rule.Requires {
+		if !matched[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkResponseRule checks if a response-phase rule matches the response
+func (e *Engine) checkResponseRule(rule *Rule, resp *http.Response, body []byte) bool {
+	if rule.Operator == "" && len(rule.Conditions) == 0 {
+		return len(rule.Requires) > 0
+	}
+
+	if rule.Operator != "" && !e.matchResponseTarget(rule.ID, rule.Target, resp, body, rule.Match) {
+		return false
+	}
+	for i := range rule.Conditions {
+		if !e.matchResponseTarget(rule.ID, rule.Conditions[i].Target, resp, body, rule.Conditions[i].Match) {
+			return false
+		}
+	}
+
+	e.logger.Debug("Rule %d matched response: %s", rule.ID, rule.Name)
+	return true
+}
+
+// matchResponseTarget extracts a target from the response and its body and
+// reports whether match accepts it
+func (e *Engine) matchResponseTarget(ruleID int, target string, resp *http.Response, body []byte, match func(string) bool) bool {
+	var data string
+
+	switch {
+	case target == "RESPONSE_BODY":
+		data = string(body)
+	case strings.HasPrefix(target, "RESPONSE_HEADERS:"):
+		data = resp.Header.Get(strings.TrimPrefix(target, "RESPONSE_HEADERS:"))
+	case target == "RESPONSE_HEADERS":
+		for name, values := range resp.Header {
+			for _, value := range values {
+				if match(value) {
+					e.logger.Debug("Rule %d matched in response header %s", ruleID, name)
+					return true
+				}
+			}
+		}
+		return false
+	default:
+		return false
+	}
+
+	return data != "" && match(data)
+}
+
+// HasResponseRules reports whether any enabled rule runs in a response phase,
+// so callers can skip buffering response bodies when none do
+func (e *Engine) HasResponseRules() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, rule := range e.rules {
+		if rule.Enabled && (rule.Phase == PhaseResponseHeaders || rule.Phase == PhaseResponseBody) {
+			return true
+		}
+	}
+	return false
+}
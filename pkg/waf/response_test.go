@@ -0,0 +1,51 @@
+package waf
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestCheckResponse(t *testing.T) {
+	engine := newTestEngine(t, &config.Config{CustomRules: []config.CustomRule{
+		{
+			ID: 9300, Name: "SQL Error Leak", Phase: "response_body", Operator: "contains",
+			Target: "RESPONSE_BODY", Pattern: "SQL syntax error", Action: "block", Severity: "high", Enabled: true,
+		},
+		{
+			ID: 9301, Name: "Server Banner", Phase: "response_headers", Operator: "contains",
+			Target: "RESPONSE_HEADERS:Server", Pattern: "Apache/2.2", Action: "block", Severity: "medium", Enabled: true,
+		},
+	}})
+
+	tests := []struct {
+		name         string
+		header       http.Header
+		body         string
+		wantDecision Decision
+		wantReason   string
+		wantSeverity string
+	}{
+		{name: "leaked SQL error", body: "You have an error in your SQL syntax error", wantDecision: DecisionBlock, wantReason: "Rule 9300: SQL Error Leak", wantSeverity: "high"},
+		{name: "server banner", header: http.Header{"Server": {"Apache/2.2.15"}}, wantDecision: DecisionBlock, wantReason: "Rule 9301: Server Banner", wantSeverity: "medium"},
+		{name: "banner in the body only", body: "Powered by Apache/2.2", wantDecision: DecisionAllow},
+		{name: "clean response", header: http.Header{"Server": {"nginx"}}, body: "<h1>Users</h1>", wantDecision: DecisionAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			resp := &http.Response{StatusCode: http.StatusOK, Header: header}
+
+			decision, reason, severity := engine.CheckResponse(resp, []byte(tt.body))
+			if decision != tt.wantDecision || reason != tt.wantReason || severity != tt.wantSeverity {
+				t.Errorf("CheckResponse = (%v, %q, %q), want (%v, %q, %q)",
+					decision, reason, severity, tt.wantDecision, tt.wantReason, tt.wantSeverity)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package waf
 
 import (
+	"fmt"
 	"math"
 	"regexp"
 	"strings"
@@ -44,58 +45,126 @@ const (
 
 // Rule represents a single WAF rule
 type Rule struct {
-	ID          int
-	Name        string
-	Description string
-	Phase       RulePhase
-	Operator    RuleOperator
-	Pattern     string
-	Target      string // e.g., "REQUEST_URI", "REQUEST_HEADERS", "REQUEST_BODY", "ARGS"
-	Action      RuleAction
-	Severity    string // "low", "medium", "high", "critical"
-	Enabled     bool
-	regex       *regexp.Regexp // compiled regex pattern
+	ID              int
+	Name            string
+	Description     string
+	Phase           RulePhase
+	Operator        RuleOperator
+	Pattern         string
+	Target          string // e.g., "REQUEST_URI", "REQUEST_HEADERS", "REQUEST_BODY", "ARGS", "QUERY_STRING", "RESPONSE_BODY"
+	Action          RuleAction
+	Severity        string // "low", "medium", "high", "critical"
+	Enabled         bool
+	Requires        []int           // IDs of rules that must also match for this rule to take action
+	Transformations []string        // applied to the data in order before the operator runs
+	FullBody        bool            // REQUEST_BODY rule that needs the whole body, not just the inspection window
+	Conditions      []RuleCondition // further conditions that must all match the same request
+	regex           *regexp.Regexp  // compiled regex pattern
 }
 
-// Compile compiles the rule's regex pattern if needed
+// RuleCondition is a further condition of a rule, with a target, operator
+// and pattern of its own. A rule with conditions matches only when its own
+// operator, if it has one, and every condition match, so e.g. a suspicious
+// URI can be required to come with a specific header value.
+type RuleCondition struct {
+	Target          string
+	Operator        RuleOperator
+	Pattern         string
+	Transformations []string       // applied to the target's data before this condition's operator runs
+	regex           *regexp.Regexp // compiled regex pattern
+}
+
+// Compile validates the rule's transformations and compiles its regex pattern
+// and those of its conditions if needed
 func (r *Rule) Compile() error {
+	if err := validateTransformations(r.Transformations); err != nil {
+		return err
+	}
 	if r.Operator == OpRegex || r.Operator == OpNotRegex {
-		re, err := regexp.Compile(r.Pattern)
+		re, err := compiledPatterns.compile(r.Pattern)
 		if err != nil {
 			return err
 		}
 		r.regex = re
 	}
+	for i := range r.Conditions {
+		if err := r.Conditions[i].compile(); err != nil {
+			return fmt.Errorf("condition %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// compile validates the condition's transformations and compiles its regex
+// pattern if needed
+func (c *RuleCondition) compile() error {
+	if c.Operator == "" {
+		return fmt.Errorf("missing operator")
+	}
+	if err := validateTransformations(c.Transformations); err != nil {
+		return err
+	}
+	if c.Operator == OpRegex || c.Operator == OpNotRegex {
+		re, err := compiledPatterns.compile(c.Pattern)
+		if err != nil {
+			return err
+		}
+		c.regex = re
+	}
 	return nil
 }
 
+// Match checks if the condition matches the given data
+func (c *RuleCondition) Match(data string) bool {
+	return matchOperator(c.Operator, c.Pattern, c.regex, applyTransformations(data, c.Transformations))
+}
+
+// inspects reports whether the rule or any of its conditions reads target
+func (r *Rule) inspects(target string) bool {
+	if r.Operator != "" && r.Target == target {
+		return true
+	}
+	for _, c := range r.Conditions {
+		if c.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
 // Match checks if the rule matches the given data
 func (r *Rule) Match(data string) bool {
 	if !r.Enabled {
 		return false
 	}
 
-	switch r.Operator {
+	return matchOperator(r.Operator, r.Pattern, r.regex, applyTransformations(data, r.Transformations))
+}
+
+// matchOperator applies an operator with its pattern, or compiled regex, to
+// transformed data
+func matchOperator(operator RuleOperator, pattern string, re *regexp.Regexp, data string) bool {
+	switch operator {
 	case OpContains:
-		return strings.Contains(data, r.Pattern)
+		return strings.Contains(data, pattern)
 	case OpNotContains:
-		return !strings.Contains(data, r.Pattern)
+		return !strings.Contains(data, pattern)
 	case OpRegex:
-		if r.regex == nil {
+		if re == nil {
 			return false
 		}
-		return r.regex.MatchString(data)
+		return re.MatchString(data)
 	case OpNotRegex:
-		if r.regex == nil {
+		if re == nil {
 			return false
 		}
-		return !r.regex.MatchString(data)
+		return !re.MatchString(data)
 	case OpStartsWith:
-		return strings.HasPrefix(data, r.Pattern)
+		return strings.HasPrefix(data, pattern)
 	case OpEndsWith:
-		return strings.HasSuffix(data, r.Pattern)
+		return strings.HasSuffix(data, pattern)
 	case OpEquals:
-		return data == r.Pattern
+		return data == pattern
 	case OpHighEntropy:
 		return calculateEntropy(data) > 4.0
 	case OpSQLi:
@@ -107,6 +176,27 @@ func (r *Rule) Match(data string) bool {
 	}
 }
 
+// FindMatch returns the text a regex rule matches in data after its
+// transformations, and whether it matched; other operators have no match text
+func (r *Rule) FindMatch(data string) (string, bool) {
+	if r.Operator != OpRegex || r.regex == nil {
+		return "", false
+	}
+
+	data = applyTransformations(data, r.Transformations)
+	loc := r.regex.FindStringIndex(data)
+	if loc == nil {
+		return "", false
+	}
+	return data[loc[0]:loc[1]], true
+}
+
+// Entropy returns the Shannon entropy of s in bits per character, as judged
+// by the high_entropy operator
+func Entropy(s string) float64 {
+	return calculateEntropy(s)
+}
+
 // calculateEntropy calculates Shannon entropy of a string
 func calculateEntropy(s string) float64 {
 	if len(s) == 0 {
@@ -126,52 +216,3 @@ func calculateEntropy(s string) float64 {
 
 	return entropy
 }
-
-// detectSQLi detects common SQL injection patterns
-func detectSQLi(data string) bool {
-	sqlPatterns := []string{
-		"' OR '1'='1",
-		"' OR 1=1",
-		"'; DROP TABLE",
-		"UNION SELECT",
-		"' OR 'a'='a",
-		"admin' --",
-		"' /*",
-		"*/ OR /*",
-		"xp_",
-		"sp_",
-	}
-
-	upperData := strings.ToUpper(data)
-	for _, pattern := range sqlPatterns {
-		if strings.Contains(upperData, strings.ToUpper(pattern)) {
-			return true
-		}
-	}
-	return false
-}
-
-// detectXSS detects common XSS patterns
-func detectXSS(data string) bool {
-	xssPatterns := []string{
-		"<script",
-		"javascript:",
-		"onerror=",
-		"onload=",
-		"onclick=",
-		"onmouseover=",
-		"<iframe",
-		"<object",
-		"<embed",
-		"<img",
-		"<svg",
-	}
-
-	lowerData := strings.ToLower(data)
-	for _, pattern := range xssPatterns {
-		if strings.Contains(lowerData, pattern) {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,86 @@
+package waf
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultAnomalyThreshold is the scoring-mode threshold used when none is
+// configured: two medium matches (6) pass, three (9) or a critical and a low
+// match (7) block
+const DefaultAnomalyThreshold = 7
+
+// severityScores is the anomaly score each matched rule contributes by severity
+var severityScores = map[string]int{
+	"critical": 5,
+	"high":     4,
+	"medium":   3,
+	"low":      2,
+}
+
+// SeverityScore returns the anomaly score a matched rule of a severity
+// contributes, or 0 for an unknown severity
+func SeverityScore(severity string) int {
+	return severityScores[severity]
+}
+
+// CheckWithScore checks a request in anomaly-scoring mode. Instead of blocking
+// on the first matching block rule, every matching rule that is not a pass
+// rule adds a score based on its severity, and the request is blocked only
+// once the total reaches the configured anomaly threshold. It returns the
+// decision, the total score and the contributing rules.
+func (e *Engine) CheckWithScore(r *http.Request) (Decision, int, []string) {
+	result, score, contributing := e.CheckDetailedWithScore(r)
+	return result.Decision, score, contributing
+}
+
+// CheckDetailedWithScore is CheckWithScore that also returns every rule
+// evaluation, as CheckDetailed does. The result holds the scoring decision,
+// and on a block the score and contributing rules as the reason and the
+// highest effective severity among them.
+func (e *Engine) CheckDetailedWithScore(r *http.Request) (*CheckResult, int, []string) {
+	if e.ipFilter != nil {
+		if decision, reason, ok := e.ipFilter.Check(r); ok {
+			result := &CheckResult{Decision: decision, Reason: reason}
+			if decision == DecisionBlock {
+				return result, 0, []string{reason}
+			}
+			return result, 0, nil
+		}
+	}
+
+	body := e.requestBody(r)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	result := e.evaluate(r, body)
+
+	score := 0
+	var contributing []string
+	for _, eval := range result.Evaluations {
+		if !eval.Matched || eval.Action == ActionPass {
+			continue
+		}
+		points := severityScores[eval.Severity]
+		score += points
+		contributing = append(contributing, fmt.Sprintf("Rule %d: %s (+%d)", eval.RuleID, eval.RuleName, points))
+	}
+
+	threshold := e.config.AnomalyThreshold
+	if threshold <= 0 {
+		threshold = DefaultAnomalyThreshold
+	}
+	result.Decision, result.Reason, result.Severity = DecisionAllow, "", ""
+	if score >= threshold {
+		result.Decision = DecisionBlock
+		result.Reason = fmt.Sprintf("anomaly score %d (%s)", score, strings.Join(contributing, ", "))
+		for _, eval := range result.Evaluations {
+			if eval.Matched && eval.Action != ActionPass && severityScores[eval.Severity] > severityScores[result.Severity] {
+				result.Severity = eval.Severity
+			}
+		}
+	}
+	return result, score, contributing
+}
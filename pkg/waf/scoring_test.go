@@ -0,0 +1,42 @@
+package waf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestCheckWithScore(t *testing.T) {
+	var rules []config.CustomRule
+	for i, pattern := range []string{"probe-a", "probe-b", "probe-c"} {
+		rules = append(rules, config.CustomRule{
+			ID: 9400 + i, Name: "Probe " + pattern, Phase: "request_uri", Operator: "contains",
+			Target: "REQUEST_URI", Pattern: pattern, Action: "block", Severity: "medium", Enabled: true,
+		})
+	}
+	engine := newTestEngine(t, &config.Config{ScoringMode: true, CustomRules: rules})
+
+	tests := []struct {
+		name         string
+		target       string
+		wantDecision Decision
+		wantScore    int
+		wantRules    int
+	}{
+		{name: "no match", target: "/search?q=shoes", wantDecision: DecisionAllow},
+		{name: "one medium match", target: "/search?q=probe-a", wantDecision: DecisionAllow, wantScore: 3, wantRules: 1},
+		{name: "two medium matches", target: "/search?q=probe-a+probe-b", wantDecision: DecisionAllow, wantScore: 6, wantRules: 2},
+		{name: "three medium matches", target: "/search?q=probe-a+probe-b+probe-c", wantDecision: DecisionBlock, wantScore: 9, wantRules: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, score, contributing := engine.CheckWithScore(httptest.NewRequest("GET", tt.target, nil))
+			if decision != tt.wantDecision || score != tt.wantScore || len(contributing) != tt.wantRules {
+				t.Errorf("CheckWithScore = (%v, %d, %q), want (%v, %d, %d rules)",
+					decision, score, contributing, tt.wantDecision, tt.wantScore, tt.wantRules)
+			}
+		})
+	}
+}
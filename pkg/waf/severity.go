@@ -0,0 +1,118 @@
+package waf
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+	"github.com/shieldcli/shieldcli/pkg/netutil"
+)
+
+// severityBoost is a compiled config.SeverityBoost
+type severityBoost struct {
+	path     *regexp.Regexp
+	ips      []*net.IPNet
+	minScore int
+	levels   int
+}
+
+// newSeverityBoosts validates and compiles the configured severity boosts
+func newSeverityBoosts(boosts []config.SeverityBoost) ([]*severityBoost, error) {
+	compiled := make([]*severityBoost, 0, len(boosts))
+	for i, b := range boosts {
+		if b.Path == "" && len(b.IPs) == 0 && b.MinAnomalyScore <= 0 {
+			return nil, fmt.Errorf("severity boost %d: no path, ips or min_anomaly_score condition", i+1)
+		}
+
+		boost := &severityBoost{minScore: b.MinAnomalyScore, levels: b.Levels}
+		if boost.levels <= 0 {
+			boost.levels = 1
+		}
+		if b.Path != "" {
+			re, err := compiledPatterns.compile(b.Path)
+			if err != nil {
+				return nil, fmt.Errorf("severity boost %d: invalid path pattern: %w", i+1, err)
+			}
+			boost.path = re
+		}
+		ips, err := netutil.ParseCIDRs(b.IPs)
+		if err != nil {
+			return nil, fmt.Errorf("severity boost %d: invalid IP watchlist entry: %w", i+1, err)
+		}
+		boost.ips = ips
+
+		compiled = append(compiled, boost)
+	}
+	return compiled, nil
+}
+
+// applies reports whether every condition of the boost holds for a request
+// from clientIP whose matched rules add up to score
+func (b *severityBoost) applies(r *http.Request, clientIP net.IP, score int) bool {
+	if b.path != nil && !b.path.MatchString(r.URL.Path) {
+		return false
+	}
+	if len(b.ips) > 0 {
+		if clientIP == nil || !netutil.ContainsIP(b.ips, clientIP) {
+			return false
+		}
+	}
+	return score >= b.minScore
+}
+
+// applySeverityBoosts raises the severity of every matched rule by the levels
+// of the boosts that apply to the request, and notes the raise in the reason
+// of a block decision. Callers must hold e.mu.
+func (e *Engine) applySeverityBoosts(r *http.Request, result *CheckResult) {
+	score := 0
+	for _, eval := range result.Evaluations {
+		if eval.Matched && eval.Action != ActionPass {
+			score += severityScores[eval.Severity]
+		}
+	}
+
+	clientIP := netutil.ClientIP(r, e.trustedProxies)
+	levels := 0
+	for _, boost := range e.severityBoosts {
+		if boost.applies(r, clientIP, score) {
+			levels += boost.levels
+		}
+	}
+	if levels == 0 {
+		return
+	}
+
+	decided := false
+	for i := range result.Evaluations {
+		eval := &result.Evaluations[i]
+		if !eval.Matched {
+			continue
+		}
+
+		base := eval.Severity
+		eval.Severity = raiseSeverity(base, levels)
+
+		// The first matched block rule is the one that decided the request
+		if eval.Action == ActionBlock && !decided {
+			decided = true
+			result.Severity = eval.Severity
+			if eval.Severity != base {
+				result.Reason = fmt.Sprintf("Rule %d: %s (severity raised from %s to %s)",
+					eval.RuleID, eval.RuleName, base, eval.Severity)
+			}
+		}
+	}
+}
+
+// raiseSeverity returns the severity the given number of levels higher,
+// capped at critical
+func raiseSeverity(severity string, levels int) string {
+	for i, s := range validSeverities {
+		if s == severity {
+			return validSeverities[min(i+levels, len(validSeverities)-1)]
+		}
+	}
+	return severity
+}
@@ -0,0 +1,103 @@
+package waf
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// snippetContext is how many bytes of context a pattern match snippet shows
+// on each side of the match
+const snippetContext = 20
+
+// PatternMatch is one match of a candidate pattern in a request
+type PatternMatch struct {
+	Location string // where the match was found, e.g. "ARGS:q" or "REQUEST_HEADERS:User-Agent"
+	Match    string // the matched text
+	Snippet  string // the match with surrounding context
+}
+
+// targetValue is one piece of request data a rule target inspects
+type targetValue struct {
+	location string
+	data     string
+}
+
+// MatchPattern runs a candidate pattern against the data a rule with the
+// given request target would inspect and returns the first match in each
+// inspected value. It lets a pattern be tried out before a rule is added.
+func MatchPattern(re *regexp.Regexp, target string, r *http.Request) ([]PatternMatch, error) {
+	body, err := BufferRequestBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []PatternMatch
+	for _, value := range targetValues(target, r, string(body)) {
+		loc := re.FindStringIndex(value.data)
+		if loc == nil {
+			continue
+		}
+		matches = append(matches, PatternMatch{
+			Location: value.location,
+			Match:    value.data[loc[0]:loc[1]],
+			Snippet:  snippet(value.data, loc[0], loc[1]),
+		})
+	}
+	return matches, nil
+}
+
+// targetValues returns the request data a target inspects, in a stable order
+func targetValues(target string, r *http.Request, body string) []targetValue {
+	switch {
+	case target == "REQUEST_URI":
+		return []targetValue{{"REQUEST_URI", r.RequestURI}}
+	case target == "REQUEST_BODY":
+		return []targetValue{{"REQUEST_BODY", body}}
+	case target == "QUERY_STRING":
+		return []targetValue{{"QUERY_STRING", r.URL.RawQuery}}
+	case strings.HasPrefix(target, "REQUEST_HEADERS:"):
+		name := strings.TrimPrefix(target, "REQUEST_HEADERS:")
+		return []targetValue{{target, r.Header.Get(name)}}
+	case target == "REQUEST_HEADERS":
+		return multiValues("REQUEST_HEADERS:", r.Header)
+	case target == "ARGS":
+		return multiValues("ARGS:", r.URL.Query())
+	default:
+		return nil
+	}
+}
+
+// multiValues flattens headers or query arguments sorted by name
+func multiValues(prefix string, values map[string][]string) []targetValue {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var flat []targetValue
+	for _, name := range names {
+		for _, value := range values[name] {
+			flat = append(flat, targetValue{prefix + name, value})
+		}
+	}
+	return flat
+}
+
+// snippet returns data[start:end] with up to snippetContext bytes on either
+// side, marking truncated ends with "..."
+func snippet(data string, start, end int) string {
+	from := max(start-snippetContext, 0)
+	to := min(end+snippetContext, len(data))
+
+	s := strings.ToValidUTF8(data[from:to], "")
+	if from > 0 {
+		s = "..." + s
+	}
+	if to < len(data) {
+		s += "..."
+	}
+	return s
+}
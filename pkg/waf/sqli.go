@@ -0,0 +1,470 @@
+package waf
+
+import (
+	"strings"
+)
+
+// SQL token kinds
+const (
+	sqlString   = 's' // quoted string literal
+	sqlNumber   = 'n' // number, or NULL, TRUE and FALSE
+	sqlWord     = 'b' // bareword: an identifier or an ordinary word
+	sqlVariable = 'v' // @var or @@var
+	sqlKeyword  = 'k' // SQL keyword, see sqlKeywords
+	sqlFunction = 'f' // bareword followed by '('
+	sqlLogic    = '&' // AND, OR, XOR, && and ||
+	sqlOperator = 'o' // comparison or arithmetic operator, LIKE, IS, IN, ...
+	sqlComment  = 'c' // -- or # comment, which runs to the end of the input
+	sqlPunct    = 'p' // ( ) , ;
+)
+
+// sqlToken is a token of the SQLi tokenizer. Keywords are upper-cased.
+type sqlToken struct {
+	kind byte
+	text string
+}
+
+// sqlKeywords are the words the tokenizer classifies as anything but a
+// bareword
+var sqlKeywords = map[string]byte{
+	"AND": sqlLogic, "OR": sqlLogic, "XOR": sqlLogic,
+
+	"LIKE": sqlOperator, "RLIKE": sqlOperator, "REGEXP": sqlOperator, "IS": sqlOperator,
+	"IN": sqlOperator, "DIV": sqlOperator, "MOD": sqlOperator,
+
+	"NULL": sqlNumber, "TRUE": sqlNumber, "FALSE": sqlNumber,
+
+	"SELECT": sqlKeyword, "UNION": sqlKeyword, "INSERT": sqlKeyword, "UPDATE": sqlKeyword,
+	"DELETE": sqlKeyword, "DROP": sqlKeyword, "CREATE": sqlKeyword, "ALTER": sqlKeyword,
+	"TRUNCATE": sqlKeyword, "EXEC": sqlKeyword, "EXECUTE": sqlKeyword, "SHUTDOWN": sqlKeyword,
+	"DECLARE": sqlKeyword, "WAITFOR": sqlKeyword, "DELAY": sqlKeyword, "TIME": sqlKeyword,
+	"FROM": sqlKeyword, "WHERE": sqlKeyword, "INTO": sqlKeyword, "SET": sqlKeyword,
+	"TABLE": sqlKeyword, "DATABASE": sqlKeyword, "SCHEMA": sqlKeyword, "VIEW": sqlKeyword,
+	"PROCEDURE": sqlKeyword, "INDEX": sqlKeyword, "USER": sqlKeyword, "ALL": sqlKeyword,
+	"DISTINCT": sqlKeyword, "ORDER": sqlKeyword, "GROUP": sqlKeyword, "BY": sqlKeyword,
+	"HAVING": sqlKeyword, "LIMIT": sqlKeyword,
+}
+
+// sqlKeywordTokens are the tokens of sqlKeywords, so a keyword's text is
+// not allocated each time it is seen
+var sqlKeywordTokens = func() map[string]sqlToken {
+	tokens := make(map[string]sqlToken, len(sqlKeywords))
+	for word, kind := range sqlKeywords {
+		tokens[word] = sqlToken{kind: kind, text: word}
+	}
+	return tokens
+}()
+
+// sqlMaxKeywordLen is the length of the longest word in sqlKeywords
+const sqlMaxKeywordLen = len("PROCEDURE")
+
+// detectSQLi reports whether data looks like an SQL injection. The data is
+// tokenized as SQL, with quotes, comments and whitespace normalized, and the
+// token sequence is searched for the shapes injections take: a tautology
+// after a logic operator, UNION SELECT after a value, a stacked statement, a
+// string broken out of and commented off, and so on. Since injected input
+// usually starts inside a quoted string of the query, data is tokenized as
+// is and, if it contains quotes, as if it were preceded by one.
+func detectSQLi(data string) bool {
+	if scanSQLi(data, 0) {
+		return true
+	}
+	for _, quote := range []byte{'\'', '"'} {
+		if strings.IndexByte(data, quote) >= 0 && scanSQLi(data, quote) {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlBatch is how many tokens scanSQLi holds at a time
+const sqlBatch = 256
+
+// sqlLookahead is how many tokens past its first the longest injection shape
+// reads, e.g. ; SELECT DISTINCT (...) or OR ((( SELECT 1,
+const sqlLookahead = 10
+
+// scanSQLi tokenizes data, starting inside a string literal closed by quote
+// if it is non-zero, and reports whether the tokens contain an injection
+// shape. Tokens are checked in batches, so memory does not grow with the
+// size of data.
+func scanSQLi(data string, quote byte) bool {
+	var buf [sqlBatch]sqlToken
+	tokens := buf[:0]
+	tokenizer := newSQLTokenizer(data, quote)
+
+	// Only the first batch can break out of the starting string literal
+	quoted := quote != 0
+
+	from := 0
+	for {
+		tok, ok := tokenizer.next()
+		if ok {
+			tokens = append(tokens, tok)
+			if len(tokens) < len(buf) {
+				continue
+			}
+		}
+
+		to := len(tokens)
+		if ok {
+			to -= sqlLookahead
+		}
+		if quoted && sqlBreakout(tokens) || sqliTokens(tokens, from, to) {
+			return true
+		}
+		if !ok {
+			return false
+		}
+
+		// Carry the unchecked tokens over, along with one to look back at
+		quoted = false
+		tokens = tokens[:copy(buf[:], tokens[to-1:])]
+		from = 1
+	}
+}
+
+// sqlBreakout reports whether tokens that start inside a string literal
+// close it and comment off the rest of the query, e.g. admin'-- or admin')#
+func sqlBreakout(tokens []sqlToken) bool {
+	at := tokenAt(tokens)
+	i := 1
+	for at(i).text == ")" {
+		i++
+	}
+	return at(i).kind == sqlComment
+}
+
+// sqliTokens reports whether an injection shape starts at one of the tokens
+// in [from, to)
+func sqliTokens(tokens []sqlToken, from, to int) bool {
+	at := tokenAt(tokens)
+
+	for i := from; i < to; i++ {
+		tok := tokens[i]
+		switch {
+		case tok.kind == sqlLogic:
+			// Tautologies: OR 1=1, OR 'a'='a', OR x=x, OR (1=1, AND 1=CONVERT(...)
+			j := i + 1
+			for j < i+4 && at(j).text == "(" && at(j+1).kind != sqlKeyword {
+				j++
+			}
+			left, op, right := at(j), at(j+1), at(j+2)
+			if isSQLLiteral(left) && op.kind == sqlOperator {
+				if isSQLLiteral(right) || right.kind == sqlFunction {
+					return true
+				}
+			}
+			if left.kind == sqlWord && op.kind == sqlOperator && right.kind == sqlWord && strings.EqualFold(left.text, right.text) {
+				return true
+			}
+			// A truthy value with the rest commented off: OR 1--
+			if isSQLLiteral(left) && op.kind == sqlComment {
+				return true
+			}
+			// Blind and time-based probes: AND SLEEP(5), AND (SELECT ...)
+			if sqlFunctionCall(tokens, j) || left.text == "(" && sqlStatement(tokens, j+1) {
+				return true
+			}
+
+		case tok.kind == sqlOperator && at(i+1).text == "(":
+			// Subqueries compared against: =(SELECT ...)
+			if sqlStatement(tokens, i+2) {
+				return true
+			}
+
+		case tok.text == ";":
+			// Stacked statements: ; DROP TABLE users
+			if sqlStatement(tokens, i+1) {
+				return true
+			}
+
+		case tok.text == "UNION":
+			// UNION [ALL|DISTINCT] SELECT after a value
+			prev := at(i - 1)
+			if !isSQLLiteral(prev) && prev.kind != sqlVariable && prev.text != ")" {
+				continue
+			}
+			next := i + 1
+			if at(next).text == "ALL" || at(next).text == "DISTINCT" {
+				next++
+			}
+			if at(next).text == "(" {
+				next++
+			}
+			if at(next).text == "SELECT" && sqlStatement(tokens, next) {
+				return true
+			}
+
+		case tok.text == "ORDER" && at(i+1).text == "BY":
+			// Column count probes after a value: 1' ORDER BY 3--
+			prev := at(i - 1)
+			if (isSQLLiteral(prev) || prev.text == ")") && at(i+2).kind == sqlNumber && at(i+3).kind == sqlComment {
+				return true
+			}
+
+		case tok.text == "WAITFOR" && isSQLLiteral(at(i-1)):
+			// MSSQL delays appended without a semicolon: 1 WAITFOR DELAY '0:0:5'
+			if sqlStatement(tokens, i) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSQLLiteral reports whether a token is a string or number literal
+func isSQLLiteral(tok sqlToken) bool {
+	return tok.kind == sqlString || tok.kind == sqlNumber
+}
+
+// tokenAt returns a function that returns the token at an index, or the
+// zero token outside the sequence
+func tokenAt(tokens []sqlToken) func(i int) sqlToken {
+	return func(i int) sqlToken {
+		if i < 0 || i >= len(tokens) {
+			return sqlToken{}
+		}
+		return tokens[i]
+	}
+}
+
+// sqlFunctionCall reports whether tokens[i:] is a function call with a
+// literal first argument, e.g. SLEEP(5) or BENCHMARK(1000000, MD5(1))
+func sqlFunctionCall(tokens []sqlToken, i int) bool {
+	at := tokenAt(tokens)
+	if at(i).kind != sqlFunction || at(i+1).text != "(" || !isSQLLiteral(at(i+2)) {
+		return false
+	}
+	next := at(i + 3)
+	return next.kind == 0 || next.text == ")" || next.text == ","
+}
+
+// sqlStatement reports whether tokens[i:] starts an SQL statement, as
+// opposed to prose that merely begins with an SQL keyword
+func sqlStatement(tokens []sqlToken, i int) bool {
+	at := tokenAt(tokens)
+
+	switch at(i).text {
+	case "SELECT":
+		next := i + 1
+		if at(next).text == "ALL" || at(next).text == "DISTINCT" {
+			next++
+		}
+		// The first column, then whatever may follow it
+		first := at(next)
+		switch {
+		case first.kind == sqlFunction || first.text == "(":
+			return true
+		case first.text == "*" || isSQLLiteral(first) || first.kind == sqlVariable:
+			after := at(next + 1)
+			return after.kind == 0 || after.kind == sqlComment || after.kind == sqlOperator ||
+				after.kind == sqlPunct || after.kind == sqlKeyword
+		case first.kind == sqlWord:
+			after := at(next + 1)
+			return after.kind == sqlComment || after.kind == sqlOperator || after.kind == sqlPunct ||
+				after.text == "FROM" || after.text == "INTO"
+		}
+		return false
+	case "INSERT":
+		return at(i+1).text == "INTO"
+	case "DELETE":
+		return at(i+1).text == "FROM"
+	case "UPDATE":
+		return at(i+1).kind == sqlWord && at(i+2).text == "SET"
+	case "DROP", "CREATE", "ALTER":
+		switch at(i + 1).text {
+		case "TABLE", "DATABASE", "SCHEMA", "VIEW", "PROCEDURE", "INDEX", "USER":
+			return true
+		}
+		return false
+	case "TRUNCATE":
+		return at(i+1).text == "TABLE"
+	case "EXEC", "EXECUTE":
+		return at(i+1).kind == sqlWord || at(i+1).kind == sqlFunction
+	case "SHUTDOWN":
+		next := at(i + 1)
+		return next.kind == 0 || next.kind == sqlComment || next.text == ";"
+	case "DECLARE":
+		return at(i+1).kind == sqlVariable
+	case "WAITFOR":
+		return (at(i+1).text == "DELAY" || at(i+1).text == "TIME") && at(i+2).kind == sqlString
+	}
+	return false
+}
+
+// sqlTokenizer splits data into SQL tokens. Whitespace and /* */ comments
+// separate tokens and are dropped, except that the body of a MySQL
+// /*! ... */ comment is tokenized as code. Unterminated strings run to the
+// end of the input.
+type sqlTokenizer struct {
+	data  string
+	pos   int
+	quote byte // the quote closing the string literal the input starts in
+}
+
+// newSQLTokenizer creates a tokenizer for data. A non-zero quote starts the
+// input inside a string literal closed by that quote.
+func newSQLTokenizer(data string, quote byte) *sqlTokenizer {
+	return &sqlTokenizer{data: data, quote: quote}
+}
+
+// next returns the next token, or false at the end of the input
+func (t *sqlTokenizer) next() (sqlToken, bool) {
+	data, i := t.data, t.pos
+	defer func() { t.pos = i }()
+
+	if t.quote != 0 {
+		end := scanSQLString(data, 0, t.quote)
+		t.quote = 0
+		i = end + 1
+		return sqlToken{kind: sqlString, text: data[:min(end, len(data))]}, true
+	}
+
+	for i < len(data) {
+		c := data[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			if i+2 < len(data) && data[i+2] == '!' {
+				// MySQL executes the body of /*! */ and /*!50000 */
+				i += 3
+				for i < len(data) && data[i] >= '0' && data[i] <= '9' {
+					i++
+				}
+				continue
+			}
+			end := strings.Index(data[i+2:], "*/")
+			if end == -1 {
+				i = len(data)
+			} else {
+				i += 2 + end + 2
+			}
+
+		case c == '*' && i+1 < len(data) && data[i+1] == '/':
+			// The end of a /*! */ comment
+			i += 2
+
+		case c == '-' && i+1 < len(data) && data[i+1] == '-', c == '#':
+			tok := sqlToken{kind: sqlComment, text: data[i:]}
+			i = len(data)
+			return tok, true
+
+		case c == '\'' || c == '"':
+			end := scanSQLString(data, i+1, c)
+			tok := sqlToken{kind: sqlString, text: data[i+1 : min(end, len(data))]}
+			i = end + 1
+			return tok, true
+
+		case c == '`':
+			// Quoted identifier
+			end := strings.IndexByte(data[i+1:], '`')
+			if end == -1 {
+				end = len(data) - i - 1
+			}
+			tok := sqlToken{kind: sqlWord, text: data[i+1 : i+1+end]}
+			i += end + 2
+			return tok, true
+
+		case c == '(' || c == ')' || c == ',' || c == ';':
+			i++
+			return sqlToken{kind: sqlPunct, text: data[i-1 : i]}, true
+
+		case c == '@':
+			start := i
+			for i < len(data) && data[i] == '@' {
+				i++
+			}
+			for i < len(data) && isSQLWordByte(data[i]) {
+				i++
+			}
+			return sqlToken{kind: sqlVariable, text: data[start:i]}, true
+
+		case isSQLWordByte(c):
+			start := i
+			for i < len(data) && isSQLWordByte(data[i]) {
+				i++
+			}
+			return classifySQLWord(data[start:i], data[i:]), true
+
+		case strings.IndexByte("=<>!+-*/%&|^~", c) >= 0:
+			start := i
+			for i < len(data) && strings.IndexByte("=<>!&|", data[i]) >= 0 && i-start < 2 {
+				i++
+			}
+			if i == start {
+				i++
+			}
+			op := data[start:i]
+			if op == "&&" || op == "||" {
+				return sqlToken{kind: sqlLogic, text: op}, true
+			}
+			return sqlToken{kind: sqlOperator, text: op}, true
+
+		default:
+			// Other punctuation and non-ASCII text separate tokens
+			i++
+		}
+	}
+	return sqlToken{}, false
+}
+
+// scanSQLString returns the index of the quote that closes a string literal
+// starting at start, or len(data) if it is unterminated. Doubled quotes and
+// backslash escapes do not close it.
+func scanSQLString(data string, start int, quote byte) int {
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(data) && data[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(data)
+}
+
+// classifySQLWord classifies a word by its text and what follows it
+func classifySQLWord(word, rest string) sqlToken {
+	if word[0] >= '0' && word[0] <= '9' || word[0] == '.' {
+		return sqlToken{kind: sqlNumber, text: word}
+	}
+
+	// Upper-case into a buffer, so ordinary words cost no allocation
+	var buf [sqlMaxKeywordLen]byte
+	var keyword sqlToken
+	ok := false
+	if len(word) <= len(buf) {
+		for i := 0; i < len(word); i++ {
+			c := word[i]
+			if c >= 'a' && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			buf[i] = c
+		}
+		keyword, ok = sqlKeywordTokens[string(buf[:len(word)])]
+	}
+	if strings.HasPrefix(strings.TrimLeft(rest, " \t\r\n"), "(") {
+		// USER(), DATABASE() and SCHEMA() are functions as well as keywords
+		if !ok || keyword.kind == sqlKeyword && (strings.EqualFold(word, "USER") ||
+			strings.EqualFold(word, "DATABASE") || strings.EqualFold(word, "SCHEMA")) {
+			return sqlToken{kind: sqlFunction, text: word}
+		}
+	}
+	if ok {
+		return keyword
+	}
+	return sqlToken{kind: sqlWord, text: word}
+}
+
+// isSQLWordByte reports whether c can be part of a bareword or number
+func isSQLWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '$' || c == '.'
+}
@@ -0,0 +1,88 @@
+package waf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDetectSQLi(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		// Tautologies
+		{"' OR 1=1--", true},
+		{"1' OR '1'='1' --", true},
+		{"admin' or 'a'='a", true},
+		{"x' OR x=x#", true},
+		{"1 OR (1=1)", true},
+		{"' OR 1--", true},
+		// String breakouts commented off
+		{"admin'--", true},
+		{"admin')#", true},
+		// UNION SELECT after a value
+		{"1 UNION SELECT username, password FROM users--", true},
+		{"-1' UNION ALL SELECT NULL,NULL--", true},
+		{"1 union/**/select 1,2,3", true},
+		// Stacked statements
+		{"1; DROP TABLE users", true},
+		{"'; EXEC xp_cmdshell 'dir'--", true},
+		// Blind, time-based and subquery probes
+		{"1 AND SLEEP(5)", true},
+		{"1' AND (SELECT 1 FROM dual)--", true},
+		{"id=(SELECT password FROM users)", true},
+		{"1 WAITFOR DELAY '0:0:5'", true},
+		{"1' ORDER BY 3--", true},
+		// MySQL executable comments
+		{"1 /*!UNION*/ /*!SELECT*/ 1,2", true},
+
+		// Prose and ordinary input
+		{"Learn how UNION SELECT works in SQL", false},
+		{"select your favourite color", false},
+		{"Tom and Jerry", false},
+		{"rock 'n' roll or jazz", false},
+		{"O'Brien", false},
+		{"Drop me a line; update the table when you can", false},
+		{"1 + 1 = 2", false},
+		{"email=alice@example.com&password=hunter2", false},
+		{"order by date", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := detectSQLi(tt.input); got != tt.want {
+				t.Errorf("detectSQLi(%q) = %t, want %t", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectSQLiLongInput(t *testing.T) {
+	// Injections past the first token batch are still found
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "benign text", input: strings.Repeat("lorem ipsum ", 1000), want: false},
+		{name: "injection at the end", input: strings.Repeat("lorem ipsum ", 1000) + "1 UNION SELECT password FROM users--", want: true},
+	}
+	// Shift an injection across the boundary of the first batch
+	for pad := sqlBatch - sqlLookahead - 5; pad <= sqlBatch+5; pad++ {
+		tests = append(tests, struct {
+			name  string
+			input string
+			want  bool
+		}{name: fmt.Sprintf("injection after %d tokens", pad), input: strings.Repeat("a ", pad) + "1 OR 1=1", want: true})
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectSQLi(tt.input); got != tt.want {
+				t.Errorf("detectSQLi = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package waf
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Corpus labels
+const (
+	LabelMalicious = "malicious"
+	LabelBenign    = "benign"
+)
+
+// CorpusEntry is a labeled request in a test-suite corpus
+type CorpusEntry struct {
+	Name       string            `json:"name,omitempty"`
+	Label      string            `json:"label"`
+	Method     string            `json:"method"`
+	URL        string            `json:"url"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
+	RemoteAddr string            `json:"remote_addr,omitempty"`
+}
+
+// Misclassification is a corpus entry the engine decided wrongly
+type Misclassification struct {
+	Line   int
+	Entry  CorpusEntry
+	Reason string // the blocking rule, for benign requests that were blocked
+}
+
+// TestSuiteResult is the confusion matrix of a test-suite run. Malicious is the
+// positive class: a blocked attack is a true positive.
+type TestSuiteResult struct {
+	TruePositives  int
+	FalsePositives int
+	TrueNegatives  int
+	FalseNegatives int
+
+	MissedAttacks []Misclassification // malicious requests that were allowed
+	BlockedBenign []Misclassification // benign requests that were blocked
+}
+
+// Total returns the number of evaluated corpus entries
+func (r *TestSuiteResult) Total() int {
+	return r.TruePositives + r.FalsePositives + r.TrueNegatives + r.FalseNegatives
+}
+
+// Accuracy returns the fraction of entries classified correctly
+func (r *TestSuiteResult) Accuracy() float64 {
+	if r.Total() == 0 {
+		return 0
+	}
+	return float64(r.TruePositives+r.TrueNegatives) / float64(r.Total())
+}
+
+// Recall returns the fraction of malicious entries that were blocked
+func (r *TestSuiteResult) Recall() float64 {
+	if r.TruePositives+r.FalseNegatives == 0 {
+		return 0
+	}
+	return float64(r.TruePositives) / float64(r.TruePositives+r.FalseNegatives)
+}
+
+// Precision returns the fraction of blocked entries that were malicious
+func (r *TestSuiteResult) Precision() float64 {
+	if r.TruePositives+r.FalsePositives == 0 {
+		return 0
+	}
+	return float64(r.TruePositives) / float64(r.TruePositives+r.FalsePositives)
+}
+
+// LoadCorpus reads a JSONL corpus of labeled requests. Blank lines are skipped.
+func LoadCorpus(path string) ([]CorpusEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+	defer file.Close()
+
+	var entries []CorpusEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry CorpusEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		entry.Label = strings.ToLower(entry.Label)
+		if entry.Label != LabelMalicious && entry.Label != LabelBenign {
+			return nil, fmt.Errorf("line %d: invalid label %q (expected %q or %q)", lineNum, entry.Label, LabelMalicious, LabelBenign)
+		}
+		if entry.Method == "" {
+			entry.Method = http.MethodGet
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+
+	return entries, nil
+}
+
+// RunTestSuite runs every corpus entry through the engine and tallies the
+// decisions against the labels
+func (e *Engine) RunTestSuite(entries []CorpusEntry) (*TestSuiteResult, error) {
+	result := &TestSuiteResult{}
+
+	for i, entry := range entries {
+		req, err := http.NewRequest(entry.Method, entry.URL, strings.NewReader(entry.Body))
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		for key, value := range entry.Headers {
+			req.Header.Set(key, value)
+		}
+		req.RequestURI = entry.URL
+		req.RemoteAddr = entry.RemoteAddr
+
+		decision, reason := e.Check(req)
+		blocked := decision == DecisionBlock
+		miss := Misclassification{Line: i + 1, Entry: entry, Reason: reason}
+
+		switch {
+		case entry.Label == LabelMalicious && blocked:
+			result.TruePositives++
+		case entry.Label == LabelMalicious:
+			result.FalseNegatives++
+			result.MissedAttacks = append(result.MissedAttacks, miss)
+		case blocked:
+			result.FalsePositives++
+			result.BlockedBenign = append(result.BlockedBenign, miss)
+		default:
+			result.TrueNegatives++
+		}
+	}
+
+	return result, nil
+}
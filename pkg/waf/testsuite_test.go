@@ -0,0 +1,61 @@
+package waf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+// testCorpus holds one request for each cell of the confusion matrix
+const testCorpus = `{"name": "sqli", "label": "malicious", "method": "POST", "url": "/login", "body": "user=admin' OR 1=1--"}
+{"name": "ldap filter", "label": "Malicious", "url": "/users?filter=*)(uid=*))(|(uid=*"}
+
+{"name": "home", "label": "benign", "url": "/"}
+{"name": "relative asset", "label": "benign", "url": "/static/../css/site.css"}
+`
+
+func writeCorpus(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "corpus.jsonl")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunTestSuite(t *testing.T) {
+	entries, err := LoadCorpus(writeCorpus(t, testCorpus))
+	if err != nil {
+		t.Fatalf("LoadCorpus: %v", err)
+	}
+	if len(entries) != 4 || entries[1].Label != LabelMalicious || entries[1].Method != "GET" {
+		t.Fatalf("entries %+v, want 4 with normalized labels and methods", entries)
+	}
+
+	result, err := newTestEngine(t, &config.Config{}).RunTestSuite(entries)
+	if err != nil {
+		t.Fatalf("RunTestSuite: %v", err)
+	}
+
+	if result.TruePositives != 1 || result.FalseNegatives != 1 || result.FalsePositives != 1 || result.TrueNegatives != 1 {
+		t.Errorf("matrix TP=%d FN=%d FP=%d TN=%d, want 1 each",
+			result.TruePositives, result.FalseNegatives, result.FalsePositives, result.TrueNegatives)
+	}
+	if result.Accuracy() != 0.5 || result.Recall() != 0.5 || result.Precision() != 0.5 {
+		t.Errorf("accuracy %v, recall %v, precision %v, want 0.5 each", result.Accuracy(), result.Recall(), result.Precision())
+	}
+	if len(result.MissedAttacks) != 1 || result.MissedAttacks[0].Entry.Name != "ldap filter" {
+		t.Errorf("missed attacks %+v, want the ldap filter", result.MissedAttacks)
+	}
+	if len(result.BlockedBenign) != 1 || result.BlockedBenign[0].Entry.Name != "relative asset" || result.BlockedBenign[0].Reason == "" {
+		t.Errorf("blocked benign %+v, want the relative asset with its rule", result.BlockedBenign)
+	}
+}
+
+func TestLoadCorpusInvalidLabel(t *testing.T) {
+	if _, err := LoadCorpus(writeCorpus(t, `{"label": "suspicious", "url": "/"}`)); err == nil {
+		t.Error("LoadCorpus accepted an unknown label")
+	}
+}
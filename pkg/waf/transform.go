@@ -0,0 +1,69 @@
+package waf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Transformation names usable in Rule.Transformations
+const (
+	TransformURLDecode          = "url_decode"
+	TransformBase64Decode       = "base64_decode"
+	TransformHexDecode          = "hex_decode"
+	TransformHTMLDecode         = "html_decode"
+	TransformLowercase          = "lowercase"
+	TransformCompressWhitespace = "compress_whitespace"
+)
+
+// transformations maps each transformation name to its function. Decoding
+// transformations leave input that is not encoded that way unchanged.
+var transformations = map[string]func(string) string{
+	TransformURLDecode:          decodeOrKeep(decodeURL),
+	TransformBase64Decode:       decodeOrKeep(decodeBase64),
+	TransformHexDecode:          decodeOrKeep(decodeHex),
+	TransformHTMLDecode:         decodeOrKeep(decodeHTML),
+	TransformLowercase:          strings.ToLower,
+	TransformCompressWhitespace: compressWhitespace,
+}
+
+// decodeOrKeep adapts a decoder into a transformation that returns its input
+// when it does not decode
+func decodeOrKeep(decode func(string) (string, bool)) func(string) string {
+	return func(s string) string {
+		if decoded, ok := decode(s); ok {
+			return decoded
+		}
+		return s
+	}
+}
+
+// compressWhitespace collapses runs of whitespace into a single space
+func compressWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// validateTransformations checks that every named transformation is known
+func validateTransformations(names []string) error {
+	for _, name := range names {
+		if _, ok := transformations[name]; !ok {
+			known := make([]string, 0, len(transformations))
+			for k := range transformations {
+				known = append(known, k)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown transformation %q (valid: %s)", name, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// applyTransformations runs data through the named transformations in order
+func applyTransformations(data string, names []string) string {
+	for _, name := range names {
+		if transform, ok := transformations[name]; ok {
+			data = transform(data)
+		}
+	}
+	return data
+}
@@ -0,0 +1,72 @@
+package waf
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/shieldcli/shieldcli/pkg/config"
+)
+
+func TestTransformationsBeforeMatching(t *testing.T) {
+	tests := []struct {
+		name            string
+		transformations []string
+		wantDecision    Decision
+	}{
+		{name: "without url_decode", wantDecision: DecisionAllow},
+		{name: "with url_decode", transformations: []string{"url_decode"}, wantDecision: DecisionBlock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newTestEngine(t, &config.Config{CustomRules: []config.CustomRule{{
+				ID: 9700, Name: "Script Tag", Phase: "request_uri", Operator: "contains", Target: "REQUEST_URI",
+				Pattern: "<script>", Action: "block", Severity: "high", Enabled: true, Transformations: tt.transformations,
+			}}})
+
+			r := httptest.NewRequest("GET", "/search?q=%3Cscript%3Ealert(1)", nil)
+			if decision, reason := engine.Check(r); decision != tt.wantDecision {
+				t.Errorf("decision %v (%s), want %v", decision, reason, tt.wantDecision)
+			}
+		})
+	}
+}
+
+func TestRuleTransformations(t *testing.T) {
+	tests := []struct {
+		name            string
+		transformations []string
+		pattern         string
+		data            string
+	}{
+		{name: "base64_decode", transformations: []string{"base64_decode"}, pattern: "<script>", data: "PHNjcmlwdD4="},
+		{name: "hex_decode", transformations: []string{"hex_decode"}, pattern: "<script>", data: "3c7363726970743e"},
+		{name: "html_decode", transformations: []string{"html_decode"}, pattern: "<script>", data: "&lt;script&gt;"},
+		{name: "lowercase", transformations: []string{"lowercase"}, pattern: "<script>", data: "<ScRiPt>"},
+		{name: "compress_whitespace", transformations: []string{"compress_whitespace"}, pattern: "union select", data: "union \t\n  select"},
+		{name: "in order", transformations: []string{"url_decode", "html_decode", "lowercase"}, pattern: "<script>", data: "%26lt%3BSCRIPT%26gt%3B"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &Rule{Operator: OpContains, Pattern: tt.pattern, Enabled: true}
+			if rule.Match(tt.data) {
+				t.Fatalf("%q matched without transformations", tt.data)
+			}
+			rule.Transformations = tt.transformations
+			if err := rule.Compile(); err != nil {
+				t.Fatalf("Compile: %v", err)
+			}
+			if !rule.Match(tt.data) {
+				t.Errorf("%q not matched after %v", tt.data, tt.transformations)
+			}
+		})
+	}
+}
+
+func TestUnknownTransformation(t *testing.T) {
+	rule := &Rule{Operator: OpContains, Pattern: "x", Transformations: []string{"url_decode", "rot13"}}
+	if err := rule.Compile(); err == nil {
+		t.Error("Compile accepted an unknown transformation")
+	}
+}
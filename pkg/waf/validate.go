@@ -0,0 +1,99 @@
+package waf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Known values for each rule field
+var (
+	validPhases = []RulePhase{
+		PhaseRequestHeaders, PhaseRequestURI, PhaseRequestBody,
+		PhaseResponseHeaders, PhaseResponseBody,
+	}
+	validOperators = []RuleOperator{
+		OpContains, OpRegex, OpStartsWith, OpEndsWith, OpEquals,
+		OpNotContains, OpNotRegex, OpHighEntropy, OpSQLi, OpXSS,
+	}
+	validActions    = []RuleAction{ActionBlock, ActionLog, ActionPass}
+	validSeverities = []string{"low", "medium", "high", "critical"}
+	validTargets    = []string{
+		"REQUEST_URI", "REQUEST_HEADERS", "REQUEST_HEADERS:<name>", "REQUEST_BODY", "ARGS", "QUERY_STRING",
+		"RESPONSE_HEADERS", "RESPONSE_HEADERS:<name>", "RESPONSE_BODY",
+	}
+)
+
+// ParsePhase normalizes and validates a rule phase
+func ParsePhase(s string) (RulePhase, error) {
+	phase := RulePhase(strings.ToLower(strings.TrimSpace(s)))
+	for _, valid := range validPhases {
+		if phase == valid {
+			return phase, nil
+		}
+	}
+	return "", fmt.Errorf("invalid phase %q (valid: %s)", s, joinValues(validPhases))
+}
+
+// ParseOperator normalizes and validates a rule operator
+func ParseOperator(s string) (RuleOperator, error) {
+	op := RuleOperator(strings.ToLower(strings.TrimSpace(s)))
+	for _, valid := range validOperators {
+		if op == valid {
+			return op, nil
+		}
+	}
+	return "", fmt.Errorf("invalid operator %q (valid: %s)", s, joinValues(validOperators))
+}
+
+// ParseAction normalizes and validates a rule action
+func ParseAction(s string) (RuleAction, error) {
+	action := RuleAction(strings.ToLower(strings.TrimSpace(s)))
+	for _, valid := range validActions {
+		if action == valid {
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("invalid action %q (valid: %s)", s, joinValues(validActions))
+}
+
+// ParseSeverity normalizes and validates a rule severity
+func ParseSeverity(s string) (string, error) {
+	severity := strings.ToLower(strings.TrimSpace(s))
+	for _, valid := range validSeverities {
+		if severity == valid {
+			return severity, nil
+		}
+	}
+	return "", fmt.Errorf("invalid severity %q (valid: %s)", s, strings.Join(validSeverities, ", "))
+}
+
+// ParseTarget normalizes and validates a rule target. The target name is
+// upper-cased; a header name after "REQUEST_HEADERS:" or "RESPONSE_HEADERS:"
+// keeps its case.
+func ParseTarget(s string) (string, error) {
+	target := strings.TrimSpace(s)
+	name, arg, hasArg := strings.Cut(target, ":")
+	name = strings.ToUpper(name)
+
+	if hasArg {
+		if (name == "REQUEST_HEADERS" || name == "RESPONSE_HEADERS") && strings.TrimSpace(arg) != "" {
+			return name + ":" + strings.TrimSpace(arg), nil
+		}
+	} else {
+		for _, valid := range validTargets {
+			if name == valid {
+				return name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid target %q (valid: %s)", s, strings.Join(validTargets, ", "))
+}
+
+// joinValues formats a list of string-typed values for an error message
+func joinValues[T ~string](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = string(v)
+	}
+	return strings.Join(parts, ", ")
+}
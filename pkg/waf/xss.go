@@ -0,0 +1,143 @@
+package waf
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// <script> tags
+	xssScriptTagPattern = regexp.MustCompile(`<script(?:[\s/>]|$)`)
+
+	// An event handler attribute name and its equals sign, e.g. onerror=
+	xssEventHandlerPattern = regexp.MustCompile(`^on[a-z]+\s*=`)
+
+	// What follows "script" in a javascript: or vbscript: URL with code in
+	// it, as opposed to prose such as "JavaScript: the good parts"
+	xssScriptURLPattern = regexp.MustCompile("^\\s*:\\s*(?:[\\w$.\\[\\]]+\\s*[(`=]|[/(`'\"\\[\\\\])")
+
+	// What follows "data:" in a URL of a document that can run script
+	xssDataURLPattern = regexp.MustCompile(`^\s*(?:text/html|text/javascript|image/svg\+xml|application/(?:xhtml\+xml|javascript))`)
+)
+
+// xssQuoteContextLimit is how far back past whitespace an event handler
+// looks for the quote that closes an attribute value
+const xssQuoteContextLimit = 16
+
+// detectXSS reports whether data can run script when reflected into a page.
+// HTML entities are decoded first, as the browser would, so &#x3c;script&#x3e;
+// is caught. Only script contexts match: script tags, event handler
+// attributes, and javascript: and data: URLs. Bare tag names such as <img>
+// or <svg> do not, so text and markup that merely mention them pass.
+func detectXSS(data string) bool {
+	if strings.IndexByte(data, '&') >= 0 {
+		data = html.UnescapeString(data)
+	}
+	data = strings.ToLower(data)
+
+	return xssScriptTagPattern.MatchString(data) ||
+		hasEventHandler(data) ||
+		hasScriptURL(data) ||
+		hasDataURL(data)
+}
+
+// hasEventHandler reports whether data holds an event handler attribute,
+// inside a tag or after a quote that closes an attribute value:
+// <img src=x onerror=...>, <svg/onload=...> or " onfocus=...
+func hasEventHandler(data string) bool {
+	inTag := false
+	scanned := 0
+	for i := 0; ; {
+		j := strings.Index(data[i:], "on")
+		if j == -1 {
+			return false
+		}
+		j += i
+		i = j + 2
+
+		if j == 0 || !isXSSAttributeSeparator(data[j-1]) || !xssEventHandlerPattern.MatchString(data[j:]) {
+			continue
+		}
+
+		// Follow tags up to the attribute
+		for ; scanned < j; scanned++ {
+			switch data[scanned] {
+			case '<':
+				inTag = scanned+1 < len(data) && data[scanned+1] >= 'a' && data[scanned+1] <= 'z'
+			case '>':
+				inTag = false
+			}
+		}
+		if inTag {
+			return true
+		}
+
+		k := j - 1
+		for k > 0 && j-k <= xssQuoteContextLimit && (isSpaceByte(data[k]) || data[k] == '/') {
+			k--
+		}
+		if isQuoteByte(data[k]) {
+			return true
+		}
+	}
+}
+
+// hasScriptURL reports whether data holds a javascript: or vbscript: URL
+func hasScriptURL(data string) bool {
+	for i := 0; ; {
+		j := strings.Index(data[i:], "script")
+		if j == -1 {
+			return false
+		}
+		j += i
+		i = j + len("script")
+
+		// Browsers drop whitespace inside the scheme, as in java&#x09;script:
+		k := j
+		for k > 0 && j-k < xssQuoteContextLimit && isSpaceByte(data[k-1]) {
+			k--
+		}
+		if !strings.HasSuffix(data[:k], "java") && !strings.HasSuffix(data[:k], "vb") {
+			continue
+		}
+		if xssScriptURLPattern.MatchString(data[i:]) {
+			return true
+		}
+	}
+}
+
+// hasDataURL reports whether data holds a data: URL of a document that can
+// run script, e.g. in <iframe src> or <object data>
+func hasDataURL(data string) bool {
+	for i := 0; ; {
+		j := strings.Index(data[i:], "data:")
+		if j == -1 {
+			return false
+		}
+		j += i
+		i = j + len("data:")
+
+		if j > 0 && !isXSSAttributeSeparator(data[j-1]) && data[j-1] != '=' && data[j-1] != '(' {
+			continue
+		}
+		if xssDataURLPattern.MatchString(data[i:]) {
+			return true
+		}
+	}
+}
+
+// isXSSAttributeSeparator reports whether c can precede an attribute name
+func isXSSAttributeSeparator(c byte) bool {
+	return isSpaceByte(c) || c == '/' || isQuoteByte(c)
+}
+
+// isSpaceByte reports whether c is HTML whitespace
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isQuoteByte reports whether c can quote an attribute value
+func isQuoteByte(c byte) bool {
+	return c == '"' || c == '\'' || c == '`'
+}
@@ -0,0 +1,43 @@
+package waf
+
+import "testing"
+
+func TestDetectXSS(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		// Script tags and event handlers
+		{"<script>alert(1)</script>", true},
+		{"<SCRIPT SRC=//evil.example/x.js>", true},
+		{`<img src=x onerror=alert(1)>`, true},
+		{`<div onmouseenter="steal()">`, true},
+		{"<svg/onload=alert(1)>", true},
+		{`" onfocus="alert(1)" autofocus="`, true},
+		// Entity-encoded payloads are decoded first
+		{"&#x3c;script&#x3e;alert(1)&#x3c;/script&#x3e;", true},
+		{"&lt;script&gt;alert(1)&lt;/script&gt;", true},
+		{`&lt;img src=x onerror=alert(1)&gt;`, true},
+		{"<a href=\"java&#x09;script:alert(1)\">", true},
+		// Script and document URLs
+		{`<a href="javascript:alert(document.cookie)">`, true},
+		{"<iframe src=\"data:text/html;base64,PHNjcmlwdD4=\">", true},
+
+		// Markup and text that merely mention tags or handlers
+		{`<img alt="svg diagram" src="chart.png">`, false},
+		{"Use an <svg> or <img> element for icons", false},
+		{"JavaScript: the good parts", false},
+		{"The event fires on mouseenter", false},
+		{"Button text: on=off toggle", false},
+		{"data: 42 rows", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := detectXSS(tt.input); got != tt.want {
+				t.Errorf("detectXSS(%q) = %t, want %t", tt.input, got, tt.want)
+			}
+		})
+	}
+}